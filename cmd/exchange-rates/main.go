@@ -0,0 +1,58 @@
+// Command exchange-rates replaces the exchange rates salaries are converted with by a JSON rates file such as
+// {"base": "EUR", "rates": {"USD": 1.08, "INR": 90.1}}, and converts the annual salaries of all live jobs to the
+// SALARY_CURRENCY base currency again. Without -file the rates are read from standard input.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"job-portal-api/internal/database"
+	"job-portal-api/internal/money"
+	"job-portal-api/internal/services"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	file := flag.String("file", "", "JSON exchange rates file (default standard input)")
+	flag.Parse()
+
+	// Loading the environment variables file, when there is one
+	if err := godotenv.Load(".env"); err != nil && !os.IsNotExist(err) {
+		log.Panic(err)
+	}
+
+	var r io.Reader = os.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Panic(err)
+		}
+		defer f.Close()
+		r = f
+	}
+	rates, err := money.ParseRates(r)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	db, err := database.Open(database.DefaultPostgresConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+	defer db.Close()
+
+	cs, err := services.NewCurrencyService(db, services.DefaultCurrencyConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+	report, err := cs.LoadRates(context.Background(), rates)
+	if err != nil {
+		log.Panic(err)
+	}
+	json.NewEncoder(os.Stdout).Encode(report)
+}
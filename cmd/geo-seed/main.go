@@ -0,0 +1,83 @@
+// Command geo-seed replaces the offline geocoding table with GeoNames files and geocodes all live companies and
+// jobs again. Without flags it loads the sample of the larger cities of the world bundled with the API.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"job-portal-api/internal/database"
+	"job-portal-api/internal/geo"
+	"job-portal-api/internal/services"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	countriesFile := flag.String("countries", "", "GeoNames countryInfo.txt file (default the bundled countries)")
+	admin1File := flag.String("admin1", "", "GeoNames admin1CodesASCII.txt file naming the regions of -cities")
+	citiesFile := flag.String("cities", "", "GeoNames cities file, such as cities15000.txt (default the bundled cities)")
+	flag.Parse()
+
+	// Loading the environment variables file, when there is one
+	if err := godotenv.Load(".env"); err != nil && !os.IsNotExist(err) {
+		log.Panic(err)
+	}
+
+	countries, places, err := geo.DefaultDataset()
+	if err != nil {
+		log.Panic(err)
+	}
+	if *countriesFile != "" {
+		f := open(*countriesFile)
+		countries, err = geo.ParseCountries(f)
+		f.Close()
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+	if *citiesFile != "" {
+		admin1 := map[string]string{}
+		if *admin1File != "" {
+			f := open(*admin1File)
+			admin1, err = geo.ParseAdmin1(f)
+			f.Close()
+			if err != nil {
+				log.Panic(err)
+			}
+		}
+		f := open(*citiesFile)
+		places, err = geo.ParseCities(f, admin1)
+		f.Close()
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	db, err := database.Open(database.DefaultPostgresConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+	defer db.Close()
+
+	gs, err := services.NewGeoService(db)
+	if err != nil {
+		log.Panic(err)
+	}
+	report, err := gs.LoadDataset(context.Background(), countries, places)
+	if err != nil {
+		log.Panic(err)
+	}
+	json.NewEncoder(os.Stdout).Encode(report)
+}
+
+// open opens a dataset file, exiting when it cannot be read
+func open(name string) *os.File {
+	f, err := os.Open(name)
+	if err != nil {
+		log.Panic(err)
+	}
+	return f
+}
@@ -0,0 +1,49 @@
+// Command job-dedup fingerprints all live jobs and groups the near duplicates among them, so that listings can
+// collapse reposts of the same role. It uses the database settings and DUPLICATE_JOB_MAX_DISTANCE of the API.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"job-portal-api/internal/database"
+	"job-portal-api/internal/services"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report the clusters without saving them")
+	maxDistance := flag.Int("max-distance", -1, "largest fingerprint distance of near duplicates, 0 to 3 (default DUPLICATE_JOB_MAX_DISTANCE)")
+	flag.Parse()
+
+	// Loading the environment variables file, when there is one
+	if err := godotenv.Load(".env"); err != nil && !os.IsNotExist(err) {
+		log.Panic(err)
+	}
+
+	db, err := database.Open(database.DefaultPostgresConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+	defer db.Close()
+
+	cfg := services.DefaultDuplicateConfig()
+	if *maxDistance >= 0 {
+		cfg.MaxDistance = *maxDistance
+	}
+	// Clustering runs whatever the policy for new jobs is
+	cfg.Policy = services.DuplicatePolicyWarn
+	ds, err := services.NewDuplicateService(db, cfg)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	report, err := ds.ClusterJobs(context.Background(), *dryRun)
+	if err != nil {
+		log.Panic(err)
+	}
+	json.NewEncoder(os.Stdout).Encode(report)
+}
@@ -1,15 +1,27 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"job-portal-api/internal/audit"
 	"job-portal-api/internal/auth"
+	"job-portal-api/internal/careers"
 	"job-portal-api/internal/database"
 	"job-portal-api/internal/handlers"
+	"job-portal-api/internal/idempotency"
+	"job-portal-api/internal/logging"
+	"job-portal-api/internal/mailer"
 	"job-portal-api/internal/middleware"
+	"job-portal-api/internal/moderation"
+	"job-portal-api/internal/ratelimit"
 	"job-portal-api/internal/services"
+	"job-portal-api/internal/verification"
+	"job-portal-api/internal/webhooks"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt"
@@ -23,12 +35,31 @@ func main() {
 		log.Panic("Error loading .env file")
 	}
 
+	// Configure the log level, format and redacted fields
+	logging.Setup(logging.DefaultConfig())
+
 	// Create a new Chi router
 	r := chi.NewRouter()
 
+	// Tag every request with an ID and the real client IP before logging it
+	r.Use(middleware.RequestID)
+	r.Use(middleware.RealIP(middleware.TrustedProxies()))
+	r.Use(middleware.AuditMeta)
+
 	// Use custom middleware for HTTP request logging
 	r.Use(middleware.HttpLogger)
 
+	// Turn panics into 500 problem responses, inside the logger so they are logged with their status
+	r.Use(middleware.Recoverer)
+
+	// Add security headers and answer CORS preflights before routing
+	r.Use(middleware.SecurityHeaders(middleware.DefaultSecurityHeadersConfig()))
+	corsConfig := middleware.DefaultCORSConfig()
+	if err := corsConfig.Validate(); err != nil {
+		log.Panic(err)
+	}
+	r.Use(middleware.CORS(corsConfig))
+
 	cfg := database.DefaultPostgresConfig()
 	db, err := database.Open(cfg)
 	if err != nil {
@@ -42,20 +73,134 @@ func main() {
 	fmt.Println("database Connected")
 	defer db.Close()
 
+	// Set up the mailer used for account emails
+	ml := mailer.New(mailer.DefaultSMTPConfig())
+
 	// Set up user service
-	us, err := services.NewUserService(db)
+	us, err := services.NewUserService(db, ml, services.DefaultLockoutConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Set up content moderation, screening new and edited companies and jobs with the rule engine
+	ms, err := services.NewModerationService(db, moderation.NewEngine(moderation.DefaultConfig()), services.DefaultModerationConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Bound the exports streamed through database cursors, each holding a connection while the client reads
+	exportConfig := services.DefaultExportConfig()
+	cursors, err := services.NewCursors(db, exportConfig)
 	if err != nil {
 		log.Panic(err)
 	}
 
 	// Set up company service
-	cs, err := services.NewCompanyService(db)
+	retention := services.DefaultSoftDeleteRetention()
+	cs, err := services.NewCompanyService(db, retention, ms, cursors)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Set up near duplicate job detection
+	ds, err := services.NewDuplicateService(db, services.DefaultDuplicateConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Set up the skills taxonomy jobs are tagged with
+	ss, err := services.NewSkillService(db)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Set up the exchange rates salaries are converted to the SALARY_CURRENCY base currency with
+	cur, err := services.NewCurrencyService(db, services.DefaultCurrencyConfig())
 	if err != nil {
 		log.Panic(err)
 	}
 
 	// Set up job service
-	js, err := services.NewJobService(db)
+	js, err := services.NewJobService(db, retention, ms, ds, ss, cur, cursors)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Set up bulk job imports, failing async imports abandoned by a stopped instance
+	jis, err := services.NewJobImportService(db, ms, ds, ss, cur, services.DefaultJobImportConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+	go jis.RunStaleCheck(context.Background(), time.Minute)
+
+	// Build the job-board XML feed, rebuilding it on job changes and at least every AGGREGATOR_FEED_INTERVAL_MINUTES
+	agg, err := services.NewAggregatorService(js, services.DefaultAggregatorConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+	go agg.Run(context.Background(), time.Minute)
+
+	// Build the sitemaps of public job pages, rebuilding them on job changes and at least every SITEMAP_INTERVAL_MINUTES
+	sitemaps, err := services.NewSitemapService(js, services.DefaultSitemapConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+	go sitemaps.Run(context.Background(), time.Minute)
+
+	// Permanently delete companies and jobs once they have been soft deleted for longer than the retention period
+	go cs.RunPurge(context.Background(), time.Hour)
+
+	// Set up the audit log and purge events past the retention period once a day
+	as, err := audit.NewService(db)
+	if err != nil {
+		log.Panic(err)
+	}
+	retentionDays, err := strconv.Atoi(os.Getenv("AUDIT_RETENTION_DAYS"))
+	if err != nil || retentionDays <= 0 {
+		retentionDays = 365
+	}
+	go as.RunRetention(context.Background(), time.Duration(retentionDays)*24*time.Hour, 24*time.Hour)
+
+	// Set up webhooks, start delivering outbox events to them and purge dispatched events once a day
+	sender := webhooks.NewSender(10 * time.Second)
+	ws, err := services.NewWebhookService(db, sender)
+	if err != nil {
+		log.Panic(err)
+	}
+	dispatcher, err := webhooks.NewDispatcher(db, sender, webhooks.DefaultDispatcherConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+	go dispatcher.Run(context.Background(), 5*time.Second)
+	go dispatcher.RunRetention(context.Background(), 24*time.Hour)
+
+	// Set up company reviews
+	rvs, err := services.NewReviewService(db)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Set up company verification, checking DNS records with the system resolver
+	vs, err := services.NewVerificationService(db, ml, verification.DefaultResolver(), services.DefaultVerificationConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Set up candidate profiles, job recommendations and suggested candidates
+	recs, err := services.NewRecommendationService(db, cur)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Set up salary insights and compute their aggregates every SALARY_INSIGHTS_REFRESH_MINUTES
+	ins, err := services.NewInsightService(db, services.DefaultInsightConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+	go ins.RunRefresh(context.Background())
+
+	// Set up interview scheduling, emailing iCalendar invitations to both sides
+	ivs, err := services.NewInterviewService(db, ml, services.DefaultInterviewConfig())
 	if err != nil {
 		log.Panic(err)
 	}
@@ -92,8 +237,50 @@ func main() {
 		log.Panic(err)
 	}
 
+	// Setup rate limiting, shared through Postgres when running more than one instance
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if os.Getenv("RATE_LIMIT_BACKEND") == "postgres" {
+		pgStore, err := ratelimit.NewPostgresStore(db)
+		if err != nil {
+			log.Panic(err)
+		}
+		go pgStore.RunPurge(context.Background(), time.Hour)
+		store = pgStore
+	}
+	rl, err := middleware.NewLimiter(store)
+	if err != nil {
+		log.Panic(err)
+	}
+	loginLimit := middleware.RateLimitPolicy{
+		IP:      ratelimit.NewPolicy("login-ip", 20, time.Minute),
+		Email:   ratelimit.NewPolicy("login-email", 5, time.Minute),
+		ByEmail: true,
+	}
+	registerLimit := middleware.RateLimitPolicy{
+		IP: ratelimit.NewPolicy("register-ip", 5, time.Hour),
+	}
+	unlockLimit := middleware.RateLimitPolicy{
+		IP:      ratelimit.NewPolicy("unlock-ip", 10, time.Hour),
+		Email:   ratelimit.NewPolicy("unlock-email", 3, time.Hour),
+		ByEmail: true,
+	}
+	publicLimit := middleware.RateLimitPolicy{
+		IP: ratelimit.NewPolicy("public-ip", 120, time.Minute),
+	}
+
+	// Setup idempotency keys for POST endpoints that create resources
+	idemStore, err := idempotency.NewStore(db)
+	if err != nil {
+		log.Panic(err)
+	}
+	idem, err := middleware.NewIdempotency(idemStore, middleware.IdempotencyTTL())
+	if err != nil {
+		log.Panic(err)
+	}
+	go idemStore.RunPurge(context.Background(), time.Hour)
+
 	// Create handlers for user, company, and job operations
-	usersC, err := handlers.NewUsers(us, a)
+	usersC, err := handlers.NewUsers(us, a, auth.DefaultCookieConfig())
 	if err != nil {
 		log.Panic(err)
 	}
@@ -105,12 +292,70 @@ func main() {
 	if err != nil {
 		log.Panic(err)
 	}
-	
-	r.Post("/api/register", usersC.CreateUser)
+	importC, err := handlers.NewJobImport(jis)
+	if err != nil {
+		log.Panic(err)
+	}
+	publicC, err := handlers.NewPublic(js, agg, sitemaps, handlers.DefaultPublicConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+	careersC, err := handlers.NewCareers(cs, js)
+	if err != nil {
+		log.Panic(err)
+	}
+	webhookC, err := handlers.NewWebhook(ws)
+	if err != nil {
+		log.Panic(err)
+	}
+	auditC, err := handlers.NewAudit(as)
+	if err != nil {
+		log.Panic(err)
+	}
+	verificationC, err := handlers.NewVerification(vs)
+	if err != nil {
+		log.Panic(err)
+	}
+	reviewC, err := handlers.NewReview(rvs)
+	if err != nil {
+		log.Panic(err)
+	}
+	moderationC, err := handlers.NewModeration(ms)
+	if err != nil {
+		log.Panic(err)
+	}
+	recommendationC, err := handlers.NewRecommendation(recs)
+	if err != nil {
+		log.Panic(err)
+	}
+	skillC, err := handlers.NewSkill(ss)
+	if err != nil {
+		log.Panic(err)
+	}
+	insightC, err := handlers.NewInsight(ins)
+	if err != nil {
+		log.Panic(err)
+	}
+	currencyC, err := handlers.NewCurrency(cur)
+	if err != nil {
+		log.Panic(err)
+	}
+	interviewC, err := handlers.NewInterview(ivs)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	r.Post("/api/register", rl.RateLimit(idem.Idempotent(usersC.CreateUser), registerLimit))
+
+	r.Post("/api/login", rl.RateLimit(usersC.ProcessLoginIn, loginLimit))
 
-	r.Post("/api/login", usersC.ProcessLoginIn)
+	r.Post("/api/unlock/request", rl.RateLimit(usersC.RequestUnlock, unlockLimit))
 
-	r.Post("/api/companies", m.JWTMiddlewareCookie(companyC.CreateCompany, auth.Admin))
+	r.Post("/api/unlock", rl.RateLimit(usersC.Unlock, unlockLimit))
+
+	r.Get("/api/csrf", usersC.CSRFToken)
+
+	r.Post("/api/companies", m.JWTMiddlewareCookie(idem.Idempotent(companyC.CreateCompany), auth.Admin))
 
 	r.Get("/api/companies/user", m.JWTMiddlewareCookie(companyC.GetCompanyByUserID, auth.Admin))
 
@@ -118,14 +363,22 @@ func main() {
 
 	r.Get("/api/companies/{id}", m.JWTMiddlewareCookie(companyC.GetCompanyByID, auth.User))
 
-	r.Post("/api/companies/{id}/jobs", m.JWTMiddlewareCookie(jobC.CreateJob, auth.Admin))
+	r.Post("/api/companies/{id}/logo", m.JWTMiddlewareCookie(companyC.UploadLogo, auth.Admin))
+
+	r.Post("/api/companies/{id}/jobs", m.JWTMiddlewareCookie(idem.Idempotent(jobC.CreateJob), auth.Admin))
 
 	r.Get("/api/companies/{id}/jobs", m.JWTMiddlewareCookie(jobC.GetJobByCompanyID, auth.User))
 
+	r.Post("/api/companies/{id}/jobs/import", m.JWTMiddlewareCookie(importC.ImportJobs, auth.Admin))
+
+	r.Get("/api/companies/{id}/jobs/imports/{importID}", m.JWTMiddlewareCookie(importC.GetImport, auth.Admin))
+
 	r.Delete("/api/companies/user/{id}", m.JWTMiddlewareCookie(companyC.DeleteCompanyByUserID, auth.Admin))
 
 	r.Patch("/api/companies/user/{id}", m.JWTMiddlewareCookie(companyC.UpdateCompanyByUserID, auth.Admin))
 
+	r.Post("/api/companies/user/{id}/restore", m.JWTMiddlewareCookie(companyC.RestoreCompanyByUserID, auth.Admin))
+
 	r.Get("/api/jobs", m.JWTMiddlewareCookie(jobC.GetAllJob, auth.User))
 
 	r.Get("/api/jobs/{id}", m.JWTMiddlewareCookie(jobC.GetJobByID, auth.User))
@@ -134,5 +387,153 @@ func main() {
 
 	r.Patch("/api/jobs/user/{id}", m.JWTMiddlewareCookie(jobC.UpdateJobByUserID, auth.Admin))
 
-	http.ListenAndServe(":3030", r)
+	r.Post("/api/jobs/user/{id}/restore", m.JWTMiddlewareCookie(jobC.RestoreJobByUserID, auth.Admin))
+
+	r.Post("/api/companies/{id}/webhooks", m.JWTMiddlewareCookie(webhookC.CreateWebhook, auth.Admin))
+
+	r.Get("/api/companies/{id}/webhooks", m.JWTMiddlewareCookie(webhookC.GetWebhooks, auth.Admin))
+
+	r.Post("/api/companies/{id}/webhooks/{webhookID}/test", m.JWTMiddlewareCookie(webhookC.TestWebhook, auth.Admin))
+
+	r.Get("/api/companies/{id}/webhooks/{webhookID}/deliveries", m.JWTMiddlewareCookie(webhookC.GetDeliveries, auth.Admin))
+
+	r.Post("/api/companies/{id}/webhooks/{webhookID}/deliveries/{deliveryID}/replay", m.JWTMiddlewareCookie(webhookC.ReplayDelivery, auth.Admin))
+
+	r.Get("/api/audit", m.JWTMiddlewareCookie(auditC.ListEvents, auth.Operator))
+
+	// Company verification by owners and review by platform operators
+	r.Post("/api/companies/{id}/verifications", m.JWTMiddlewareCookie(verificationC.StartVerification, auth.Admin))
+
+	r.Get("/api/companies/{id}/verifications", m.JWTMiddlewareCookie(verificationC.GetVerifications, auth.Admin))
+
+	r.Post("/api/companies/{id}/verifications/{verificationID}/confirm", m.JWTMiddlewareCookie(verificationC.ConfirmVerification, auth.Admin))
+
+	r.Post("/api/companies/{id}/disputes", m.JWTMiddlewareCookie(verificationC.RaiseDispute, auth.User))
+
+	r.Get("/api/verifications", m.JWTMiddlewareCookie(verificationC.ListVerifications, auth.Operator))
+
+	r.Post("/api/verifications/{id}/approve", m.JWTMiddlewareCookie(verificationC.ApproveVerification, auth.Operator))
+
+	r.Post("/api/verifications/{id}/reject", m.JWTMiddlewareCookie(verificationC.RejectVerification, auth.Operator))
+
+	r.Get("/api/disputes", m.JWTMiddlewareCookie(verificationC.ListDisputes, auth.Operator))
+
+	r.Post("/api/disputes/{id}/uphold", m.JWTMiddlewareCookie(verificationC.UpholdDispute, auth.Operator))
+
+	r.Post("/api/disputes/{id}/dismiss", m.JWTMiddlewareCookie(verificationC.DismissDispute, auth.Operator))
+
+	// Company reviews by candidates, moderated by platform operators
+	r.Post("/api/companies/{id}/reviews", m.JWTMiddlewareCookie(reviewC.CreateReview, auth.User))
+
+	r.Get("/api/companies/{id}/reviews", m.JWTMiddlewareCookie(reviewC.GetReviews, auth.User))
+
+	r.Put("/api/companies/{id}/reviews/mine", m.JWTMiddlewareCookie(reviewC.UpdateReview, auth.User))
+
+	r.Delete("/api/companies/{id}/reviews/mine", m.JWTMiddlewareCookie(reviewC.DeleteReview, auth.User))
+
+	r.Post("/api/companies/{id}/reviews/{reviewID}/response", m.JWTMiddlewareCookie(reviewC.RespondToReview, auth.Admin))
+
+	r.Get("/api/reviews", m.JWTMiddlewareCookie(reviewC.ListReviews, auth.Operator))
+
+	r.Post("/api/reviews/{id}/approve", m.JWTMiddlewareCookie(reviewC.ApproveReview, auth.Operator))
+
+	r.Post("/api/reviews/{id}/reject", m.JWTMiddlewareCookie(reviewC.RejectReview, auth.Operator))
+
+	// Content moderation: abuse reports by users, the review queue and suspensions handled by platform operators
+	r.Post("/api/jobs/{id}/reports", m.JWTMiddlewareCookie(moderationC.ReportJob, auth.User))
+
+	r.Post("/api/companies/{id}/reports", m.JWTMiddlewareCookie(moderationC.ReportCompany, auth.User))
+
+	r.Get("/api/companies/{id}/moderation", m.JWTMiddlewareCookie(moderationC.GetCompanyItems, auth.Admin))
+
+	r.Get("/api/moderation", m.JWTMiddlewareCookie(moderationC.ListQueue, auth.Operator))
+
+	r.Post("/api/moderation/{id}/approve", m.JWTMiddlewareCookie(moderationC.ApproveItem, auth.Operator))
+
+	r.Post("/api/moderation/{id}/reject", m.JWTMiddlewareCookie(moderationC.RejectItem, auth.Operator))
+
+	r.Post("/api/users/{id}/unsuspend", m.JWTMiddlewareCookie(moderationC.Unsuspend, auth.Operator))
+
+	// Candidate profiles, applications and recommendations
+	r.Get("/api/me/profile", m.JWTMiddlewareCookie(recommendationC.GetProfile, auth.User))
+
+	r.Put("/api/me/profile", m.JWTMiddlewareCookie(recommendationC.SaveProfile, auth.User))
+
+	r.Get("/api/me/recommended-jobs", m.JWTMiddlewareCookie(recommendationC.RecommendedJobs, auth.User))
+
+	r.Post("/api/jobs/{id}/applications", m.JWTMiddlewareCookie(jobC.ApplyToJob, auth.User))
+
+	r.Get("/api/jobs/{id}/suggested-candidates", m.JWTMiddlewareCookie(recommendationC.SuggestedCandidates, auth.Admin))
+
+	// Skills taxonomy, curated by platform operators
+	r.Get("/api/skills", m.JWTMiddlewareCookie(skillC.ListSkills, auth.User))
+
+	r.Post("/api/skills/{slug}/aliases", m.JWTMiddlewareCookie(skillC.AddAlias, auth.Operator))
+
+	r.Delete("/api/skills/aliases/{id}", m.JWTMiddlewareCookie(skillC.DeleteAlias, auth.Operator))
+
+	// Market statistics
+	r.Get("/api/insights/salaries", m.JWTMiddlewareCookie(insightC.SalaryInsights, auth.User))
+
+	// Exchange rates salaries are converted with
+	r.Get("/api/exchange-rates", m.JWTMiddlewareCookie(currencyC.ListRates, auth.User))
+
+	r.Put("/api/exchange-rates", m.JWTMiddlewareCookie(currencyC.LoadRates, auth.Operator))
+
+	// Interview slots recruiters publish and interviews candidates book in them
+	r.Post("/api/jobs/{id}/interview-slots", m.JWTMiddlewareCookie(interviewC.CreateSlots, auth.Admin))
+
+	r.Get("/api/jobs/{id}/interview-slots", m.JWTMiddlewareCookie(interviewC.ListSlots, auth.User))
+
+	r.Delete("/api/interview-slots/{id}", m.JWTMiddlewareCookie(interviewC.DeleteSlot, auth.Admin))
+
+	r.Post("/api/jobs/{id}/interviews", m.JWTMiddlewareCookie(interviewC.BookInterview, auth.User))
+
+	r.Get("/api/me/interviews", m.JWTMiddlewareCookie(interviewC.ListInterviews, auth.User))
+
+	r.Get("/api/interviews/{id}", m.JWTMiddlewareCookie(interviewC.GetInterview, auth.User))
+
+	r.Post("/api/interviews/{id}/reschedule", m.JWTMiddlewareCookie(interviewC.RescheduleInterview, auth.User))
+
+	r.Post("/api/interviews/{id}/cancel", m.JWTMiddlewareCookie(interviewC.CancelInterview, auth.User))
+
+	r.Get("/api/interviews/{id}/invite.ics", m.JWTMiddlewareCookie(interviewC.Invitation, auth.User))
+
+	// Public pages and feeds for search engines and aggregators, no authentication, rate limited per client IP
+	r.Get("/feeds/jobs.rss", rl.RateLimit(publicC.JobsRSS, publicLimit))
+
+	r.Get("/feeds/jobs.atom", rl.RateLimit(publicC.JobsAtom, publicLimit))
+
+	r.Get("/feeds/companies/{id}/jobs.rss", rl.RateLimit(publicC.CompanyJobsRSS, publicLimit))
+
+	r.Get("/feeds/companies/{id}/jobs.atom", rl.RateLimit(publicC.CompanyJobsAtom, publicLimit))
+
+	r.Get("/public/jobs/{id}", rl.RateLimit(publicC.GetJob, publicLimit))
+
+	r.Get("/public/companies/{id}/logo/{size}.png", rl.RateLimit(companyC.GetLogo, publicLimit))
+
+	r.Get("/sitemap.xml", rl.RateLimit(publicC.Sitemap, publicLimit))
+
+	r.Get("/sitemap-{page}.xml", rl.RateLimit(publicC.SitemapPage, publicLimit))
+
+	r.Get("/feeds/aggregator.xml", rl.RateLimit(publicC.AggregatorFeed, publicLimit))
+
+	// Server-rendered company careers pages, no authentication, rate limited per client IP
+	r.Get("/careers/{companySlug}", rl.RateLimit(careersC.CompanyPage, publicLimit))
+
+	r.Get("/careers/{companySlug}/jobs/{id}", rl.RateLimit(careersC.JobPage, publicLimit))
+
+	r.Handle(careers.StaticPrefix+"*", careersC.Static())
+
+	// Bound how long clients may take to send and read, so slow ones cannot hold connections open. Exports are
+	// cancelled at the export timeout, before the write timeout cuts them off.
+	srv := &http.Server{
+		Addr:              ":3030",
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       2 * time.Minute,
+		WriteTimeout:      exportConfig.Timeout + time.Minute,
+		IdleTimeout:       2 * time.Minute,
+	}
+	log.Panic(srv.ListenAndServe())
 }
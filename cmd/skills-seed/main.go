@@ -0,0 +1,55 @@
+// Command skills-seed loads a skills taxonomy seed file into the database and tags all live jobs again with the
+// skills named in their roles. Without -file it loads the skills bundled with the API.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"job-portal-api/internal/database"
+	"job-portal-api/internal/services"
+	"job-portal-api/internal/taxonomy"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	file := flag.String("file", "", "seed file to load, a JSON array of skills (default the bundled skills)")
+	flag.Parse()
+
+	// Loading the environment variables file, when there is one
+	if err := godotenv.Load(".env"); err != nil && !os.IsNotExist(err) {
+		log.Panic(err)
+	}
+
+	seed, err := taxonomy.DefaultSeed()
+	if *file != "" {
+		f, openErr := os.Open(*file)
+		if openErr != nil {
+			log.Panic(openErr)
+		}
+		seed, err = taxonomy.ParseSeed(f)
+		f.Close()
+	}
+	if err != nil {
+		log.Panic(err)
+	}
+
+	db, err := database.Open(database.DefaultPostgresConfig())
+	if err != nil {
+		log.Panic(err)
+	}
+	defer db.Close()
+
+	ss, err := services.NewSkillService(db)
+	if err != nil {
+		log.Panic(err)
+	}
+	report, err := ss.LoadSeed(context.Background(), seed)
+	if err != nil {
+		log.Panic(err)
+	}
+	json.NewEncoder(os.Stdout).Encode(report)
+}
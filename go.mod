@@ -2,7 +2,11 @@ module job-portal-api
 
 go 1.21.1
 
-require github.com/go-chi/chi/v5 v5.0.10
+require (
+	github.com/go-chi/chi/v5 v5.0.10
+	golang.org/x/net v0.20.0
+	golang.org/x/text v0.14.0
+)
 
 require (
 	github.com/KyleBanks/depth v1.2.1 // indirect
@@ -36,9 +40,7 @@ require (
 	github.com/xrash/smetrics v0.0.0-20231213231151-1d8dd44e695e // indirect
 	github.com/yvasiyarov/swagger v0.0.0-20180817222219-39eb437316e9 // indirect
 	golang.org/x/crypto v0.18.0 // indirect
-	golang.org/x/net v0.20.0 // indirect
 	golang.org/x/sync v0.6.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
 	golang.org/x/tools v0.17.0 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/protobuf v1.31.0 // indirect
@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
+)
+
+// SystemActor is the actor ID recorded for mutations made by background workers
+const SystemActor = 0
+
+// Entity types recorded in the audit log
+const (
+	EntityCompany             = "company"
+	EntityJob                 = "job"
+	EntityCompanyVerification = "company_verification"
+	EntityCompanyDispute      = "company_dispute"
+	EntityCompanyReview       = "company_review"
+	EntityModerationItem      = "moderation_item"
+	EntityUser                = "user"
+	EntitySkillAlias          = "skill_alias"
+	EntityInterviewSlot       = "interview_slot"
+	EntityInterview           = "interview"
+)
+
+// Meta holds request details recorded with every audit event
+type Meta struct {
+	RequestID string
+	IP        string
+}
+
+// metaKey is the context key for Meta
+type metaKey struct{}
+
+// WithMeta returns a copy of ctx carrying the request details to record with audit events
+func WithMeta(ctx context.Context, m Meta) context.Context {
+	return context.WithValue(ctx, metaKey{}, m)
+}
+
+// MetaFromContext returns the request details stored with WithMeta
+func MetaFromContext(ctx context.Context) Meta {
+	m, _ := ctx.Value(metaKey{}).(Meta)
+	return m
+}
+
+// Event is a single administrative mutation to record
+type Event struct {
+	ActorID    int             // ActorID is the user performing the mutation
+	Action     string          // Action is one of the Action constants
+	EntityType string          // EntityType is one of the Entity constants
+	EntityID   int             // EntityID is the ID of the mutated row
+	Before     json.RawMessage // Before is the row before the mutation, nil on create
+	After      json.RawMessage // After is the row after the mutation, nil on delete
+}
+
+// Record appends an event to the audit log. It must be called with the transaction performing the
+// mutation so that the event is only stored if the mutation commits.
+func Record(ctx context.Context, tx *sql.Tx, ev Event) error {
+	changes, err := Diff(ev.Before, ev.After)
+	if err != nil {
+		return fmt.Errorf("record audit event: %w", err)
+	}
+
+	m := MetaFromContext(ctx)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_events (actor_id, action, entity_type, entity_id, before, after, changes, request_id, ip)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		ev.ActorID, ev.Action, ev.EntityType, ev.EntityID,
+		nullJSON(ev.Before), nullJSON(ev.After), changes, m.RequestID, m.IP)
+	if err != nil {
+		return fmt.Errorf("record audit event: %w", err)
+	}
+	return nil
+}
+
+// Change is the old and new value of a single field
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Diff returns a JSON object mapping every field that differs between two JSON objects to its change.
+// Either side may be nil, in which case every field of the other side is reported.
+func Diff(before, after json.RawMessage) ([]byte, error) {
+	var b, a map[string]interface{}
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &b); err != nil {
+			return nil, fmt.Errorf("diff: %w", err)
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &a); err != nil {
+			return nil, fmt.Errorf("diff: %w", err)
+		}
+	}
+
+	changes := make(map[string]Change)
+	for k, v := range b {
+		if w, ok := a[k]; !ok || !reflect.DeepEqual(v, w) {
+			changes[k] = Change{From: v, To: a[k]}
+		}
+	}
+	for k, w := range a {
+		if _, ok := b[k]; !ok {
+			changes[k] = Change{From: nil, To: w}
+		}
+	}
+	return json.Marshal(changes)
+}
+
+// nullJSON converts an empty JSON document to a SQL NULL
+func nullJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return []byte(raw)
+}
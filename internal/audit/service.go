@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// StoredEvent is an audit event as returned by the query API
+type StoredEvent struct {
+	ID         int64           `json:"id"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	ActorID    int             `json:"actorId"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entityType"`
+	EntityID   int             `json:"entityId"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	Changes    json.RawMessage `json:"changes"`
+	RequestID  string          `json:"requestId"`
+	IP         string          `json:"ip"`
+}
+
+// Filter restricts the events returned by List. Zero values are ignored.
+type Filter struct {
+	ActorID    int
+	EntityType string
+	EntityID   int
+	From       time.Time
+	To         time.Time
+	BeforeID   int64 // BeforeID returns events older than this ID, for paging
+	Limit      int
+}
+
+// Service reads the audit log and enforces its retention policy.
+type Service struct {
+	db *sql.DB
+}
+
+// NewService creates a new audit Service instance.
+func NewService(db *sql.DB) (*Service, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	return &Service{db: db}, nil
+}
+
+// List returns audit events matching the filter, newest first.
+func (s *Service) List(ctx context.Context, f Filter) ([]*StoredEvent, error) {
+	query := `SELECT id, occurred_at, actor_id, action, entity_type, entity_id, before, after, changes, request_id, ip
+		FROM audit_events WHERE true`
+	var args []interface{}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		query += " AND " + cond + " $" + strconv.Itoa(len(args))
+	}
+
+	if f.ActorID != 0 {
+		add("actor_id =", f.ActorID)
+	}
+	if f.EntityType != "" {
+		add("entity_type =", f.EntityType)
+	}
+	if f.EntityID != 0 {
+		add("entity_id =", f.EntityID)
+	}
+	if !f.From.IsZero() {
+		add("occurred_at >=", f.From)
+	}
+	if !f.To.IsZero() {
+		add("occurred_at <", f.To)
+	}
+	if f.BeforeID != 0 {
+		add("id <", f.BeforeID)
+	}
+	if f.Limit <= 0 || f.Limit > 500 {
+		f.Limit = 100
+	}
+	args = append(args, f.Limit)
+	query += " ORDER BY id DESC LIMIT $" + strconv.Itoa(len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*StoredEvent
+	for rows.Next() {
+		var ev StoredEvent
+		var before, after []byte
+		if err := rows.Scan(&ev.ID, &ev.OccurredAt, &ev.ActorID, &ev.Action, &ev.EntityType, &ev.EntityID,
+			&before, &after, &ev.Changes, &ev.RequestID, &ev.IP); err != nil {
+			return nil, fmt.Errorf("scan audit event: %w", err)
+		}
+		ev.Before, ev.After = before, after
+		events = append(events, &ev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate audit events: %w", err)
+	}
+	return events, nil
+}
+
+// PurgeExpired deletes events older than the retention period. The audit_events table refuses
+// deletes unless the audit.allow_purge setting is on for the transaction.
+func (s *Service) PurgeExpired(ctx context.Context, retention time.Duration) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("purge audit events: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SET LOCAL audit.allow_purge = 'on'"); err != nil {
+		return 0, fmt.Errorf("purge audit events: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM audit_events WHERE occurred_at < $1", time.Now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("purge audit events: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("purge audit events: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// RunRetention purges expired events every interval until ctx is cancelled.
+func (s *Service) RunRetention(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.PurgeExpired(ctx, retention)
+		if err != nil {
+			log.Error().Err(err).Send()
+		} else if n > 0 {
+			log.Info().Int64("deleted", n).Msg("purged expired audit events")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
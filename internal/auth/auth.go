@@ -11,10 +11,14 @@ import (
 
 // Constants for user roles
 const (
-	Admin = "admin"
-	User  = "user"
+	Admin    = "admin"
+	User     = "user"
+	Operator = "operator" // Operator is a platform operator; the role can only be granted in the database
 )
 
+// TokenTTL is how long a generated JWT token stays valid
+const TokenTTL = 50 * time.Minute
+
 // Auth struct represents the authentication module with private and public keys
 type Auth struct {
 	privateKey *rsa.PrivateKey
@@ -41,7 +45,7 @@ func (a *Auth) GenerateToken(id int, role string) (string, error) {
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "api project",
 			Subject:   strconv.Itoa(id),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(50 * time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 		Roles: role,
@@ -56,7 +60,7 @@ func (a *Auth) GenerateToken(id int, role string) (string, error) {
 	return encodedToken, nil
 }
 
-// VerifyToken verifies a JWT token and checks if the user has the required role
+// VerifyToken verifies a JWT token and checks that the user has the required role, or any role for User routes
 func (a *Auth) VerifyToken(tokenString string, requiredRole string) (*Claims, error) {
 	var c Claims
 
@@ -78,8 +82,14 @@ func (a *Auth) VerifyToken(tokenString string, requiredRole string) (*Claims, er
 		return nil, err
 	}
 
-	// If the required role is 'User', check if the user has the required role
-	if c.Roles == User {
+	// User routes are open to every role. Admin and Operator routes need exactly that role, so that neither
+	// role passes the routes of the other.
+	switch requiredRole {
+	case User:
+		if c.Roles != User && c.Roles != Admin && c.Roles != Operator {
+			return nil, errors.New("you are not authorized to perform this")
+		}
+	default:
 		if c.Roles != requiredRole {
 			return nil, errors.New("you are not authorized to perform this")
 		}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+)
+
+func TestVerifyTokenRoles(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAuth(&key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		role, required string
+		ok             bool
+	}{
+		{User, User, true},
+		{Admin, User, true},
+		{Operator, User, true},
+		{"guest", User, false},
+		{Admin, Admin, true},
+		{User, Admin, false},
+		{Operator, Admin, false},
+		{Operator, Operator, true},
+		{Admin, Operator, false},
+		{User, Operator, false},
+	}
+	for _, tt := range tests {
+		token, err := a.GenerateToken(7, tt.role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims, err := a.VerifyToken(token, tt.required)
+		if (err == nil) != tt.ok {
+			t.Errorf("VerifyToken(%s token, %s) error = %v, want ok %v", tt.role, tt.required, err, tt.ok)
+		}
+		if err == nil && (claims.Subject != "7" || claims.Roles != tt.role) {
+			t.Errorf("VerifyToken(%s token, %s) claims = %+v", tt.role, tt.required, claims)
+		}
+	}
+
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	b, _ := NewAuth(&other.PublicKey, other)
+	token, _ := b.GenerateToken(7, Admin)
+	if _, err := a.VerifyToken(token, Admin); err == nil {
+		t.Error("VerifyToken() accepted a token signed with another key")
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Names of the cookies and header used for cookie-based authentication
+const (
+	TokenCookieName = "token"
+	CSRFCookieName  = "csrf_token"
+	CSRFHeaderName  = "X-CSRF-Token"
+)
+
+// CookieConfig holds the attributes applied to authentication cookies
+type CookieConfig struct {
+	Domain   string        // Domain the cookies are scoped to, empty means the request host
+	Path     string        // Path the cookies are scoped to
+	Secure   bool          // Secure restricts the cookies to HTTPS
+	SameSite http.SameSite // SameSite controls whether cookies are sent on cross-site requests
+	TTL      time.Duration // TTL is the cookie lifetime, aligned with the JWT expiry
+}
+
+// DefaultCookieConfig returns the cookie configuration from the environment.
+// Cookies are Secure and SameSite=Lax unless configured otherwise.
+func DefaultCookieConfig() CookieConfig {
+	cfg := CookieConfig{
+		Domain:   os.Getenv("COOKIE_DOMAIN"),
+		Path:     "/",
+		Secure:   os.Getenv("COOKIE_SECURE") != "false",
+		SameSite: http.SameSiteLaxMode,
+		TTL:      TokenTTL,
+	}
+	if p := os.Getenv("COOKIE_PATH"); p != "" {
+		cfg.Path = p
+	}
+	switch strings.ToLower(os.Getenv("COOKIE_SAMESITE")) {
+	case "strict":
+		cfg.SameSite = http.SameSiteStrictMode
+	case "none":
+		// Browsers reject SameSite=None cookies that are not Secure
+		cfg.SameSite = http.SameSiteNoneMode
+		cfg.Secure = true
+	}
+	return cfg
+}
+
+// TokenCookie builds the HttpOnly cookie carrying the JWT token
+func (cfg CookieConfig) TokenCookie(token string) *http.Cookie {
+	c := cfg.cookie(TokenCookieName, token)
+	// This ensures that only the browser can access the token, not external scripts
+	c.HttpOnly = true
+	return c
+}
+
+// CSRFCookie builds the cookie carrying the CSRF token. It is readable by scripts so that
+// browser clients can echo it back in the X-CSRF-Token header.
+func (cfg CookieConfig) CSRFCookie(token string) *http.Cookie {
+	return cfg.cookie(CSRFCookieName, token)
+}
+
+// cookie builds a cookie with the configured attributes
+func (cfg CookieConfig) cookie(name, value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Domain:   cfg.Domain,
+		Path:     cfg.Path,
+		Secure:   cfg.Secure,
+		SameSite: cfg.SameSite,
+		MaxAge:   int(cfg.TTL.Seconds()),
+		Expires:  time.Now().Add(cfg.TTL),
+	}
+}
+
+// NewCSRFToken generates a random CSRF token
+func NewCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
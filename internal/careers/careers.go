@@ -0,0 +1,61 @@
+package careers
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"io/fs"
+	"job-portal-api/internal/models"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+//go:embed static
+var staticFS embed.FS
+
+// layoutData is the data of the shared header and footer
+type layoutData struct {
+	Title   string
+	Company *models.Company
+}
+
+// templates holds the careers pages, each page template sharing the layout
+var templates = template.Must(template.New("careers").Funcs(template.FuncMap{
+	"page": func(title string, c *models.Company) layoutData { return layoutData{Title: title, Company: c} },
+}).ParseFS(templateFS, "templates/*.html"))
+
+// StaticPrefix is the URL path the static assets are served under
+const StaticPrefix = "/assets/careers/"
+
+// Static returns the embedded stylesheet and images of the careers pages.
+func Static() fs.FS {
+	sub, err := fs.Sub(staticFS, "static")
+	if err != nil {
+		// The directory is embedded at build time, so this cannot happen
+		panic(err)
+	}
+	return sub
+}
+
+// ListPage is the data of a company's careers page
+type ListPage struct {
+	Company *models.Company
+	Jobs    []*models.Job
+}
+
+// JobPage is the data of a job detail page
+type JobPage struct {
+	Company *models.Company
+	Job     *models.Job
+}
+
+// RenderList writes a company's careers page listing its open jobs.
+func RenderList(w io.Writer, page ListPage) error {
+	return templates.ExecuteTemplate(w, "list.html", page)
+}
+
+// RenderJob writes a job detail page.
+func RenderJob(w io.Writer, page JobPage) error {
+	return templates.ExecuteTemplate(w, "job.html", page)
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
@@ -15,30 +17,51 @@ func Open(config PostgresConfig) (*sql.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("open: %w", err)
 	}
+
+	// Bound the pool, so slow requests queue for a connection instead of exhausting the server's
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
 	return db, nil
 }
 
 // DefaultPostgresConfig returns a default configuration for a PostgreSQL database
 func DefaultPostgresConfig() PostgresConfig {
 
-	return PostgresConfig{
-		Host:     os.Getenv("HOST"),
-		Port:     os.Getenv("PORT"),
-		User:     os.Getenv("USER"),
-		Password: os.Getenv("PASSWORD"),
-		Database: os.Getenv("DATABASE"),
-		SSLMode:  os.Getenv("SSL_MODE"),
+	cfg := PostgresConfig{
+		Host:            os.Getenv("HOST"),
+		Port:            os.Getenv("PORT"),
+		User:            os.Getenv("USER"),
+		Password:        os.Getenv("PASSWORD"),
+		Database:        os.Getenv("DATABASE"),
+		SSLMode:         os.Getenv("SSL_MODE"),
+		MaxOpenConns:    25,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+	}
+	if n, err := strconv.Atoi(os.Getenv("DB_MAX_OPEN_CONNS")); err == nil && n > 0 {
+		cfg.MaxOpenConns = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("DB_MAX_IDLE_CONNS")); err == nil && n >= 0 {
+		cfg.MaxIdleConns = n
 	}
+	return cfg
 }
 
 // PostgresConfig represents the configuration parameters for a PostgreSQL database connection
 type PostgresConfig struct {
-	Host     string // Database host
-	Port     string // Database port
-	User     string // Database user
-	Password string // Database password
-	Database string // Database name
-	SSLMode  string // SSL mode for the connection
+	Host            string        // Database host
+	Port            string        // Database port
+	User            string        // Database user
+	Password        string        // Database password
+	Database        string        // Database name
+	SSLMode         string        // SSL mode for the connection
+	MaxOpenConns    int           // Most connections open at once
+	MaxIdleConns    int           // Most idle connections kept in the pool
+	ConnMaxLifetime time.Duration // How long a connection is reused before it is closed
+	ConnMaxIdleTime time.Duration // How long a connection may stay idle before it is closed
 }
 
 // String method converts the PostgresConfig to a connection string
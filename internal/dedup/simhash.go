@@ -0,0 +1,253 @@
+// Package dedup fingerprints job postings so that reposts of the same role can be found among many jobs.
+//
+// A fingerprint is a 64 bit simhash of the words of the role, the hiring company and the location. Postings that
+// only differ in word order, case, punctuation, filler words such as "urgent" or common abbreviations get the same
+// fingerprint, and small changes only flip a few bits, so near duplicates are fingerprints within a small Hamming
+// distance of each other. Unrelated postings can still get near fingerprints, so near duplicates are confirmed by
+// comparing the postings themselves with Similar.
+package dedup
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Posting is the part of a job that is fingerprinted
+type Posting struct {
+	Role     string // Role is the job title
+	Company  string // Company is the name of the hiring company
+	Location string // Location is where the job is, such as the company address
+}
+
+// Feature weights. The company weighs more than a single word of the role so that the same role at two
+// different companies is not taken for a duplicate.
+const (
+	roleWeight     = 1
+	companyWeight  = 2
+	locationWeight = 1
+)
+
+// fillerWords carry no meaning in a job title and are often added to bump a repost
+var fillerWords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "of": true, "for": true, "in": true, "at": true,
+	"to": true, "with": true, "urgent": true, "urgently": true, "hiring": true, "now": true, "immediate": true,
+	"immediately": true, "new": true, "apply": true, "today": true, "job": true, "position": true, "opening": true,
+	"wanted": true, "needed": true,
+}
+
+// abbreviations map short forms in job titles to the word they stand for
+var abbreviations = map[string]string{
+	"sr": "senior", "snr": "senior", "jr": "junior", "jnr": "junior", "dev": "developer", "devs": "developer",
+	"eng": "engineer", "engr": "engineer", "mgr": "manager", "mngr": "manager", "asst": "assistant",
+	"admin": "administrator", "exec": "executive", "ops": "operations", "qa": "quality", "swe": "software engineer",
+}
+
+// legalSuffixes are dropped from company names, so "Acme Inc." and "ACME" are the same company
+var legalSuffixes = map[string]bool{
+	"inc": true, "incorporated": true, "ltd": true, "limited": true, "llc": true, "llp": true, "plc": true,
+	"gmbh": true, "ag": true, "corp": true, "corporation": true, "co": true, "company": true, "sa": true,
+	"bv": true, "pvt": true, "private": true,
+}
+
+// Words returns the lower case words of a text, split on anything but letters and digits
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(norm.NFKC.String(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// RoleTerms returns the distinct meaningful words of a job title, with abbreviations expanded and filler
+// words dropped
+func RoleTerms(role string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, w := range Words(role) {
+		if full, ok := abbreviations[w]; ok {
+			w = full
+		}
+		for _, t := range strings.Fields(w) {
+			if !fillerWords[t] && !seen[t] {
+				seen[t] = true
+				terms = append(terms, t)
+			}
+		}
+	}
+	return terms
+}
+
+// NormalizeRole returns the meaningful words of a job title in order, so that "Sr. Software Eng. (Urgent)" and
+// "senior software engineer" are the same role
+func NormalizeRole(role string) string {
+	return strings.Join(RoleTerms(role), " ")
+}
+
+// CompanyKey returns the company name without case, punctuation and legal suffixes
+func CompanyKey(name string) string {
+	var words []string
+	for _, w := range Words(name) {
+		if !legalSuffixes[w] {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Fingerprint returns the simhash of a posting. The role words are features of their own, while the company and
+// the location are one feature each.
+func Fingerprint(p Posting) uint64 {
+	var v [64]int
+	add := func(feature string, weight int) {
+		h := hash(feature)
+		for i := 0; i < 64; i++ {
+			if h&(1<<uint(i)) != 0 {
+				v[i] += weight
+			} else {
+				v[i] -= weight
+			}
+		}
+	}
+	for _, t := range RoleTerms(p.Role) {
+		add("r:"+t, roleWeight)
+	}
+	if c := CompanyKey(p.Company); c != "" {
+		add("c:"+c, companyWeight)
+	}
+	if l := strings.Join(Words(p.Location), " "); l != "" {
+		add("l:"+l, locationWeight)
+	}
+
+	var fp uint64
+	for i, n := range v {
+		if n > 0 {
+			fp |= 1 << uint(i)
+		}
+	}
+	return fp
+}
+
+// hash returns a well mixed 64 bit hash of a feature
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	// The splitmix64 finalizer spreads FNV's weak high bits
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Distance returns the number of bits in which two fingerprints differ
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Bands is the number of 16 bit bands a fingerprint is split into for lookups. Two fingerprints within a
+// distance below Bands have at least one band in common, so candidates can be found by exact band matches.
+const Bands = 4
+
+// Band returns the i-th 16 bit band of a fingerprint, counted from the most significant bits
+func Band(fp uint64, i int) int {
+	return int(fp >> uint(48-16*i) & 0xffff)
+}
+
+// MinRoleSimilarity is the least share of role terms that near duplicates have in common
+const MinRoleSimilarity = 0.75
+
+// Jaccard returns the number of terms two sets have in common divided by the number of terms in either, 1 for
+// two empty sets
+func Jaccard(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[t] = true
+	}
+	common, union := 0, len(set)
+	seen := map[string]bool{}
+	for _, t := range b {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		if set[t] {
+			common++
+		} else {
+			union++
+		}
+	}
+	return float64(common) / float64(union)
+}
+
+// Similar reports whether two postings with near fingerprints are the same job: the same company and location,
+// and roles sharing at least MinRoleSimilarity of their terms. It rules out fingerprints that only collide.
+func Similar(a, b Posting) bool {
+	return CompanyKey(a.Company) == CompanyKey(b.Company) &&
+		strings.Join(Words(a.Location), " ") == strings.Join(Words(b.Location), " ") &&
+		Jaccard(RoleTerms(a.Role), RoleTerms(b.Role)) >= MinRoleSimilarity
+}
+
+// Item is a fingerprinted job to cluster
+type Item struct {
+	ID          int     // ID identifies the job
+	Fingerprint uint64  // Fingerprint is the simhash of the job
+	Posting     Posting // Posting is what was fingerprinted, to confirm near duplicates with
+}
+
+// Cluster groups items whose fingerprints are within maxDistance of each other and whose postings are Similar,
+// directly or through other items. It returns the representative of every item, the lowest ID of its cluster, by
+// item ID. maxDistance must be below Bands for all near duplicates to be found.
+func Cluster(items []Item, maxDistance int) map[int]int {
+	parent := make(map[int]int, len(items))
+	var find func(int) int
+	find = func(id int) int {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+	union := func(a, b int) {
+		ra, rb := find(a), find(b)
+		if ra == rb {
+			return
+		}
+		if rb < ra {
+			ra, rb = rb, ra
+		}
+		parent[rb] = ra
+	}
+
+	for _, it := range items {
+		parent[it.ID] = it.ID
+	}
+	for band := 0; band < Bands; band++ {
+		buckets := map[int][]Item{}
+		for _, it := range items {
+			key := Band(it.Fingerprint, band)
+			buckets[key] = append(buckets[key], it)
+		}
+		for _, bucket := range buckets {
+			for i := range bucket {
+				for j := i + 1; j < len(bucket); j++ {
+					if Distance(bucket[i].Fingerprint, bucket[j].Fingerprint) <= maxDistance &&
+						Similar(bucket[i].Posting, bucket[j].Posting) {
+						union(bucket[i].ID, bucket[j].ID)
+					}
+				}
+			}
+		}
+	}
+
+	reps := make(map[int]int, len(items))
+	for _, it := range items {
+		reps[it.ID] = find(it.ID)
+	}
+	return reps
+}
@@ -0,0 +1,176 @@
+package dedup
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoleTerms(t *testing.T) {
+	tests := map[string]string{
+		"Sr. Software Eng. (Urgent)":  "senior software engineer",
+		"senior software engineer":    "senior software engineer",
+		"URGENT!! Hiring: Jr Dev now": "junior developer",
+		"SWE - Backend":               "software engineer backend",
+		"Senior senior engineer":      "senior engineer",
+		"Head of QA & Ops":            "head quality operations",
+		"":                            "",
+	}
+	for in, want := range tests {
+		if got := NormalizeRole(in); got != want {
+			t.Errorf("NormalizeRole(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCompanyKey(t *testing.T) {
+	tests := map[string]string{
+		"Acme Inc.":             "acme",
+		"ACME":                  "acme",
+		"Globex Corporation":    "globex",
+		"Initech Pvt. Ltd.":     "initech",
+		"Stark & Wayne, L.L.C.": "stark wayne l l c",
+		"Ltd":                   "",
+	}
+	for in, want := range tests {
+		if got := CompanyKey(in); got != want {
+			t.Errorf("CompanyKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	base := Posting{Role: "Senior Software Engineer", Company: "Acme", Location: "Berlin, Germany"}
+	tests := []struct {
+		desc string
+		p    Posting
+		same bool // same is whether the fingerprint equals the one of base, otherwise it must be out of reach
+	}{
+		{"abbreviations and filler words", Posting{"Sr. Software Eng. (Urgent)", "ACME Inc.", "berlin germany"}, true},
+		{"word order and legal suffix", Posting{"Software Engineer Senior", "Acme GmbH", "Berlin, Germany"}, true},
+		{"repeated words", Posting{"Senior Senior Software Engineer", "acme", "BERLIN GERMANY"}, true},
+		{"other company", Posting{"Senior Software Engineer", "Globex", "Berlin, Germany"}, false},
+		{"other role", Posting{"Accountant", "Acme", "Berlin, Germany"}, false},
+		{"other location", Posting{"Senior Software Engineer", "Acme", "Munich, Germany"}, false},
+	}
+	fp := Fingerprint(base)
+	for _, tt := range tests {
+		d := Distance(fp, Fingerprint(tt.p))
+		if tt.same && d != 0 || !tt.same && d < Bands {
+			t.Errorf("%s: distance %d to base, want same %t", tt.desc, d, tt.same)
+		}
+	}
+	if Fingerprint(base) != fp {
+		t.Error("Fingerprint() is not deterministic")
+	}
+}
+
+func TestDistanceAndBand(t *testing.T) {
+	if d := Distance(0xff00, 0x0f0f); d != 8 {
+		t.Errorf("Distance() = %d, want 8", d)
+	}
+	fp := uint64(0x1111222233334444)
+	for i, want := range []int{0x1111, 0x2222, 0x3333, 0x4444} {
+		if got := Band(fp, i); got != want {
+			t.Errorf("Band(%d) = %#x, want %#x", i, got, want)
+		}
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want float64
+	}{
+		{nil, nil, 1},
+		{[]string{"go"}, nil, 0},
+		{[]string{"senior", "go", "developer"}, []string{"go", "developer", "senior"}, 1},
+		{[]string{"senior", "go", "developer"}, []string{"senior", "go", "developer", "remote"}, 0.75},
+		{[]string{"senior", "go", "developer"}, []string{"go", "go", "developer"}, 2.0 / 3},
+		{[]string{"accountant"}, []string{"developer"}, 0},
+	}
+	for _, tt := range tests {
+		if got := Jaccard(tt.a, tt.b); got != tt.want {
+			t.Errorf("Jaccard(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	base := Posting{Role: "Senior Go Developer", Company: "Acme", Location: "Berlin"}
+	tests := []struct {
+		desc string
+		p    Posting
+		want bool
+	}{
+		{"same", base, true},
+		{"normalized", Posting{"Sr. Go Dev (urgent)", "ACME Inc.", "berlin"}, true},
+		{"one more word", Posting{"Senior Go Developer Remote", "Acme", "Berlin"}, true},
+		{"one other word", Posting{"Senior Go Engineer", "Acme", "Berlin"}, false},
+		{"other role", Posting{"Accountant", "Acme", "Berlin"}, false},
+		{"other company", Posting{"Senior Go Developer", "Globex", "Berlin"}, false},
+		{"other location", Posting{"Senior Go Developer", "Acme", "Munich"}, false},
+	}
+	for _, tt := range tests {
+		if got := Similar(base, tt.p); got != tt.want {
+			t.Errorf("Similar(%s) = %t, want %t", tt.desc, got, tt.want)
+		}
+		if got := Similar(tt.p, base); got != tt.want {
+			t.Errorf("Similar(%s) reversed = %t, want %t", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestCluster(t *testing.T) {
+	dev := Posting{Role: "Senior Go Developer", Company: "Acme", Location: "Berlin"}
+	devRemote := Posting{Role: "Senior Go Developer Remote", Company: "Acme", Location: "Berlin"}
+	accountant := Posting{Role: "Accountant", Company: "Acme", Location: "Berlin"}
+	tests := []struct {
+		desc        string
+		items       []Item
+		maxDistance int
+		want        map[int]int
+	}{
+		{
+			desc:        "empty",
+			maxDistance: 3,
+			want:        map[int]int{},
+		},
+		{
+			desc:        "equal fingerprints",
+			items:       []Item{{5, 0xabcd, dev}, {2, 0xabcd, dev}, {9, 0xabcd, devRemote}},
+			maxDistance: 0,
+			want:        map[int]int{2: 2, 5: 2, 9: 2},
+		},
+		{
+			desc:        "within and beyond the distance",
+			items:       []Item{{1, 0, dev}, {2, 0b111, dev}, {3, 0b11110000, dev}},
+			maxDistance: 3,
+			want:        map[int]int{1: 1, 2: 1, 3: 3},
+		},
+		{
+			desc: "chained through another item",
+			// 1 and 3 are 6 bits apart, 2 is 3 bits from either
+			items:       []Item{{1, 0, dev}, {2, 0b111, dev}, {3, 0b111111, dev}},
+			maxDistance: 3,
+			want:        map[int]int{1: 1, 2: 1, 3: 1},
+		},
+		{
+			desc:        "near fingerprints in different bands",
+			items:       []Item{{1, 1 << 63, dev}, {2, 1<<63 | 1<<47 | 1, dev}},
+			maxDistance: 2,
+			want:        map[int]int{1: 1, 2: 1},
+		},
+		{
+			desc:        "colliding fingerprints of different roles",
+			items:       []Item{{1, 0xabcd, dev}, {2, 0xabcd, accountant}, {3, 0xabcc, dev}},
+			maxDistance: 3,
+			want:        map[int]int{1: 1, 2: 2, 3: 1},
+		},
+	}
+	for _, tt := range tests {
+		got := Cluster(tt.items, tt.maxDistance)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Cluster(%s) = %v, want %v", tt.desc, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Supported export formats
+const (
+	FormatJSON  = "json"
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+)
+
+// Content types of the export formats
+const (
+	ContentTypeCSV   = "text/csv; charset=utf-8"
+	ContentTypeJSONL = "application/x-ndjson"
+	ContentTypeXLSX  = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// mediaTypes maps Accept media types to formats
+var mediaTypes = map[string]string{
+	"application/json":        FormatJSON,
+	"text/csv":                FormatCSV,
+	"application/x-ndjson":    FormatJSONL,
+	"application/jsonl":       FormatJSONL,
+	"application/x-jsonlines": FormatJSONL,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": FormatXLSX,
+}
+
+// Negotiate picks the response format from the format query parameter, then the Accept header.
+// It returns FormatJSON when neither asks for an export format, and "" for an unknown format parameter.
+func Negotiate(r *http.Request) string {
+	if f := strings.ToLower(r.URL.Query().Get("format")); f != "" {
+		switch f {
+		case FormatJSON, FormatCSV, FormatJSONL, FormatXLSX:
+			return f
+		case "ndjson":
+			return FormatJSONL
+		}
+		return ""
+	}
+
+	// Take the first listed media type we can produce; quality values are not weighed
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if f, ok := mediaTypes[mediaType]; ok {
+			return f
+		}
+	}
+	return FormatJSON
+}
+
+// ContentType returns the Content-Type of an export format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return ContentTypeCSV
+	case FormatJSONL:
+		return ContentTypeJSONL
+	case FormatXLSX:
+		return ContentTypeXLSX
+	}
+	return "application/json"
+}
+
+// Writer writes rows of an export. Values are written in column order.
+type Writer interface {
+	Write(values []interface{}) error
+	Flush() error // Flush writes buffered rows to the underlying writer
+	Close() error // Close finishes the file; the underlying writer is not closed
+}
+
+// NewWriter returns a writer producing the format with the given columns.
+func NewWriter(format string, w io.Writer, columns []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, fmt.Errorf("write csv header: %w", err)
+		}
+		return &csvWriter{w: cw, record: make([]string, len(columns))}, nil
+	case FormatJSONL:
+		return &jsonlWriter{w: w, columns: columns}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// csvWriter writes RFC 4180 CSV with a header row.
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+// Write writes one CSV record.
+func (c *csvWriter) Write(values []interface{}) error {
+	for i := range c.record {
+		c.record[i] = ""
+		if i < len(values) {
+			c.record[i] = formatValue(values[i])
+			if _, ok := values[i].(string); ok {
+				c.record[i] = escapeFormula(c.record[i])
+			}
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+// Flush flushes buffered CSV records.
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Close flushes the remaining records.
+func (c *csvWriter) Close() error {
+	return c.Flush()
+}
+
+// jsonlWriter writes one JSON object per line with keys in column order.
+type jsonlWriter struct {
+	w       io.Writer
+	columns []string
+	buf     bytes.Buffer
+}
+
+// Write writes one JSON line.
+func (j *jsonlWriter) Write(values []interface{}) error {
+	j.buf.WriteByte('{')
+	for i, col := range j.columns {
+		if i > 0 {
+			j.buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(col)
+		j.buf.Write(key)
+		j.buf.WriteByte(':')
+
+		var v interface{}
+		if i < len(values) {
+			v = values[i]
+		}
+		val, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", col, err)
+		}
+		j.buf.Write(val)
+	}
+	j.buf.WriteString("}\n")
+
+	// Rows are buffered and written out in chunks on Flush
+	if j.buf.Len() >= 32<<10 {
+		return j.Flush()
+	}
+	return nil
+}
+
+// Flush writes buffered lines.
+func (j *jsonlWriter) Flush() error {
+	_, err := j.w.Write(j.buf.Bytes())
+	j.buf.Reset()
+	return err
+}
+
+// Close writes the remaining lines.
+func (j *jsonlWriter) Close() error {
+	return j.Flush()
+}
+
+// formatValue formats a value for a text cell.
+func formatValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case int:
+		return strconv.Itoa(t)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case fmt.Stringer:
+		return t.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// escapeFormula prefixes text that a spreadsheet would evaluate as a formula with a quote.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,98 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ContentTypeAggregator is the content type of the aggregator feed
+const ContentTypeAggregator = "application/xml; charset=utf-8"
+
+// cdata is text written as a CDATA section, as job boards expect for free text
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+// AggregatorJob is a <job> of the job-board XML feed.
+type AggregatorJob struct {
+	Reference   string
+	Title       string
+	Company     string
+	City        string
+	Country     string
+	Salary      string
+	Description string
+	URL         string
+	Date        time.Time
+}
+
+// aggregatorJob is the XML form of AggregatorJob
+type aggregatorJob struct {
+	XMLName         xml.Name `xml:"job"`
+	Title           cdata    `xml:"title"`
+	Date            cdata    `xml:"date"`
+	ReferenceNumber cdata    `xml:"referencenumber"`
+	URL             cdata    `xml:"url"`
+	Company         cdata    `xml:"company"`
+	City            cdata    `xml:"city"`
+	Country         cdata    `xml:"country"`
+	Description     cdata    `xml:"description"`
+	Salary          cdata    `xml:"salary"`
+}
+
+// AggregatorFeed streams the job-board XML feed, a <source> with one <job> per posting.
+type AggregatorFeed struct {
+	enc *xml.Encoder
+}
+
+// NewAggregatorFeed writes the start of the feed with the publisher details.
+func NewAggregatorFeed(w io.Writer, publisher, publisherURL string, built time.Time) (*AggregatorFeed, error) {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, err
+	}
+	enc := xml.NewEncoder(w)
+	source := xml.StartElement{Name: xml.Name{Local: "source"}}
+	if err := enc.EncodeToken(source); err != nil {
+		return nil, fmt.Errorf("write aggregator feed: %w", err)
+	}
+	fields := []struct{ name, value string }{
+		{"publisher", publisher},
+		{"publisherurl", publisherURL},
+		{"lastBuildDate", built.UTC().Format(time.RFC1123Z)},
+	}
+	for _, f := range fields {
+		if err := enc.EncodeElement(f.value, xml.StartElement{Name: xml.Name{Local: f.name}}); err != nil {
+			return nil, fmt.Errorf("write aggregator feed: %w", err)
+		}
+	}
+	return &AggregatorFeed{enc: enc}, nil
+}
+
+// Add writes a job.
+func (f *AggregatorFeed) Add(job AggregatorJob) error {
+	err := f.enc.Encode(aggregatorJob{
+		Title:           cdata{job.Title},
+		Date:            cdata{job.Date.UTC().Format(time.RFC1123Z)},
+		ReferenceNumber: cdata{job.Reference},
+		URL:             cdata{job.URL},
+		Company:         cdata{job.Company},
+		City:            cdata{job.City},
+		Country:         cdata{job.Country},
+		Description:     cdata{job.Description},
+		Salary:          cdata{job.Salary},
+	})
+	if err != nil {
+		return fmt.Errorf("write aggregator feed: %w", err)
+	}
+	return nil
+}
+
+// Close ends the feed.
+func (f *AggregatorFeed) Close() error {
+	if err := f.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "source"}}); err != nil {
+		return fmt.Errorf("write aggregator feed: %w", err)
+	}
+	return f.enc.Flush()
+}
@@ -0,0 +1,99 @@
+package feeds
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// JobPosting is a schema.org JobPosting, rendered as JSON-LD.
+type JobPosting struct {
+	Context            string       `json:"@context"`
+	Type               string       `json:"@type"`
+	Title              string       `json:"title"`
+	Description        string       `json:"description"`
+	Identifier         *PropertyID  `json:"identifier,omitempty"`
+	URL                string       `json:"url,omitempty"`
+	DatePosted         string       `json:"datePosted"`
+	HiringOrganization Organization `json:"hiringOrganization"`
+	JobLocation        *Place       `json:"jobLocation,omitempty"`
+	BaseSalary         *Salary      `json:"baseSalary,omitempty"`
+}
+
+// PropertyID is a schema.org PropertyValue identifying the posting.
+type PropertyID struct {
+	Type  string `json:"@type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Organization is a schema.org Organization.
+type Organization struct {
+	Type   string `json:"@type"`
+	Name   string `json:"name"`
+	SameAs string `json:"sameAs,omitempty"`
+}
+
+// Place is a schema.org Place.
+type Place struct {
+	Type    string        `json:"@type"`
+	Address PostalAddress `json:"address"`
+}
+
+// PostalAddress is a schema.org PostalAddress.
+type PostalAddress struct {
+	Type            string `json:"@type"`
+	AddressLocality string `json:"addressLocality,omitempty"`
+	AddressCountry  string `json:"addressCountry,omitempty"`
+}
+
+// Salary is a schema.org MonetaryAmount.
+type Salary struct {
+	Type     string       `json:"@type"`
+	Currency string       `json:"currency"`
+	Value    SalaryAmount `json:"value"`
+}
+
+// SalaryAmount is a schema.org QuantitativeValue.
+type SalaryAmount struct {
+	Type     string `json:"@type"`
+	Value    int    `json:"value"`
+	UnitText string `json:"unitText"`
+}
+
+// NewJobPosting returns a JobPosting for a job. salary is the salary per pay period, such as "month", in the ISO
+// 4217 currency, omitted when not positive. The location is omitted when both the city and the ISO 3166-1 country
+// code are empty.
+func NewJobPosting(id, title, description, url string, posted time.Time, company, companyURL, city, country string, salary int, currency, period string) JobPosting {
+	p := JobPosting{
+		Context:            "https://schema.org/",
+		Type:               "JobPosting",
+		Title:              title,
+		Description:        description,
+		URL:                url,
+		DatePosted:         posted.UTC().Format("2006-01-02"),
+		HiringOrganization: Organization{Type: "Organization", Name: company, SameAs: companyURL},
+	}
+	if id != "" {
+		p.Identifier = &PropertyID{Type: "PropertyValue", Name: company, Value: id}
+	}
+	if city != "" || country != "" {
+		p.JobLocation = &Place{
+			Type:    "Place",
+			Address: PostalAddress{Type: "PostalAddress", AddressLocality: city, AddressCountry: country},
+		}
+	}
+	if salary > 0 {
+		p.BaseSalary = &Salary{
+			Type:     "MonetaryAmount",
+			Currency: currency,
+			Value:    SalaryAmount{Type: "QuantitativeValue", Value: salary, UnitText: strings.ToUpper(period)},
+		}
+	}
+	return p
+}
+
+// JobDescription returns the plain text description of a job used in feeds and JSON-LD.
+func JobDescription(company, role string, salary int, currency, period string) string {
+	return fmt.Sprintf("%s is hiring a %s. Salary: %d %s per %s.", company, role, salary, currency, period)
+}
@@ -0,0 +1,106 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// MaxSitemapURLs is the most URLs a single sitemap may list
+const MaxSitemapURLs = 50000
+
+// ErrSitemapFull is returned when adding a URL to a sitemap that already lists MaxSitemapURLs
+var ErrSitemapFull = errors.New("sitemap is full")
+
+// sitemapURL is a <url> entry of a sitemap
+type sitemapURL struct {
+	XMLName xml.Name `xml:"url"`
+	Loc     string   `xml:"loc"`
+	LastMod string   `xml:"lastmod,omitempty"`
+}
+
+// Sitemap streams a sitemaps.org urlset, one URL at a time.
+type Sitemap struct {
+	enc *xml.Encoder
+	n   int
+}
+
+// NewSitemap writes the start of the urlset.
+func NewSitemap(w io.Writer) (*Sitemap, error) {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, err
+	}
+	enc := xml.NewEncoder(w)
+	start := xml.StartElement{
+		Name: xml.Name{Local: "urlset"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: "http://www.sitemaps.org/schemas/sitemap/0.9"}},
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return nil, fmt.Errorf("write sitemap: %w", err)
+	}
+	return &Sitemap{enc: enc}, nil
+}
+
+// Add writes a URL. It fails with ErrSitemapFull past MaxSitemapURLs, the URL then belongs in the next sitemap.
+func (s *Sitemap) Add(loc string, lastMod time.Time) error {
+	if s.n >= MaxSitemapURLs {
+		return ErrSitemapFull
+	}
+	s.n++
+	u := sitemapURL{Loc: loc}
+	if !lastMod.IsZero() {
+		u.LastMod = lastMod.UTC().Format(time.RFC3339)
+	}
+	if err := s.enc.Encode(u); err != nil {
+		return fmt.Errorf("write sitemap: %w", err)
+	}
+	return nil
+}
+
+// Close ends the urlset.
+func (s *Sitemap) Close() error {
+	if err := s.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "urlset"}}); err != nil {
+		return fmt.Errorf("write sitemap: %w", err)
+	}
+	return s.enc.Flush()
+}
+
+// SitemapRef is a sitemap listed in a sitemap index
+type SitemapRef struct {
+	Loc     string    // Loc is the absolute URL of the sitemap
+	LastMod time.Time // LastMod is when the newest URL in the sitemap changed, left out when zero
+}
+
+// sitemapIndex is a sitemaps.org sitemapindex
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	Xmlns    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// sitemapEntry is a <sitemap> entry of a sitemap index
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// WriteSitemapIndex writes a sitemap index listing the sitemaps.
+func WriteSitemapIndex(w io.Writer, sitemaps []SitemapRef) error {
+	index := sitemapIndex{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, sm := range sitemaps {
+		entry := sitemapEntry{Loc: sm.Loc}
+		if !sm.LastMod.IsZero() {
+			entry.LastMod = sm.LastMod.UTC().Format(time.RFC3339)
+		}
+		index.Sitemaps = append(index.Sitemaps, entry)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("write sitemap index: %w", err)
+	}
+	if err := xml.NewEncoder(w).Encode(index); err != nil {
+		return fmt.Errorf("write sitemap index: %w", err)
+	}
+	return nil
+}
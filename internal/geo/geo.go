@@ -0,0 +1,186 @@
+// Package geo normalizes free text locations, such as "Berlin, Germany" or a company address, to places of an
+// offline geocoding table loaded from GeoNames files, and does the distance arithmetic of radius searches.
+package geo
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	// Time zones of remote jobs are checked against the embedded database, whatever the host has installed
+	_ "time/tzdata"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// EarthRadiusKm is the mean radius of the Earth
+const EarthRadiusKm = 6371.0088
+
+// Place is a populated place of the geocoding table
+type Place struct {
+	ID         int      // ID is the GeoNames ID
+	Name       string   // Name is the name of the place
+	Keys       []string // Keys are the normalized names the place is found by
+	Country    string   // Country is the ISO 3166-1 alpha-2 code
+	RegionCode string   // RegionCode is the first level administrative division code, such as "CA" in the US
+	Region     string   // Region is the name of the division, such as "California"
+	Latitude   float64  // Latitude is in decimal degrees
+	Longitude  float64  // Longitude is in decimal degrees
+	Population int64    // Population breaks ties between places of the same name
+	Timezone   string   // Timezone is the IANA time zone of the place
+}
+
+// Country is a country of the geocoding table
+type Country struct {
+	Code     string   // Code is the ISO 3166-1 alpha-2 code
+	ISO3     string   // ISO3 is the ISO 3166-1 alpha-3 code
+	Name     string   // Name is the English name
+	Keys     []string // Keys are the normalized names the country is found by
+	Currency string   // Currency is the ISO 4217 code of the currency
+}
+
+// stripMarks removes accents, so that "São Paulo" and "Sao Paulo" are the same name
+var stripMarks = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// Key returns the form names are compared in: lower case words without accents, separated by single spaces
+func Key(name string) string {
+	s, _, err := transform.String(stripMarks, norm.NFKC.String(name))
+	if err != nil {
+		s = name
+	}
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// maxNameWords is the longest place name looked up, in words
+const maxNameWords = 4
+
+// Query is what a location text may name
+type Query struct {
+	Names []string // Names are the word sequences of the text that may be the name of a place, region or country
+	Codes []string // Codes are the parts of the text short enough to be a country or region code, in upper case
+}
+
+// ParseQuery splits a location text such as "Unter den Linden 1, 10117 Berlin, Germany" on commas and similar
+// separators. Every run of up to four words of a part without digits may be a name, and a part of two or three
+// letters may be a code.
+func ParseQuery(text string) Query {
+	var q Query
+	seen := map[string]bool{}
+	for _, part := range strings.FieldsFunc(text, func(r rune) bool { return strings.ContainsRune(",;|/()", r) }) {
+		var words []string
+		for _, w := range strings.Fields(Key(part)) {
+			// House numbers and postal codes name nothing
+			if !strings.ContainsAny(w, "0123456789") {
+				words = append(words, w)
+			}
+		}
+		if len(words) == 1 && len(words[0]) >= 2 && len(words[0]) <= 3 {
+			q.Codes = append(q.Codes, strings.ToUpper(words[0]))
+		}
+		for i := range words {
+			for n := 1; n <= maxNameWords && i+n <= len(words); n++ {
+				name := strings.Join(words[i:i+n], " ")
+				if !seen[name] {
+					seen[name] = true
+					q.Names = append(q.Names, name)
+				}
+			}
+		}
+	}
+	return q
+}
+
+// Empty reports whether the text had nothing to look up
+func (q Query) Empty() bool {
+	return len(q.Names) == 0 && len(q.Codes) == 0
+}
+
+// hasName reports whether the text contains the name
+func (q Query) hasName(name string) bool {
+	for _, n := range q.Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCode reports whether the text has the code as a part of its own
+func (q Query) hasCode(code string) bool {
+	for _, c := range q.Codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Best picks the place the text most likely means among the places found by its names. A place in one of the
+// countries named in the text comes first, then a place in a region named in the text, then the larger one,
+// so "Paris" is the French capital and "Paris, Texas" is not.
+func (q Query) Best(places []Place, countries []string) (Place, bool) {
+	if len(places) == 0 {
+		return Place{}, false
+	}
+	score := func(p Place) int {
+		s := 0
+		for _, c := range countries {
+			if p.Country == c {
+				s += 4
+				break
+			}
+		}
+		if (p.Region != "" && q.hasName(Key(p.Region))) || (p.RegionCode != "" && q.hasCode(p.RegionCode)) {
+			s += 2
+		}
+		return s
+	}
+	ranked := append([]Place{}, places...)
+	sort.SliceStable(ranked, func(a, b int) bool {
+		sa, sb := score(ranked[a]), score(ranked[b])
+		if sa != sb {
+			return sa > sb
+		}
+		return ranked[a].Population > ranked[b].Population
+	})
+	return ranked[0], true
+}
+
+// BoundingBox returns the latitude and longitude ranges around a point that contain every point within the
+// radius. Near the poles, or when the box would cross the antimeridian, every longitude is in range.
+func BoundingBox(lat, lng, radiusKm float64) (minLat, maxLat, minLng, maxLng float64) {
+	dLat := radiusKm / EarthRadiusKm * 180 / math.Pi
+	minLat, maxLat = math.Max(lat-dLat, -90), math.Min(lat+dLat, 90)
+	minLng, maxLng = -180, 180
+	if minLat > -90 && maxLat < 90 {
+		dLng := math.Asin(math.Min(1, math.Sin(radiusKm/EarthRadiusKm)/math.Cos(lat*math.Pi/180))) * 180 / math.Pi
+		if lng-dLng >= -180 && lng+dLng <= 180 {
+			minLng, maxLng = lng-dLng, lng+dLng
+		}
+	}
+	return minLat, maxLat, minLng, maxLng
+}
+
+// DistanceKm returns the great circle distance in kilometres between two points by the haversine formula, the
+// same the database computes for radius searches
+func DistanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	h := math.Pow(math.Sin((lat2-lat1)*rad/2), 2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Pow(math.Sin((lng2-lng1)*rad/2), 2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// ValidTimezone reports whether name is an IANA time zone such as "Europe/Berlin"
+func ValidTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
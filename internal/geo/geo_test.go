@@ -0,0 +1,147 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		want, tolerance        float64
+	}{
+		{"same point", 52.52, 13.405, 52.52, 13.405, 0, 1e-9},
+		{"Berlin to Paris", 52.52, 13.405, 48.8566, 2.3522, 878, 2},
+		{"New York to London", 40.7128, -74.006, 51.5074, -0.1278, 5570, 10},
+		{"one degree along the equator", 0, 0, 0, 1, 111.195, 0.01},
+		{"one degree of latitude", 10, 20, 11, 20, 111.195, 0.01},
+		{"across the antimeridian", 0, 179.5, 0, -179.5, 111.195, 0.01},
+		{"Fiji to Samoa across the antimeridian", -18.1416, 178.4419, -13.8333, -171.7667, 1150, 15},
+		{"pole to pole", 90, 0, -90, 0, math.Pi * EarthRadiusKm, 1e-6},
+		{"antipodes", 10, 20, -10, -160, math.Pi * EarthRadiusKm, 1e-6},
+		{"longitude does not matter at the pole", 90, 0, 90, 120, 0, 1e-9},
+		{"pole to equator", 90, 45, 0, -100, math.Pi / 2 * EarthRadiusKm, 1e-6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DistanceKm(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
+			if math.Abs(got-tt.want) > tt.tolerance {
+				t.Errorf("DistanceKm() = %v, want %v ± %v", got, tt.want, tt.tolerance)
+			}
+			if back := DistanceKm(tt.lat2, tt.lng2, tt.lat1, tt.lng1); math.Abs(back-got) > 1e-9 {
+				t.Errorf("DistanceKm() = %v one way and %v the other", got, back)
+			}
+		})
+	}
+}
+
+// destination returns the point at the distance from a point on the initial bearing, in degrees
+func destination(lat, lng, bearing, distanceKm float64) (float64, float64) {
+	rad := math.Pi / 180
+	d := distanceKm / EarthRadiusKm
+	lat1, lng1, b := lat*rad, lng*rad, bearing*rad
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(b))
+	lng2 := lng1 + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	// Back into -180 to 180
+	lng2 = math.Mod(lng2/rad+540, 360) - 180
+	return lat2 / rad, lng2
+}
+
+func TestBoundingBox(t *testing.T) {
+	tests := []struct {
+		name                           string
+		lat, lng, radiusKm             float64
+		minLat, maxLat, minLng, maxLng float64
+	}{
+		{"equator", 0, 0, 111.195, -1, 1, -1, 1},
+		{"Berlin", 52.52, 13.405, 50, 52.0703, 52.9697, 12.6662, 14.1438},
+		{"west of the antimeridian", 0, 179.5, 111.195, -1, 1, -180, 180},
+		{"east of the antimeridian", 0, -179.5, 111.195, -1, 1, -180, 180},
+		{"touching the antimeridian", 0, 179, 111.195, -1, 1, 178, 180},
+		{"north pole", 90, 0, 100, 89.1007, 90, -180, 180},
+		{"south pole", -90, 45, 100, -90, -89.1007, -180, 180},
+		{"radius over the pole", 89.5, 10, 100, 88.6007, 90, -180, 180},
+		{"close to the pole", 85, 10, 100, 84.1007, 85.8993, -0.3747, 20.3747},
+		{"radius around the world", 40, 10, 30000, -90, 90, -180, 180},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minLat, maxLat, minLng, maxLng := BoundingBox(tt.lat, tt.lng, tt.radiusKm)
+			for _, c := range []struct{ got, want float64 }{{minLat, tt.minLat}, {maxLat, tt.maxLat}, {minLng, tt.minLng}, {maxLng, tt.maxLng}} {
+				if math.Abs(c.got-c.want) > 1e-3 {
+					t.Fatalf("BoundingBox() = %v, %v, %v, %v, want %v, %v, %v, %v",
+						minLat, maxLat, minLng, maxLng, tt.minLat, tt.maxLat, tt.minLng, tt.maxLng)
+				}
+			}
+
+			// Every point on the circle, and the centre, is in the box
+			for bearing := 0.0; bearing < 360; bearing += 5 {
+				lat, lng := destination(tt.lat, tt.lng, bearing, math.Min(tt.radiusKm, math.Pi*EarthRadiusKm))
+				if lat < minLat-1e-9 || lat > maxLat+1e-9 || lng < minLng-1e-9 || lng > maxLng+1e-9 {
+					t.Errorf("point %v, %v at bearing %v is outside the box", lat, lng, bearing)
+				}
+			}
+			if tt.lat < minLat || tt.lat > maxLat || tt.lng < minLng || tt.lng > maxLng {
+				t.Errorf("centre is outside the box")
+			}
+		})
+	}
+}
+
+func TestBoundingBoxTightAtWidestLatitude(t *testing.T) {
+	// Away from the equator the widest longitude of a circle is not at the centre's latitude, which the box
+	// must still contain while not being wider than needed
+	lat, lng, radius := 60.0, 25.0, 500.0
+	_, _, minLng, maxLng := BoundingBox(lat, lng, radius)
+	widest := 0.0
+	for bearing := 0.0; bearing <= 180; bearing += 0.01 {
+		_, l := destination(lat, lng, bearing, radius)
+		widest = math.Max(widest, l-lng)
+	}
+	if math.Abs((maxLng-lng)-widest) > 1e-3 || math.Abs((lng-minLng)-widest) > 1e-3 {
+		t.Errorf("BoundingBox() longitudes %v to %v, want %v either side of %v", minLng, maxLng, widest, lng)
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"São Paulo", "sao paulo"},
+		{"  NEW-YORK  city ", "new york city"},
+		{"Zürich", "zurich"},
+		{"Ｂｅｒｌｉｎ", "berlin"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.name); got != tt.want {
+			t.Errorf("Key(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBest(t *testing.T) {
+	paris := Place{Name: "Paris", Country: "FR", Region: "Île-de-France", Population: 2100000}
+	parisTexas := Place{Name: "Paris", Country: "US", Region: "Texas", RegionCode: "TX", Population: 25000}
+	tests := []struct {
+		text      string
+		countries []string
+		want      Place
+	}{
+		{"Paris", nil, paris},
+		{"Paris, Texas", nil, parisTexas},
+		{"Paris, TX", nil, parisTexas},
+		{"Paris, United States", []string{"US"}, parisTexas},
+	}
+	for _, tt := range tests {
+		got, ok := ParseQuery(tt.text).Best([]Place{paris, parisTexas}, tt.countries)
+		if !ok || got.Country != tt.want.Country {
+			t.Errorf("Best(%q) = %v, %v, want %v", tt.text, got, ok, tt.want)
+		}
+	}
+	if _, ok := ParseQuery("Paris").Best(nil, nil); ok {
+		t.Errorf("Best() without places found one")
+	}
+}
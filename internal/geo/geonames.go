@@ -0,0 +1,152 @@
+package geo
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The bundled dataset is a sample of the GeoNames countryInfo.txt, admin1CodesASCII.txt and cities files with
+// the larger cities of the world. The full files from https://download.geonames.org/export/dump/ can be loaded
+// in its place.
+var (
+	//go:embed data/countryInfo.txt
+	defaultCountries []byte
+	//go:embed data/admin1CodesASCII.txt
+	defaultAdmin1 []byte
+	//go:embed data/cities.txt
+	defaultCities []byte
+)
+
+// countryAliases are other names of countries that the GeoNames country file does not list
+var countryAliases = map[string][]string{
+	"US": {"usa", "united states of america", "america"},
+	"GB": {"uk", "great britain", "britain", "england", "scotland", "wales", "northern ireland"},
+	"NL": {"holland", "the netherlands"},
+	"DE": {"deutschland"},
+	"ES": {"espana"},
+	"AE": {"uae"},
+	"KR": {"korea", "republic of korea"},
+	"CZ": {"czechia", "czech republic"},
+	"CH": {"schweiz", "suisse"},
+	"AT": {"osterreich"},
+	"IT": {"italia"},
+	"RU": {"russian federation"},
+	"VN": {"viet nam"},
+}
+
+// DefaultDataset returns the countries and places bundled with the API.
+func DefaultDataset() ([]Country, []Place, error) {
+	countries, err := ParseCountries(bytes.NewReader(defaultCountries))
+	if err != nil {
+		return nil, nil, err
+	}
+	admin1, err := ParseAdmin1(bytes.NewReader(defaultAdmin1))
+	if err != nil {
+		return nil, nil, err
+	}
+	places, err := ParseCities(bytes.NewReader(defaultCities), admin1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return countries, places, nil
+}
+
+// eachRecord calls fn with the tab separated fields of every line that is neither blank nor a # comment
+func eachRecord(r io.Reader, minFields int, fn func(fields []string) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := sc.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) < minFields {
+			return fmt.Errorf("line %d: %d fields, want %d", line, len(fields), minFields)
+		}
+		if err := fn(fields); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return sc.Err()
+}
+
+// ParseCountries reads a GeoNames countryInfo.txt file.
+func ParseCountries(r io.Reader) ([]Country, error) {
+	var countries []Country
+	err := eachRecord(r, 5, func(f []string) error {
+		c := Country{Code: strings.ToUpper(f[0]), ISO3: strings.ToUpper(f[1]), Name: f[4]}
+		if len(c.Code) != 2 || c.Name == "" {
+			return fmt.Errorf("invalid country %q", f[0])
+		}
+		if len(f) > 10 {
+			c.Currency = strings.ToUpper(f[10])
+		}
+		c.Keys = []string{Key(c.Name)}
+		for _, alias := range countryAliases[c.Code] {
+			c.Keys = append(c.Keys, Key(alias))
+		}
+		countries = append(countries, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parse countries: %w", err)
+	}
+	return countries, nil
+}
+
+// ParseAdmin1 reads a GeoNames admin1CodesASCII.txt file into the names of the divisions by "<country>.<code>".
+func ParseAdmin1(r io.Reader) (map[string]string, error) {
+	names := map[string]string{}
+	err := eachRecord(r, 2, func(f []string) error {
+		names[f[0]] = f[1]
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parse admin1 codes: %w", err)
+	}
+	return names, nil
+}
+
+// ParseCities reads a GeoNames cities file, such as cities15000.txt, naming the regions with admin1.
+func ParseCities(r io.Reader, admin1 map[string]string) ([]Place, error) {
+	var places []Place
+	err := eachRecord(r, 19, func(f []string) error {
+		var p Place
+		var err error
+		if p.ID, err = strconv.Atoi(f[0]); err != nil {
+			return fmt.Errorf("invalid id %q", f[0])
+		}
+		if p.Latitude, err = strconv.ParseFloat(f[4], 64); err != nil || p.Latitude < -90 || p.Latitude > 90 {
+			return fmt.Errorf("invalid latitude %q", f[4])
+		}
+		if p.Longitude, err = strconv.ParseFloat(f[5], 64); err != nil || p.Longitude < -180 || p.Longitude > 180 {
+			return fmt.Errorf("invalid longitude %q", f[5])
+		}
+		p.Population, _ = strconv.ParseInt(f[14], 10, 64)
+		p.Name, p.Country, p.RegionCode, p.Timezone = f[1], strings.ToUpper(f[8]), f[10], f[17]
+		p.Region = admin1[p.Country+"."+p.RegionCode]
+
+		seen := map[string]bool{}
+		for i, name := range append([]string{f[1], f[2]}, strings.Split(f[3], ",")...) {
+			// Alternate names include codes such as airports, which would shadow the country codes
+			if k := Key(name); k != "" && !seen[k] && (i < 2 || len(k) > 3) {
+				seen[k] = true
+				p.Keys = append(p.Keys, k)
+			}
+		}
+		places = append(places, p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parse cities: %w", err)
+	}
+	return places, nil
+}
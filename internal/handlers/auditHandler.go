@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"job-portal-api/internal/audit"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Audit struct represents the handler for querying the audit log
+type Audit struct {
+	auditService *audit.Service
+}
+
+// NewAudit creates a new Audit handler with the provided audit service
+func NewAudit(as *audit.Service) (*Audit, error) {
+	if as == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &Audit{auditService: as}, nil
+}
+
+// ListEvents handles the retrieval of audit events, filtered by the actor, entity_type, entity_id,
+// from, to (RFC 3339), before_id and limit query parameters
+func (a Audit) ListEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	var f audit.Filter
+	var err error
+
+	// Parse the numeric filters
+	ints := map[string]*int{"actor": &f.ActorID, "entity_id": &f.EntityID, "limit": &f.Limit}
+	for name, dst := range ints {
+		if v := q.Get(name); v != "" {
+			if *dst, err = strconv.Atoi(v); err != nil {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if v := q.Get("before_id"); v != "" {
+		if f.BeforeID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "invalid before_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Parse the time range
+	times := map[string]*time.Time{"from": &f.From, "to": &f.To}
+	for name, dst := range times {
+		if v := q.Get(name); v != "" {
+			if *dst, err = time.Parse(time.RFC3339, v); err != nil {
+				http.Error(w, "invalid "+name+", expected RFC 3339", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	f.EntityType = q.Get("entity_type")
+
+	// Get the events using the audit service
+	events, err := a.auditService.List(r.Context(), f)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"job-portal-api/internal/careers"
+	"job-portal-api/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// Careers struct represents the handler for the public, server-rendered company careers pages
+type Careers struct {
+	companyService *services.CompanyService
+	jobService     *services.JobService
+}
+
+// NewCareers creates a new Careers handler with the provided services
+func NewCareers(cs *services.CompanyService, js *services.JobService) (*Careers, error) {
+	if cs == nil || js == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &Careers{companyService: cs, jobService: js}, nil
+}
+
+// CompanyPage handles a company's careers page listing its open jobs
+func (c Careers) CompanyPage(w http.ResponseWriter, r *http.Request) {
+	company, err := c.companyService.GetCompanyBySlug(r.Context(), chi.URLParam(r, "companySlug"))
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.NotFound(w, r)
+		return
+	}
+
+	jobs, err := c.jobService.GetJobsByCompaniesID(company.ID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(publicMaxAge()))
+	if err := careers.RenderList(w, careers.ListPage{Company: company, Jobs: jobs}); err != nil {
+		log.Error().Err(err).Send()
+	}
+}
+
+// JobPage handles the detail page of one of a company's open jobs
+func (c Careers) JobPage(w http.ResponseWriter, r *http.Request) {
+	company, err := c.companyService.GetCompanyBySlug(r.Context(), chi.URLParam(r, "companySlug"))
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.NotFound(w, r)
+		return
+	}
+	jobID, err := intURLParam(r, "id")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Only jobs of the company in the URL are shown on its page
+	job, err := c.jobService.GetJobsByID(jobID)
+	if err != nil || job.CompanyId != company.ID {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(publicMaxAge()))
+	if err := careers.RenderJob(w, careers.JobPage{Company: company, Job: job}); err != nil {
+		log.Error().Err(err).Send()
+	}
+}
+
+// Static serves the embedded stylesheet and images of the careers pages
+func (c Careers) Static() http.Handler {
+	return http.StripPrefix(careers.StaticPrefix, http.FileServer(http.FS(careers.Static())))
+}
@@ -3,7 +3,10 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"job-portal-api/internal/auth"
+	"job-portal-api/internal/export"
+	"job-portal-api/internal/images"
 	"job-portal-api/internal/models"
 	"job-portal-api/internal/services"
 	"net/http"
@@ -72,15 +75,28 @@ func (c Company) CreateCompany(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create the company using the company service
-	name := newCompany.Name
-	address := newCompany.Address
-	_, err = c.companyService.CreateCompany(userID, name, address)
+	company, err := c.companyService.CreateCompany(r.Context(), userID, newCompany)
 	if err != nil {
 		log.Error().Err(err).Send()
+		if errors.Is(err, services.ErrCompanyNameTaken) {
+			http.Error(w, "a company with this name already exists", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, services.ErrUserSuspended) {
+			http.Error(w, services.ErrUserSuspended.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, "something went wrong.", http.StatusInternalServerError)
 		return
 	}
 
+	// Flagged companies are created unpublished until an operator approves them
+	if company.ModerationStatus == models.ModerationPending {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode("Company submitted for review")
+		return
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode("Company Created Successfully")
 }
@@ -89,8 +105,39 @@ func (c Company) CreateCompany(w http.ResponseWriter, r *http.Request) {
 func (c Company) GetAllCompanies(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	// Best rated first with sort=rating, in listings and exports alike
+	var byRating bool
+	switch r.URL.Query().Get("sort") {
+	case "", "id":
+	case "rating":
+		byRating = true
+	default:
+		http.Error(w, "invalid sort, expected id or rating", http.StatusBadRequest)
+		return
+	}
+
+	// Stream CSV, JSON Lines and XLSX exports straight from the database
+	format, ok := negotiateExport(w, r)
+	if !ok {
+		return
+	}
+	if format != export.FormatJSON {
+		streamExport(w, format, "companies", companyExportColumns, func(write func([]interface{}) error) error {
+			return c.companyService.ExportAllCompanies(r.Context(), byRating, func(company *models.Company) error {
+				return write(companyExportRow(company))
+			})
+		})
+		return
+	}
+
 	// Get all companies using the company service
-	companies, err := c.companyService.GetAllCompanies()
+	var companies []*models.Company
+	var err error
+	if byRating {
+		companies, err = c.companyService.GetCompaniesByRating(r.Context())
+	} else {
+		companies, err = c.companyService.GetAllCompanies()
+	}
 	if err != nil {
 		log.Error().Err(err).Send()
 		http.Error(w, "something went wrong.", http.StatusInternalServerError)
@@ -122,6 +169,11 @@ func (c Company) GetCompanyByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Let clients revalidate cached copies with If-None-Match, the rating changes without the version
+	if notModified(w, r, etagForRevision(company.Version, company.RatingRevision)) {
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(company)
 }
@@ -156,7 +208,7 @@ func (c Company) GetCompanyByUserID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(company)
 }
 
-// DeleteCompanyByUserID handles the deletion of a company by user ID
+// DeleteCompanyByUserID handles the soft deletion of a company by user ID
 func (c Company) DeleteCompanyByUserID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -183,10 +235,21 @@ func (c Company) DeleteCompanyByUserID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only delete the version the client last saw, when it sends If-Match
+	version, err := expectedVersion(r)
+	if err != nil || version < 0 {
+		writeIfMatchError(w, err)
+		return
+	}
+
 	// Delete the company by user ID using the company service
-	err = c.companyService.DeleteCompaniesByUserID(userID, compID)
+	err = c.companyService.DeleteCompaniesByUserID(r.Context(), userID, compID, version)
 	if err != nil {
 		log.Error().Err(err).Send()
+		if errors.Is(err, services.ErrVersionMismatch) {
+			http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+			return
+		}
 		http.Error(w, "could not delete company by user id", http.StatusNotFound)
 		return
 	}
@@ -231,10 +294,29 @@ func (c Company) UpdateCompanyByUserID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only update the version the client last saw, when it sends If-Match
+	version, err := expectedVersion(r)
+	if err != nil || version < 0 {
+		writeIfMatchError(w, err)
+		return
+	}
+
 	// Perform the update
-	err = c.companyService.UpdateCompaniesByUserID(userID, compID, updates)
+	err = c.companyService.UpdateCompaniesByUserID(r.Context(), userID, compID, version, updates)
 	if err != nil {
 		log.Error().Err(err).Send()
+		if errors.Is(err, services.ErrVersionMismatch) {
+			http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+			return
+		}
+		if errors.Is(err, services.ErrCompanyNameTaken) {
+			http.Error(w, "a company with this name already exists", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, services.ErrUserSuspended) {
+			http.Error(w, services.ErrUserSuspended.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, "could not update company by user id and company id", http.StatusNotFound)
 		return
 	}
@@ -242,3 +324,104 @@ func (c Company) UpdateCompanyByUserID(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode("company updated successfully")
 }
+
+// RestoreCompanyByUserID handles restoring a soft deleted company and the jobs archived with it
+func (c Company) RestoreCompanyByUserID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, compID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+
+	// Restore the company using the company service
+	err := c.companyService.RestoreCompanyByUserID(r.Context(), userID, compID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "could not restore company by user id", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("company restored successfully")
+}
+
+// UploadLogo handles uploading a company logo as the "logo" field of a multipart form
+func (c Company) UploadLogo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+
+	// Leave room for the multipart framing around the image
+	maxBytes := services.DefaultLogoMaxBytes()
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes)+64<<10)
+	file, _, err := r.FormFile("logo")
+	if err != nil {
+		log.Error().Err(err).Send()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			sendErrorResp(w, "logo is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		sendErrorResp(w, "send the image as the logo field of a multipart form", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, int64(maxBytes)+1))
+	if err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "could not read logo", http.StatusBadRequest)
+		return
+	}
+
+	err = c.companyService.UploadLogo(r.Context(), userID, companyID, data)
+	if err != nil {
+		log.Error().Err(err).Send()
+		switch {
+		case errors.Is(err, images.ErrTooLarge):
+			sendErrorResp(w, "logo is too large", http.StatusRequestEntityTooLarge)
+		case errors.Is(err, images.ErrUnsupportedType):
+			sendErrorResp(w, err.Error(), http.StatusUnsupportedMediaType)
+		case errors.Is(err, services.ErrLogoBusy):
+			w.Header().Set("Retry-After", "5")
+			sendErrorResp(w, services.ErrLogoBusy.Error(), http.StatusTooManyRequests)
+		default:
+			sendErrorResp(w, "could not upload logo", http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("logo uploaded successfully")
+}
+
+// GetLogo handles serving a company logo thumbnail, without authentication
+func (c Company) GetLogo(w http.ResponseWriter, r *http.Request) {
+	companyID, err := intURLParam(r, "id")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	size, err := intURLParam(r, "size")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, updatedAt, err := c.companyService.GetLogo(r.Context(), companyID, size)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.NotFound(w, r)
+		return
+	}
+	if notModifiedSince(w, r, updatedAt) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
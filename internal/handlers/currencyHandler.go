@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/money"
+	"job-portal-api/internal/services"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+// Currency struct represents the handler for the exchange rates salaries are converted with
+type Currency struct {
+	currencyService *services.CurrencyService
+}
+
+// NewCurrency creates a new Currency handler with the provided service
+func NewCurrency(cs *services.CurrencyService) (*Currency, error) {
+	if cs == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &Currency{currencyService: cs}, nil
+}
+
+// ListRates handles listing the exchange rates against the base currency
+func (cu Currency) ListRates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rates, err := cu.currencyService.Rates(r.Context())
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rates)
+}
+
+// LoadRates handles operators replacing the exchange rates, which converts the annual salaries of all jobs again
+func (cu Currency) LoadRates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var newRates models.NewExchangeRates
+	if err := json.NewDecoder(r.Body).Decode(&newRates); err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validator.New().Struct(newRates); err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "send valid values", http.StatusBadRequest)
+		return
+	}
+
+	report, err := cu.currencyService.LoadRates(r.Context(), money.Rates{Base: newRates.Base, Rates: newRates.Rates})
+	if err != nil {
+		log.Error().Err(err).Send()
+		if errors.Is(err, money.ErrUnknownCurrency) {
+			sendErrorResp(w, "rates must include the base currency "+cu.currencyService.Base(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "could not load exchange rates", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errPreconditionRequired is returned by expectedVersion when If-Match is required but missing
+var errPreconditionRequired = errors.New("If-Match header is required")
+
+// etagFor returns the strong entity tag for a resource version
+func etagFor(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// etagForRevision returns the strong entity tag of a resource version that also embeds data changing independently
+// of the version, such as the verified badge or the rating of a company. expectedVersion only reads the version part.
+func etagForRevision(version, revision int) string {
+	return `"` + strconv.Itoa(version) + "." + strconv.Itoa(revision) + `"`
+}
+
+// ifMatchRequired reports whether PATCH and DELETE must send If-Match, from REQUIRE_IF_MATCH
+func ifMatchRequired() bool {
+	return os.Getenv("REQUIRE_IF_MATCH") == "true"
+}
+
+// expectedVersion parses the If-Match header into the version a conditional write expects.
+// It returns 0 when the write is unconditional: no header (unless required) or "*".
+func expectedVersion(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		if ifMatchRequired() {
+			return 0, errPreconditionRequired
+		}
+		return 0, nil
+	}
+	if header == "*" {
+		return 0, nil
+	}
+
+	// Weak tags never match for If-Match, and a single resource only has one current tag
+	if strings.HasPrefix(header, "W/") || strings.Contains(header, ",") {
+		return -1, nil
+	}
+	tag, _, _ := strings.Cut(strings.Trim(header, `"`), ".")
+	v, err := strconv.Atoi(tag)
+	if err != nil || v <= 0 {
+		return -1, nil
+	}
+	return v, nil
+}
+
+// writeIfMatchError writes the response for an If-Match header that could not be used
+func writeIfMatchError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errPreconditionRequired) {
+		http.Error(w, err.Error(), http.StatusPreconditionRequired)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+}
+
+// notModified sets the ETag header and reports whether If-None-Match matches it, in which case
+// a 304 Not Modified has been written and the caller must not write a body
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		// If-None-Match uses the weak comparison
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// publicMaxAge returns how long shared caches may keep public pages and feeds, from PUBLIC_CACHE_MAX_AGE in seconds
+func publicMaxAge() int {
+	secs, err := strconv.Atoi(os.Getenv("PUBLIC_CACHE_MAX_AGE"))
+	if err != nil || secs < 0 {
+		secs = 300
+	}
+	return secs
+}
+
+// notModifiedSince sets the caching headers of a public resource and reports whether If-Modified-Since is not
+// older than lastModified, in which case a 304 Not Modified has been written and the caller must not write a body
+func notModifiedSince(w http.ResponseWriter, r *http.Request, lastModified time.Time) bool {
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(publicMaxAge()))
+	if lastModified.IsZero() {
+		return false
+	}
+	// HTTP dates have a resolution of one second
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.After(since) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"job-portal-api/internal/auth"
+	"job-portal-api/internal/export"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/services"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+// exportFlushRows is the number of rows sent to the client per chunk
+const exportFlushRows = 500
+
+// Export columns, named like the JSON fields
+var (
+	jobExportColumns     = []string{"id", "jobRole", "salary", "currency", "payPeriod", "companyId", "version"}
+	companyExportColumns = []string{"id", "name", "address", "userId", "version"}
+)
+
+// jobExportRow returns the export values of a job in jobExportColumns order.
+func jobExportRow(j *models.Job) []interface{} {
+	return []interface{}{j.ID, j.JobRole, j.Salary, j.Currency, j.PayPeriod, j.CompanyId, j.Version}
+}
+
+// companyExportRow returns the export values of a company in companyExportColumns order.
+func companyExportRow(c *models.Company) []interface{} {
+	return []interface{}{c.ID, c.Name, c.Address, c.UserId, c.Version}
+}
+
+// negotiateExport picks the listing format and rejects formats the user may not request.
+// It returns false after writing an error response.
+func negotiateExport(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format := export.Negotiate(r)
+	if format == "" {
+		sendErrorResp(w, "unsupported format, use json, csv, jsonl or xlsx", http.StatusBadRequest)
+		return "", false
+	}
+	// Spreadsheets are a reporting feature for company admins
+	if format == export.FormatXLSX && roleFromRequest(r) != auth.Admin {
+		sendErrorResp(w, "xlsx exports are only available to company admins", http.StatusForbidden)
+		return "", false
+	}
+	return format, true
+}
+
+// streamExport streams rows produced by stream as an export file. Rows are flushed to the client every
+// exportFlushRows rows, so large exports go out with chunked encoding instead of being built in memory. When all
+// export cursors are taken it answers 503 with Retry-After, and 404 for the jobs of an unknown company.
+func streamExport(w http.ResponseWriter, format, name string, columns []string, stream func(write func([]interface{}) error) error) {
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+"."+format+`"`)
+
+	// Buffer the first chunk so errors before any data is sent still get a proper error response
+	sw := &sentWriter{w: w}
+	buf := bufio.NewWriterSize(sw, 64<<10)
+	flusher, _ := w.(http.Flusher)
+
+	ew, err := export.NewWriter(format, buf, columns)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "could not export", http.StatusInternalServerError)
+		return
+	}
+
+	rows := 0
+	err = stream(func(values []interface{}) error {
+		if err := ew.Write(values); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows != 0 {
+			return nil
+		}
+		if err := ew.Flush(); err != nil {
+			return err
+		}
+		if err := buf.Flush(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Int("rows", rows).Msg("export failed")
+		if !sw.sent {
+			w.Header().Del("Content-Disposition")
+			if errors.Is(err, services.ErrCompanyNotFound) {
+				http.Error(w, services.ErrCompanyNotFound.Error(), http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, services.ErrExportBusy) {
+				w.Header().Set("Retry-After", "60")
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			http.Error(w, "could not export", http.StatusInternalServerError)
+			return
+		}
+		// Part of the file is already out, abort the response so the client does not take it as complete
+		panic(http.ErrAbortHandler)
+	}
+
+	if err := ew.Close(); err != nil {
+		log.Error().Err(err).Send()
+		panic(http.ErrAbortHandler)
+	}
+	if err := buf.Flush(); err != nil {
+		log.Error().Err(err).Send()
+	}
+}
+
+// sentWriter records whether anything has been written to the response.
+type sentWriter struct {
+	w    io.Writer
+	sent bool
+}
+
+// Write writes to the response.
+func (s *sentWriter) Write(p []byte) (int, error) {
+	s.sent = true
+	return s.w.Write(p)
+}
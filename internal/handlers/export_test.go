@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"job-portal-api/internal/auth"
+	"job-portal-api/internal/services"
+	"job-portal-api/internal/sqltest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// jobRow returns a row selected with the job columns
+func jobRow(id int64, role string, salary int64) []driver.Value {
+	created := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	return []driver.Value{id, role, salary, int64(3), int64(1), created, created, "approved", nil, false, []byte("[]"),
+		"Berlin", "Berlin", "Berlin", "DE", nil, nil, false, []byte("[]"), []byte("[]"), "EUR", "month", nil}
+}
+
+// companyRow returns a row selected with the company columns
+func companyRow(id int64, name string) []driver.Value {
+	return []driver.Value{id, name, strings.ToLower(name), "Berlin", int64(7), int64(2), false, strings.ToLower(name), "", "",
+		"", "", "", "", nil, []byte("{}"), nil, false, nil, nil,
+		nil, nil, nil, nil, nil, nil, nil, nil, "approved", "", "", "", nil, nil}
+}
+
+// fetchOnce answers the first FETCH of an export cursor with the rows and the next ones with none
+func fetchOnce(fake *sqltest.DB, rows ...[]driver.Value) {
+	fetched := false
+	fake.Query("FETCH", func([]driver.Value) (*sqltest.Rows, error) {
+		r := &sqltest.Rows{}
+		if !fetched {
+			for _, row := range rows {
+				r.Add(row...)
+			}
+		}
+		fetched = true
+		return r, nil
+	})
+}
+
+// exportRequest returns a GET request for the export format by a user with the role
+func exportRequest(target, role string, params map[string]string) *http.Request {
+	r := withRoute(httptest.NewRequest(http.MethodGet, target, nil), "7", params)
+	return r.WithContext(context.WithValue(r.Context(), "roles", role))
+}
+
+// xlsxSheet returns the worksheet of an xlsx export
+func xlsxSheet(t *testing.T, body []byte) string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("xlsx export is not a zip file: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		sheet, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(sheet)
+	}
+	t.Fatal("xlsx export has no worksheet")
+	return ""
+}
+
+func TestJobsByCompanyExport(t *testing.T) {
+	tests := []struct {
+		format      string
+		contentType string
+		check       func(t *testing.T, body []byte)
+	}{
+		{"csv", "text/csv", func(t *testing.T, body []byte) {
+			want := "id,jobRole,salary,currency,payPeriod,companyId,version\n11,go developer,5000,EUR,month,3,1\n12,accountant,4000,EUR,month,3,1\n"
+			if got := strings.ReplaceAll(string(body), "\r\n", "\n"); got != want {
+				t.Errorf("csv export = %q, want %q", got, want)
+			}
+		}},
+		{"jsonl", "application/x-ndjson", func(t *testing.T, body []byte) {
+			lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("jsonl export has %d lines, want 2: %s", len(lines), body)
+			}
+			var job map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[0]), &job); err != nil || job["jobRole"] != "go developer" || job["salary"] != float64(5000) {
+				t.Errorf("jsonl line = %v, %v", job, err)
+			}
+		}},
+		{"xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", func(t *testing.T, body []byte) {
+			sheet := xlsxSheet(t, body)
+			for _, want := range []string{"jobRole", "go developer", "accountant", "5000"} {
+				if !strings.Contains(sheet, want) {
+					t.Errorf("xlsx sheet does not contain %q", want)
+				}
+			}
+		}},
+	}
+	for _, tt := range tests {
+		js, fake := newTestJobService(t)
+		fake.Query("SELECT EXISTS (SELECT 1 FROM companies", func([]driver.Value) (*sqltest.Rows, error) {
+			return sqltest.Row(true), nil
+		})
+		fetchOnce(fake, jobRow(11, "go developer", 5000), jobRow(12, "accountant", 4000))
+		h, err := NewJob(js, newTestAuth(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		h.GetJobByCompanyID(w, exportRequest("/api/companies/3/jobs?format="+tt.format, auth.Admin, map[string]string{"id": "3"}))
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), tt.contentType) {
+			t.Errorf("%s export = %d %q, want 200 %s: %s", tt.format, w.Code, w.Header().Get("Content-Type"), tt.contentType, w.Body)
+			continue
+		}
+		if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="company-3-jobs.`+tt.format+`"` {
+			t.Errorf("%s export Content-Disposition = %q", tt.format, cd)
+		}
+		tt.check(t, w.Body.Bytes())
+
+		declared := fake.Execs("DECLARE export_cursor")
+		if len(declared) != 1 || declared[0].Args[0] != int64(3) {
+			t.Errorf("%s export declared %v, want a cursor over company 3", tt.format, declared)
+		}
+	}
+}
+
+func TestJobsByCompanyExportUnknownCompany(t *testing.T) {
+	js, fake := newTestJobService(t)
+	fake.Query("SELECT EXISTS (SELECT 1 FROM companies", func([]driver.Value) (*sqltest.Rows, error) {
+		return sqltest.Row(false), nil
+	})
+	h, err := NewJob(js, newTestAuth(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"csv", "jsonl", "xlsx", "json"} {
+		w := httptest.NewRecorder()
+		h.GetJobByCompanyID(w, exportRequest("/api/companies/9/jobs?format="+format, auth.Admin, map[string]string{"id": "9"}))
+		if w.Code != http.StatusNotFound || w.Header().Get("Content-Disposition") != "" {
+			t.Errorf("%s export of an unknown company = %d %q, want 404 without a file", format, w.Code, w.Header().Get("Content-Disposition"))
+		}
+	}
+	if declared := fake.Execs("DECLARE export_cursor"); len(declared) != 0 {
+		t.Errorf("export of an unknown company declared %d cursors, want 0", len(declared))
+	}
+}
+
+func TestExportXLSXForAdminsOnly(t *testing.T) {
+	js, _ := newTestJobService(t)
+	h, err := NewJob(js, newTestAuth(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.GetJobByCompanyID(w, exportRequest("/api/companies/3/jobs?format=xlsx", auth.User, map[string]string{"id": "3"}))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("xlsx export by a user = %d, want 403", w.Code)
+	}
+}
+
+func TestCompaniesExportSort(t *testing.T) {
+	tests := []struct {
+		sort  string
+		order string
+	}{
+		{"", "ORDER BY id"},
+		{"id", "ORDER BY id"},
+		{"rating", "ORDER BY overall_rating DESC NULLS LAST"},
+	}
+	for _, tt := range tests {
+		db, fake := sqltest.Open(t)
+		ms, err := services.NewModerationService(db, testEngine(), services.DefaultModerationConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		cursors, err := services.NewCursors(db, services.DefaultExportConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs, err := services.NewCompanyService(db, time.Hour, ms, cursors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := NewCompany(cs, newTestAuth(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fetchOnce(fake, companyRow(1, "Acme"), companyRow(2, "Globex"))
+
+		w := httptest.NewRecorder()
+		h.GetAllCompanies(w, exportRequest("/api/companies?format=csv&sort="+tt.sort, auth.User, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("csv export sorted by %q = %d: %s", tt.sort, w.Code, w.Body)
+			continue
+		}
+		want := "id,name,address,userId,version\n1,Acme,Berlin,7,2\n2,Globex,Berlin,7,2\n"
+		if got := strings.ReplaceAll(w.Body.String(), "\r\n", "\n"); got != want {
+			t.Errorf("csv export sorted by %q = %q, want %q", tt.sort, got, want)
+		}
+		declared := fake.Execs("DECLARE export_cursor")
+		if len(declared) != 1 || !strings.Contains(declared[0].Query, tt.order) {
+			t.Errorf("csv export sorted by %q declared %v, want %s", tt.sort, declared, tt.order)
+		}
+	}
+
+	db, _ := sqltest.Open(t)
+	cursors, _ := services.NewCursors(db, services.DefaultExportConfig())
+	ms, _ := services.NewModerationService(db, testEngine(), services.DefaultModerationConfig())
+	cs, _ := services.NewCompanyService(db, time.Hour, ms, cursors)
+	h, _ := NewCompany(cs, newTestAuth(t))
+	w := httptest.NewRecorder()
+	h.GetAllCompanies(w, exportRequest("/api/companies?format=csv&sort=name", auth.User, nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("csv export sorted by name = %d, want 400", w.Code)
+	}
+}
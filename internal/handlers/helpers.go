@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// userIDFromRequest extracts the authenticated user ID set in the request context by the JWT middleware
+func userIDFromRequest(r *http.Request) (int, error) {
+	userIDStr, ok := r.Context().Value("userID").(string)
+	if !ok {
+		return 0, errors.New("user id not found in context")
+	}
+	return strconv.Atoi(userIDStr)
+}
+
+// roleFromRequest returns the role of the authenticated user set in the request context by the JWT middleware
+func roleFromRequest(r *http.Request) string {
+	role, _ := r.Context().Value("roles").(string)
+	return role
+}
+
+// intURLParam extracts an integer URL parameter
+func intURLParam(r *http.Request, name string) (int, error) {
+	return strconv.Atoi(chi.URLParam(r, name))
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"job-portal-api/internal/dedup"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultInsightMonths is the length of the salary trend without a months parameter
+const defaultInsightMonths = 12
+
+// Insight struct represents the handler for market statistics
+type Insight struct {
+	insightService *services.InsightService
+}
+
+// NewInsight creates a new Insight handler with the provided service
+func NewInsight(is *services.InsightService) (*Insight, error) {
+	if is == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &Insight{insightService: is}, nil
+}
+
+// SalaryInsights handles the salary percentiles and trend of the postings of a role, location and company size
+func (in Insight) SalaryInsights(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.URL.Query()
+	q := models.SalaryQuery{Months: defaultInsightMonths}
+	if role := params.Get("role"); role != "" {
+		if q.Role = dedup.NormalizeRole(role); q.Role == "" {
+			sendErrorResp(w, "role has no meaningful words", http.StatusBadRequest)
+			return
+		}
+	}
+	if q.Country = strings.ToUpper(params.Get("country")); q.Country != "" && len(q.Country) != 2 {
+		sendErrorResp(w, "country must be an ISO 3166-1 alpha-2 code", http.StatusBadRequest)
+		return
+	}
+	if location := params.Get("location"); location != "" {
+		if q.Country != "" {
+			sendErrorResp(w, "send either location or country", http.StatusBadRequest)
+			return
+		}
+		loc, err := in.insightService.Locate(r.Context(), location)
+		if err != nil {
+			log.Error().Err(err).Send()
+			if errors.Is(err, services.ErrUnknownLocation) {
+				sendErrorResp(w, "unknown location", http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "something went wrong.", http.StatusInternalServerError)
+			return
+		}
+		q.Country, q.City = loc.Country, loc.City
+	}
+	if q.CompanySize = params.Get("companySize"); q.CompanySize != "" {
+		valid := false
+		for _, band := range models.CompanySizeBands {
+			valid = valid || band == q.CompanySize
+		}
+		if !valid {
+			sendErrorResp(w, "companySize must be one of "+strings.Join(models.CompanySizeBands, ", "), http.StatusBadRequest)
+			return
+		}
+	}
+	if v := params.Get("months"); v != "" {
+		months, err := strconv.Atoi(v)
+		if err != nil || months < 1 || months > 36 {
+			sendErrorResp(w, "months must be between 1 and 36", http.StatusBadRequest)
+			return
+		}
+		q.Months = months
+	}
+
+	insights, err := in.insightService.SalaryInsights(r.Context(), q)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(insights)
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"job-portal-api/internal/geo"
+	"job-portal-api/internal/ical"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+// Interview struct represents the handler for interview slots and interviews
+type Interview struct {
+	interviewService *services.InterviewService
+}
+
+// NewInterview creates a new Interview handler with the provided service
+func NewInterview(is *services.InterviewService) (*Interview, error) {
+	if is == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &Interview{interviewService: is}, nil
+}
+
+// CreateSlots handles a recruiter publishing availability slots for a job of their company
+func (iv Interview) CreateSlots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, jobID, ok := userAndID(w, r)
+	if !ok {
+		return
+	}
+	var newSlots models.NewInterviewSlots
+	if !decodeValid(w, r, &newSlots) {
+		return
+	}
+
+	slots, err := iv.interviewService.CreateSlots(r.Context(), userID, jobID, newSlots.Slots)
+	if err != nil {
+		writeInterviewError(w, err, "could not create interview slots")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(slots)
+}
+
+// ListSlots handles listing the upcoming slots of a job, to its recruiter or a candidate who applied to it
+func (iv Interview) ListSlots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, jobID, ok := userAndID(w, r)
+	if !ok {
+		return
+	}
+
+	slots, err := iv.interviewService.ListSlots(r.Context(), userID, jobID)
+	if err != nil {
+		writeInterviewError(w, err, "could not list interview slots")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(slots)
+}
+
+// DeleteSlot handles a recruiter withdrawing a slot nobody booked
+func (iv Interview) DeleteSlot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, slotID, ok := userAndID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := iv.interviewService.DeleteSlot(r.Context(), userID, slotID); err != nil {
+		writeInterviewError(w, err, "could not delete interview slot")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("interview slot deleted successfully")
+}
+
+// BookInterview handles a candidate booking a slot of a job they applied to
+func (iv Interview) BookInterview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, jobID, ok := userAndID(w, r)
+	if !ok {
+		return
+	}
+	var booking models.BookInterview
+	if !decodeValid(w, r, &booking) {
+		return
+	}
+
+	interview, err := iv.interviewService.BookInterview(r.Context(), userID, jobID, booking.SlotId)
+	if err != nil {
+		writeInterviewError(w, err, "could not book interview")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(interview)
+}
+
+// ListInterviews handles listing the interviews of the user, as a candidate or a recruiter
+func (iv Interview) ListInterviews(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	interviews, err := iv.interviewService.ListInterviews(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(interviews)
+}
+
+// GetInterview handles fetching an interview of the user
+func (iv Interview) GetInterview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, interviewID, ok := userAndID(w, r)
+	if !ok {
+		return
+	}
+
+	interview, err := iv.interviewService.GetInterview(r.Context(), userID, interviewID)
+	if err != nil {
+		writeInterviewError(w, err, "could not get interview")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(interview)
+}
+
+// RescheduleInterview handles the candidate or the recruiter moving an interview to another slot
+func (iv Interview) RescheduleInterview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, interviewID, ok := userAndID(w, r)
+	if !ok {
+		return
+	}
+	var booking models.BookInterview
+	if !decodeValid(w, r, &booking) {
+		return
+	}
+
+	interview, err := iv.interviewService.RescheduleInterview(r.Context(), userID, interviewID, booking.SlotId)
+	if err != nil {
+		writeInterviewError(w, err, "could not reschedule interview")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(interview)
+}
+
+// CancelInterview handles the candidate or the recruiter cancelling an interview
+func (iv Interview) CancelInterview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, interviewID, ok := userAndID(w, r)
+	if !ok {
+		return
+	}
+	var cancel models.CancelInterview
+	if !decodeValid(w, r, &cancel) {
+		return
+	}
+
+	interview, err := iv.interviewService.CancelInterview(r.Context(), userID, interviewID, cancel.Reason)
+	if err != nil {
+		writeInterviewError(w, err, "could not cancel interview")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(interview)
+}
+
+// Invitation handles downloading the iCalendar invitation of an interview, in the time zone of the slot or the
+// one in the timezone query parameter
+func (iv Interview) Invitation(w http.ResponseWriter, r *http.Request) {
+	userID, interviewID, ok := userAndID(w, r)
+	if !ok {
+		return
+	}
+	timezone := r.URL.Query().Get("timezone")
+	if timezone != "" && !geo.ValidTimezone(timezone) {
+		http.Error(w, "invalid timezone", http.StatusBadRequest)
+		return
+	}
+
+	data, err := iv.interviewService.Invitation(r.Context(), userID, interviewID, timezone)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		writeInterviewError(w, err, "could not get invitation")
+		return
+	}
+
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="interview-`+strconv.Itoa(interviewID)+`.ics"`)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// userAndID reads the user and the id in the URL.
+// It writes the error response and returns false when one of them is invalid.
+func userAndID(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+	id, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return userID, id, true
+}
+
+// decodeValid reads the validated request body into v.
+// It writes the error response and returns false when it is invalid.
+func decodeValid(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return false
+	}
+	if err := validator.New().Struct(v); err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "send valid values", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// writeInterviewError maps interview scheduling errors to responses
+func writeInterviewError(w http.ResponseWriter, err error, msg string) {
+	log.Error().Err(err).Send()
+	switch {
+	case errors.Is(err, services.ErrJobNotFound), errors.Is(err, services.ErrSlotNotFound),
+		errors.Is(err, services.ErrInterviewNotFound):
+		sendErrorResp(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrNotApplied), errors.Is(err, services.ErrUserSuspended):
+		sendErrorResp(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrSlotOverlap), errors.Is(err, services.ErrSlotUnavailable),
+		errors.Is(err, services.ErrSlotBooked), errors.Is(err, services.ErrInterviewScheduled),
+		errors.Is(err, services.ErrScheduleConflict), errors.Is(err, services.ErrInterviewClosed):
+		sendErrorResp(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrInvalidSlot):
+		sendErrorResp(w, err.Error(), http.StatusBadRequest)
+	default:
+		sendErrorResp(w, msg, http.StatusInternalServerError)
+	}
+}
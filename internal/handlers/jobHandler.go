@@ -3,11 +3,18 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"hash/fnv"
 	"job-portal-api/internal/auth"
+	"job-portal-api/internal/export"
+	"job-portal-api/internal/geo"
 	"job-portal-api/internal/models"
+	"job-portal-api/internal/money"
 	"job-portal-api/internal/services"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -61,16 +68,57 @@ func (j Job) CreateJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create the job using the job service
-	jobRole := newJob.JobRole
-	salary := newJob.Salary
+	// Extract user ID from the request context
+	userIDStr, ok := r.Context().Value("userID").(string)
+	if !ok {
+		http.Error(w, "user id not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid user id in context", http.StatusUnauthorized)
+		return
+	}
 
-	_, err = j.jobService.CreateJob(jobRole, salary, companyID)
+	// Create the job using the job service
+	job, err := j.jobService.CreateJob(r.Context(), userID, newJob, companyID)
 	if err != nil {
 		log.Error().Err(err).Send()
+		if errors.Is(err, services.ErrUserSuspended) {
+			http.Error(w, services.ErrUserSuspended.Error(), http.StatusForbidden)
+			return
+		}
+		// A company of another user is not revealed, it is reported as missing
+		if errors.Is(err, services.ErrCompanyNotFound) {
+			sendErrorResp(w, services.ErrCompanyNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidRemote) {
+			sendErrorResp(w, services.ErrInvalidRemote.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidSalary) {
+			sendErrorResp(w, services.ErrInvalidSalary.Error(), http.StatusBadRequest)
+			return
+		}
+		if writeDuplicateError(w, err) {
+			return
+		}
 		http.Error(w, "something went wrong in creating job", http.StatusInternalServerError)
 		return
 	}
+	if job.DuplicateOf != nil {
+		setDuplicateHeader(w, *job.DuplicateOf)
+	}
+
+	// Flagged jobs are created unpublished until an operator approves them
+	if job.ModerationStatus == models.ModerationPending {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode("Job submitted for review")
+		return
+	}
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode("Job created successfully")
@@ -89,6 +137,20 @@ func (j Job) GetJobByCompanyID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Stream CSV, JSON Lines and XLSX exports straight from the database
+	format, ok := negotiateExport(w, r)
+	if !ok {
+		return
+	}
+	if format != export.FormatJSON {
+		streamExport(w, format, "company-"+idStr+"-jobs", jobExportColumns, func(write func([]interface{}) error) error {
+			return j.jobService.ExportJobsByCompanyID(r.Context(), companyID, func(job *models.Job) error {
+				return write(jobExportRow(job))
+			})
+		})
+		return
+	}
+
 	// Get jobs by company ID using the job service
 	jobs, err := j.jobService.GetJobsByCompaniesID(companyID)
 	if err != nil {
@@ -96,6 +158,9 @@ func (j Job) GetJobByCompanyID(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "could not get jobs by company id", http.StatusNotFound)
 		return
 	}
+	if !j.convertSalaries(w, r, jobs) {
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(jobs)
@@ -105,11 +170,60 @@ func (j Job) GetJobByCompanyID(w http.ResponseWriter, r *http.Request) {
 func (j Job) GetAllJob(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	format, ok := negotiateExport(w, r)
+	if !ok {
+		return
+	}
+
+	// Collapse near duplicates into one result when asked to
+	collapse, err := parseBoolParam(r.URL.Query().Get("collapse"))
+	if err != nil {
+		sendErrorResp(w, "invalid collapse", http.StatusBadRequest)
+		return
+	}
+
+	// Filter by canonical skill, including the skills below it, and by location
+	filter := services.JobFilter{Skill: r.URL.Query().Get("skill"), Collapse: collapse}
+	if err := parseLocationFilter(r, &filter); err != nil {
+		sendErrorResp(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := parseSalaryFilter(r, &filter); err != nil {
+		sendErrorResp(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Stream CSV, JSON Lines and XLSX exports of the same jobs straight from the database
+	if format != export.FormatJSON {
+		stream := func(fn func(*models.Job) error) error {
+			return j.jobService.ExportAllJobs(r.Context(), fn)
+		}
+		if filter != (services.JobFilter{}) {
+			if stream, err = j.jobService.ExportSearchJobs(r.Context(), filter); err != nil {
+				writeJobSearchError(w, err)
+				return
+			}
+		}
+		streamExport(w, format, "jobs", jobExportColumns, func(write func([]interface{}) error) error {
+			return stream(func(job *models.Job) error {
+				return write(jobExportRow(job))
+			})
+		})
+		return
+	}
+
 	// Get all jobs using the job service
-	jobs, err := j.jobService.GetAllJobs()
+	var jobs []*models.Job
+	if filter != (services.JobFilter{}) {
+		jobs, err = j.jobService.SearchJobs(r.Context(), filter)
+	} else {
+		jobs, err = j.jobService.GetAllJobs()
+	}
 	if err != nil {
-		log.Error().Err(err).Send()
-		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		writeJobSearchError(w, err)
+		return
+	}
+	if !j.convertSalaries(w, r, jobs) {
 		return
 	}
 
@@ -117,6 +231,124 @@ func (j Job) GetAllJob(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(jobs)
 }
 
+// writeJobSearchError logs a failed job search and writes its error response
+func writeJobSearchError(w http.ResponseWriter, err error) {
+	log.Error().Err(err).Send()
+	switch {
+	case errors.Is(err, services.ErrSkillNotFound):
+		sendErrorResp(w, "unknown skill", http.StatusBadRequest)
+	case errors.Is(err, money.ErrUnknownCurrency):
+		sendErrorResp(w, money.ErrUnknownCurrency.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+	}
+}
+
+// Radius search bounds, in kilometres
+const (
+	defaultRadiusKm = 50
+	maxRadiusKm     = 500
+)
+
+// parseLocationFilter reads the location query parameters of the job listing into the filter: near=lat,lng with
+// an optional radius_km, country, remote and timezone
+func parseLocationFilter(r *http.Request, f *services.JobFilter) error {
+	q := r.URL.Query()
+	if near := q.Get("near"); near != "" {
+		lat, lng, ok := strings.Cut(near, ",")
+		var err error
+		if ok {
+			if f.Latitude, err = strconv.ParseFloat(strings.TrimSpace(lat), 64); err == nil {
+				f.Longitude, err = strconv.ParseFloat(strings.TrimSpace(lng), 64)
+			}
+		}
+		if !ok || err != nil || math.Abs(f.Latitude) > 90 || math.Abs(f.Longitude) > 180 {
+			return errors.New("near must be latitude,longitude in decimal degrees")
+		}
+		f.Near, f.RadiusKm = true, defaultRadiusKm
+	}
+	if radius := q.Get("radius_km"); radius != "" {
+		km, err := strconv.ParseFloat(radius, 64)
+		if err != nil || !f.Near || km <= 0 || km > maxRadiusKm {
+			return fmt.Errorf("radius_km must be a positive number up to %d, with near", maxRadiusKm)
+		}
+		f.RadiusKm = km
+	}
+	if f.Country = strings.ToUpper(q.Get("country")); f.Country != "" && len(f.Country) != 2 {
+		return errors.New("country must be an ISO 3166-1 alpha-2 code")
+	}
+	remote, err := parseBoolParam(q.Get("remote"))
+	if err != nil {
+		return errors.New("invalid remote")
+	}
+	f.Remote = remote
+	if f.Timezone = q.Get("timezone"); f.Timezone != "" && !geo.ValidTimezone(f.Timezone) {
+		return errors.New("timezone must be an IANA time zone such as Europe/Berlin")
+	}
+	return nil
+}
+
+// parseSalaryFilter reads the salary query parameters of the job listing into the filter: min_salary and
+// max_salary per year, in the currency parameter or the base currency, and sort=salary or sort=-salary
+func parseSalaryFilter(r *http.Request, f *services.JobFilter) error {
+	q := r.URL.Query()
+	for _, p := range []struct {
+		name  string
+		value *float64
+	}{{"min_salary", &f.MinSalary}, {"max_salary", &f.MaxSalary}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil || amount <= 0 || math.IsInf(amount, 0) {
+			return fmt.Errorf("%s must be a positive number", p.name)
+		}
+		*p.value = amount
+	}
+	if f.MaxSalary > 0 && f.MinSalary > f.MaxSalary {
+		return errors.New("min_salary must not be above max_salary")
+	}
+	if f.MinSalary > 0 || f.MaxSalary > 0 {
+		if f.SalaryCurrency = strings.ToUpper(q.Get("currency")); f.SalaryCurrency != "" && !money.ValidCurrency(f.SalaryCurrency) {
+			return errors.New("currency must be an ISO 4217 code")
+		}
+	}
+	switch q.Get("sort") {
+	case "":
+	case "salary":
+		f.SortSalary = services.SortSalaryAsc
+	case "-salary":
+		f.SortSalary = services.SortSalaryDesc
+	default:
+		return errors.New("sort must be salary or -salary")
+	}
+	return nil
+}
+
+// convertSalaries converts the salaries of the jobs into the currency parameter, when there is one. It returns
+// false after writing an error response.
+func (j Job) convertSalaries(w http.ResponseWriter, r *http.Request, jobs []*models.Job) bool {
+	currency := strings.ToUpper(r.URL.Query().Get("currency"))
+	if currency == "" {
+		return true
+	}
+	if !money.ValidCurrency(currency) {
+		sendErrorResp(w, "currency must be an ISO 4217 code", http.StatusBadRequest)
+		return false
+	}
+	if err := j.jobService.ConvertSalaries(r.Context(), jobs, currency); err != nil {
+		log.Error().Err(err).Send()
+		if errors.Is(err, money.ErrUnknownCurrency) {
+			sendErrorResp(w, money.ErrUnknownCurrency.Error(), http.StatusBadRequest)
+			return false
+		}
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
 // GetJobByID handles the retrieval of a job by ID
 func (j Job) GetJobByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -138,10 +370,79 @@ func (j Job) GetJobByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Remember the view for the user's recommendations, without failing the request over it
+	if userID, err := userIDFromRequest(r); err == nil {
+		if err := j.jobService.RecordView(r.Context(), userID, jobID); err != nil {
+			log.Error().Err(err).Send()
+		}
+	}
+
+	if !j.convertSalaries(w, r, []*models.Job{job}) {
+		return
+	}
+
+	// Let clients revalidate cached copies with If-None-Match
+	if notModified(w, r, etagForRevision(job.Version, jobRevision(job))) {
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(job)
 }
 
+// jobRevision sums up what changes on a job without its version: the company badge, the duplicate clustering,
+// the skills, which are tagged again when the taxonomy is curated, the place, which follows the company
+// address for jobs without a location of their own, and the salary amounts that follow the exchange rates
+func jobRevision(job *models.Job) int {
+	duplicateOf := 0
+	if job.DuplicateOf != nil {
+		duplicateOf = *job.DuplicateOf
+	}
+	var annualSalary float64
+	if job.AnnualSalary != nil {
+		annualSalary = *job.AnnualSalary
+	}
+	var converted models.ConvertedSalary
+	if job.Converted != nil {
+		converted = *job.Converted
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%t|%d|%s|%s|%s|%s|%g|%s|%g", job.CompanyVerified, duplicateOf, strings.Join(job.Skills, ","),
+		job.Location.City, job.Location.Region, job.Location.Country, annualSalary, converted.Currency, converted.Salary)
+	return int(h.Sum32())
+}
+
+// ApplyToJob handles a candidate applying to a job
+func (j Job) ApplyToJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jobID, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	app, err := j.jobService.Apply(r.Context(), userID, jobID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		if errors.Is(err, services.ErrAlreadyApplied) {
+			sendErrorResp(w, "you already applied to this job", http.StatusConflict)
+			return
+		}
+		http.Error(w, "could not apply to this job", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(app)
+}
+
 // sendErrorResp is a helper function to send error responses with a custom message and status code
 func sendErrorResp(w http.ResponseWriter, msg string, statusCode int) {
 	errorMsg := struct {
@@ -154,7 +455,7 @@ func sendErrorResp(w http.ResponseWriter, msg string, statusCode int) {
 	json.NewEncoder(w).Encode(errorMsg)
 }
 
-// DeleteJobByUserID handles the deletion of a job by user ID
+// DeleteJobByUserID handles the soft deletion of a job by user ID
 func (j Job) DeleteJobByUserID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -181,10 +482,21 @@ func (j Job) DeleteJobByUserID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only delete the version the client last saw, when it sends If-Match
+	version, err := expectedVersion(r)
+	if err != nil || version < 0 {
+		writeIfMatchError(w, err)
+		return
+	}
+
 	// Delete the job by user ID using the job service
-	err = j.jobService.DeleteJobsByUserID(userID, jobID)
+	err = j.jobService.DeleteJobsByUserID(r.Context(), userID, jobID, version)
 	if err != nil {
 		log.Error().Err(err).Send()
+		if errors.Is(err, services.ErrVersionMismatch) {
+			http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+			return
+		}
 		http.Error(w, "could not delete job by user id", http.StatusNotFound)
 		return
 	}
@@ -229,14 +541,94 @@ func (j Job) UpdateJobByUserID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only update the version the client last saw, when it sends If-Match
+	version, err := expectedVersion(r)
+	if err != nil || version < 0 {
+		writeIfMatchError(w, err)
+		return
+	}
+
 	// Perform the update using the job service
-	err = j.jobService.UpdateJobByUserID(userID, jobID, updates)
+	duplicateOf, err := j.jobService.UpdateJobByUserID(r.Context(), userID, jobID, version, updates)
 	if err != nil {
 		log.Error().Err(err).Send()
+		if errors.Is(err, services.ErrVersionMismatch) {
+			http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+			return
+		}
+		if errors.Is(err, services.ErrUserSuspended) {
+			http.Error(w, services.ErrUserSuspended.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidRemote) {
+			sendErrorResp(w, services.ErrInvalidRemote.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidSalary) {
+			sendErrorResp(w, services.ErrInvalidSalary.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidSalaryAmount) {
+			sendErrorResp(w, services.ErrInvalidSalaryAmount.Error(), http.StatusBadRequest)
+			return
+		}
+		if writeDuplicateError(w, err) {
+			return
+		}
 		http.Error(w, "could not update job by user id and job id", http.StatusNotFound)
 		return
 	}
+	if duplicateOf != 0 {
+		setDuplicateHeader(w, duplicateOf)
+	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode("job updated successfully")
 }
+
+// RestoreJobByUserID handles restoring a soft deleted job
+func (j Job) RestoreJobByUserID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract job ID from the URL parameter
+	jobID, err := intURLParam(r, "id")
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid job id", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid user id in context", http.StatusUnauthorized)
+		return
+	}
+
+	// Restore the job using the job service
+	err = j.jobService.RestoreJobByUserID(r.Context(), userID, jobID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "could not restore job by user id", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("job restored successfully")
+}
+
+// setDuplicateHeader tells the client that the job it saved nearly duplicates an earlier posting
+func setDuplicateHeader(w http.ResponseWriter, duplicateOf int) {
+	w.Header().Set("X-Duplicate-Of", strconv.Itoa(duplicateOf))
+}
+
+// writeDuplicateError writes 409 Conflict when the duplicate policy refused the job, and reports whether it did
+func writeDuplicateError(w http.ResponseWriter, err error) bool {
+	var dup *services.DuplicateJobError
+	if !errors.As(err, &dup) {
+		return false
+	}
+	w.Header().Set("X-Duplicate-Of", strconv.Itoa(dup.DuplicateOf))
+	sendErrorResp(w, dup.Error(), http.StatusConflict)
+	return true
+}
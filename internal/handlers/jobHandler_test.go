@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql/driver"
+	"job-portal-api/internal/auth"
+	"job-portal-api/internal/moderation"
+	"job-portal-api/internal/services"
+	"job-portal-api/internal/sqltest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// newTestJobService returns a job service on a sqltest database
+func newTestJobService(t *testing.T) (*services.JobService, *sqltest.DB) {
+	t.Helper()
+	db, fake := sqltest.Open(t)
+	ms, err := services.NewModerationService(db, testEngine(), services.DefaultModerationConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := services.NewDuplicateService(db, services.DefaultDuplicateConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err := services.NewSkillService(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := services.NewCurrencyService(db, services.CurrencyConfig{Base: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursors, err := services.NewCursors(db, services.DefaultExportConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := services.NewJobService(db, time.Hour, ms, ds, ss, cs, cursors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return js, fake
+}
+
+// testEngine returns the default moderation rule engine
+func testEngine() *moderation.Engine {
+	return moderation.NewEngine(moderation.DefaultConfig())
+}
+
+// newTestAuth returns an Auth with a freshly generated key
+func newTestAuth(t *testing.T) *auth.Auth {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := auth.NewAuth(&key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// withRoute returns the request as routed by chi with the URL parameters, on behalf of the user
+func withRoute(r *http.Request, userID string, params map[string]string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	if userID != "" {
+		ctx = context.WithValue(ctx, "userID", userID)
+	}
+	return r.WithContext(ctx)
+}
+
+func TestCreateJobCompanyOfAnotherUser(t *testing.T) {
+	js, fake := newTestJobService(t)
+	fake.Query("SELECT suspended_at IS NOT NULL FROM users", func([]driver.Value) (*sqltest.Rows, error) {
+		return sqltest.Row(false), nil
+	})
+	// The company is live but not owned by the user, so the insert selects no row
+	var insertArgs []driver.Value
+	fake.Query("INSERT INTO jobs", func(args []driver.Value) (*sqltest.Rows, error) {
+		insertArgs = args
+		return nil, nil
+	})
+
+	h, err := NewJob(js, newTestAuth(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/companies/3/jobs", strings.NewReader(`{"jobRole":"Go Developer","salary":100}`))
+	w := httptest.NewRecorder()
+	h.CreateJob(w, withRoute(r, "7", map[string]string{"id": "3"}))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("CreateJob() status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body)
+	}
+	if !strings.Contains(w.Body.String(), services.ErrCompanyNotFound.Error()) {
+		t.Errorf("CreateJob() body = %s, want %q", w.Body, services.ErrCompanyNotFound)
+	}
+	if len(insertArgs) != 11 || insertArgs[5] != int64(3) || insertArgs[10] != int64(7) {
+		t.Errorf("insert ran with %v, want company 3 checked against user 7", insertArgs)
+	}
+	if fake.Commits() != 0 {
+		t.Errorf("CreateJob() committed %d transactions, want 0", fake.Commits())
+	}
+}
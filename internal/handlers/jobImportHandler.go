@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"job-portal-api/internal/jobimport"
+	"job-portal-api/internal/services"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// JobImport struct represents the handler for bulk job imports
+type JobImport struct {
+	importService *services.JobImportService
+	maxBytes      int64
+}
+
+// NewJobImport creates a new JobImport handler with the provided service
+func NewJobImport(is *services.JobImportService) (*JobImport, error) {
+	if is == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &JobImport{importService: is, maxBytes: ImportMaxBytes()}, nil
+}
+
+// ImportMaxBytes returns the largest accepted import file from JOB_IMPORT_MAX_MB, defaulting to 50 MB.
+func ImportMaxBytes() int64 {
+	mb, err := strconv.Atoi(os.Getenv("JOB_IMPORT_MAX_MB"))
+	if err != nil || mb <= 0 {
+		mb = 50
+	}
+	return int64(mb) << 20
+}
+
+// ImportJobs handles bulk importing jobs from a CSV or JSON Lines request body. The format comes from
+// the format query parameter or the Content-Type; mapping, mode, dry_run and async tune the import.
+func (ji JobImport) ImportJobs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse the import options
+	q := r.URL.Query()
+	opts := services.ImportOptions{Format: importFormat(r), Mode: q.Get("mode")}
+	if opts.Format == "" {
+		sendErrorResp(w, "unsupported format, send text/csv or application/x-ndjson", http.StatusUnsupportedMediaType)
+		return
+	}
+	mapping, err := jobimport.ParseMapping(q.Get("mapping"))
+	if err != nil {
+		sendErrorResp(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Mapping = mapping
+	if opts.DryRun, err = parseBoolParam(q.Get("dry_run")); err != nil {
+		sendErrorResp(w, "invalid dry_run", http.StatusBadRequest)
+		return
+	}
+	async, err := parseBoolParam(q.Get("async"))
+	if err != nil {
+		sendErrorResp(w, "invalid async", http.StatusBadRequest)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, ji.maxBytes)
+	if async {
+		report, err := ji.importService.StartImport(r.Context(), userID, companyID, body, opts)
+		if err != nil {
+			writeImportError(w, err)
+			return
+		}
+		w.Header().Set("Location", "/api/companies/"+strconv.Itoa(companyID)+"/jobs/imports/"+strconv.Itoa(report.ID))
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+
+	report, err := ji.importService.Import(r.Context(), userID, companyID, body, opts)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetImport handles polling the progress of an async import
+func (ji JobImport) GetImport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+	importID, err := intURLParam(r, "importID")
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid import id", http.StatusBadRequest)
+		return
+	}
+
+	report, err := ji.importService.GetImport(r.Context(), userID, companyID, importID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "import not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// importFormat picks the import format from the format query parameter, falling back to the Content-Type.
+func importFormat(r *http.Request) string {
+	switch r.URL.Query().Get("format") {
+	case jobimport.FormatCSV:
+		return jobimport.FormatCSV
+	case jobimport.FormatJSONL, "ndjson":
+		return jobimport.FormatJSONL
+	case "":
+	default:
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return jobimport.FormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return jobimport.FormatJSONL
+	}
+	return ""
+}
+
+// parseBoolParam parses an optional boolean query parameter.
+func parseBoolParam(v string) (bool, error) {
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// writeImportError maps an import failure to a response.
+func writeImportError(w http.ResponseWriter, err error) {
+	log.Error().Err(err).Send()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		sendErrorResp(w, "import file too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if errors.Is(err, services.ErrUserSuspended) {
+		sendErrorResp(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, services.ErrImportBusy) {
+		w.Header().Set("Retry-After", "60")
+		sendErrorResp(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, services.ErrCompanyNotFound) {
+		sendErrorResp(w, services.ErrCompanyNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrInvalidImport) {
+		sendErrorResp(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sendErrorResp(w, "could not import jobs", http.StatusInternalServerError)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/services"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+// Moderation struct represents the handler for the review queue, abuse reports and suspensions
+type Moderation struct {
+	moderationService *services.ModerationService
+}
+
+// NewModeration creates a new Moderation handler with the provided service
+func NewModeration(ms *services.ModerationService) (*Moderation, error) {
+	if ms == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &Moderation{moderationService: ms}, nil
+}
+
+// ListQueue handles operators listing the review queue, by the status query parameter
+func (md Moderation) ListQueue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	items, err := md.moderationService.ListQueue(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(items)
+}
+
+// GetCompanyItems handles a company owner listing why their company or its jobs were held for review
+func (md Moderation) GetCompanyItems(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := md.moderationService.GetCompanyItems(r.Context(), userID, companyID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "could not get moderation items", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(items)
+}
+
+// ApproveItem handles an operator publishing held content
+func (md Moderation) ApproveItem(w http.ResponseWriter, r *http.Request) {
+	md.decide(w, r, true)
+}
+
+// RejectItem handles an operator rejecting held content. The notes are required, they tell the owner why.
+func (md Moderation) RejectItem(w http.ResponseWriter, r *http.Request) {
+	md.decide(w, r, false)
+}
+
+// decide records an operator's decision on the review queue item in the URL
+func (md Moderation) decide(w http.ResponseWriter, r *http.Request, approve bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	operatorID, itemID, decision, ok := operatorReview(w, r)
+	if !ok {
+		return
+	}
+	if !approve && strings.TrimSpace(decision.Notes) == "" {
+		sendErrorResp(w, "notes are required to reject", http.StatusBadRequest)
+		return
+	}
+
+	item, err := md.moderationService.Decide(r.Context(), operatorID, itemID, approve, strings.TrimSpace(decision.Notes))
+	if err != nil {
+		writeModerationError(w, err, "could not decide moderation item")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(item)
+}
+
+// ReportJob handles a user reporting a job
+func (md Moderation) ReportJob(w http.ResponseWriter, r *http.Request) {
+	md.report(w, r, md.moderationService.ReportJob)
+}
+
+// ReportCompany handles a user reporting a company
+func (md Moderation) ReportCompany(w http.ResponseWriter, r *http.Request) {
+	md.report(w, r, md.moderationService.ReportCompany)
+}
+
+// report records the user's abuse report on the job or company in the URL with the given service method
+func (md Moderation) report(w http.ResponseWriter, r *http.Request,
+	fn func(ctx context.Context, userID, id int, nr models.NewAbuseReport) (*models.AbuseReport, error)) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var newReport models.NewAbuseReport
+	if err := json.NewDecoder(r.Body).Decode(&newReport); err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validator.New().Struct(newReport); err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "send valid values", http.StatusBadRequest)
+		return
+	}
+
+	report, err := fn(r.Context(), userID, id, newReport)
+	if err != nil {
+		writeModerationError(w, err, "could not report")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Unsuspend handles an operator lifting the suspension of the user in the URL
+func (md Moderation) Unsuspend(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	operatorID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	userID, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := md.moderationService.Unsuspend(r.Context(), operatorID, userID); err != nil {
+		writeModerationError(w, err, "could not unsuspend user")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("user unsuspended successfully")
+}
+
+// writeModerationError maps moderation errors to responses
+func writeModerationError(w http.ResponseWriter, err error, msg string) {
+	log.Error().Err(err).Send()
+	switch {
+	case errors.Is(err, services.ErrReportExists), errors.Is(err, services.ErrInvalidTransition):
+		sendErrorResp(w, err.Error(), http.StatusConflict)
+	default:
+		sendErrorResp(w, msg, http.StatusNotFound)
+	}
+}
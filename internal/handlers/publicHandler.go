@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"job-portal-api/internal/feeds"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/services"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PublicConfig represents the configuration of the unauthenticated public pages and feeds
+type PublicConfig struct {
+	BaseURL   string // BaseURL is the absolute URL the API is reachable at, used in feed and sitemap links
+	FeedItems int    // FeedItems is the number of most recent jobs listed in a feed
+}
+
+// DefaultPublicConfig returns the public pages configuration from PUBLIC_BASE_URL and FEED_ITEMS
+func DefaultPublicConfig() PublicConfig {
+	cfg := PublicConfig{
+		BaseURL:   strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/"),
+		FeedItems: 100,
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "http://localhost:3030"
+	}
+	if n, err := strconv.Atoi(os.Getenv("FEED_ITEMS")); err == nil && n > 0 {
+		cfg.FeedItems = n
+	}
+	return cfg
+}
+
+// Public struct represents the handler for public job pages, feeds and the sitemap
+type Public struct {
+	jobService *services.JobService
+	aggregator *services.AggregatorService
+	sitemaps   *services.SitemapService
+	cfg        PublicConfig
+}
+
+// NewPublic creates a new Public handler with the provided services and configuration
+func NewPublic(js *services.JobService, agg *services.AggregatorService, sm *services.SitemapService, cfg PublicConfig) (*Public, error) {
+	if js == nil || agg == nil || sm == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &Public{jobService: js, aggregator: agg, sitemaps: sm, cfg: cfg}, nil
+}
+
+// JobsRSS handles the RSS feed of all public jobs
+func (p Public) JobsRSS(w http.ResponseWriter, r *http.Request) {
+	p.writeFeed(w, r, 0, "/feeds/jobs.rss", feeds.ContentTypeRSS, feeds.WriteRSS)
+}
+
+// JobsAtom handles the Atom feed of all public jobs
+func (p Public) JobsAtom(w http.ResponseWriter, r *http.Request) {
+	p.writeFeed(w, r, 0, "/feeds/jobs.atom", feeds.ContentTypeAtom, feeds.WriteAtom)
+}
+
+// CompanyJobsRSS handles the RSS feed of a company's public jobs
+func (p Public) CompanyJobsRSS(w http.ResponseWriter, r *http.Request) {
+	companyID, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	p.writeFeed(w, r, companyID, "/feeds/companies/"+strconv.Itoa(companyID)+"/jobs.rss", feeds.ContentTypeRSS, feeds.WriteRSS)
+}
+
+// CompanyJobsAtom handles the Atom feed of a company's public jobs
+func (p Public) CompanyJobsAtom(w http.ResponseWriter, r *http.Request) {
+	companyID, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	p.writeFeed(w, r, companyID, "/feeds/companies/"+strconv.Itoa(companyID)+"/jobs.atom", feeds.ContentTypeAtom, feeds.WriteAtom)
+}
+
+// writeFeed writes the feed of the most recent public jobs, of one company when companyID is not 0
+func (p Public) writeFeed(w http.ResponseWriter, r *http.Request, companyID int, path, contentType string, write func(io.Writer, feeds.Feed) error) {
+	lastModified, err := p.jobService.PublicJobsLastModified(r.Context(), companyID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	if notModifiedSince(w, r, lastModified) {
+		return
+	}
+
+	jobs, err := p.jobService.GetPublicJobs(r.Context(), companyID, p.cfg.FeedItems)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	feed := feeds.Feed{
+		Title:       "Jobs",
+		Link:        p.cfg.BaseURL + "/",
+		SelfLink:    p.cfg.BaseURL + path,
+		Description: "The latest job postings",
+		Updated:     lastModified,
+	}
+	if companyID != 0 {
+		if len(jobs) == 0 {
+			http.Error(w, "company not found or has no open jobs", http.StatusNotFound)
+			return
+		}
+		feed.Title = "Jobs at " + jobs[0].CompanyName
+		feed.Description = "The latest job postings of " + jobs[0].CompanyName
+	}
+	for _, job := range jobs {
+		feed.Items = append(feed.Items, feeds.Item{
+			ID:          p.jobURL(job.ID),
+			Title:       job.JobRole + " at " + job.CompanyName,
+			Link:        p.jobURL(job.ID),
+			Description: p.jobDescription(job),
+			Author:      job.CompanyName,
+			Published:   job.CreatedAt,
+			Updated:     job.UpdatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if err := write(w, feed); err != nil {
+		log.Error().Err(err).Send()
+	}
+}
+
+// publicJobPage renders a public job with its JSON-LD for search engines
+var publicJobPage = template.Must(template.New("job").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Job.JobRole}} at {{.Job.CompanyName}}</title>
+<link rel="canonical" href="{{.URL}}">
+<script type="application/ld+json">{{.Posting}}</script>
+</head>
+<body>
+<h1>{{.Job.JobRole}}</h1>
+<p>{{.Job.CompanyName}}</p>
+<p>{{.Description}}</p>
+</body>
+</html>
+`))
+
+// GetJob handles a public job. Browsers asking for text/html get a page embedding the JSON-LD,
+// everything else gets the schema.org JobPosting as application/ld+json.
+func (p Public) GetJob(w http.ResponseWriter, r *http.Request) {
+	jobID, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	job, err := p.jobService.GetPublicJob(r.Context(), jobID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	if notModifiedSince(w, r, job.UpdatedAt) {
+		return
+	}
+	w.Header().Set("Vary", "Accept")
+
+	posting := p.jobPosting(job)
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err = publicJobPage.Execute(w, struct {
+			Job         *models.PublicJob
+			URL         string
+			Description string
+			Posting     feeds.JobPosting
+		}{job, p.jobURL(job.ID), posting.Description, posting})
+		if err != nil {
+			log.Error().Err(err).Send()
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/ld+json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(posting)
+}
+
+// Sitemap handles the sitemap index listing the sitemaps of public job pages. The files are rebuilt in the
+// background, so crawlers never hold a database cursor.
+func (p Public) Sitemap(w http.ResponseWriter, r *http.Request) {
+	p.serveGenerated(w, r, p.sitemaps.IndexPath(), feeds.ContentTypeSitemap, "sitemap is being generated")
+}
+
+// SitemapPage handles a sitemap listed in the sitemap index
+func (p Public) SitemapPage(w http.ResponseWriter, r *http.Request) {
+	page, err := intURLParam(r, "page")
+	if err != nil || page < 1 {
+		http.Error(w, "sitemap not found", http.StatusNotFound)
+		return
+	}
+	if _, err := os.Stat(p.sitemaps.PagePath(page)); errors.Is(err, os.ErrNotExist) {
+		http.Error(w, "sitemap not found", http.StatusNotFound)
+		return
+	}
+	p.serveGenerated(w, r, p.sitemaps.PagePath(page), feeds.ContentTypeSitemap, "sitemap is being generated")
+}
+
+// AggregatorFeed handles the job-board XML feed. The file is rebuilt in the background.
+func (p Public) AggregatorFeed(w http.ResponseWriter, r *http.Request) {
+	p.serveGenerated(w, r, p.aggregator.Path(), feeds.ContentTypeAggregator, "feed is being generated")
+}
+
+// serveGenerated serves a file built in the background as is, with its modification time as Last-Modified.
+// Until it is first built, clients are asked to retry later.
+func (p Public) serveGenerated(w http.ResponseWriter, r *http.Request, path, contentType, pending string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Error().Err(err).Send()
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, pending, http.StatusServiceUnavailable)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(publicMaxAge()))
+	http.ServeContent(w, r, filepath.Base(path), info.ModTime(), f)
+}
+
+// jobURL returns the absolute URL of a public job page
+func (p Public) jobURL(id int) string {
+	return p.cfg.BaseURL + "/public/jobs/" + strconv.Itoa(id)
+}
+
+// jobDescription returns the text description of a public job
+func (p Public) jobDescription(job *models.PublicJob) string {
+	return feeds.JobDescription(job.CompanyName, job.JobRole, job.Salary, job.Currency, job.PayPeriod)
+}
+
+// jobPosting returns the schema.org JobPosting of a public job
+func (p Public) jobPosting(job *models.PublicJob) feeds.JobPosting {
+	return feeds.NewJobPosting(strconv.Itoa(job.ID), job.JobRole, p.jobDescription(job), p.jobURL(job.ID),
+		job.CreatedAt, job.CompanyName, "", job.City, job.Country, job.Salary, job.Currency, job.PayPeriod)
+}
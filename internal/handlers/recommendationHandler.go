@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+// Recommendation struct represents the handler for candidate profiles, job recommendations and suggested candidates
+type Recommendation struct {
+	recommendationService *services.RecommendationService
+}
+
+// NewRecommendation creates a new Recommendation handler with the provided service
+func NewRecommendation(rs *services.RecommendationService) (*Recommendation, error) {
+	if rs == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &Recommendation{recommendationService: rs}, nil
+}
+
+// GetProfile handles a candidate reading their profile
+func (rc Recommendation) GetProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := rc.recommendationService.GetProfile(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		if errors.Is(err, services.ErrProfileNotFound) {
+			http.Error(w, "profile not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+// SaveProfile handles a candidate creating or replacing their profile
+func (rc Recommendation) SaveProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var newProfile models.NewCandidateProfile
+	if err := json.NewDecoder(r.Body).Decode(&newProfile); err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validator.New().Struct(newProfile); err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "send valid values", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := rc.recommendationService.SaveProfile(r.Context(), userID, newProfile)
+	if err != nil {
+		log.Error().Err(err).Send()
+		if errors.Is(err, services.ErrInvalidSalary) {
+			sendErrorResp(w, services.ErrInvalidSalary.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "could not save profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+// RecommendedJobs handles a candidate listing the jobs recommended to them, up to the limit query parameter
+func (rc Recommendation) RecommendedJobs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	limit, ok := limitParam(w, r)
+	if !ok {
+		return
+	}
+
+	jobs, err := rc.recommendationService.RecommendJobs(r.Context(), userID, limit)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// SuggestedCandidates handles a recruiter listing the candidates suggested for a job of their company, up to the
+// limit query parameter
+func (rc Recommendation) SuggestedCandidates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jobID, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	limit, ok := limitParam(w, r)
+	if !ok {
+		return
+	}
+
+	candidates, err := rc.recommendationService.SuggestCandidates(r.Context(), userID, jobID, limit)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "could not get suggested candidates for this job", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(candidates)
+}
+
+// limitParam parses the optional limit query parameter, 0 when absent
+func limitParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return 0, true
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 0 {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return 0, false
+	}
+	return limit, true
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+// Review struct represents the handler for company reviews
+type Review struct {
+	reviewService *services.ReviewService
+}
+
+// NewReview creates a new Review handler with the provided service
+func NewReview(rs *services.ReviewService) (*Review, error) {
+	if rs == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &Review{reviewService: rs}, nil
+}
+
+// CreateReview handles a candidate reviewing a company
+func (rv Review) CreateReview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, newReview, ok := decodeReview(w, r)
+	if !ok {
+		return
+	}
+
+	review, err := rv.reviewService.CreateReview(r.Context(), userID, companyID, newReview)
+	if err != nil {
+		writeReviewError(w, err, "could not create review")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(review)
+}
+
+// UpdateReview handles a candidate replacing their review of a company
+func (rv Review) UpdateReview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, newReview, ok := decodeReview(w, r)
+	if !ok {
+		return
+	}
+
+	review, err := rv.reviewService.UpdateReview(r.Context(), userID, companyID, newReview)
+	if err != nil {
+		writeReviewError(w, err, "could not update review")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
+
+// DeleteReview handles a candidate deleting their review of a company
+func (rv Review) DeleteReview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	companyID, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := rv.reviewService.DeleteReview(r.Context(), userID, companyID); err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "could not delete review", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("review deleted successfully")
+}
+
+// GetReviews handles listing the approved reviews of a company, paged with the before_id and limit query parameters
+func (rv Review) GetReviews(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	companyID, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var beforeID, limit int
+	ints := map[string]*int{"before_id": &beforeID, "limit": &limit}
+	for name, dst := range ints {
+		if v := r.URL.Query().Get(name); v != "" {
+			if *dst, err = strconv.Atoi(v); err != nil {
+				http.Error(w, "invalid "+name, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	reviews, err := rv.reviewService.GetReviews(r.Context(), companyID, beforeID, limit)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// ListReviews handles operators listing reviews, by the status query parameter
+func (rv Review) ListReviews(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	reviews, err := rv.reviewService.ListReviews(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// ApproveReview handles an operator publishing a review
+func (rv Review) ApproveReview(w http.ResponseWriter, r *http.Request) {
+	rv.moderateReview(w, r, true)
+}
+
+// RejectReview handles an operator rejecting or taking down a review
+func (rv Review) RejectReview(w http.ResponseWriter, r *http.Request) {
+	rv.moderateReview(w, r, false)
+}
+
+// moderateReview records an operator's decision on the review in the URL
+func (rv Review) moderateReview(w http.ResponseWriter, r *http.Request, approve bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	operatorID, reviewID, decision, ok := operatorReview(w, r)
+	if !ok {
+		return
+	}
+
+	review, err := rv.reviewService.ModerateReview(r.Context(), operatorID, reviewID, approve, decision.Notes)
+	if err != nil {
+		writeReviewError(w, err, "could not moderate review")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
+
+// RespondToReview handles a company owner answering a review of their company
+func (rv Review) RespondToReview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+	reviewID, err := intURLParam(r, "reviewID")
+	if err != nil {
+		http.Error(w, "invalid review id", http.StatusBadRequest)
+		return
+	}
+
+	var response models.ReviewResponse
+	if err := json.NewDecoder(r.Body).Decode(&response); err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validator.New().Struct(response); err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "send valid values", http.StatusBadRequest)
+		return
+	}
+
+	review, err := rv.reviewService.RespondToReview(r.Context(), userID, companyID, reviewID, response.Response)
+	if err != nil {
+		writeReviewError(w, err, "could not respond to review")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
+
+// decodeReview reads the user, the company in the URL and the validated review body.
+// It writes the error response and returns false when one of them is invalid.
+func decodeReview(w http.ResponseWriter, r *http.Request) (int, int, models.NewCompanyReview, bool) {
+	var newReview models.NewCompanyReview
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return 0, 0, newReview, false
+	}
+	companyID, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return 0, 0, newReview, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&newReview); err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return 0, 0, newReview, false
+	}
+	if err := validator.New().Struct(newReview); err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "send valid values", http.StatusBadRequest)
+		return 0, 0, newReview, false
+	}
+	return userID, companyID, newReview, true
+}
+
+// writeReviewError maps review errors to responses
+func writeReviewError(w http.ResponseWriter, err error, msg string) {
+	log.Error().Err(err).Send()
+	switch {
+	case errors.Is(err, services.ErrReviewExists), errors.Is(err, services.ErrInvalidTransition):
+		sendErrorResp(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrReviewOwnCompany):
+		sendErrorResp(w, err.Error(), http.StatusForbidden)
+	default:
+		sendErrorResp(w, msg, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/services"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+// Skill struct represents the handler for the skills taxonomy
+type Skill struct {
+	skillService *services.SkillService
+}
+
+// NewSkill creates a new Skill handler with the provided service
+func NewSkill(ss *services.SkillService) (*Skill, error) {
+	if ss == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &Skill{skillService: ss}, nil
+}
+
+// ListSkills handles listing the skills taxonomy with the aliases of every skill
+func (sk Skill) ListSkills(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	skills, err := sk.skillService.ListSkills(r.Context())
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(skills)
+}
+
+// AddAlias handles operators adding another name to a skill
+func (sk Skill) AddAlias(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	operatorID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var newAlias models.NewSkillAlias
+	if err := json.NewDecoder(r.Body).Decode(&newAlias); err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validator.New().Struct(newAlias); err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "send valid values", http.StatusBadRequest)
+		return
+	}
+
+	alias, err := sk.skillService.AddAlias(r.Context(), operatorID, chi.URLParam(r, "slug"), newAlias)
+	if err != nil {
+		log.Error().Err(err).Send()
+		switch {
+		case errors.Is(err, services.ErrSkillNotFound):
+			http.Error(w, "skill not found", http.StatusNotFound)
+		case errors.Is(err, services.ErrInvalidAlias):
+			sendErrorResp(w, "alias must contain letters or digits", http.StatusBadRequest)
+		case errors.Is(err, services.ErrAliasExists):
+			sendErrorResp(w, "alias already names a skill", http.StatusConflict)
+		default:
+			http.Error(w, "could not add alias", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(alias)
+}
+
+// DeleteAlias handles operators removing an alias of a skill
+func (sk Skill) DeleteAlias(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	operatorID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	aliasID, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := sk.skillService.DeleteAlias(r.Context(), operatorID, aliasID); err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "could not delete alias", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("alias deleted successfully")
+}
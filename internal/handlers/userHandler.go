@@ -16,16 +16,18 @@ import (
 type Users struct {
 	userService *services.UserService
 	a           *auth.Auth
+	cookies     auth.CookieConfig
 }
 
-// NewUsers creates a new Users handler with the provided services and authentication
-func NewUsers(us *services.UserService, a *auth.Auth) (*Users, error) {
+// NewUsers creates a new Users handler with the provided services, authentication and cookie settings
+func NewUsers(us *services.UserService, a *auth.Auth, cookies auth.CookieConfig) (*Users, error) {
 	if us == nil || a == nil {
 		return nil, errors.New("please provide all the values")
 	}
 	return &Users{
 		userService: us,
 		a:           a,
+		cookies:     cookies,
 	}, nil
 }
 
@@ -35,6 +37,12 @@ func customRoleValidator(fl validator.FieldLevel) bool {
 	return role == "admin" || role == "user"
 }
 
+// loginRoleValidator is a custom validation function for login roles, which also accepts platform operators
+func loginRoleValidator(fl validator.FieldLevel) bool {
+	role := fl.Field().String()
+	return role == auth.Admin || role == auth.User || role == auth.Operator
+}
+
 // HandlerError struct represents an error response structure
 type HandlerError struct {
 	Message string `json:"message"`
@@ -91,12 +99,12 @@ func (u Users) ProcessLoginIn(w http.ResponseWriter, r *http.Request) {
 	var authUser struct {
 		Email    string `json:"email" validate:"required,email"`
 		Password string `json:"password" validate:"required"`
-		Role     string `json:"role" validate:"required,customRoleValidator"`
+		Role     string `json:"role" validate:"required,loginRoleValidator"`
 	}
 
 	// Create a new validator and register the custom role validator
 	validate := validator.New()
-	validate.RegisterValidation("customRoleValidator", customRoleValidator)
+	validate.RegisterValidation("loginRoleValidator", loginRoleValidator)
 
 	err := json.NewDecoder(r.Body).Decode(&authUser)
 	if err != nil {
@@ -120,6 +128,10 @@ func (u Users) ProcessLoginIn(w http.ResponseWriter, r *http.Request) {
 	user, err := u.userService.Authenticate(authUser.Email, authUser.Password, authUser.Role)
 	if err != nil {
 		log.Error().Err(err).Send()
+		if errors.Is(err, services.ErrAccountLocked) {
+			http.Error(w, "Account is locked, check your email to unlock it.", http.StatusLocked)
+			return
+		}
 		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
 		return
 	}
@@ -132,14 +144,110 @@ func (u Users) ProcessLoginIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set the JWT token as an HTTP cookie
-	cookie := http.Cookie{
-		Name:     "token",
-		Value:    tkn,
-		HttpOnly: true, // This ensures that only the browser can access the token, not external scripts
+	// Issue a fresh CSRF token alongside the session so the client can make state-changing requests
+	csrfToken, err := auth.NewCSRFToken()
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "Failed to generate CSRF token", http.StatusInternalServerError)
+		return
 	}
-	http.SetCookie(w, &cookie)
+
+	// Set the JWT token and CSRF token as HTTP cookies
+	http.SetCookie(w, u.cookies.TokenCookie(tkn))
+	http.SetCookie(w, u.cookies.CSRFCookie(csrfToken))
+	w.Header().Set(auth.CSRFHeaderName, csrfToken)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode("User Logged-In Successfully")
 }
+
+// RequestUnlock handles a request for a new account unlock email
+func (u Users) RequestUnlock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "send valid values", http.StatusBadRequest)
+		return
+	}
+
+	// Send the unlock email, the response is the same whether or not the account exists
+	err = u.userService.RequestUnlock(req.Email)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode("If the account is locked, an unlock email has been sent")
+}
+
+// Unlock handles unlocking an account with the token sent by email
+func (u Users) Unlock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Token string `json:"token" validate:"required"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "send valid values", http.StatusBadRequest)
+		return
+	}
+
+	// Unlock the account using the user service
+	err = u.userService.Unlock(req.Token)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid or expired unlock token", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Account unlocked successfully")
+}
+
+// CSRFToken handles issuing a CSRF token for browser clients. The existing token is returned
+// if the client already has one, otherwise a new one is generated and set as a cookie.
+func (u Users) CSRFToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	var token string
+	if c, err := r.Cookie(auth.CSRFCookieName); err == nil && c.Value != "" {
+		token = c.Value
+	} else {
+		token, err = auth.NewCSRFToken()
+		if err != nil {
+			log.Error().Err(err).Send()
+			http.Error(w, "Failed to generate CSRF token", http.StatusInternalServerError)
+			return
+		}
+	}
+	http.SetCookie(w, u.cookies.CSRFCookie(token))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		CSRFToken string `json:"csrfToken"`
+	}{CSRFToken: token})
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/services"
+	"job-portal-api/internal/verification"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+// Verification struct represents the handler for company verification and name disputes
+type Verification struct {
+	verificationService *services.VerificationService
+}
+
+// NewVerification creates a new Verification handler with the provided service
+func NewVerification(vs *services.VerificationService) (*Verification, error) {
+	if vs == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &Verification{verificationService: vs}, nil
+}
+
+// StartVerification handles a company owner starting to verify their company
+func (v Verification) StartVerification(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+
+	// Decode and validate the request body
+	var newVerification models.NewVerification
+	err := json.NewDecoder(r.Body).Decode(&newVerification)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(newVerification); err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "send valid values", http.StatusBadRequest)
+		return
+	}
+
+	started, err := v.verificationService.StartVerification(r.Context(), userID, companyID, newVerification)
+	if err != nil {
+		writeVerificationError(w, err, "could not start verification")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(started)
+}
+
+// GetVerifications handles listing the verification requests of a company
+func (v Verification) GetVerifications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := v.verificationService.GetVerifications(r.Context(), userID, companyID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "could not get verifications", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(list)
+}
+
+// ConfirmVerification handles a company owner completing the proof of a verification request
+func (v Verification) ConfirmVerification(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+	verificationID, err := intURLParam(r, "verificationID")
+	if err != nil {
+		http.Error(w, "invalid verification id", http.StatusBadRequest)
+		return
+	}
+
+	// The body is optional, DNS verifications need no code
+	var confirm models.ConfirmVerification
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&confirm); err != nil {
+			log.Error().Err(err).Send()
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	proven, err := v.verificationService.ConfirmVerification(r.Context(), userID, companyID, verificationID, confirm.Code)
+	if err != nil {
+		writeVerificationError(w, err, "could not confirm verification")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(proven)
+}
+
+// ListVerifications handles operators listing verification requests, by the status query parameter
+func (v Verification) ListVerifications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	list, err := v.verificationService.ListVerifications(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(list)
+}
+
+// ApproveVerification handles an operator approving a proven verification request
+func (v Verification) ApproveVerification(w http.ResponseWriter, r *http.Request) {
+	v.reviewVerification(w, r, true)
+}
+
+// RejectVerification handles an operator rejecting a verification request
+func (v Verification) RejectVerification(w http.ResponseWriter, r *http.Request) {
+	v.reviewVerification(w, r, false)
+}
+
+// reviewVerification records an operator's decision on the verification request in the URL
+func (v Verification) reviewVerification(w http.ResponseWriter, r *http.Request, approve bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	operatorID, verificationID, review, ok := operatorReview(w, r)
+	if !ok {
+		return
+	}
+
+	reviewed, err := v.verificationService.ReviewVerification(r.Context(), operatorID, verificationID, approve, review.Notes)
+	if err != nil {
+		writeVerificationError(w, err, "could not review verification")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reviewed)
+}
+
+// RaiseDispute handles a user disputing the name of a company
+func (v Verification) RaiseDispute(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	companyID, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	// Decode and validate the request body
+	var newDispute models.NewDispute
+	err = json.NewDecoder(r.Body).Decode(&newDispute)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(newDispute); err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "send valid values", http.StatusBadRequest)
+		return
+	}
+
+	dispute, err := v.verificationService.RaiseDispute(r.Context(), userID, companyID, newDispute)
+	if err != nil {
+		writeVerificationError(w, err, "could not raise dispute")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dispute)
+}
+
+// ListDisputes handles operators listing disputes, by the status query parameter
+func (v Verification) ListDisputes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	list, err := v.verificationService.ListDisputes(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(list)
+}
+
+// UpholdDispute handles an operator upholding a dispute, which removes the company's verified badge
+func (v Verification) UpholdDispute(w http.ResponseWriter, r *http.Request) {
+	v.resolveDispute(w, r, true)
+}
+
+// DismissDispute handles an operator dismissing a dispute
+func (v Verification) DismissDispute(w http.ResponseWriter, r *http.Request) {
+	v.resolveDispute(w, r, false)
+}
+
+// resolveDispute records an operator's decision on the dispute in the URL
+func (v Verification) resolveDispute(w http.ResponseWriter, r *http.Request, upheld bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	operatorID, disputeID, review, ok := operatorReview(w, r)
+	if !ok {
+		return
+	}
+
+	resolved, err := v.verificationService.ResolveDispute(r.Context(), operatorID, disputeID, upheld, review.Notes)
+	if err != nil {
+		writeVerificationError(w, err, "could not resolve dispute")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resolved)
+}
+
+// operatorReview reads the operator, the ID in the URL and the optional notes of a review.
+// It writes the error response and returns false when one of them is invalid.
+func operatorReview(w http.ResponseWriter, r *http.Request) (int, int, models.Review, bool) {
+	var review models.Review
+	operatorID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return 0, 0, review, false
+	}
+	id, err := intURLParam(r, "id")
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return 0, 0, review, false
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			log.Error().Err(err).Send()
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return 0, 0, review, false
+		}
+	}
+	if err := validator.New().Struct(review); err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "send valid values", http.StatusBadRequest)
+		return 0, 0, review, false
+	}
+	return operatorID, id, review, true
+}
+
+// writeVerificationError maps verification errors to responses
+func writeVerificationError(w http.ResponseWriter, err error, msg string) {
+	log.Error().Err(err).Send()
+	switch {
+	case errors.Is(err, services.ErrAlreadyVerified), errors.Is(err, services.ErrInvalidTransition), errors.Is(err, services.ErrDisputeOpen):
+		sendErrorResp(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrProofNotFound):
+		sendErrorResp(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, verification.ErrInvalidDomain), errors.Is(err, verification.ErrFreeMailDomain):
+		sendErrorResp(w, err.Error(), http.StatusBadRequest)
+	default:
+		sendErrorResp(w, msg, http.StatusBadRequest)
+	}
+}
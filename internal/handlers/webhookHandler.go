@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/services"
+	"job-portal-api/internal/webhooks"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+// Webhook struct represents the handler for company webhook operations
+type Webhook struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhook creates a new Webhook handler with the provided service
+func NewWebhook(ws *services.WebhookService) (*Webhook, error) {
+	if ws == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &Webhook{webhookService: ws}, nil
+}
+
+// CreateWebhook handles registering a webhook endpoint for a company
+func (wh Webhook) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+
+	// Decode and validate the request body
+	var newWebhook models.NewWebhook
+	err := json.NewDecoder(r.Body).Decode(&newWebhook)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(newWebhook); err != nil {
+		log.Error().Err(err).Send()
+		sendErrorResp(w, "send valid values", http.StatusBadRequest)
+		return
+	}
+
+	// Register the webhook using the webhook service
+	hook, err := wh.webhookService.CreateWebhook(r.Context(), userID, companyID, newWebhook)
+	if err != nil {
+		log.Error().Err(err).Send()
+		if errors.Is(err, webhooks.ErrForbiddenAddress) || errors.Is(err, webhooks.ErrInvalidURL) {
+			sendErrorResp(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "could not create webhook", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hook)
+}
+
+// GetWebhooks handles listing the webhooks of a company
+func (wh Webhook) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+
+	hooks, err := wh.webhookService.GetWebhooksByCompanyID(r.Context(), userID, companyID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "could not get webhooks", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(hooks)
+}
+
+// TestWebhook handles sending a test event to a webhook
+func (wh Webhook) TestWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+	webhookID, err := intURLParam(r, "webhookID")
+	if err != nil {
+		http.Error(w, "invalid webhook id", http.StatusBadRequest)
+		return
+	}
+
+	// Send the test event; the endpoint's answer is reported in the result
+	result, err := wh.webhookService.TestWebhook(r.Context(), userID, companyID, webhookID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "could not test webhook", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetDeliveries handles listing the recent deliveries of a webhook
+func (wh Webhook) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+	webhookID, err := intURLParam(r, "webhookID")
+	if err != nil {
+		http.Error(w, "invalid webhook id", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := wh.webhookService.GetDeliveries(r.Context(), userID, companyID, webhookID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "could not get deliveries", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// ReplayDelivery handles queueing a delivery to be sent again
+func (wh Webhook) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, companyID, ok := ownerAndCompany(w, r)
+	if !ok {
+		return
+	}
+	webhookID, err := intURLParam(r, "webhookID")
+	if err != nil {
+		http.Error(w, "invalid webhook id", http.StatusBadRequest)
+		return
+	}
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid delivery id", http.StatusBadRequest)
+		return
+	}
+
+	id, err := wh.webhookService.ReplayDelivery(r.Context(), userID, companyID, webhookID, deliveryID)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "could not replay delivery", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(struct {
+		DeliveryID int64 `json:"deliveryId"`
+	}{DeliveryID: id})
+}
+
+// ownerAndCompany extracts the user ID from the context and the company ID from the URL,
+// writing an error response and returning false when either is invalid
+func ownerAndCompany(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	companyID, err := intURLParam(r, "id")
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	userID, err := userIDFromRequest(r)
+	if err != nil {
+		log.Error().Err(err).Send()
+		http.Error(w, "invalid user id in context", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+	return userID, companyID, true
+}
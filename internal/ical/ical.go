@@ -0,0 +1,235 @@
+// Package ical writes iCalendar (RFC 5545) objects: meeting invitations and their updates and cancellations, as
+// understood by calendar clients, with the time zones of their times.
+package ical
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Calendar methods (RFC 5546) an invitation is sent with
+const (
+	MethodRequest = "REQUEST" // MethodRequest invites to an event, or updates it at a higher Sequence
+	MethodCancel  = "CANCEL"  // MethodCancel cancels an event
+)
+
+// Event statuses
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// ContentType is the media type of iCalendar files
+const ContentType = "text/calendar; charset=utf-8"
+
+// maxLineOctets is the longest content line before it is folded, without the line break
+const maxLineOctets = 75
+
+// Calendar is an iCalendar object holding events
+type Calendar struct {
+	ProdID string  // ProdID identifies the product that created the calendar, such as "-//Job Portal//Interviews//EN"
+	Method string  // Method is MethodRequest or MethodCancel, or empty for a published calendar
+	Events []Event // Events are the events of the calendar
+}
+
+// Event is a VEVENT. Its start and end are written in the time zone of their location, with a VTIMEZONE describing
+// it, or in UTC for the UTC location.
+type Event struct {
+	UID         string     // UID identifies the event across its updates, globally unique
+	Sequence    int        // Sequence is the revision of the event, incremented whenever its time changes or it is cancelled
+	Stamp       time.Time  // Stamp is when this revision of the event was created
+	Start       time.Time  // Start is when the event starts, in the time zone it is shown in
+	End         time.Time  // End is when the event ends, in the time zone of Start
+	Summary     string     // Summary is the title of the event
+	Description string     // Description is the plain text details of the event
+	Location    string     // Location is where the event happens, such as an address or a video call link
+	URL         string     // URL links to the event, optional
+	Status      string     // Status is StatusConfirmed or StatusCancelled
+	Organizer   Attendee   // Organizer organizes the event
+	Attendees   []Attendee // Attendees are invited to the event
+}
+
+// Attendee is the organizer of an event or a participant
+type Attendee struct {
+	Name  string // Name is the display name, optional
+	Email string // Email is the address invitations are sent to
+}
+
+// Encode writes the calendar to w
+func Encode(w io.Writer, cal Calendar) error {
+	if cal.ProdID == "" || len(cal.Events) == 0 {
+		return errors.New("encode calendar: a product identifier and an event are required")
+	}
+	e := &encoder{}
+	e.line("BEGIN:VCALENDAR")
+	e.line("VERSION:2.0")
+	e.line("PRODID:" + escapeText(cal.ProdID))
+	e.line("CALSCALE:GREGORIAN")
+	if cal.Method != "" {
+		e.line("METHOD:" + cal.Method)
+	}
+
+	// Every time zone referenced gets one VTIMEZONE covering all the events in it
+	zones := map[string][]time.Time{}
+	var names []string
+	for _, ev := range cal.Events {
+		if !ev.End.After(ev.Start) || ev.End.Location() != ev.Start.Location() {
+			return fmt.Errorf("encode calendar: event %s must end after it starts, in the same time zone", ev.UID)
+		}
+		name := ev.Start.Location().String()
+		if isUTC(ev.Start) {
+			continue
+		}
+		if _, ok := zones[name]; !ok {
+			names = append(names, name)
+		}
+		zones[name] = append(zones[name], ev.Start, ev.End)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := e.timezone(name, zones[name]); err != nil {
+			return fmt.Errorf("encode calendar: %w", err)
+		}
+	}
+
+	for _, ev := range cal.Events {
+		if err := e.event(ev); err != nil {
+			return fmt.Errorf("encode calendar: %w", err)
+		}
+	}
+	e.line("END:VCALENDAR")
+	_, err := w.Write(e.buf.Bytes())
+	return err
+}
+
+// Marshal returns the calendar as an iCalendar file
+func Marshal(cal Calendar) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, cal); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encoder accumulates folded content lines
+type encoder struct {
+	buf bytes.Buffer
+}
+
+// line writes a content line, folded into lines of at most 75 octets continued by a space, without splitting
+// UTF-8 sequences
+func (e *encoder) line(s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut--
+		}
+		e.buf.WriteString(s[:cut])
+		e.buf.WriteString("\r\n ")
+		s = s[cut:]
+		// The leading space of a continuation line counts towards its length
+		limit = maxLineOctets - 1
+	}
+	e.buf.WriteString(s)
+	e.buf.WriteString("\r\n")
+}
+
+// isRuneStart reports whether the byte starts a UTF-8 sequence
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// event writes a VEVENT
+func (e *encoder) event(ev Event) error {
+	if ev.UID == "" || ev.Organizer.Email == "" {
+		return errors.New("an event needs a UID and an organizer")
+	}
+	e.line("BEGIN:VEVENT")
+	e.line("UID:" + escapeText(ev.UID))
+	e.line(fmt.Sprintf("SEQUENCE:%d", ev.Sequence))
+	e.line("DTSTAMP:" + ev.Stamp.UTC().Format("20060102T150405Z"))
+	e.line(dateTime("DTSTART", ev.Start))
+	e.line(dateTime("DTEND", ev.End))
+	e.line("SUMMARY:" + escapeText(ev.Summary))
+	if ev.Description != "" {
+		e.line("DESCRIPTION:" + escapeText(ev.Description))
+	}
+	if ev.Location != "" {
+		e.line("LOCATION:" + escapeText(ev.Location))
+	}
+	if ev.URL != "" {
+		e.line("URL:" + ev.URL)
+	}
+	if ev.Status != "" {
+		e.line("STATUS:" + ev.Status)
+	}
+	e.line("TRANSP:OPAQUE")
+	organizer, err := calAddress(ev.Organizer)
+	if err != nil {
+		return err
+	}
+	e.line("ORGANIZER" + organizer)
+	for _, a := range ev.Attendees {
+		attendee, err := calAddress(a)
+		if err != nil {
+			return err
+		}
+		e.line("ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE" + attendee)
+	}
+	e.line("END:VEVENT")
+	return nil
+}
+
+// dateTime formats a date-time property, in UTC or with the TZID of its time zone
+func dateTime(name string, t time.Time) string {
+	if isUTC(t) {
+		return name + ":" + t.UTC().Format("20060102T150405Z")
+	}
+	return name + ";TZID=" + paramValue(t.Location().String()) + ":" + t.Format("20060102T150405")
+}
+
+// isUTC reports whether a time is written in UTC
+func isUTC(t time.Time) bool {
+	name := t.Location().String()
+	return name == "UTC" || name == ""
+}
+
+// calAddress formats the parameters and value of an ORGANIZER or ATTENDEE property
+func calAddress(a Attendee) (string, error) {
+	addr, err := mail.ParseAddress(a.Email)
+	if err != nil || addr.Address != a.Email {
+		return "", fmt.Errorf("invalid calendar address %q", a.Email)
+	}
+	s := ""
+	if a.Name != "" {
+		s = ";CN=" + paramValue(a.Name)
+	}
+	return s + ":mailto:" + a.Email, nil
+}
+
+// paramValue quotes a parameter value when it holds a separator. Double quotes and control characters cannot be
+// written in parameter values at all and are left out.
+func paramValue(v string) string {
+	v = strings.Map(func(r rune) rune {
+		if r == '"' || r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, v)
+	if strings.ContainsAny(v, ":;,") {
+		return `"` + v + `"`
+	}
+	return v
+}
+
+// escapeText escapes a TEXT value
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
@@ -0,0 +1,153 @@
+package ical
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	// Zones load without a system zone database too
+	_ "time/tzdata"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares got with the golden file, rewriting it with -update
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+// interview returns an event starting at the local time in the location
+func interview(t *testing.T, zone string, year int, month time.Month, day, hour int) Event {
+	t.Helper()
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(year, month, day, hour, 0, 0, 0, loc)
+	return Event{
+		UID:       "interview-42@job-portal",
+		Sequence:  1,
+		Stamp:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Start:     start,
+		End:       start.Add(time.Hour),
+		Summary:   "Interview: Go Developer",
+		Status:    StatusConfirmed,
+		Organizer: Attendee{Name: "Rita Recruiter", Email: "rita@example.com"},
+		Attendees: []Attendee{{Name: "Carl Candidate", Email: "carl@example.com"}},
+	}
+}
+
+func TestEncodeGolden(t *testing.T) {
+	// A zone with daylight saving time, across its change to summer time
+	berlin := interview(t, "Europe/Berlin", 2024, time.March, 28, 10)
+	moved := berlin
+	moved.Start, moved.End = moved.Start.AddDate(0, 0, 7), moved.End.AddDate(0, 0, 7)
+
+	// Values with every character TEXT and parameter values escape, and lines long enough to be folded, some of
+	// them across multibyte characters
+	escaped := interview(t, "UTC", 2024, time.May, 6, 9)
+	escaped.Summary = `Interview; round 2, with the "team" \ panel`
+	escaped.Description = "Agenda:\n1. Introductions, 10 minutes\r\n2. Pair programming; bring a laptop\n" +
+		strings.Repeat("Größenordnung überprüfen – ", 6)
+	escaped.Location = "Friedrichstraße 1, 10117 Berlin; Room: Ünter den Linden"
+	escaped.Organizer.Name = `Rita "R" Recruiter, HR: Talent`
+	escaped.Attendees[0].Name = "Carl;Candidate"
+
+	cancelled := interview(t, "America/New_York", 2024, time.November, 4, 15)
+	cancelled.Sequence, cancelled.Status = 3, StatusCancelled
+
+	tests := []struct {
+		file string
+		cal  Calendar
+	}{
+		{"berlin.ics", Calendar{ProdID: "-//Job Portal//Interviews//EN", Method: MethodRequest, Events: []Event{berlin, moved}}},
+		{"escaped.ics", Calendar{ProdID: "-//Job Portal//Interviews//EN", Method: MethodRequest, Events: []Event{escaped}}},
+		{"cancelled.ics", Calendar{ProdID: "-//Job Portal//Interviews//EN", Method: MethodCancel, Events: []Event{cancelled}}},
+	}
+	for _, tt := range tests {
+		got, err := Marshal(tt.cal)
+		if err != nil {
+			t.Errorf("Marshal(%s) error: %v", tt.file, err)
+			continue
+		}
+		golden(t, tt.file, got)
+	}
+}
+
+func TestLineFolding(t *testing.T) {
+	for _, value := range []string{
+		strings.Repeat("a", 200),
+		strings.Repeat("ü", 100),
+		strings.Repeat("x", 73) + "€€€" + strings.Repeat("y", 80),
+		strings.Repeat("😀", 50),
+	} {
+		e := &encoder{}
+		e.line("SUMMARY:" + value)
+		lines := strings.Split(strings.TrimSuffix(e.buf.String(), "\r\n"), "\r\n")
+		unfolded := lines[0]
+		for i, l := range lines {
+			if len(l) > maxLineOctets {
+				t.Errorf("line %d of %q is %d octets, want at most %d", i, value, len(l), maxLineOctets)
+			}
+			if !utf8.ValidString(l) {
+				t.Errorf("line %d of %q splits a character: %q", i, value, l)
+			}
+			if i > 0 {
+				if !strings.HasPrefix(l, " ") {
+					t.Errorf("continuation line %d of %q does not start with a space", i, value)
+				}
+				unfolded += strings.TrimPrefix(l, " ")
+			}
+		}
+		if unfolded != "SUMMARY:"+value {
+			t.Errorf("unfolded %q, want %q", unfolded, "SUMMARY:"+value)
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	ev := interview(t, "Europe/Berlin", 2024, time.March, 28, 10)
+	backwards := ev
+	backwards.End = ev.Start
+	otherZone := ev
+	otherZone.End = ev.End.UTC()
+	badAddress := ev
+	badAddress.Attendees = []Attendee{{Email: "Carl <carl@example.com>"}}
+	for desc, cal := range map[string]Calendar{
+		"no product":        {Events: []Event{ev}},
+		"no event":          {ProdID: "-//Job Portal//Interviews//EN"},
+		"ends before start": {ProdID: "p", Events: []Event{backwards}},
+		"end in other zone": {ProdID: "p", Events: []Event{otherZone}},
+		"invalid address":   {ProdID: "p", Events: []Event{badAddress}},
+	} {
+		if _, err := Marshal(cal); err == nil {
+			t.Errorf("Marshal(%s) succeeded, want an error", desc)
+		}
+	}
+}
+
+func TestUTCOffset(t *testing.T) {
+	for seconds, want := range map[int]string{0: "+0000", 3600: "+0100", -18000: "-0500", 19800: "+0530", -2670: "-004430"} {
+		if got := utcOffset(seconds); got != want {
+			t.Errorf("utcOffset(%d) = %s, want %s", seconds, got, want)
+		}
+	}
+}
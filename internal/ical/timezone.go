@@ -0,0 +1,128 @@
+package ical
+
+import (
+	"fmt"
+	"time"
+)
+
+// transitionSearchWindow is how far before the first event the last change of UTC offset is looked for. Zones
+// that have not changed their offset for that long are written with a single observance.
+const transitionSearchWindow = 2 * 366 * 24 * time.Hour
+
+// transition is a change of the UTC offset of a time zone
+type transition struct {
+	at         time.Time // at is the instant of the change
+	fromOffset int       // fromOffset is the offset in seconds before the change
+	toOffset   int       // toOffset is the offset in seconds after the change
+	name       string    // name is the abbreviation of the zone after the change, such as CEST
+	dst        bool      // dst is set when daylight saving time is in effect after the change
+}
+
+// transitions returns the changes of UTC offset of the location in (from, to], found by stepping a day at a time
+// and bisecting down to the second. No zone changes its offset twice within a day.
+func transitions(loc *time.Location, from, to time.Time) []transition {
+	var found []transition
+	offset := func(t time.Time) int {
+		_, off := t.In(loc).Zone()
+		return off
+	}
+	prev := from
+	for prev.Before(to) {
+		next := prev.Add(24 * time.Hour)
+		if next.After(to) {
+			next = to
+		}
+		if offset(prev) != offset(next) {
+			lo, hi := prev, next
+			for hi.Sub(lo) > time.Second {
+				mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Second)
+				if !mid.After(lo) {
+					break
+				}
+				if offset(mid) == offset(lo) {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			at := hi.In(loc)
+			name, off := at.Zone()
+			found = append(found, transition{at: at, fromOffset: offset(lo), toOffset: off, name: name, dst: at.IsDST()})
+		}
+		prev = next
+	}
+	return found
+}
+
+// timezone writes the VTIMEZONE of a named location, with an observance for the last change of UTC offset before
+// the earliest of the times and for every change until the latest, so that clients resolve the local times of
+// the events exactly as the zone database does
+func (e *encoder) timezone(name string, times []time.Time) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("unknown time zone %q: %w", name, err)
+	}
+	first, last := times[0], times[0]
+	for _, t := range times[1:] {
+		if t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+
+	found := transitions(loc, first.Add(-transitionSearchWindow), last)
+	// Only the last change before the first event matters, with all that follow it
+	start := 0
+	for i, tr := range found {
+		if !tr.at.After(first) {
+			start = i
+		}
+	}
+	found = found[start:]
+
+	e.line("BEGIN:VTIMEZONE")
+	e.line("TZID:" + escapeText(name))
+	if len(found) == 0 || found[0].at.After(first) {
+		// A zone without changes before the first event in the window starts with an observance since long before
+		abbrev, off := first.In(loc).Zone()
+		since := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC).Add(-time.Duration(off) * time.Second)
+		e.observance(transition{at: since, fromOffset: off, toOffset: off, name: abbrev, dst: first.In(loc).IsDST()})
+	}
+	for _, tr := range found {
+		e.observance(tr)
+	}
+	e.line("END:VTIMEZONE")
+	return nil
+}
+
+// observance writes a STANDARD or DAYLIGHT observance starting at the transition. Its DTSTART is the local time
+// of the change at the offset in effect before it.
+func (e *encoder) observance(tr transition) {
+	kind := "STANDARD"
+	if tr.dst {
+		kind = "DAYLIGHT"
+	}
+	e.line("BEGIN:" + kind)
+	e.line("DTSTART:" + tr.at.UTC().Add(time.Duration(tr.fromOffset)*time.Second).Format("20060102T150405"))
+	e.line("TZOFFSETFROM:" + utcOffset(tr.fromOffset))
+	e.line("TZOFFSETTO:" + utcOffset(tr.toOffset))
+	if tr.name != "" {
+		e.line("TZNAME:" + escapeText(tr.name))
+	}
+	e.line("END:" + kind)
+}
+
+// utcOffset formats an offset in seconds as +hhmm, or +hhmmss when it is not a whole number of minutes
+func utcOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+	if seconds%60 != 0 {
+		s += fmt.Sprintf("%02d", seconds%60)
+	}
+	return s
+}
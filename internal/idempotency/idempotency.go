@@ -0,0 +1,165 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Errors returned by Begin
+var (
+	ErrInFlight            = errors.New("a request with this idempotency key is still being processed")
+	ErrFingerprintMismatch = errors.New("idempotency key was used with a different request")
+)
+
+// Response is a stored response replayed for retries
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Lease is how long a request holds its key before a retry can reclaim it, when the instance processing it
+// crashed or could not complete or release the key
+const Lease = 2 * time.Minute
+
+// Store keeps idempotency keys and their responses in the idempotency_keys table.
+type Store struct {
+	db    *sql.DB
+	lease time.Duration
+}
+
+// NewStore creates a new idempotency Store.
+func NewStore(db *sql.DB) (*Store, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	return &Store{db: db, lease: Lease}, nil
+}
+
+// Begin claims the key for the principal. It returns (nil, nil) when the caller now owns the key and must
+// process the request, the stored response when the request already completed, ErrInFlight when another
+// request holds the key, and ErrFingerprintMismatch when the key was used with a different request. A key held
+// past its lease is reclaimed by the caller.
+func (s *Store) Begin(ctx context.Context, principal, key, fingerprint string, ttl time.Duration) (*Response, error) {
+	// Two attempts: the second one follows the removal of an expired key
+	for attempt := 0; attempt < 2; attempt++ {
+		res, err := s.db.ExecContext(ctx, `
+			INSERT INTO idempotency_keys (principal, key, fingerprint, expires_at)
+			VALUES ($1, $2, $3, $4) ON CONFLICT (principal, key) DO NOTHING`,
+			principal, key, fingerprint, time.Now().Add(ttl))
+		if err != nil {
+			return nil, fmt.Errorf("begin idempotent request: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("begin idempotent request: %w", err)
+		} else if n == 1 {
+			return nil, nil
+		}
+
+		// The key exists, look at what it holds
+		var (
+			storedFingerprint string
+			completed         bool
+			expiresAt         time.Time
+			status            sql.NullInt64
+			header, body      []byte
+		)
+		err = s.db.QueryRowContext(ctx, `
+			SELECT fingerprint, completed, expires_at, response_status, response_header, response_body
+			FROM idempotency_keys WHERE principal = $1 AND key = $2`, principal, key).
+			Scan(&storedFingerprint, &completed, &expiresAt, &status, &header, &body)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("begin idempotent request: %w", err)
+		}
+
+		if expiresAt.Before(time.Now()) {
+			_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE principal = $1 AND key = $2 AND expires_at < now()", principal, key)
+			if err != nil {
+				return nil, fmt.Errorf("begin idempotent request: %w", err)
+			}
+			continue
+		}
+		if storedFingerprint != fingerprint {
+			return nil, ErrFingerprintMismatch
+		}
+		if !completed {
+			// Take over a key whose request has held it past the lease, checked on the database clock
+			res, err := s.db.ExecContext(ctx, `
+				UPDATE idempotency_keys SET locked_at = now()
+				WHERE principal = $1 AND key = $2 AND NOT completed AND locked_at < now() - make_interval(secs => $3)`,
+				principal, key, s.lease.Seconds())
+			if err != nil {
+				return nil, fmt.Errorf("begin idempotent request: %w", err)
+			}
+			if n, err := res.RowsAffected(); err != nil {
+				return nil, fmt.Errorf("begin idempotent request: %w", err)
+			} else if n == 1 {
+				return nil, nil
+			}
+			return nil, ErrInFlight
+		}
+
+		resp := &Response{Status: int(status.Int64), Header: http.Header{}, Body: body}
+		if len(header) > 0 {
+			if err := json.Unmarshal(header, &resp.Header); err != nil {
+				return nil, fmt.Errorf("begin idempotent request: %w", err)
+			}
+		}
+		return resp, nil
+	}
+	return nil, ErrInFlight
+}
+
+// Complete stores the response for the key so that retries replay it.
+func (s *Store) Complete(ctx context.Context, principal, key string, resp Response) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return fmt.Errorf("complete idempotent request: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET completed = true, response_status = $1, response_header = $2, response_body = $3
+		WHERE principal = $4 AND key = $5`, resp.Status, header, resp.Body, principal, key)
+	if err != nil {
+		return fmt.Errorf("complete idempotent request: %w", err)
+	}
+	return nil
+}
+
+// Release removes an unfinished key so that the request can be retried, used when processing failed.
+func (s *Store) Release(ctx context.Context, principal, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE principal = $1 AND key = $2 AND NOT completed", principal, key)
+	if err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// RunPurge deletes expired keys every interval until ctx is cancelled.
+func (s *Store) RunPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		res, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < now()")
+		if err != nil {
+			log.Error().Err(err).Send()
+		} else if n, _ := res.RowsAffected(); n > 0 {
+			log.Info().Int64("deleted", n).Msg("purged expired idempotency keys")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
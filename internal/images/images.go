@@ -0,0 +1,123 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+
+	// Register the decoders of the accepted upload formats
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+// Accepted upload content types
+var allowedTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+}
+
+// maxDimension is the largest width or height accepted, which keeps decoding of small files with huge canvases in check
+const maxDimension = 4096
+
+// ThumbnailSizes are the square boxes uploaded logos are scaled down to fit
+var ThumbnailSizes = []int{64, 128, 256}
+
+// ErrUnsupportedType is returned for uploads that are not PNG, JPEG or GIF images
+var ErrUnsupportedType = errors.New("image must be a png, jpeg or gif")
+
+// ErrTooLarge is returned for uploads above the size limit or maxDimension
+var ErrTooLarge = errors.New("image is too large")
+
+// Check checks the content type, size and dimensions of an uploaded image from its header only, without
+// allocating its pixels.
+func Check(data []byte, maxBytes int) error {
+	if len(data) > maxBytes {
+		return ErrTooLarge
+	}
+	// Trust the bytes rather than the declared type
+	if !allowedTypes[http.DetectContentType(data)] {
+		return ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("decode image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return fmt.Errorf("decode image: empty image")
+	}
+	if cfg.Width > maxDimension || cfg.Height > maxDimension {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+// Decode checks an uploaded image like Check and decodes it.
+func Decode(data []byte, maxBytes int) (image.Image, error) {
+	if err := Check(data, maxBytes); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	return img, nil
+}
+
+// Thumbnail scales the image down to fit a size x size box, keeping its aspect ratio. Smaller images are not enlarged.
+// Each target pixel is the average of the source pixels it covers, which avoids the aliasing of nearest neighbour.
+func Thumbnail(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				dst.Set(x, y, src.At(b.Min.X+x, b.Min.Y+y))
+			}
+		}
+		return dst
+	}
+
+	tw, th := size, size
+	if w > h {
+		th = max(1, h*size/w)
+	} else {
+		tw = max(1, w*size/h)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for ty := 0; ty < th; ty++ {
+		y0, y1 := b.Min.Y+ty*h/th, b.Min.Y+(ty+1)*h/th
+		for tx := 0; tx < tw; tx++ {
+			x0, x1 := b.Min.X+tx*w/tw, b.Min.X+(tx+1)*w/tw
+
+			// Average in premultiplied alpha so transparent pixels do not darken the edges
+			var r, g, bl, a, n uint64
+			for y := y0; y < max(y1, y0+1); y++ {
+				for x := x0; x < max(x1, x0+1); x++ {
+					cr, cg, cb, ca := src.At(x, y).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			c := color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)}
+			dst.Set(tx, ty, c)
+		}
+	}
+	return dst
+}
+
+// EncodePNG encodes the image as PNG.
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("encode png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,128 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// encodePNG returns a PNG of the size, filled with the color
+func encodePNG(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pngHeader returns a PNG of only a signature and a valid header declaring the size, as a decompression bomb
+// starts, without any pixel data
+func pngHeader(w, h uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], w)
+	binary.BigEndian.PutUint32(ihdr[4:], h)
+	ihdr[8], ihdr[9] = 8, 6 // 8 bit RGBA
+	chunk := append([]byte("IHDR"), ihdr...)
+	data := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0d")
+	data = append(data, chunk...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(chunk))
+}
+
+// gifHeader returns the header of a GIF with a logical screen of the size
+func gifHeader(w, h uint16) []byte {
+	data := []byte("GIF89a")
+	data = binary.LittleEndian.AppendUint16(data, w)
+	data = binary.LittleEndian.AppendUint16(data, h)
+	return append(data, 0, 0, 0, ';')
+}
+
+func TestCheck(t *testing.T) {
+	small := encodePNG(t, 10, 20, color.White)
+	tests := []struct {
+		name string
+		data []byte
+		max  int
+		err  error
+	}{
+		{"png", small, 1 << 20, nil},
+		{"largest sides", pngHeader(maxDimension, maxDimension), 1 << 20, nil},
+		{"over the byte limit", small, len(small) - 1, ErrTooLarge},
+		{"wide canvas", pngHeader(maxDimension+1, 1), 1 << 20, ErrTooLarge},
+		{"tall canvas", pngHeader(1, maxDimension+1), 1 << 20, ErrTooLarge},
+		{"huge canvas", pngHeader(100000, 100000), 1 << 20, ErrTooLarge},
+		{"huge gif screen", gifHeader(65535, 65535), 1 << 20, ErrTooLarge},
+		{"text", []byte("hello, world"), 1 << 20, ErrUnsupportedType},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), 1 << 20, ErrUnsupportedType},
+		{"bmp", append([]byte("BM"), make([]byte, 64)...), 1 << 20, ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Check(tt.data, tt.max); !errors.Is(err, tt.err) {
+				t.Errorf("Check() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	if err := Check(pngHeader(0, 10), 1<<20); err == nil {
+		t.Error("Check() of an empty image succeeded, want an error")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	img, err := Decode(encodePNG(t, 10, 20, color.White), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 10 || b.Dy() != 20 {
+		t.Errorf("Decode() bounds = %v, want 10x20", b)
+	}
+
+	// The size is refused from the header, before the missing pixel data could be read
+	if _, err := Decode(pngHeader(100000, 100000), 1<<20); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Decode() of a huge canvas error = %v, want %v", err, ErrTooLarge)
+	}
+	if _, err := Decode(pngHeader(10, 10), 1<<20); err == nil || errors.Is(err, ErrTooLarge) {
+		t.Errorf("Decode() without pixel data error = %v, want a decoding error", err)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		w, h, size   int
+		wantW, wantH int
+	}{
+		{512, 512, 128, 128, 128},
+		{512, 256, 128, 128, 64},
+		{256, 512, 128, 64, 128},
+		{1000, 1, 64, 64, 1},
+		{50, 30, 64, 50, 30},
+	}
+	for _, tt := range tests {
+		src := image.NewNRGBA(image.Rect(0, 0, tt.w, tt.h))
+		b := Thumbnail(src, tt.size).Bounds()
+		if b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+			t.Errorf("Thumbnail(%dx%d, %d) = %dx%d, want %dx%d", tt.w, tt.h, tt.size, b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+		}
+	}
+
+	// Transparent pixels do not darken the average
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	src.Set(1, 0, color.NRGBA{})
+	r, g, bl, a := Thumbnail(src, 1).At(0, 0).RGBA()
+	if a == 0 || r*0xffff/a < 0xfff0 || g != 0 || bl != 0 {
+		t.Errorf("Thumbnail() pixel = %v, %v, %v, %v, want half transparent red", r, g, bl, a)
+	}
+}
@@ -0,0 +1,255 @@
+package jobimport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/money"
+	"strconv"
+	"strings"
+)
+
+// Supported import formats
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Target fields of an imported job
+const (
+	FieldJobRole   = "jobRole"
+	FieldSalary    = "salary"
+	FieldLocation  = "location"
+	FieldCurrency  = "currency"
+	FieldPayPeriod = "payPeriod"
+)
+
+// Fields lists the job fields that can be mapped from import columns
+var Fields = []string{FieldJobRole, FieldSalary, FieldLocation, FieldCurrency, FieldPayPeriod}
+
+// maxLocationLength is the longest location accepted, as on jobs created through the API
+const maxLocationLength = 200
+
+// maxLineSize is the longest JSON line accepted
+const maxLineSize = 1 << 20
+
+// Record is a raw input row with its 1-based row number; for CSV the header is row 0.
+// A row that could not be parsed has nil Values and says why in Invalid.
+type Record struct {
+	Row     int
+	Values  map[string]string
+	Invalid string
+}
+
+// Reader streams records from an import file.
+type Reader interface {
+	Next() (*Record, error) // Next returns io.EOF after the last record
+}
+
+// Mapping maps job fields to the source column (CSV header or JSON key) they are read from.
+type Mapping map[string]string
+
+// DefaultMapping reads every field from the column of the same name.
+func DefaultMapping() Mapping {
+	m := Mapping{}
+	for _, f := range Fields {
+		m[f] = f
+	}
+	return m
+}
+
+// ParseMapping parses a "field:column,field:column" option on top of the default mapping.
+func ParseMapping(s string) (Mapping, error) {
+	m := DefaultMapping()
+	if strings.TrimSpace(s) == "" {
+		return m, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(pair, ":")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid mapping %q, expected field:column", pair)
+		}
+		known := false
+		for _, f := range Fields {
+			if strings.EqualFold(f, field) {
+				m[f] = column
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown field %q in mapping", field)
+		}
+	}
+	return m, nil
+}
+
+// NewReader returns a streaming reader for the given format.
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		cr.ReuseRecord = true
+		cr.FieldsPerRecord = -1
+		return &csvReader{r: cr}, nil
+	case FormatJSONL:
+		s := bufio.NewScanner(r)
+		s.Buffer(make([]byte, 64<<10), maxLineSize)
+		return &jsonlReader{s: s}, nil
+	}
+	return nil, fmt.Errorf("unsupported import format %q", format)
+}
+
+// csvReader reads records from a CSV file whose first line is the header.
+type csvReader struct {
+	r      *csv.Reader
+	header []string
+	row    int
+}
+
+// Next returns the next CSV record keyed by header name.
+func (c *csvReader) Next() (*Record, error) {
+	if c.header == nil {
+		h, err := c.r.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil, errors.New("csv file has no header")
+			}
+			return nil, fmt.Errorf("read csv header: %w", err)
+		}
+		for _, name := range h {
+			c.header = append(c.header, strings.TrimSpace(name))
+		}
+	}
+
+	fields, err := c.r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		// A malformed record is a row error, not a fatal one, reading goes on after it
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			c.row++
+			return &Record{Row: c.row, Values: nil, Invalid: "row is not valid CSV: " + parseErr.Err.Error()}, nil
+		}
+		return nil, fmt.Errorf("read csv row %d: %w", c.row+1, err)
+	}
+	c.row++
+
+	rec := &Record{Row: c.row, Values: make(map[string]string, len(fields))}
+	for i, v := range fields {
+		if i < len(c.header) {
+			rec.Values[c.header[i]] = v
+		}
+	}
+	return rec, nil
+}
+
+// jsonlReader reads one JSON object per line, skipping blank lines.
+type jsonlReader struct {
+	s   *bufio.Scanner
+	row int
+}
+
+// Next returns the next JSON object with its values converted to strings.
+func (j *jsonlReader) Next() (*Record, error) {
+	for j.s.Scan() {
+		j.row++
+		line := strings.TrimSpace(j.s.Text())
+		if line == "" {
+			continue
+		}
+
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			// A malformed line is a row error, not a fatal one
+			return &Record{Row: j.row, Values: nil, Invalid: "row is not a valid JSON object"}, nil
+		}
+		rec := &Record{Row: j.row, Values: make(map[string]string, len(obj))}
+		for k, raw := range obj {
+			var s string
+			if err := json.Unmarshal(raw, &s); err == nil {
+				rec.Values[k] = s
+				continue
+			}
+			rec.Values[k] = string(raw)
+		}
+		return rec, nil
+	}
+	if err := j.s.Err(); err != nil {
+		return nil, fmt.Errorf("read jsonl line %d: %w", j.row+1, err)
+	}
+	return nil, io.EOF
+}
+
+// Job is a validated job ready to insert
+type Job struct {
+	Row       int
+	JobRole   string
+	Salary    int
+	Location  string
+	Currency  string
+	PayPeriod string
+}
+
+// Validate maps a record to a job, returning every problem found in the row.
+func Validate(rec *Record, m Mapping) (*Job, []models.ImportRowError) {
+	if rec.Values == nil {
+		return nil, []models.ImportRowError{{Row: rec.Row, Message: rec.Invalid}}
+	}
+
+	lookup := func(field string) string {
+		col := m[field]
+		if v, ok := rec.Values[col]; ok {
+			return strings.TrimSpace(v)
+		}
+		// Fall back to a case-insensitive header match
+		for k, v := range rec.Values {
+			if strings.EqualFold(k, col) {
+				return strings.TrimSpace(v)
+			}
+		}
+		return ""
+	}
+
+	job := &Job{Row: rec.Row, JobRole: lookup(FieldJobRole)}
+	var errs []models.ImportRowError
+	if job.JobRole == "" {
+		errs = append(errs, models.ImportRowError{Row: rec.Row, Field: FieldJobRole, Message: "is required"})
+	}
+
+	salary := lookup(FieldSalary)
+	if salary == "" {
+		errs = append(errs, models.ImportRowError{Row: rec.Row, Field: FieldSalary, Message: "is required"})
+	} else if n, err := strconv.Atoi(salary); err != nil || n <= 0 {
+		errs = append(errs, models.ImportRowError{Row: rec.Row, Field: FieldSalary, Message: "must be a positive whole number"})
+	} else {
+		job.Salary = n
+	}
+
+	// The location is optional, jobs without one are at the company address
+	job.Location = lookup(FieldLocation)
+	if len(job.Location) > maxLocationLength {
+		errs = append(errs, models.ImportRowError{Row: rec.Row, Field: FieldLocation, Message: "must be at most 200 characters"})
+	}
+
+	// The salary is in the base currency per year unless the row says otherwise
+	job.Currency = strings.ToUpper(lookup(FieldCurrency))
+	if job.Currency != "" && !money.ValidCurrency(job.Currency) {
+		errs = append(errs, models.ImportRowError{Row: rec.Row, Field: FieldCurrency, Message: "must be an ISO 4217 code"})
+	}
+	job.PayPeriod = strings.ToLower(lookup(FieldPayPeriod))
+	if job.PayPeriod != "" && !money.ValidPeriod(job.PayPeriod) {
+		errs = append(errs, models.ImportRowError{Row: rec.Row, Field: FieldPayPeriod, Message: "must be one of " + strings.Join(money.Periods, ", ")})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return job, nil
+}
@@ -0,0 +1,151 @@
+package jobimport
+
+import (
+	"errors"
+	"io"
+	"job-portal-api/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readAll reads every record, failing the test on a fatal error
+func readAll(t *testing.T, format, input string) []*Record {
+	t.Helper()
+	r, err := NewReader(format, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("NewReader(%s) error: %v", format, err)
+	}
+	var recs []*Record
+	for {
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return recs
+		}
+		if err != nil {
+			t.Fatalf("Next(%s) error: %v", format, err)
+		}
+		recs = append(recs, rec)
+	}
+}
+
+func TestReaderMalformedRows(t *testing.T) {
+	tests := []struct {
+		desc   string
+		format string
+		input  string
+		want   []*Record
+	}{
+		{
+			desc:   "csv bare quote",
+			format: FormatCSV,
+			input:  "jobRole,salary\nGo Developer,100\nBad \"quote,200\nAccountant,300\n",
+			want: []*Record{
+				{Row: 1, Values: map[string]string{"jobRole": "Go Developer", "salary": "100"}},
+				{Row: 2, Invalid: `row is not valid CSV: bare " in non-quoted-field`},
+				{Row: 3, Values: map[string]string{"jobRole": "Accountant", "salary": "300"}},
+			},
+		},
+		{
+			desc:   "csv unterminated quote at the end",
+			format: FormatCSV,
+			input:  "jobRole,salary\nGo Developer,100\n\"Accountant,300\n",
+			want: []*Record{
+				{Row: 1, Values: map[string]string{"jobRole": "Go Developer", "salary": "100"}},
+				{Row: 2, Invalid: `row is not valid CSV: extraneous or missing " in quoted-field`},
+			},
+		},
+		{
+			desc:   "jsonl invalid line",
+			format: FormatJSONL,
+			input:  "{\"jobRole\":\"Go Developer\",\"salary\":100}\n{not json\n\n{\"jobRole\":\"Accountant\",\"salary\":\"300\"}\n",
+			want: []*Record{
+				{Row: 1, Values: map[string]string{"jobRole": "Go Developer", "salary": "100"}},
+				{Row: 2, Invalid: "row is not a valid JSON object"},
+				{Row: 4, Values: map[string]string{"jobRole": "Accountant", "salary": "300"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := readAll(t, tt.format, tt.input)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %d records, want %d", tt.desc, len(got), len(tt.want))
+			continue
+		}
+		for i := range got {
+			if !reflect.DeepEqual(got[i], tt.want[i]) {
+				t.Errorf("%s: record %d = %+v, want %+v", tt.desc, i, *got[i], *tt.want[i])
+			}
+		}
+		// Malformed rows are reported as row errors by Validate
+		for _, rec := range got {
+			if rec.Values != nil {
+				continue
+			}
+			job, errs := Validate(rec, DefaultMapping())
+			want := []models.ImportRowError{{Row: rec.Row, Message: rec.Invalid}}
+			if job != nil || !reflect.DeepEqual(errs, want) {
+				t.Errorf("%s: Validate(row %d) = %v, %v, want %v", tt.desc, rec.Row, job, errs, want)
+			}
+		}
+	}
+}
+
+func TestReaderFatalErrors(t *testing.T) {
+	if _, err := NewReader("xml", strings.NewReader("")); err == nil {
+		t.Error("NewReader(xml) succeeded, want an error")
+	}
+
+	r, _ := NewReader(FormatCSV, strings.NewReader(""))
+	if _, err := r.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Next() on an empty csv = %v, want a missing header error", err)
+	}
+
+	r, _ = NewReader(FormatJSONL, strings.NewReader(strings.Repeat("x", maxLineSize+1)))
+	if _, err := r.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Next() on a too long line = %v, want an error", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		desc   string
+		values map[string]string
+		want   *Job
+		fields []string // fields is the fields with errors, in order
+	}{
+		{"minimal", map[string]string{"jobRole": " Go Developer ", "salary": "100"}, &Job{Row: 1, JobRole: "Go Developer", Salary: 100}, nil},
+		{"currency and period", map[string]string{"jobRole": "Go", "salary": "100", "currency": "usd", "payPeriod": "Month"}, &Job{Row: 1, JobRole: "Go", Salary: 100, Currency: "USD", PayPeriod: "month"}, nil},
+		{"case-insensitive columns", map[string]string{"JOBROLE": "Go", "Salary": "100"}, &Job{Row: 1, JobRole: "Go", Salary: 100}, nil},
+		{"missing", map[string]string{}, nil, []string{FieldJobRole, FieldSalary}},
+		{"not a number", map[string]string{"jobRole": "Go", "salary": "1e3"}, nil, []string{FieldSalary}},
+		{"not positive", map[string]string{"jobRole": "Go", "salary": "0"}, nil, []string{FieldSalary}},
+		{"location too long", map[string]string{"jobRole": "Go", "salary": "1", "location": strings.Repeat("a", 201)}, nil, []string{FieldLocation}},
+		{"unknown currency and period", map[string]string{"jobRole": "Go", "salary": "1", "currency": "XX", "payPeriod": "fortnight"}, nil, []string{FieldCurrency, FieldPayPeriod}},
+	}
+	for _, tt := range tests {
+		job, errs := Validate(&Record{Row: 1, Values: tt.values}, DefaultMapping())
+		var fields []string
+		for _, e := range errs {
+			fields = append(fields, e.Field)
+		}
+		if !reflect.DeepEqual(job, tt.want) || !reflect.DeepEqual(fields, tt.fields) {
+			t.Errorf("Validate(%s) = %+v, %v, want %+v, %v", tt.desc, job, fields, tt.want, tt.fields)
+		}
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	m, err := ParseMapping("jobrole: Title , salary:Pay")
+	if err != nil {
+		t.Fatalf("ParseMapping() error: %v", err)
+	}
+	if m[FieldJobRole] != "Title" || m[FieldSalary] != "Pay" || m[FieldLocation] != FieldLocation {
+		t.Errorf("ParseMapping() = %v", m)
+	}
+	for _, in := range []string{"jobRole", "jobRole:", "title:Title"} {
+		if _, err := ParseMapping(in); err == nil {
+			t.Errorf("ParseMapping(%q) succeeded, want an error", in)
+		}
+	}
+}
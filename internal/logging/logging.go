@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Redacted replaces sensitive values in logs
+const Redacted = "[REDACTED]"
+
+// defaultSensitiveFields are query parameters and headers whose values are never logged
+var defaultSensitiveFields = []string{"email", "password", "token", "csrf_token", "authorization", "cookie", "set-cookie", "x-csrf-token"}
+
+// Config represents the logging configuration
+type Config struct {
+	Level           zerolog.Level // Minimum level that is written
+	Format          string        // Format is "json" or "console"
+	SensitiveFields []string      // Field names, compared case-insensitively, whose values are redacted
+}
+
+// DefaultConfig returns the logging configuration from the environment
+func DefaultConfig() Config {
+	cfg := Config{
+		Level:           zerolog.InfoLevel,
+		Format:          "json",
+		SensitiveFields: defaultSensitiveFields,
+	}
+	if lvl, err := zerolog.ParseLevel(strings.ToLower(os.Getenv("LOG_LEVEL"))); err == nil && lvl != zerolog.NoLevel {
+		cfg.Level = lvl
+	}
+	if f := strings.ToLower(os.Getenv("LOG_FORMAT")); f == "console" {
+		cfg.Format = f
+	}
+	for _, f := range strings.Split(os.Getenv("LOG_REDACT_FIELDS"), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			cfg.SensitiveFields = append(cfg.SensitiveFields, f)
+		}
+	}
+	return cfg
+}
+
+// sensitive holds the lower-cased names of the configured sensitive fields
+var sensitive = toSet(defaultSensitiveFields)
+
+// Setup configures the global zerolog logger
+func Setup(cfg Config) {
+	zerolog.SetGlobalLevel(cfg.Level)
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	if cfg.Format == "console" {
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	} else {
+		log.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
+	}
+	sensitive = toSet(cfg.SensitiveFields)
+}
+
+// IsSensitive reports whether values of the named field must be redacted
+func IsSensitive(name string) bool {
+	_, ok := sensitive[strings.ToLower(name)]
+	return ok
+}
+
+// RedactQuery returns the query string with the values of sensitive parameters replaced
+func RedactQuery(q url.Values) string {
+	if len(q) == 0 {
+		return ""
+	}
+	out := make(url.Values, len(q))
+	for k, v := range q {
+		if IsSensitive(k) {
+			out[k] = []string{Redacted}
+			continue
+		}
+		out[k] = v
+	}
+	// Encode escapes the brackets of the placeholder, keep it readable in the logs
+	return strings.ReplaceAll(out.Encode(), url.QueryEscape(Redacted), Redacted)
+}
+
+// toSet lower-cases the names into a set
+func toSet(names []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		set[strings.ToLower(n)] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,181 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/logging"
+	"mime"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Mailer sends plain text emails, optionally with attachments.
+type Mailer interface {
+	Send(to, subject, body string, attachments ...Attachment) error
+}
+
+// Attachment is a file attached to an email
+type Attachment struct {
+	Name        string // Name is the file name shown to the recipient
+	ContentType string // ContentType is the media type of the file, such as "text/calendar; method=REQUEST"
+	Data        []byte // Data is the content of the file
+}
+
+// SMTPConfig represents the configuration parameters for an SMTP server
+type SMTPConfig struct {
+	Host     string // SMTP server host
+	Port     string // SMTP server port
+	User     string // SMTP user
+	Password string // SMTP password
+	From     string // Sender address used on outgoing mail
+}
+
+// DefaultSMTPConfig returns the SMTP configuration from the environment
+func DefaultSMTPConfig() SMTPConfig {
+	return SMTPConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		User:     os.Getenv("SMTP_USER"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+}
+
+// New returns an SMTP mailer when a host is configured, otherwise a mailer that only logs messages.
+func New(cfg SMTPConfig) Mailer {
+	if cfg.Host == "" {
+		return LogMailer{}
+	}
+	return SMTPMailer{cfg: cfg}
+}
+
+// SMTPMailer sends emails through an SMTP server.
+type SMTPMailer struct {
+	cfg SMTPConfig
+}
+
+// Send sends an email through the configured SMTP server. An email with attachments is sent as multipart/mixed,
+// the body first.
+func (m SMTPMailer) Send(to, subject, body string, attachments ...Attachment) error {
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return errors.New("send mail: header values cannot contain line breaks")
+	}
+	for _, a := range attachments {
+		if strings.ContainsAny(a.Name+a.ContentType, "\r\n\"") {
+			return errors.New("send mail: attachment names and types cannot contain line breaks or quotes")
+		}
+	}
+
+	msg, err := m.message(to, subject, body, attachments, time.Now())
+	if err != nil {
+		return fmt.Errorf("send mail: %w", err)
+	}
+
+	var a smtp.Auth
+	if m.cfg.User != "" {
+		a = smtp.PlainAuth("", m.cfg.User, m.cfg.Password, m.cfg.Host)
+	}
+	err = smtp.SendMail(m.cfg.Host+":"+m.cfg.Port, a, m.cfg.From, []string{to}, []byte(msg))
+	if err != nil {
+		return fmt.Errorf("send mail: %w", err)
+	}
+	return nil
+}
+
+// message returns the email with its headers. The subject is encoded as UTF-8, and the Message-ID is random
+// and in the domain of the sender.
+func (m SMTPMailer) message(to, subject, body string, attachments []Attachment, date time.Time) (string, error) {
+	id, err := randomHex()
+	if err != nil {
+		return "", err
+	}
+	domain := "localhost"
+	if _, d, ok := strings.Cut(m.cfg.From, "@"); ok && d != "" {
+		domain = strings.TrimSuffix(d, ">")
+	}
+
+	msg := "From: " + m.cfg.From + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n" +
+		"Date: " + date.Format(time.RFC1123Z) + "\r\n" +
+		"Message-ID: <" + id + "@" + domain + ">\r\n" +
+		"MIME-Version: 1.0\r\n"
+	if len(attachments) == 0 {
+		return msg + "Content-Type: text/plain; charset=UTF-8\r\n" +
+			"Content-Transfer-Encoding: 8bit\r\n" +
+			"\r\n" + body, nil
+	}
+	multipart, boundary, err := multipartBody(body, attachments)
+	if err != nil {
+		return "", err
+	}
+	return msg + "Content-Type: multipart/mixed; boundary=\"" + boundary + "\"\r\n" +
+		"\r\n" + multipart, nil
+}
+
+// randomHex returns 16 random bytes in hex
+func randomHex() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
+
+// multipartBody returns the parts of an email with attachments and the boundary separating them
+func multipartBody(body string, attachments []Attachment) (string, string, error) {
+	random, err := randomHex()
+	if err != nil {
+		return "", "", err
+	}
+	boundary := "job-portal-" + random
+
+	var b bytes.Buffer
+	b.WriteString("--" + boundary + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"Content-Transfer-Encoding: 8bit\r\n" +
+		"\r\n" + body + "\r\n")
+	for _, a := range attachments {
+		b.WriteString("--" + boundary + "\r\n" +
+			"Content-Type: " + a.ContentType + "; name=\"" + a.Name + "\"\r\n" +
+			"Content-Disposition: attachment; filename=\"" + a.Name + "\"\r\n" +
+			"Content-Transfer-Encoding: base64\r\n" +
+			"\r\n")
+		// Base64 lines are kept to 76 characters
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			b.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		b.WriteString(encoded + "\r\n")
+	}
+	b.WriteString("--" + boundary + "--\r\n")
+	return b.String(), boundary, nil
+}
+
+// LogMailer writes emails to the log instead of sending them. It is used in development.
+type LogMailer struct{}
+
+// Send logs that an email was not sent, with its subject and the domain of the recipient. The body and
+// attachments carry unlock tokens and verification codes and are never logged.
+func (LogMailer) Send(to, subject, body string, attachments ...Attachment) error {
+	log.Info().Str("to", redactAddress(to)).Str("subject", subject).Msg("email not sent, no SMTP host configured")
+	return nil
+}
+
+// redactAddress keeps the first character of the local part of an email address and its domain
+func redactAddress(addr string) string {
+	local, domain, ok := strings.Cut(addr, "@")
+	if !ok || local == "" {
+		return logging.Redacted
+	}
+	return local[:1] + "***@" + domain
+}
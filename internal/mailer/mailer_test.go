@@ -0,0 +1,69 @@
+package mailer
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageHeaders(t *testing.T) {
+	m := SMTPMailer{cfg: SMTPConfig{From: "Job Portal <noreply@jobs.example.com>"}}
+	date := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	for _, attachments := range [][]Attachment{nil, {{Name: "invite.ics", ContentType: "text/calendar; method=REQUEST", Data: []byte("BEGIN:VCALENDAR")}}} {
+		raw, err := m.message("carl@example.com", "Interview über Zoom – Größe", "Hallo Carl", attachments, date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := mail.ReadMessage(strings.NewReader(raw))
+		if err != nil {
+			t.Fatalf("ReadMessage() error: %v", err)
+		}
+
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		if err != nil || subject != "Interview über Zoom – Größe" {
+			t.Errorf("Subject = %q, %v, want it decoded back", subject, err)
+		}
+		if strings.ContainsFunc(msg.Header.Get("Subject"), func(r rune) bool { return r > 0x7f }) {
+			t.Errorf("Subject %q is not encoded", msg.Header.Get("Subject"))
+		}
+		if got, err := msg.Header.Date(); err != nil || !got.Equal(date) {
+			t.Errorf("Date = %v, %v, want %v", got, err, date)
+		}
+		if id := msg.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@jobs.example.com>") {
+			t.Errorf("Message-ID = %q, want an id in the sender domain", id)
+		}
+		if msg.Header.Get("MIME-Version") != "1.0" {
+			t.Errorf("MIME-Version = %q, want 1.0", msg.Header.Get("MIME-Version"))
+		}
+
+		mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		if err != nil {
+			t.Fatalf("Content-Type %q: %v", msg.Header.Get("Content-Type"), err)
+		}
+		if attachments == nil {
+			if mediaType != "text/plain" || params["charset"] != "UTF-8" {
+				t.Errorf("Content-Type = %q, want text/plain in UTF-8", msg.Header.Get("Content-Type"))
+			}
+			body, _ := io.ReadAll(msg.Body)
+			if !bytes.Equal(body, []byte("Hallo Carl")) {
+				t.Errorf("body = %q", body)
+			}
+		} else if mediaType != "multipart/mixed" || params["boundary"] == "" {
+			t.Errorf("Content-Type = %q, want multipart/mixed with a boundary", msg.Header.Get("Content-Type"))
+		}
+	}
+}
+
+func TestSendRejectsHeaderInjection(t *testing.T) {
+	m := SMTPMailer{cfg: SMTPConfig{Host: "127.0.0.1", Port: "0", From: "noreply@example.com"}}
+	if err := m.Send("carl@example.com\r\nBcc: eve@example.com", "Hi", "body"); err == nil {
+		t.Error("Send() with a line break in the recipient succeeded, want an error")
+	}
+	if err := m.Send("carl@example.com", "Hi\nBcc: eve@example.com", "body"); err == nil {
+		t.Error("Send() with a line break in the subject succeeded, want an error")
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"job-portal-api/internal/audit"
+	"net/http"
+)
+
+// AuditMeta is a middleware that stores the request ID and client IP in the request context
+// so that services can record them with audit events. It must run after RequestID and RealIP.
+func AuditMeta(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := audit.WithMeta(r.Context(), audit.Meta{
+			RequestID: RequestIDFromContext(r.Context()),
+			IP:        clientIP(r),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
+	"job-portal-api/internal/auth"
 	"net/http"
 
 	"github.com/rs/zerolog/log"
@@ -10,30 +12,67 @@ import (
 // JWTMiddlewareCookie is a middleware function that checks for a JWT token in the request cookie,
 // verifies the token, and sets the user ID in the request context if the token is valid.
 // It also performs role-based authorization by checking the required role against the token claims.
+// A bearer token in the Authorization header is accepted as well; cookie-authenticated requests that
+// change state must carry a CSRF token, bearer-authenticated ones are not exposed to CSRF and skip the check.
 func (m Mid) JWTMiddlewareCookie(next http.HandlerFunc, requiredRole string) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Retrieve the JWT token from the request cookie
-		cookie, err := r.Cookie("token")
-		if err != nil {
-			log.Error().Err(err).Send()
-			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
-			return
+		// Prefer a bearer token, then fall back to the JWT token from the request cookie
+		tokenString := extractTokenFromHeader(r)
+		fromCookie := false
+		if tokenString == "" {
+			cookie, err := r.Cookie(auth.TokenCookieName)
+			if err != nil {
+				log.Error().Err(err).Send()
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			tokenString = cookie.Value
+			fromCookie = true
 		}
 
 		// Verify the JWT token using the authentication service
-		claim, err := m.a.VerifyToken(cookie.Value, requiredRole)
+		claim, err := m.a.VerifyToken(tokenString, requiredRole)
 		if err != nil {
 			log.Error().Err(err).Send()
 			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 			return
 		}
 
+		// Browsers attach cookies to cross-site requests, so state-changing requests must prove they came from our client
+		if fromCookie && !isSafeMethod(r.Method) && !validCSRF(r) {
+			log.Error().Str("method", r.Method).Msg("missing or invalid csrf token")
+			http.Error(w, "missing or invalid csrf token", http.StatusForbidden)
+			return
+		}
+
 		// You can use the claims for further authorization checks
 
-		// Set the user ID from the token claims in the request context
+		// Set the user ID and role from the token claims in the request context, and record the user for the access log
 		ctx := context.WithValue(r.Context(), "userID", claim.Subject)
+		ctx = context.WithValue(ctx, "roles", claim.Roles)
+		setLogUserID(ctx, claim.Subject)
 
 		// Call the next handler in the chain with the updated context
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// isSafeMethod reports whether the HTTP method does not change state.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// validCSRF implements the double-submit check: the X-CSRF-Token header must match the csrf_token cookie.
+// A cross-site attacker can make the browser send the cookie but cannot read it to set the header.
+func validCSRF(r *http.Request) bool {
+	header := r.Header.Get(auth.CSRFHeaderName)
+	cookie, err := r.Cookie(auth.CSRFCookieName)
+	if err != nil || header == "" || cookie.Value == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) == 1
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig holds the cross-origin resource sharing policy
+type CORSConfig struct {
+	AllowedOrigins   []string // Origins allowed to call the API; "*" allows any origin and "https://*.example.com" any subdomain
+	AllowedMethods   []string // Methods allowed on cross-origin requests
+	AllowedHeaders   []string // Request headers allowed on cross-origin requests
+	ExposedHeaders   []string // Response headers readable by cross-origin scripts
+	AllowCredentials bool     // AllowCredentials lets browsers send cookies on cross-origin requests
+	MaxAge           int      // MaxAge is how long in seconds a preflight response may be cached
+}
+
+// DefaultCORSConfig returns the CORS configuration from the environment.
+// With no CORS_ALLOWED_ORIGINS set, cross-origin requests are not allowed.
+func DefaultCORSConfig() CORSConfig {
+	cfg := CORSConfig{
+		AllowedOrigins:   splitList(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-CSRF-Token", "X-Request-ID", "If-Match", "If-None-Match", "Idempotency-Key"},
+		ExposedHeaders:   []string{"ETag", "X-CSRF-Token", "X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "Idempotent-Replayed", "X-Duplicate-Of"},
+		AllowCredentials: os.Getenv("CORS_ALLOW_CREDENTIALS") != "false",
+		MaxAge:           600,
+	}
+	if v := splitList(os.Getenv("CORS_ALLOWED_METHODS")); len(v) > 0 {
+		cfg.AllowedMethods = v
+	}
+	if v := splitList(os.Getenv("CORS_ALLOWED_HEADERS")); len(v) > 0 {
+		cfg.AllowedHeaders = v
+	}
+	if v := splitList(os.Getenv("CORS_EXPOSED_HEADERS")); len(v) > 0 {
+		cfg.ExposedHeaders = v
+	}
+	if n, err := strconv.Atoi(os.Getenv("CORS_MAX_AGE")); err == nil && n >= 0 {
+		cfg.MaxAge = n
+	}
+	return cfg
+}
+
+// ErrCORSWildcardCredentials is returned for a policy that would let any site, or any subdomain, make requests with
+// the cookies of the user and read the responses, such as the CSRF token
+var ErrCORSWildcardCredentials = errors.New("CORS origins with a wildcard cannot be allowed with credentials, list them or set CORS_ALLOW_CREDENTIALS=false")
+
+// Validate checks that wildcard origins are not combined with credentials.
+func (cfg CORSConfig) Validate() error {
+	if !cfg.AllowCredentials {
+		return nil
+	}
+	for _, allowed := range cfg.AllowedOrigins {
+		if strings.Contains(allowed, "*") {
+			return ErrCORSWildcardCredentials
+		}
+	}
+	return nil
+}
+
+// CORS returns a middleware applying the given CORS policy. It must be registered with Use on the router
+// so that preflight OPTIONS requests are answered before chi's routing replies 405 Method Not Allowed.
+func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(cfg.MaxAge)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			// The response depends on the Origin header, caches must key on it
+			w.Header().Add("Vary", "Origin")
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if origin == "" || !cfg.originAllowed(origin) {
+				if preflight {
+					// Answer the preflight without CORS headers, the browser will block the request
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Echo the origin rather than "*" so that credentialed requests are accepted
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if cfg.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if preflight {
+				w.Header().Set("Access-Control-Allow-Methods", methods)
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if exposed != "" {
+				w.Header().Set("Access-Control-Expose-Headers", exposed)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// originAllowed reports whether the origin matches the allow list.
+func (cfg CORSConfig) originAllowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range cfg.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		// Support a single leading wildcard for subdomains, e.g. https://*.example.com
+		if scheme, host, ok := strings.Cut(allowed, "://*."); ok {
+			if strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+host) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitList splits a comma separated environment value, dropping empty entries.
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
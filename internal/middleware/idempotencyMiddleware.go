@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"job-portal-api/internal/idempotency"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// IdempotencyKeyHeader is the header clients use to make POST requests safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotentBody is the largest request or response body handled by the idempotency middleware
+const maxIdempotentBody = 1 << 20
+
+// replayedHeaders are the response headers stored and replayed with an idempotent response. X-Duplicate-Of
+// is kept so that a retried job posting still names the posting it repeats.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag", "X-Duplicate-Of"}
+
+// Idempotency replays stored responses for retried requests carrying the same Idempotency-Key.
+type Idempotency struct {
+	store *idempotency.Store
+	ttl   time.Duration
+}
+
+// NewIdempotency creates a new idempotency middleware keeping keys for ttl.
+func NewIdempotency(store *idempotency.Store, ttl time.Duration) (*Idempotency, error) {
+	if store == nil {
+		return nil, errors.New("idempotency store cannot be nil")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("idempotency key ttl must be positive")
+	}
+	return &Idempotency{store: store, ttl: ttl}, nil
+}
+
+// Idempotent is a middleware function for POST handlers. When the request has an Idempotency-Key header,
+// the first request with that key is processed and its response stored per principal; retries with the
+// same body get the stored response, concurrent duplicates get 409 Conflict and reuse of the key with a
+// different body gets 422 Unprocessable Entity. Requests without the header are processed as usual.
+// It must run after JWTMiddlewareCookie on authenticated routes so that keys are scoped to the user.
+func (id *Idempotency) Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > 255 {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		// Fingerprint the request so that a reused key with a different payload is detected
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+		if err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxIdempotentBody {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), body...))
+		fingerprint := hex.EncodeToString(sum[:])
+
+		principal := idempotencyPrincipal(r)
+		stored, err := id.store.Begin(r.Context(), principal, key, fingerprint, id.ttl)
+		switch {
+		case errors.Is(err, idempotency.ErrInFlight):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case errors.Is(err, idempotency.ErrFingerprintMismatch):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case err != nil:
+			log.Error().Err(err).Send()
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		case stored != nil:
+			for k, v := range stored.Header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		// We own the key: process the request while capturing the response. The key is completed or released
+		// even when the client has gone away, since the resource may already exist.
+		storeCtx := context.WithoutCancel(r.Context())
+		capture := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			// Free the key if the handler failed or panicked, so that the client can retry
+			if !completed {
+				if err := id.store.Release(storeCtx, principal, key); err != nil {
+					log.Error().Err(err).Send()
+				}
+			}
+		}()
+		next.ServeHTTP(capture, r)
+
+		if capture.status >= http.StatusInternalServerError || capture.overflow {
+			return
+		}
+		resp := idempotency.Response{Status: capture.status, Header: http.Header{}, Body: capture.buf.Bytes()}
+		for _, h := range replayedHeaders {
+			if v := w.Header().Values(h); len(v) > 0 {
+				resp.Header[h] = v
+			}
+		}
+		if err := id.store.Complete(storeCtx, principal, key, resp); err != nil {
+			log.Error().Err(err).Send()
+			return
+		}
+		completed = true
+	})
+}
+
+// idempotencyPrincipal scopes keys to the authenticated user, or to the client IP on anonymous routes.
+func idempotencyPrincipal(r *http.Request) string {
+	if userID, ok := r.Context().Value("userID").(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + clientIP(r)
+}
+
+// captureWriter writes the response through to the client while keeping a copy of it.
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	buf         bytes.Buffer
+	wroteHeader bool
+	overflow    bool
+}
+
+// WriteHeader records the status code.
+func (c *captureWriter) WriteHeader(status int) {
+	if !c.wroteHeader {
+		c.status = status
+		c.wroteHeader = true
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+// Write copies the body into the buffer, giving up on storing it once it is too large.
+func (c *captureWriter) Write(b []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.buf.Len()+len(b) > maxIdempotentBody {
+		c.overflow = true
+	} else {
+		c.buf.Write(b)
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+// IdempotencyTTL returns how long idempotency keys are kept, from IDEMPOTENCY_KEY_TTL_HOURS, defaulting to 24 hours.
+func IdempotencyTTL() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_KEY_TTL_HOURS"))
+	if err != nil || hours <= 0 {
+		hours = 24
+	}
+	return time.Duration(hours) * time.Hour
+}
@@ -1,48 +1,110 @@
 package middleware
 
 import (
+	"context"
+	"job-portal-api/internal/logging"
 	"net/http"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
 )
 
-// ResponseRecorder is a custom implementation of http.ResponseWriter that records the HTTP status code.
+// ResponseRecorder is a custom implementation of http.ResponseWriter that records the HTTP status code and response size.
 type ResponseRecorder struct {
 	http.ResponseWriter
-	StatusCode int
+	StatusCode  int
+	Bytes       int
+	wroteHeader bool
 }
 
 // WriteHeader overrides the WriteHeader method of http.ResponseWriter to record the HTTP status code.
 func (rec *ResponseRecorder) WriteHeader(statusCode int) {
-	rec.StatusCode = statusCode
+	if !rec.wroteHeader {
+		rec.StatusCode = statusCode
+		rec.wroteHeader = true
+	}
 	rec.ResponseWriter.WriteHeader(statusCode)
 }
 
-// HttpLogger is a middleware that logs information about incoming HTTP requests, including method, URL, status code, and duration.
+// Write overrides the Write method of http.ResponseWriter to record the number of bytes written.
+func (rec *ResponseRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.Bytes += n
+	return n, err
+}
+
+// Flush sends any buffered data to the client, so streamed responses keep working through the recorder.
+func (rec *ResponseRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		if !rec.wroteHeader {
+			rec.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (rec *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// logInfo carries values discovered deeper in the handler chain back to the access log.
+type logInfo struct {
+	UserID string
+}
+
+// setLogUserID records the authenticated user ID for the access log of the current request.
+func setLogUserID(ctx context.Context, userID string) {
+	if info, ok := ctx.Value(logInfoKey).(*logInfo); ok {
+		info.UserID = userID
+	}
+}
+
+// HttpLogger is a middleware that logs information about incoming HTTP requests, including method, route pattern,
+// redacted query, status code, response size, duration, request ID, client IP and authenticated user ID.
 // It wraps an existing http.Handler and returns a new http.Handler.
 func HttpLogger(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Record the start time of the request processing.
 		startTime := time.Now()
-		// Create a custom ResponseRecorder to capture the status code.
+		// Create a custom ResponseRecorder to capture the status code and size.
 		rec := &ResponseRecorder{
 			ResponseWriter: w,
-			// Initialize StatusCode to http.StatusOK if needed.
-			// StatusCode: http.StatusOK,
+			StatusCode:     http.StatusOK,
 		}
+		info := &logInfo{}
+		ctx := context.WithValue(r.Context(), logInfoKey, info)
 		// Serve the HTTP request using the wrapped handler.
-		handler.ServeHTTP(rec, r)
+		handler.ServeHTTP(rec, r.WithContext(ctx))
 		// Calculate the duration of the request processing.
 		duration := time.Since(startTime)
+
+		// Log the matched route pattern rather than the raw path, which may contain identifiers
+		route := ""
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			route = rctx.RoutePattern()
+		}
+
 		// Create a logger with request information.
 		logger := log.Info()
+		if rec.StatusCode >= http.StatusInternalServerError {
+			logger = log.Error()
+		}
 		logger.
 			Str("protocol", "http").
+			Str("request_id", RequestIDFromContext(r.Context())).
 			Str("method", r.Method).
-			Stringer("url", r.URL).
+			Str("route", route).
+			Str("query", logging.RedactQuery(r.URL.Query())).
+			Str("client_ip", clientIP(r)).
+			Str("user_id", info.UserID).
 			Int("status", rec.StatusCode).
 			Str("status_text", http.StatusText(rec.StatusCode)).
+			Int("bytes", rec.Bytes).
 			Dur("duration", duration).
 			Msg("Received an HTTP Request")
 	})
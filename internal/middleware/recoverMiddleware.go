@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Problem is an RFC 7807 problem details response body.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// Recoverer is a middleware that recovers from panics in later handlers, logs the panic with its
+// stack trace and request ID, and responds with a 500 problem response. A handler that panics after writing
+// its headers has already sent a status and part of a body, so the connection is aborted instead, and the
+// client sees a truncated response rather than a problem appended to it.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &ResponseRecorder{ResponseWriter: w, StatusCode: http.StatusOK}
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+			// ErrAbortHandler is used to abort a response on purpose, let net/http handle it
+			if rvr == http.ErrAbortHandler {
+				panic(rvr)
+			}
+
+			requestID := RequestIDFromContext(r.Context())
+			log.Error().
+				Str("request_id", requestID).
+				Interface("panic", rvr).
+				Bytes("stack", debug.Stack()).
+				Bool("headers_written", rec.wroteHeader).
+				Msg("recovered from panic")
+
+			if rec.wroteHeader {
+				panic(http.ErrAbortHandler)
+			}
+			writeProblem(w, http.StatusInternalServerError, "", requestID)
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// writeProblem writes a problem details response with the given status.
+func writeProblem(w http.ResponseWriter, status int, detail, requestID string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		RequestID: requestID,
+	})
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecovererBeforeHeaders(t *testing.T) {
+	h := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Partial", "1")
+		panic("boom")
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("Recoverer() = %d %q, want a 500 problem", w.Code, w.Header().Get("Content-Type"))
+	}
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil || p.Status != http.StatusInternalServerError {
+		t.Errorf("Recoverer() body = %+v, %v", p, err)
+	}
+}
+
+func TestRecovererAfterHeaders(t *testing.T) {
+	for name, write := range map[string]func(http.ResponseWriter){
+		"WriteHeader": func(w http.ResponseWriter) { w.WriteHeader(http.StatusOK) },
+		"Write":       func(w http.ResponseWriter) { w.Write([]byte("id,role\n1,")) },
+		"Flush":       func(w http.ResponseWriter) { w.(http.Flusher).Flush() },
+	} {
+		h := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/csv")
+			write(w)
+			panic("boom")
+		}))
+		w := httptest.NewRecorder()
+		func() {
+			defer func() {
+				if rvr := recover(); rvr != http.ErrAbortHandler {
+					t.Errorf("%s then panic: recovered %v, want http.ErrAbortHandler", name, rvr)
+				}
+			}()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		}()
+		if w.Header().Get("Content-Type") != "text/csv" {
+			t.Errorf("%s then panic: Content-Type = %q, want the handler's", name, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestRecovererAbortHandler(t *testing.T) {
+	h := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if rvr := recover(); rvr != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", rvr)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
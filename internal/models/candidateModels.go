@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// NewCandidateProfile represents the profile a candidate saves to get job recommendations.
+type NewCandidateProfile struct {
+	Headline         string   `json:"headline" validate:"max=200"`                   // Headline is a one line summary of the candidate.
+	Skills           []string `json:"skills" validate:"max=50,dive,required,max=60"` // Skills are the candidate's skills, such as "go" or "postgresql".
+	DesiredSalary    int      `json:"desiredSalary" validate:"min=0"`                // DesiredSalary is the lowest salary the candidate looks for per pay period, 0 if any.
+	DesiredCurrency  string   `json:"desiredCurrency"`                               // DesiredCurrency is the ISO 4217 currency of the desired salary, the base currency when empty.
+	DesiredPayPeriod string   `json:"desiredPayPeriod"`                              // DesiredPayPeriod is what the desired salary is per: hour, day, week, month or year (default).
+	Location         string   `json:"location" validate:"max=200"`                   // Location is where the candidate wants to work, such as a city.
+	OpenToWork       *bool    `json:"openToWork"`                                    // OpenToWork lets recruiters find the candidate, true when not sent.
+}
+
+// CandidateProfile represents a candidate's profile.
+type CandidateProfile struct {
+	UserId              int       `json:"userId"`                        // UserId is the candidate.
+	Headline            string    `json:"headline"`                      // Headline is a one line summary of the candidate.
+	Skills              []string  `json:"skills"`                        // Skills are the candidate's skills.
+	DesiredSalary       int       `json:"desiredSalary"`                 // DesiredSalary is the lowest salary the candidate looks for per pay period, 0 if any.
+	DesiredCurrency     string    `json:"desiredCurrency,omitempty"`     // DesiredCurrency is the ISO 4217 currency of the desired salary.
+	DesiredPayPeriod    string    `json:"desiredPayPeriod,omitempty"`    // DesiredPayPeriod is what the desired salary is per.
+	DesiredAnnualSalary *float64  `json:"desiredAnnualSalary,omitempty"` // DesiredAnnualSalary is the desired salary per year in the base currency, nil without an exchange rate.
+	Location            string    `json:"location"`                      // Location is where the candidate wants to work.
+	City                string    `json:"city,omitempty"`                // City is the place the location was geocoded to.
+	Country             string    `json:"country,omitempty"`             // Country is the ISO 3166-1 alpha-2 code of the location's country.
+	OpenToWork          bool      `json:"openToWork"`                    // OpenToWork lets recruiters find the candidate.
+	UpdatedAt           time.Time `json:"updatedAt"`                     // UpdatedAt is when the profile was last saved.
+}
+
+// JobApplication represents a candidate applying to a job.
+type JobApplication struct {
+	ID        int       `json:"id"`        // ID is the identifier of the application.
+	JobId     int       `json:"jobId"`     // JobId is the job applied to.
+	UserId    int       `json:"userId"`    // UserId is the candidate.
+	CreatedAt time.Time `json:"createdAt"` // CreatedAt is when the candidate applied.
+}
+
+// RecommendedJob represents a job recommended to a candidate, with why it matched.
+type RecommendedJob struct {
+	Job     *Job     `json:"job"`     // Job is the recommended job.
+	Score   float64  `json:"score"`   // Score is how well the job matches, from 0 to 1.
+	Reasons []string `json:"reasons"` // Reasons explain the score.
+}
+
+// SuggestedCandidate represents a candidate suggested to a recruiter for a job, with why they matched.
+type SuggestedCandidate struct {
+	UserId   int      `json:"userId"`   // UserId is the candidate.
+	Headline string   `json:"headline"` // Headline is the candidate's summary.
+	Skills   []string `json:"skills"`   // Skills are the candidate's skills.
+	Location string   `json:"location"` // Location is where the candidate wants to work.
+	Applied  bool     `json:"applied"`  // Applied is set when the candidate already applied to the job.
+	Score    float64  `json:"score"`    // Score is how well the candidate matches, from 0 to 1.
+	Reasons  []string `json:"reasons"`  // Reasons explain the score.
+}
@@ -1,15 +1,52 @@
 package models
 
+import "time"
+
+// Company size bands
+var CompanySizeBands = []string{"1-10", "11-50", "51-200", "201-500", "501-1000", "1001-5000", "5001+"}
+
+// CompanyProfile holds the optional profile details of a company.
+type CompanyProfile struct {
+	Description string            `json:"description" validate:"max=5000"`                                                        // Description is a free text introduction of the company.
+	Website     string            `json:"website" validate:"omitempty,url,max=2048"`                                              // Website is the company's home page.
+	Industry    string            `json:"industry" validate:"max=100"`                                                            // Industry is the sector the company works in.
+	SizeBand    string            `json:"sizeBand" validate:"omitempty,oneof=1-10 11-50 51-200 201-500 501-1000 1001-5000 5001+"` // SizeBand is the number of employees, one of CompanySizeBands.
+	FoundedYear *int              `json:"foundedYear" validate:"omitempty,min=1800"`                                              // FoundedYear is the year the company was founded.
+	SocialLinks map[string]string `json:"socialLinks" validate:"max=10,dive,keys,max=30,endkeys,url"`                             // SocialLinks maps a network name such as "linkedin" to the company's profile URL.
+}
+
 // NewCompany represents the structure for creating a new company. It includes fields for the company's name and address.
 type NewCompany struct {
-	Name    string `json:"name" validate:"required"`    // Name is the name of the company and is required.
-	Address string `json:"address" validate:"required"` // Address is the physical address of the company and is required.
+	Name           string `json:"name" validate:"required"`    // Name is the name of the company as it should be displayed and is required.
+	Address        string `json:"address" validate:"required"` // Address is the physical address of the company and is required.
+	CompanyProfile        // CompanyProfile holds the optional profile details.
 }
 
 // Company represents the structure for a company entity. It includes fields such as ID, name, address, and UserId.
 type Company struct {
-	ID      int    `json:"id"`      // ID is a unique identifier for the company.
-	Name    string `json:"name"`    // Name is the name of the company.
-	Address string `json:"address"` // Address is the physical address of the company.
-	UserId  int    `json:"userId"`  // UserId is the identifier of the user associated with the company.
+	ID               int    `json:"id"`               // ID is a unique identifier for the company.
+	Name             string `json:"name"`             // Name is the display name of the company, as entered.
+	NormalizedName   string `json:"normalizedName"`   // NormalizedName is the case and space folded name that must be unique among live companies.
+	Address          string `json:"address"`          // Address is the physical address of the company.
+	UserId           int    `json:"userId"`           // UserId is the identifier of the user associated with the company.
+	Version          int    `json:"version"`          // Version is incremented on every change and used for optimistic concurrency.
+	AggregatorOptOut bool   `json:"aggregatorOptOut"` // AggregatorOptOut keeps the company's jobs out of the aggregator XML feed.
+	Slug             string `json:"slug"`             // Slug is the unique URL segment of the company's careers page, generated from its name.
+	LogoURL          string `json:"logoUrl"`          // LogoURL is the URL of the company logo shown on its careers page.
+	BrandColor       string `json:"brandColor"`       // BrandColor is the #rrggbb accent colour of the company's careers page.
+	CompanyProfile          // CompanyProfile holds the optional profile details.
+
+	LogoThumbnails map[string]string `json:"logoThumbnails,omitempty"` // LogoThumbnails maps a thumbnail size to the URL of the uploaded logo at that size.
+	LogoUpdatedAt  *time.Time        `json:"-"`                        // LogoUpdatedAt is when the logo was last uploaded.
+
+	Verified       bool       `json:"verified"`                 // Verified is set once an operator approved proof that the owner controls the company domain.
+	VerifiedDomain string     `json:"verifiedDomain,omitempty"` // VerifiedDomain is the domain the owner proved control of.
+	VerifiedAt     *time.Time `json:"verifiedAt,omitempty"`     // VerifiedAt is when the verification was approved.
+
+	Rating         *CompanyRating `json:"rating,omitempty"` // Rating aggregates the approved reviews of the company, nil without any.
+	RatingRevision int            `json:"-"`                // RatingRevision is incremented whenever Rating is recomputed.
+
+	ModerationStatus string `json:"moderationStatus"` // ModerationStatus is approved once the company is published, see the Moderation statuses.
+
+	Location Location `json:"location"` // Location is the place the address was geocoded to.
 }
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// NewExchangeRates represents exchange rates loaded by an operator, in the shape most rate services publish.
+type NewExchangeRates struct {
+	Base  string             `json:"base" validate:"required,len=3,uppercase"`                               // Base is the ISO 4217 currency the rates are against and is required.
+	Rates map[string]float64 `json:"rates" validate:"required,min=1,dive,keys,len=3,uppercase,endkeys,gt=0"` // Rates are how many units of every currency one unit of Base buys.
+}
+
+// ExchangeRates represents the exchange rates salaries are converted with.
+type ExchangeRates struct {
+	Base      string             `json:"base"`                // Base is the ISO 4217 currency annual salaries are compared in, SALARY_CURRENCY.
+	Rates     map[string]float64 `json:"rates"`               // Rates are how many units of every currency one unit of Base buys.
+	UpdatedAt *time.Time         `json:"updatedAt,omitempty"` // UpdatedAt is when the rates were last loaded.
+}
+
+// ExchangeRateReport represents the outcome of loading exchange rates.
+type ExchangeRateReport struct {
+	Rates    int `json:"rates"`    // Rates is the number of currencies whose rate was loaded.
+	Jobs     int `json:"jobs"`     // Jobs is the number of jobs whose annual salary in the base currency was computed again.
+	Profiles int `json:"profiles"` // Profiles is the number of candidate profiles whose desired salary in the base currency was computed again.
+}
+
+// ConvertedSalary represents the salary of a job in the currency asked for on reads.
+type ConvertedSalary struct {
+	Currency     string  `json:"currency"`     // Currency is the ISO 4217 currency converted to.
+	Salary       float64 `json:"salary"`       // Salary is the salary per pay period of the job, converted.
+	AnnualSalary float64 `json:"annualSalary"` // AnnualSalary is the salary per year, converted.
+}
@@ -0,0 +1,20 @@
+package models
+
+// Location represents where a job or company is, as geocoded from the text entered.
+type Location struct {
+	Text      string   `json:"text,omitempty"`      // Text is the location as entered, empty for a job at the company address.
+	City      string   `json:"city,omitempty"`      // City is the name of the place the text was geocoded to.
+	Region    string   `json:"region,omitempty"`    // Region is the state or province of the place.
+	Country   string   `json:"country,omitempty"`   // Country is the ISO 3166-1 alpha-2 code of the country.
+	Latitude  *float64 `json:"latitude,omitempty"`  // Latitude is in decimal degrees, nil unless a place was found.
+	Longitude *float64 `json:"longitude,omitempty"` // Longitude is in decimal degrees, nil unless a place was found.
+}
+
+// GeoSeedReport represents the outcome of loading the geocoding table.
+type GeoSeedReport struct {
+	Countries  int `json:"countries"`  // Countries is the number of countries loaded.
+	Places     int `json:"places"`     // Places is the number of places loaded.
+	Companies  int `json:"companies"`  // Companies is the number of live companies geocoded again.
+	Jobs       int `json:"jobs"`       // Jobs is the number of live jobs geocoded again.
+	Candidates int `json:"candidates"` // Candidates is the number of candidate profiles geocoded again.
+}
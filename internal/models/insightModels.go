@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// SalaryQuery represents the postings salary insights are computed over. An empty field means any value.
+type SalaryQuery struct {
+	Role        string // Role is the normalized job role, see dedup.NormalizeRole.
+	Country     string // Country is the ISO 3166-1 alpha-2 code of the country the jobs are in.
+	City        string // City is the geocoded city the jobs are in, within Country.
+	CompanySize string // CompanySize is the size band of the hiring companies, one of CompanySizeBands.
+	Months      int    // Months is the number of months of the trend, up to the current one.
+}
+
+// SalaryStats represents the distribution of the annual salaries of a group of postings. The percentiles are left out when the
+// group is too small to keep the salaries of individual companies private.
+type SalaryStats struct {
+	Postings   int      `json:"postings"`             // Postings is the number of job postings in the group.
+	Companies  int      `json:"companies"`            // Companies is the number of companies that posted them.
+	Suppressed bool     `json:"suppressed,omitempty"` // Suppressed is set when there are too few postings or companies to publish the percentiles.
+	P10        *float64 `json:"p10,omitempty"`        // P10 is the 10th percentile of the salaries.
+	P25        *float64 `json:"p25,omitempty"`        // P25 is the 25th percentile of the salaries.
+	Median     *float64 `json:"median,omitempty"`     // Median is the 50th percentile of the salaries.
+	P75        *float64 `json:"p75,omitempty"`        // P75 is the 75th percentile of the salaries.
+	P90        *float64 `json:"p90,omitempty"`        // P90 is the 90th percentile of the salaries.
+}
+
+// SalaryTrendPoint represents the salary distribution of the postings of one month.
+type SalaryTrendPoint struct {
+	Month       string `json:"month"` // Month is the month the jobs were posted in, as YYYY-MM.
+	SalaryStats        // SalaryStats is the distribution of the month.
+}
+
+// SalaryInsights represents the salary statistics of the postings matching a SalaryQuery.
+type SalaryInsights struct {
+	Role        string `json:"role,omitempty"`        // Role is the normalized role the postings are for.
+	Country     string `json:"country,omitempty"`     // Country is the country the jobs are in.
+	City        string `json:"city,omitempty"`        // City is the city the jobs are in.
+	CompanySize string `json:"companySize,omitempty"` // CompanySize is the size band of the hiring companies.
+	SalaryStats        // SalaryStats is the distribution over the last three years.
+
+	Currency        string             `json:"currency"`              // Currency is the base currency the salaries are per year in.
+	Trend           []SalaryTrendPoint `json:"trend"`                 // Trend is the distribution of every month, oldest first.
+	MinPostings     int                `json:"minPostings"`           // MinPostings is the smallest group whose percentiles are published.
+	MinCompanies    int                `json:"minCompanies"`          // MinCompanies is the fewest companies a published group is posted by.
+	MaxCompanyShare int                `json:"maxCompanyShare"`       // MaxCompanyShare is the largest percentage of a published group one company posts.
+	RefreshedAt     *time.Time         `json:"refreshedAt,omitempty"` // RefreshedAt is when the statistics were last computed.
+}
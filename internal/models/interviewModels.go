@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Interview statuses
+const (
+	InterviewScheduled = "scheduled" // InterviewScheduled holds its slot until it is rescheduled or cancelled.
+	InterviewCancelled = "cancelled" // InterviewCancelled was cancelled by the candidate or the recruiter.
+)
+
+// NewInterviewSlot represents a time a recruiter is available to interview candidates for a job.
+type NewInterviewSlot struct {
+	StartsAt time.Time `json:"startsAt" validate:"required"`        // StartsAt is when the slot starts, as RFC 3339, and is required.
+	EndsAt   time.Time `json:"endsAt" validate:"required"`          // EndsAt is when the slot ends, as RFC 3339, and is required.
+	Timezone string    `json:"timezone" validate:"required,max=64"` // Timezone is the IANA time zone the slot is shown in, such as Europe/Berlin, and is required.
+	Location string    `json:"location" validate:"max=500"`         // Location is where the interview happens, such as an address or a video call link.
+}
+
+// NewInterviewSlots represents the slots a recruiter publishes at once.
+type NewInterviewSlots struct {
+	Slots []NewInterviewSlot `json:"slots" validate:"required,min=1,max=100,dive"` // Slots are the slots to publish, from 1 to 100.
+}
+
+// InterviewSlot represents a time a recruiter is available to interview candidates for a job.
+type InterviewSlot struct {
+	ID          int       `json:"id"`          // ID is the identifier of the slot.
+	JobId       int       `json:"jobId"`       // JobId is the job the interviews are for.
+	RecruiterId int       `json:"recruiterId"` // RecruiterId is the recruiter who published the slot.
+	StartsAt    time.Time `json:"startsAt"`    // StartsAt is when the slot starts, in Timezone.
+	EndsAt      time.Time `json:"endsAt"`      // EndsAt is when the slot ends, in Timezone.
+	Timezone    string    `json:"timezone"`    // Timezone is the IANA time zone the slot is shown in.
+	Location    string    `json:"location"`    // Location is where the interview happens.
+	Booked      bool      `json:"booked"`      // Booked is set when an interview is scheduled in the slot.
+	CreatedAt   time.Time `json:"createdAt"`   // CreatedAt is when the slot was published.
+}
+
+// BookInterview represents a candidate booking a slot, or moving an interview to another slot.
+type BookInterview struct {
+	SlotId int `json:"slotId" validate:"required,min=1"` // SlotId is the slot to book and is required.
+}
+
+// CancelInterview represents the cancellation of an interview.
+type CancelInterview struct {
+	Reason string `json:"reason" validate:"max=1000"` // Reason tells the other side why the interview is cancelled.
+}
+
+// Interview represents an interview of a candidate for a job.
+type Interview struct {
+	ID            int       `json:"id"`                     // ID is the identifier of the interview.
+	ApplicationId int       `json:"applicationId"`          // ApplicationId is the application the candidate is interviewed for.
+	JobId         int       `json:"jobId"`                  // JobId is the job applied to.
+	JobRole       string    `json:"jobRole"`                // JobRole is the role of the job.
+	CompanyName   string    `json:"companyName"`            // CompanyName is the name of the hiring company.
+	CandidateId   int       `json:"candidateId"`            // CandidateId is the candidate interviewed.
+	RecruiterId   int       `json:"recruiterId"`            // RecruiterId is the recruiter interviewing.
+	SlotId        *int      `json:"slotId,omitempty"`       // SlotId is the booked slot, nil once a cancelled interview's slot is deleted.
+	StartsAt      time.Time `json:"startsAt"`               // StartsAt is when the interview starts, in Timezone.
+	EndsAt        time.Time `json:"endsAt"`                 // EndsAt is when the interview ends, in Timezone.
+	Timezone      string    `json:"timezone"`               // Timezone is the IANA time zone of the slot.
+	Location      string    `json:"location"`               // Location is where the interview happens.
+	Status        string    `json:"status"`                 // Status is InterviewScheduled or InterviewCancelled.
+	Sequence      int       `json:"sequence"`               // Sequence is the revision of the invitation.
+	CancelReason  string    `json:"cancelReason,omitempty"` // CancelReason is why the interview was cancelled.
+	CreatedAt     time.Time `json:"createdAt"`              // CreatedAt is when the interview was booked.
+	UpdatedAt     time.Time `json:"updatedAt"`              // UpdatedAt is when the interview was last rescheduled or cancelled.
+}
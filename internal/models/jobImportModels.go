@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ImportRowError represents a validation or insert error for a single row of a job import.
+type ImportRowError struct {
+	Row     int    `json:"row"`             // Row is the 1-based data row (CSV) or line (JSONL) number.
+	Field   string `json:"field,omitempty"` // Field is the job field the error relates to, if any.
+	Message string `json:"message"`         // Message describes the problem.
+}
+
+// JobImport represents the progress and outcome of a bulk job import.
+type JobImport struct {
+	ID         int              `json:"id,omitempty"`         // ID identifies an async import for progress polling.
+	CompanyId  int              `json:"companyId"`            // CompanyId is the company the jobs are imported into.
+	Status     string           `json:"status"`               // Status is pending, running, completed or failed.
+	Mode       string           `json:"mode"`                 // Mode is all_or_nothing or best_effort.
+	DryRun     bool             `json:"dryRun"`               // DryRun reports whether rows were only validated.
+	Total      int              `json:"total"`                // Total is the number of rows read so far.
+	Valid      int              `json:"valid"`                // Valid is the number of rows that passed validation.
+	Inserted   int              `json:"inserted"`             // Inserted is the number of jobs created.
+	Failed     int              `json:"failed"`               // Failed is the number of rows rejected.
+	Errors     []ImportRowError `json:"errors"`               // Errors lists row errors, capped to the first 1000.
+	Message    string           `json:"message,omitempty"`    // Message describes a failure of the whole import.
+	CreatedAt  time.Time        `json:"createdAt"`            // CreatedAt is when the import started.
+	FinishedAt *time.Time       `json:"finishedAt,omitempty"` // FinishedAt is when the import ended.
+}
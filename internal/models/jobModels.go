@@ -1,15 +1,55 @@
 package models
 
+import "time"
+
 // NewJob represents the structure for creating a new job. It includes fields for the job role and salary.
 type NewJob struct {
 	JobRole string `json:"jobRole" validate:"required"` // JobRole is the role or title of the job and is required.
-	Salary  int    `json:"salary" validate:"required"`  // Salary is the salary associated with the job and is required.
+	Salary  int    `json:"salary" validate:"gt=0"`      // Salary is the salary associated with the job and must be positive.
+
+	Currency  string `json:"currency" validate:"omitempty,len=3,uppercase"`                 // Currency is the ISO 4217 currency of the salary, defaulting to the base currency.
+	PayPeriod string `json:"payPeriod" validate:"omitempty,oneof=hour day week month year"` // PayPeriod is what the salary is paid per, defaulting to year.
+
+	Location        string   `json:"location" validate:"max=200"`                        // Location is where the job is, such as "Berlin, Germany", defaulting to the company address.
+	Remote          bool     `json:"remote"`                                             // Remote is set for jobs that can be done from elsewhere.
+	RemoteCountries []string `json:"remoteCountries" validate:"max=50,dive,len=2,alpha"` // RemoteCountries are the ISO 3166-1 alpha-2 codes of the countries remote candidates may live in, empty for any.
+	RemoteTimezones []string `json:"remoteTimezones" validate:"max=50"`                  // RemoteTimezones are the IANA time zones remote candidates may work in, empty for any.
 }
 
 // Job represents the structure for a job entity. It includes fields such as ID, job role, salary, and CompanyId.
 type Job struct {
-	ID        int    `json:"id"`        // ID is a unique identifier for the job.
-	JobRole   string `json:"jobRole"`   // JobRole is the role or title of the job.
-	Salary    int    `json:"salary"`    // Salary is the salary associated with the job.
-	CompanyId int    `json:"companyId"` // CompanyId is the identifier of the company associated with the job.
+	ID        int       `json:"id"`        // ID is a unique identifier for the job.
+	JobRole   string    `json:"jobRole"`   // JobRole is the role or title of the job.
+	Salary    int       `json:"salary"`    // Salary is the salary associated with the job, per PayPeriod in Currency.
+	CompanyId int       `json:"companyId"` // CompanyId is the identifier of the company associated with the job.
+	Version   int       `json:"version"`   // Version is incremented on every change and used for optimistic concurrency.
+	CreatedAt time.Time `json:"createdAt"` // CreatedAt is when the job was posted.
+	UpdatedAt time.Time `json:"updatedAt"` // UpdatedAt is when the job last changed, including deletion and restore.
+
+	CompanyVerified  bool   `json:"companyVerified"`  // CompanyVerified is the verified badge of the hiring company.
+	ModerationStatus string `json:"moderationStatus"` // ModerationStatus is approved once the job is published, see the Moderation statuses.
+
+	Skills []string `json:"skills"` // Skills are the slugs of the canonical skills named in the job role.
+
+	Location        Location `json:"location"`             // Location is where the job is, geocoded from its location text or the company address.
+	Remote          bool     `json:"remote"`               // Remote is set for jobs that can be done from elsewhere.
+	RemoteCountries []string `json:"remoteCountries"`      // RemoteCountries restrict a remote job to candidates in these countries, empty for any.
+	RemoteTimezones []string `json:"remoteTimezones"`      // RemoteTimezones restrict a remote job to candidates in these time zones, empty for any.
+	DistanceKm      *float64 `json:"distanceKm,omitempty"` // DistanceKm is the distance from the point of a radius search.
+
+	Currency     string           `json:"currency"`               // Currency is the ISO 4217 currency of the salary.
+	PayPeriod    string           `json:"payPeriod"`              // PayPeriod is what the salary is paid per: hour, day, week, month or year.
+	AnnualSalary *float64         `json:"annualSalary,omitempty"` // AnnualSalary is the salary per year in the base currency, nil without an exchange rate.
+	Converted    *ConvertedSalary `json:"converted,omitempty"`    // Converted is the salary in the currency asked for on reads.
+
+	DuplicateOf    *int `json:"duplicateOf,omitempty"`    // DuplicateOf is the first posting of the role when the job is a near duplicate of it.
+	DuplicateCount int  `json:"duplicateCount,omitempty"` // DuplicateCount is the number of postings a collapsed listing result stands for.
+}
+
+// DuplicateReport represents the outcome of clustering the live jobs into near duplicates.
+type DuplicateReport struct {
+	Jobs       int `json:"jobs"`       // Jobs is the number of live jobs fingerprinted.
+	Clusters   int `json:"clusters"`   // Clusters is the number of groups of two or more near duplicate jobs.
+	Duplicates int `json:"duplicates"` // Duplicates is the number of jobs marked as a duplicate of an earlier one.
+	Updated    int `json:"updated"`    // Updated is the number of jobs whose fingerprint or duplicate changed.
 }
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Moderation statuses of jobs, companies and review queue items
+const (
+	ModerationApproved  = "approved"  // ModerationApproved content is published.
+	ModerationPending   = "pending"   // ModerationPending content was flagged and waits for an operator.
+	ModerationRejected  = "rejected"  // ModerationRejected content was turned down by an operator.
+	ModerationSuspended = "suspended" // ModerationSuspended content belongs to a suspended user.
+)
+
+// Sources of review queue items
+const (
+	ModerationSourceRules    = "rules"        // ModerationSourceRules items were flagged by the rule engine.
+	ModerationSourceReports  = "reports"      // ModerationSourceReports items were flagged by abuse reports.
+	ModerationSourceResubmit = "resubmission" // ModerationSourceResubmit items are rejected content edited by the owner.
+)
+
+// Abuse report statuses
+const (
+	ReportOpen      = "open"      // ReportOpen waits for the reported content to be reviewed.
+	ReportUpheld    = "upheld"    // ReportUpheld led to the content being rejected.
+	ReportDismissed = "dismissed" // ReportDismissed was turned down when the content was approved.
+)
+
+// ModerationFinding is a reason content was flagged.
+type ModerationFinding struct {
+	Rule   string `json:"rule"`   // Rule is the name of the rule that matched, such as "keyword" or "contact_info".
+	Field  string `json:"field"`  // Field is the field the rule matched in.
+	Detail string `json:"detail"` // Detail describes the match.
+}
+
+// ModerationItem represents a job or company waiting in, or decided through, the review queue.
+type ModerationItem struct {
+	ID         int                 `json:"id"`                   // ID is the identifier of the item.
+	EntityType string              `json:"entityType"`           // EntityType is "job" or "company".
+	EntityId   int                 `json:"entityId"`             // EntityId is the flagged job or company.
+	CompanyId  int                 `json:"companyId"`            // CompanyId is the company the content belongs to.
+	UserId     int                 `json:"userId"`               // UserId is the owner of the company.
+	Source     string              `json:"source"`               // Source is one of the ModerationSource values.
+	Findings   []ModerationFinding `json:"findings"`             // Findings are the reasons the content was flagged.
+	Status     string              `json:"status"`               // Status is pending, approved or rejected.
+	Reason     string              `json:"reason"`               // Reason is the operator's explanation of the decision.
+	ReviewedBy *int                `json:"reviewedBy,omitempty"` // ReviewedBy is the operator who decided.
+	ReviewedAt *time.Time          `json:"reviewedAt,omitempty"` // ReviewedAt is when the operator decided.
+	CreatedAt  time.Time           `json:"createdAt"`            // CreatedAt is when the content was flagged.
+}
+
+// NewAbuseReport represents a user reporting a job or company.
+type NewAbuseReport struct {
+	Reason  string `json:"reason" validate:"required,oneof=spam scam offensive misleading other"` // Reason is the kind of abuse.
+	Details string `json:"details" validate:"max=2000"`                                           // Details explain the report.
+}
+
+// AbuseReport represents a user's report of a job or company.
+type AbuseReport struct {
+	ID         int       `json:"id"`         // ID is the identifier of the report.
+	EntityType string    `json:"entityType"` // EntityType is "job" or "company".
+	EntityId   int       `json:"entityId"`   // EntityId is the reported job or company.
+	Reason     string    `json:"reason"`     // Reason is the kind of abuse.
+	Details    string    `json:"details"`    // Details explain the report.
+	Status     string    `json:"status"`     // Status is one of the Report statuses.
+	CreatedAt  time.Time `json:"createdAt"`  // CreatedAt is when the report was made.
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PublicJob represents a live job of a live company as shown on public pages and feeds.
+type PublicJob struct {
+	ID          int       `json:"id"`          // ID is the identifier of the job.
+	JobRole     string    `json:"jobRole"`     // JobRole is the role or title of the job.
+	Salary      int       `json:"salary"`      // Salary is the salary associated with the job.
+	Currency    string    `json:"currency"`    // Currency is the ISO 4217 currency of the salary.
+	PayPeriod   string    `json:"payPeriod"`   // PayPeriod is what the salary is paid per.
+	CompanyId   int       `json:"companyId"`   // CompanyId is the identifier of the hiring company.
+	CompanyName string    `json:"companyName"` // CompanyName is the name of the hiring company.
+	City        string    `json:"city"`        // City is where the job is, geocoded from its location or the company address.
+	Country     string    `json:"country"`     // Country is the ISO 3166-1 alpha-2 code of the country of the job.
+	CreatedAt   time.Time `json:"createdAt"`   // CreatedAt is when the job was posted.
+	UpdatedAt   time.Time `json:"updatedAt"`   // UpdatedAt is when the job last changed.
+
+	CompanyVerified bool `json:"companyVerified"` // CompanyVerified is the verified badge of the hiring company.
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Company review moderation statuses
+const (
+	ReviewPending  = "pending"  // ReviewPending waits for an operator and is not public yet.
+	ReviewApproved = "approved" // ReviewApproved is public and counted in the company rating.
+	ReviewRejected = "rejected" // ReviewRejected was turned down by an operator.
+)
+
+// Employment statuses of company reviewers
+var EmploymentStatuses = []string{"current", "former", "intern", "contractor"}
+
+// SubRatings holds the optional 1 to 5 ratings of aspects of working at a company.
+type SubRatings struct {
+	WorkLife     *int `json:"workLife" validate:"omitempty,min=1,max=5"`     // WorkLife rates the work-life balance.
+	Compensation *int `json:"compensation" validate:"omitempty,min=1,max=5"` // Compensation rates pay and benefits.
+	Culture      *int `json:"culture" validate:"omitempty,min=1,max=5"`      // Culture rates the culture and values.
+	Management   *int `json:"management" validate:"omitempty,min=1,max=5"`   // Management rates senior management.
+	CareerGrowth *int `json:"careerGrowth" validate:"omitempty,min=1,max=5"` // CareerGrowth rates career opportunities.
+}
+
+// NewCompanyReview represents a candidate's review of a company.
+type NewCompanyReview struct {
+	Overall          int    `json:"overall" validate:"required,min=1,max=5"` // Overall is the 1 to 5 overall rating and is required.
+	SubRatings              // SubRatings are the optional aspect ratings.
+	Title            string `json:"title" validate:"max=200"`                                                    // Title is a one line summary.
+	Pros             string `json:"pros" validate:"required,max=5000"`                                           // Pros is what the reviewer liked and is required.
+	Cons             string `json:"cons" validate:"required,max=5000"`                                           // Cons is what the reviewer disliked and is required.
+	EmploymentStatus string `json:"employmentStatus" validate:"required,oneof=current former intern contractor"` // EmploymentStatus is the reviewer's relation to the company, one of EmploymentStatuses.
+	JobTitle         string `json:"jobTitle" validate:"max=200"`                                                 // JobTitle is the reviewer's role at the company.
+}
+
+// CompanyReview represents a review of a company. Reviews are anonymous, the author is not returned.
+type CompanyReview struct {
+	ID               int        `json:"id"`        // ID is the identifier of the review.
+	CompanyId        int        `json:"companyId"` // CompanyId is the reviewed company.
+	UserId           int        `json:"-"`         // UserId is the author.
+	Overall          int        `json:"overall"`   // Overall is the 1 to 5 overall rating.
+	SubRatings                  // SubRatings are the optional aspect ratings.
+	Title            string     `json:"title"`                     // Title is a one line summary.
+	Pros             string     `json:"pros"`                      // Pros is what the reviewer liked.
+	Cons             string     `json:"cons"`                      // Cons is what the reviewer disliked.
+	EmploymentStatus string     `json:"employmentStatus"`          // EmploymentStatus is the reviewer's relation to the company.
+	JobTitle         string     `json:"jobTitle"`                  // JobTitle is the reviewer's role at the company.
+	Status           string     `json:"status"`                    // Status is one of the Review statuses.
+	ModerationNotes  string     `json:"moderationNotes,omitempty"` // ModerationNotes are the operator's notes on the decision.
+	Response         string     `json:"response,omitempty"`        // Response is the company's public answer to the review.
+	RespondedAt      *time.Time `json:"respondedAt,omitempty"`     // RespondedAt is when the company last answered.
+	CreatedAt        time.Time  `json:"createdAt"`                 // CreatedAt is when the review was submitted.
+	UpdatedAt        time.Time  `json:"updatedAt"`                 // UpdatedAt is when the review was last edited.
+}
+
+// ReviewResponse represents a company's answer to a review.
+type ReviewResponse struct {
+	Response string `json:"response" validate:"required,max=5000"` // Response is the public answer.
+}
+
+// CompanyRating aggregates the approved reviews of a company. Averages are nil when no review rated the aspect.
+type CompanyRating struct {
+	ReviewCount  int      `json:"reviewCount"`  // ReviewCount is the number of approved reviews.
+	Overall      *float64 `json:"overall"`      // Overall is the average overall rating.
+	WorkLife     *float64 `json:"workLife"`     // WorkLife is the average work-life balance rating.
+	Compensation *float64 `json:"compensation"` // Compensation is the average compensation rating.
+	Culture      *float64 `json:"culture"`      // Culture is the average culture rating.
+	Management   *float64 `json:"management"`   // Management is the average management rating.
+	CareerGrowth *float64 `json:"careerGrowth"` // CareerGrowth is the average career growth rating.
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Skill represents a canonical skill or category of the skills taxonomy.
+type Skill struct {
+	ID      int           `json:"id"`               // ID is the identifier of the skill.
+	Slug    string        `json:"slug"`             // Slug is the unique key jobs are filtered by, such as "machine-learning".
+	Name    string        `json:"name"`             // Name is the display name of the skill.
+	Parent  string        `json:"parent,omitempty"` // Parent is the slug of the category the skill belongs to.
+	Aliases []*SkillAlias `json:"aliases"`          // Aliases are the curated other names of the skill.
+}
+
+// NewSkillAlias represents another name of a skill added by an operator.
+type NewSkillAlias struct {
+	Alias string `json:"alias" validate:"required,max=100"` // Alias is the name and is required.
+}
+
+// SkillAlias represents another name of a skill, matched in job roles.
+type SkillAlias struct {
+	ID        int       `json:"id"`        // ID is the identifier of the alias.
+	Alias     string    `json:"alias"`     // Alias is the name in lower case words.
+	SkillId   int       `json:"skillId"`   // SkillId is the skill it names.
+	CreatedAt time.Time `json:"createdAt"` // CreatedAt is when the alias was added.
+}
+
+// SkillSeedReport represents the outcome of loading a skills seed file.
+type SkillSeedReport struct {
+	Skills  int `json:"skills"`  // Skills is the number of skills created or updated.
+	Aliases int `json:"aliases"` // Aliases is the number of aliases created or moved to another skill.
+	Jobs    int `json:"jobs"`    // Jobs is the number of live jobs tagged again.
+}
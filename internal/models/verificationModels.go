@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// Company verification statuses
+const (
+	VerificationPending  = "pending"  // VerificationPending waits for the owner to complete the proof.
+	VerificationProven   = "proven"   // VerificationProven has a checked proof and waits for an operator.
+	VerificationApproved = "approved" // VerificationApproved made the company verified.
+	VerificationRejected = "rejected" // VerificationRejected was turned down by an operator.
+	VerificationExpired  = "expired"  // VerificationExpired was replaced by a newer request or not proven in time.
+)
+
+// Company dispute statuses
+const (
+	DisputeOpen      = "open"      // DisputeOpen waits for an operator.
+	DisputeUpheld    = "upheld"    // DisputeUpheld was accepted and the company lost its verified badge.
+	DisputeDismissed = "dismissed" // DisputeDismissed was turned down.
+)
+
+// NewVerification represents a request to verify a company by proving control of its domain.
+type NewVerification struct {
+	Method string `json:"method" validate:"required,oneof=dns email"` // Method is "dns" for a TXT record or "email" for a code sent to the company domain.
+	Domain string `json:"domain" validate:"required_if=Method dns"`   // Domain is the company domain to publish the TXT record on, for the dns method.
+	Email  string `json:"email" validate:"required_if=Method email"`  // Email is an address at the company domain to send the code to, for the email method.
+}
+
+// ConfirmVerification represents the owner completing a verification. The code is only needed for the email method.
+type ConfirmVerification struct {
+	Code string `json:"code"` // Code is the code emailed to the company address.
+}
+
+// Verification represents a company verification request.
+type Verification struct {
+	ID          int        `json:"id"`                    // ID is the identifier of the request.
+	CompanyId   int        `json:"companyId"`             // CompanyId is the company to verify.
+	RequestedBy int        `json:"requestedBy"`           // RequestedBy is the owner who started the request.
+	Method      string     `json:"method"`                // Method is "dns" or "email".
+	Domain      string     `json:"domain"`                // Domain is the domain control is proven for.
+	Email       string     `json:"email,omitempty"`       // Email is the address the code was sent to.
+	RecordName  string     `json:"recordName,omitempty"`  // RecordName is the TXT record to publish, for the dns method.
+	RecordValue string     `json:"recordValue,omitempty"` // RecordValue is the value of the TXT record, for the dns method.
+	ExpiresAt   time.Time  `json:"expiresAt"`             // ExpiresAt is when the proof has to be completed by.
+	Status      string     `json:"status"`                // Status is one of the Verification statuses.
+	ProvenAt    *time.Time `json:"provenAt,omitempty"`    // ProvenAt is when the proof was checked.
+	ReviewedBy  *int       `json:"reviewedBy,omitempty"`  // ReviewedBy is the operator who approved or rejected the request.
+	ReviewedAt  *time.Time `json:"reviewedAt,omitempty"`  // ReviewedAt is when the request was approved or rejected.
+	Notes       string     `json:"notes"`                 // Notes are the operator's notes on the decision.
+	CreatedAt   time.Time  `json:"createdAt"`             // CreatedAt is when the request was started.
+}
+
+// Review represents an operator's decision on a verification request or a dispute.
+type Review struct {
+	Notes string `json:"notes" validate:"max=2000"` // Notes explain the decision to the owner or claimant.
+}
+
+// NewDispute represents a claim that a company name belongs to someone else.
+type NewDispute struct {
+	Reason string `json:"reason" validate:"required,max=2000"` // Reason explains why the claimant should own the name.
+	Domain string `json:"domain" validate:"max=253"`           // Domain is the claimant's own company domain, if any.
+}
+
+// Dispute represents a dispute over a company name.
+type Dispute struct {
+	ID         int        `json:"id"`                   // ID is the identifier of the dispute.
+	CompanyId  int        `json:"companyId"`            // CompanyId is the company holding the disputed name.
+	RaisedBy   int        `json:"raisedBy"`             // RaisedBy is the user who raised the dispute.
+	Reason     string     `json:"reason"`               // Reason is the claimant's explanation.
+	Domain     string     `json:"domain"`               // Domain is the claimant's company domain.
+	Status     string     `json:"status"`               // Status is one of the Dispute statuses.
+	ResolvedBy *int       `json:"resolvedBy,omitempty"` // ResolvedBy is the operator who resolved the dispute.
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"` // ResolvedAt is when the dispute was resolved.
+	Notes      string     `json:"notes"`                // Notes are the operator's notes on the resolution.
+	CreatedAt  time.Time  `json:"createdAt"`            // CreatedAt is when the dispute was raised.
+}
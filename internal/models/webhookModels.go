@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// NewWebhook represents the structure for registering a webhook endpoint for a company.
+type NewWebhook struct {
+	URL        string   `json:"url" validate:"required,url"` // URL is the endpoint that receives events and is required.
+	EventTypes []string `json:"eventTypes"`                  // EventTypes limits the events sent; empty means all events.
+}
+
+// Webhook represents a webhook endpoint registered by a company.
+type Webhook struct {
+	ID         int       `json:"id"`               // ID is a unique identifier for the webhook.
+	CompanyId  int       `json:"companyId"`        // CompanyId is the company whose events are delivered.
+	URL        string    `json:"url"`              // URL is the endpoint that receives events.
+	Secret     string    `json:"secret,omitempty"` // Secret signs deliveries; it is only returned when the webhook is registered.
+	EventTypes []string  `json:"eventTypes"`       // EventTypes limits the events sent; empty means all events.
+	Active     bool      `json:"active"`           // Active reports whether events are delivered.
+	CreatedAt  time.Time `json:"createdAt"`        // CreatedAt is when the webhook was registered.
+}
+
+// WebhookDelivery represents one attempt history of delivering an event to a webhook.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`                       // ID is a unique identifier for the delivery.
+	WebhookID      int             `json:"webhookId"`                // WebhookID is the endpoint the event is delivered to.
+	EventID        int64           `json:"eventId"`                  // EventID is the outbox event being delivered.
+	EventType      string          `json:"eventType"`                // EventType is the type of the event.
+	Payload        json.RawMessage `json:"payload"`                  // Payload is the event data.
+	Status         string          `json:"status"`                   // Status is pending, succeeded or dead.
+	Attempts       int             `json:"attempts"`                 // Attempts is the number of delivery attempts made.
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`            // NextAttemptAt is when the next attempt is due.
+	LastStatusCode *int            `json:"lastStatusCode,omitempty"` // LastStatusCode is the HTTP status of the last attempt.
+	LastError      *string         `json:"lastError,omitempty"`      // LastError describes why the last attempt failed.
+	CreatedAt      time.Time       `json:"createdAt"`                // CreatedAt is when the delivery was created.
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`    // DeliveredAt is when the event was accepted.
+}
+
+// WebhookTestResult represents the outcome of sending a test event to a webhook.
+type WebhookTestResult struct {
+	Delivered  bool   `json:"delivered"`            // Delivered reports whether the endpoint answered with a 2xx status.
+	StatusCode int    `json:"statusCode,omitempty"` // StatusCode is the HTTP status returned by the endpoint.
+	Error      string `json:"error,omitempty"`      // Error describes why the delivery failed.
+}
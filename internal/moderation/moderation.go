@@ -0,0 +1,99 @@
+package moderation
+
+import (
+	"context"
+	"job-portal-api/internal/models"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Field is a text field of the content to check
+type Field struct {
+	Name         string // Name is the field name reported in findings
+	Text         string // Text is the field value
+	CheckContact bool   // CheckContact enables the external contact info rule, for free text such as descriptions
+}
+
+// Content is a job or company to evaluate
+type Content struct {
+	EntityType   string   // EntityType is "job" or "company"
+	EntityID     int      // EntityID is the job or company, 0 before it is created
+	UserID       int      // UserID is the owner of the content
+	Fields       []Field  // Fields are the text fields
+	AnnualSalary *float64 // AnnualSalary is the salary of a job per year in the base currency, nil for companies and without an exchange rate
+}
+
+// Rule checks content and returns why it should be reviewed, if at all
+type Rule interface {
+	Check(ctx context.Context, c Content) ([]models.ModerationFinding, error)
+}
+
+// RuleFunc adapts a function to a Rule
+type RuleFunc func(ctx context.Context, c Content) ([]models.ModerationFinding, error)
+
+// Check calls f
+func (f RuleFunc) Check(ctx context.Context, c Content) ([]models.ModerationFinding, error) {
+	return f(ctx, c)
+}
+
+// Config represents the configuration of the built-in rules
+type Config struct {
+	Keywords  []string // Keywords are words and phrases that flag content, matched case-insensitively as whole words
+	MinSalary int      // MinSalary flags lower annual job salaries, in the base currency
+	MaxSalary int      // MaxSalary flags higher annual job salaries, in the base currency
+}
+
+// defaultKeywords are phrases common in scam and spam postings
+var defaultKeywords = []string{
+	"wire transfer", "western union", "moneygram", "gift card", "bitcoin", "crypto wallet", "upfront fee",
+	"registration fee", "training fee", "processing fee", "pay to apply", "no interview", "guaranteed income",
+	"get rich", "easy money", "work from home and earn", "mystery shopper", "reshipping", "package forwarding",
+}
+
+// DefaultConfig returns the rule configuration. MODERATION_KEYWORDS adds comma separated keywords to the built-in
+// list, MODERATION_SALARY_MIN and MODERATION_SALARY_MAX bound plausible annual salaries in the base currency
+// (defaults 1000 and 5000000).
+func DefaultConfig() Config {
+	cfg := Config{Keywords: append([]string{}, defaultKeywords...), MinSalary: 1000, MaxSalary: 5000000}
+	for _, k := range strings.Split(os.Getenv("MODERATION_KEYWORDS"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			cfg.Keywords = append(cfg.Keywords, k)
+		}
+	}
+	if n, err := strconv.Atoi(os.Getenv("MODERATION_SALARY_MIN")); err == nil && n >= 0 {
+		cfg.MinSalary = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("MODERATION_SALARY_MAX")); err == nil && n > 0 {
+		cfg.MaxSalary = n
+	}
+	return cfg
+}
+
+// Engine evaluates content against a set of rules
+type Engine struct {
+	rules []Rule
+}
+
+// NewEngine returns an engine with the built-in keyword, salary and contact info rules
+func NewEngine(cfg Config) *Engine {
+	return &Engine{rules: []Rule{
+		NewKeywordRule(cfg.Keywords),
+		SalaryRule{Min: cfg.MinSalary, Max: cfg.MaxSalary},
+		ContactInfoRule{},
+	}}
+}
+
+// Evaluate runs the engine's rules and the extra rules, such as ones needing a database transaction, and returns
+// all findings. Content without findings can be published.
+func (e *Engine) Evaluate(ctx context.Context, c Content, extra ...Rule) ([]models.ModerationFinding, error) {
+	findings := []models.ModerationFinding{}
+	for _, rule := range append(append([]Rule{}, e.rules...), extra...) {
+		found, err := rule.Check(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, found...)
+	}
+	return findings, nil
+}
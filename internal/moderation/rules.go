@@ -0,0 +1,157 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"job-portal-api/internal/models"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// wordPattern compiles alternatives matched case-insensitively as whole words, captured in the first group. Words
+// end at any character other than a letter or digit of any script; \b only knows ASCII letters, so it found "café"
+// in "cafés" and never found "über" after a space.
+func wordPattern(alternatives string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}])(` + alternatives + `)(?:$|[^\p{L}\p{N}])`)
+}
+
+// KeywordRule flags fields containing one of a list of words or phrases
+type KeywordRule struct {
+	re *regexp.Regexp
+}
+
+// NewKeywordRule returns a rule matching the keywords case-insensitively as whole words, in any script. Runs of
+// spaces in a phrase match any whitespace.
+func NewKeywordRule(keywords []string) KeywordRule {
+	alternatives := make([]string, 0, len(keywords))
+	for _, k := range keywords {
+		words := strings.Fields(strings.ToLower(k))
+		for i, w := range words {
+			words[i] = regexp.QuoteMeta(w)
+		}
+		if len(words) > 0 {
+			alternatives = append(alternatives, strings.Join(words, `\s+`))
+		}
+	}
+	if len(alternatives) == 0 {
+		return KeywordRule{}
+	}
+	return KeywordRule{re: wordPattern(strings.Join(alternatives, "|"))}
+}
+
+// Check reports every field containing a keyword
+func (r KeywordRule) Check(_ context.Context, c Content) ([]models.ModerationFinding, error) {
+	var findings []models.ModerationFinding
+	if r.re == nil {
+		return nil, nil
+	}
+	for _, f := range c.Fields {
+		if m := r.re.FindStringSubmatch(f.Text); m != nil {
+			findings = append(findings, models.ModerationFinding{Rule: "keyword", Field: f.Name, Detail: fmt.Sprintf("contains %q", m[1])})
+		}
+	}
+	return findings, nil
+}
+
+// SalaryRule flags job salaries outside a plausible range, compared per year in the base currency so that hourly
+// and monthly salaries in any currency are held to the same bounds
+type SalaryRule struct {
+	Min int // Min is the lowest plausible annual salary in the base currency
+	Max int // Max is the highest plausible annual salary in the base currency
+}
+
+// Check reports an annual salary below Min or above Max
+func (r SalaryRule) Check(_ context.Context, c Content) ([]models.ModerationFinding, error) {
+	if c.AnnualSalary == nil {
+		return nil, nil
+	}
+	switch s := *c.AnnualSalary; {
+	case s < float64(r.Min):
+		return []models.ModerationFinding{{Rule: "salary", Field: "salary", Detail: fmt.Sprintf("%.0f a year is below %d", s, r.Min)}}, nil
+	case s > float64(r.Max):
+		return []models.ModerationFinding{{Rule: "salary", Field: "salary", Detail: fmt.Sprintf("%.0f a year is above %d", s, r.Max)}}, nil
+	}
+	return nil, nil
+}
+
+// Patterns of contact details that take candidates off the platform
+var (
+	emailPattern     = regexp.MustCompile(`(?i)[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`)
+	obfuscatedEmail  = regexp.MustCompile(`(?i)[a-z0-9._-]+\s*(?:\[at\]|\(at\)|\sat\s)\s*[a-z0-9-]+\s*(?:\[dot\]|\(dot\)|\sdot\s)\s*[a-z]{2,}`)
+	urlPattern       = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+	messengerPattern = wordPattern(`whatsapp|telegram|signal|wechat|viber|skype|t\.me|wa\.me`)
+	phonePattern     = regexp.MustCompile(`\+?\d[\d\s().-]{6,}\d`)
+)
+
+// minPhoneDigits is the least number of digits taken for a phone number, so that salaries and years are not
+const minPhoneDigits = 8
+
+// ContactInfoRule flags email addresses, phone numbers, links and messenger handles in free text fields
+type ContactInfoRule struct{}
+
+// Check reports the first kind of contact detail found in each field with CheckContact set
+func (ContactInfoRule) Check(_ context.Context, c Content) ([]models.ModerationFinding, error) {
+	var findings []models.ModerationFinding
+	for _, f := range c.Fields {
+		if !f.CheckContact {
+			continue
+		}
+		detail := ""
+		switch {
+		case emailPattern.MatchString(f.Text), obfuscatedEmail.MatchString(f.Text):
+			detail = "contains an email address"
+		case urlPattern.MatchString(f.Text):
+			detail = "contains a link"
+		case messengerPattern.MatchString(f.Text):
+			detail = "refers to a messenger"
+		case hasPhoneNumber(f.Text):
+			detail = "contains a phone number"
+		}
+		if detail != "" {
+			findings = append(findings, models.ModerationFinding{Rule: "contact_info", Field: f.Name, Detail: detail})
+		}
+	}
+	return findings, nil
+}
+
+// hasPhoneNumber reports whether the text has a run of digits and separators with at least minPhoneDigits digits
+func hasPhoneNumber(text string) bool {
+	for _, m := range phonePattern.FindAllString(text, -1) {
+		digits := 0
+		for _, r := range m {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits >= minPhoneDigits {
+			return true
+		}
+	}
+	return false
+}
+
+// DuplicateRule flags long text fields that another user already published word for word, a sign of spam
+// posted from several accounts
+type DuplicateRule struct {
+	MinLength int                                                         // MinLength is the shortest text checked, in characters
+	Exists    func(ctx context.Context, c Content, f Field) (bool, error) // Exists looks the text of the field up
+}
+
+// Check reports every long field whose text already exists
+func (r DuplicateRule) Check(ctx context.Context, c Content) ([]models.ModerationFinding, error) {
+	var findings []models.ModerationFinding
+	for _, f := range c.Fields {
+		if utf8.RuneCountInString(strings.TrimSpace(f.Text)) < r.MinLength {
+			continue
+		}
+		dup, err := r.Exists(ctx, c, f)
+		if err != nil {
+			return nil, err
+		}
+		if dup {
+			findings = append(findings, models.ModerationFinding{Rule: "duplicate", Field: f.Name, Detail: "same text is used by another account"})
+		}
+	}
+	return findings, nil
+}
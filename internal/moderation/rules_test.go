@@ -0,0 +1,89 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestKeywordRule(t *testing.T) {
+	rule := NewKeywordRule([]string{"wire transfer", "café", "über", "Geld", "c++", "bitcoin"})
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"whole word", "Paid in bitcoin only", "bitcoin"},
+		{"start and end of text", "bitcoin", "bitcoin"},
+		{"case-insensitive", "Pay by Wire Transfer.", "Wire Transfer"},
+		{"phrase across whitespace", "wire\n\ttransfer", "wire\n\ttransfer"},
+		{"inside a word", "bitcoins accepted", ""},
+		{"prefix of a word", "antibitcoin", ""},
+		{"accented last letter", "Meet at the café, 5pm", "café"},
+		{"accented last letter inside a word", "cafés nearby", ""},
+		{"accented first letter after a space", "Bezahlung über Western", "über"},
+		{"accented first letter inside a word", "Gegenüber", ""},
+		{"non-ASCII case", "ÜBER uns", "ÜBER"},
+		{"next to a non-ASCII letter", "Geldß", ""},
+		{"next to a digit of another script", "Geld٣", ""},
+		{"next to punctuation", "«Geld»", "Geld"},
+		{"underscore separates words", "send_bitcoin_now", "bitcoin"},
+		{"keyword ending in a symbol", "Senior C++ developer", "C++"},
+		{"keyword ending in a symbol inside a word", "Senior C++x developer", ""},
+		{"no keyword", "Senior Go developer", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := rule.Check(context.Background(), Content{Fields: []Field{{Name: "description", Text: tt.text}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.want == "" && len(findings) != 0:
+				t.Errorf("Check(%q) = %v, want no finding", tt.text, findings)
+			case tt.want != "" && (len(findings) != 1 || findings[0].Detail != fmt.Sprintf("contains %q", tt.want)):
+				t.Errorf("Check(%q) = %v, want one finding containing %q", tt.text, findings, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeywordRuleWithoutKeywords(t *testing.T) {
+	rule := NewKeywordRule([]string{"", "   "})
+	findings, err := rule.Check(context.Background(), Content{Fields: []Field{{Name: "description", Text: "anything"}}})
+	if err != nil || findings != nil {
+		t.Errorf("Check() = %v, %v, want no finding", findings, err)
+	}
+}
+
+func TestContactInfoRule(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"email", "Write to jobs@example.com", "contains an email address"},
+		{"obfuscated email", "jobs [at] example [dot] com", "contains an email address"},
+		{"link", "See https://example.com/apply", "contains a link"},
+		{"messenger", "Contact us on Telegram", "refers to a messenger"},
+		{"messenger after a non-ASCII letter", "Kontakt: überTelegram", ""},
+		{"messenger in a word", "telegraphy and signals", ""},
+		{"messenger next to a non-ASCII letter", "WhatsAppé", ""},
+		{"phone number", "Call +49 30 1234 5678", "contains a phone number"},
+		{"salary is not a phone number", "Salary 60000 to 80000", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := ContactInfoRule{}.Check(context.Background(), Content{Fields: []Field{{Name: "description", Text: tt.text, CheckContact: true}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.want == "" && len(findings) != 0:
+				t.Errorf("Check(%q) = %v, want no finding", tt.text, findings)
+			case tt.want != "" && (len(findings) != 1 || findings[0].Detail != tt.want):
+				t.Errorf("Check(%q) = %v, want %q", tt.text, findings, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// Package money annualizes salaries paid per hour, day, week or month, and converts them between currencies with
+// a table of exchange rates against a base currency.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Pay periods a salary can be quoted per
+const (
+	PeriodHour  = "hour"
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodYear  = "year"
+)
+
+// Periods lists the pay periods, shortest first
+var Periods = []string{PeriodHour, PeriodDay, PeriodWeek, PeriodMonth, PeriodYear}
+
+// periodsPerYear is how many of a pay period a full time year has: 52 weeks of 5 days of 8 hours
+var periodsPerYear = map[string]float64{
+	PeriodHour:  2080,
+	PeriodDay:   260,
+	PeriodWeek:  52,
+	PeriodMonth: 12,
+	PeriodYear:  1,
+}
+
+// PerYear returns how many of the pay period a year has, 0 for an unknown period
+func PerYear(period string) float64 {
+	return periodsPerYear[period]
+}
+
+// ValidPeriod reports whether period is one of Periods
+func ValidPeriod(period string) bool {
+	return periodsPerYear[period] > 0
+}
+
+// ValidCurrency reports whether code has the shape of an ISO 4217 code: three upper case letters
+func ValidCurrency(code string) bool {
+	return len(code) == 3 && strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+}
+
+// Rates are exchange rates: how many units of every currency one unit of Base buys
+type Rates struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// ErrUnknownCurrency is returned when there is no rate for a currency
+var ErrUnknownCurrency = errors.New("no exchange rate for currency")
+
+// ParseRates reads a JSON rates file such as {"base": "EUR", "rates": {"USD": 1.08, "INR": 90.1}}, the shape
+// most exchange rate services publish
+func ParseRates(r io.Reader) (Rates, error) {
+	var rates Rates
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rates); err != nil {
+		return Rates{}, fmt.Errorf("parse exchange rates: %w", err)
+	}
+	if err := rates.Validate(); err != nil {
+		return Rates{}, fmt.Errorf("parse exchange rates: %w", err)
+	}
+	return rates, nil
+}
+
+// Validate checks that the codes are ISO 4217 shaped and the rates positive
+func (r Rates) Validate() error {
+	if !ValidCurrency(r.Base) {
+		return fmt.Errorf("invalid base currency %q", r.Base)
+	}
+	for code, rate := range r.Rates {
+		if !ValidCurrency(code) {
+			return fmt.Errorf("invalid currency %q", code)
+		}
+		if rate <= 0 {
+			return fmt.Errorf("rate of %s must be positive", code)
+		}
+	}
+	return nil
+}
+
+// Rate returns how many units of the currency one unit of the base buys
+func (r Rates) Rate(code string) (float64, error) {
+	if code == r.Base {
+		return 1, nil
+	}
+	rate, ok := r.Rates[code]
+	if !ok {
+		return 0, fmt.Errorf("%w %s", ErrUnknownCurrency, code)
+	}
+	return rate, nil
+}
+
+// Rebase returns the same rates against another base currency, which must have a rate
+func (r Rates) Rebase(base string) (Rates, error) {
+	if base == r.Base {
+		return r, nil
+	}
+	per, err := r.Rate(base)
+	if err != nil {
+		return Rates{}, err
+	}
+	rebased := Rates{Base: base, Rates: map[string]float64{r.Base: 1 / per}}
+	for code, rate := range r.Rates {
+		if code != base {
+			rebased.Rates[code] = rate / per
+		}
+	}
+	return rebased, nil
+}
+
+// Convert converts an amount between two currencies
+func (r Rates) Convert(amount float64, from, to string) (float64, error) {
+	fromRate, err := r.Rate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := r.Rate(to)
+	if err != nil {
+		return 0, err
+	}
+	return amount / fromRate * toRate, nil
+}
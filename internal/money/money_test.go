@@ -0,0 +1,150 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPerYear(t *testing.T) {
+	tests := []struct {
+		period string
+		want   float64
+	}{
+		{PeriodHour, 2080},
+		{PeriodDay, 260},
+		{PeriodWeek, 52},
+		{PeriodMonth, 12},
+		{PeriodYear, 1},
+		{"fortnight", 0},
+		{"Year", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := PerYear(tt.period); got != tt.want {
+			t.Errorf("PerYear(%q) = %v, want %v", tt.period, got, tt.want)
+		}
+		if got := ValidPeriod(tt.period); got != (tt.want > 0) {
+			t.Errorf("ValidPeriod(%q) = %v, want %v", tt.period, got, tt.want > 0)
+		}
+	}
+	for _, p := range Periods {
+		if !ValidPeriod(p) {
+			t.Errorf("ValidPeriod(%q) = false for a listed period", p)
+		}
+	}
+}
+
+func TestValidCurrency(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"USD", true},
+		{"EUR", true},
+		{"usd", false},
+		{"US", false},
+		{"USDT", false},
+		{"U5D", false},
+		{"ÉUR", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidCurrency(tt.code); got != tt.want {
+			t.Errorf("ValidCurrency(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestParseRates(t *testing.T) {
+	rates, err := ParseRates(strings.NewReader(`{"base": "EUR", "rates": {"USD": 1.08, "INR": 90.1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates.Base != "EUR" || rates.Rates["USD"] != 1.08 || rates.Rates["INR"] != 90.1 {
+		t.Errorf("ParseRates() = %+v", rates)
+	}
+
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"malformed", `{"base": "EUR"`, "parse exchange rates"},
+		{"unknown field", `{"base": "EUR", "rates": {}, "date": "2024-01-01"}`, "unknown field"},
+		{"invalid base", `{"base": "eur", "rates": {}}`, `invalid base currency "eur"`},
+		{"invalid currency", `{"base": "EUR", "rates": {"US$": 1.08}}`, `invalid currency "US$"`},
+		{"zero rate", `{"base": "EUR", "rates": {"USD": 0}}`, "rate of USD must be positive"},
+		{"negative rate", `{"base": "EUR", "rates": {"USD": -1}}`, "rate of USD must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseRates(strings.NewReader(tt.json)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseRates() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// testRates are rates against the euro
+var testRates = Rates{Base: "EUR", Rates: map[string]float64{"USD": 1.25, "INR": 90, "JPY": 160}}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		from, to string
+		want     float64
+		err      error
+	}{
+		{100, "EUR", "EUR", 100, nil},
+		{100, "EUR", "USD", 125, nil},
+		{125, "USD", "EUR", 100, nil},
+		{125, "USD", "INR", 9000, nil},
+		{160, "JPY", "USD", 1.25, nil},
+		{0, "USD", "JPY", 0, nil},
+		{100, "USD", "USD", 100, nil},
+		{100, "GBP", "EUR", 0, ErrUnknownCurrency},
+		{100, "EUR", "GBP", 0, ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		got, err := testRates.Convert(tt.amount, tt.from, tt.to)
+		if !errors.Is(err, tt.err) || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Convert(%v, %s, %s) = %v, %v, want %v, %v", tt.amount, tt.from, tt.to, got, err, tt.want, tt.err)
+		}
+	}
+	if _, err := testRates.Rate("GBP"); err == nil || !strings.Contains(err.Error(), "GBP") {
+		t.Errorf("Rate(GBP) error = %v, want it to name the currency", err)
+	}
+}
+
+func TestRebase(t *testing.T) {
+	rebased, err := testRates.Rebase("USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{"EUR": 0.8, "INR": 72, "JPY": 128}
+	if rebased.Base != "USD" || len(rebased.Rates) != len(want) {
+		t.Fatalf("Rebase(USD) = %+v, want %v against USD", rebased, want)
+	}
+	for code, rate := range want {
+		if math.Abs(rebased.Rates[code]-rate) > 1e-9 {
+			t.Errorf("Rebase(USD) rate of %s = %v, want %v", code, rebased.Rates[code], rate)
+		}
+	}
+	// Converting gives the same amounts whatever the base
+	for _, pair := range [][2]string{{"INR", "JPY"}, {"EUR", "INR"}, {"JPY", "USD"}} {
+		a, _ := testRates.Convert(1000, pair[0], pair[1])
+		b, _ := rebased.Convert(1000, pair[0], pair[1])
+		if math.Abs(a-b) > 1e-9 {
+			t.Errorf("Convert(1000, %s, %s) = %v against EUR and %v against USD", pair[0], pair[1], a, b)
+		}
+	}
+
+	if same, err := testRates.Rebase("EUR"); err != nil || same.Base != "EUR" {
+		t.Errorf("Rebase(EUR) = %+v, %v, want the rates unchanged", same, err)
+	}
+	if _, err := testRates.Rebase("GBP"); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("Rebase(GBP) error = %v, want %v", err, ErrUnknownCurrency)
+	}
+}
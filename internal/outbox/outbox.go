@@ -0,0 +1,35 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// Domain event types written to the outbox
+const (
+	JobCreated      = "job.created"
+	JobUpdated      = "job.updated"
+	JobDeleted      = "job.deleted"
+	JobRestored     = "job.restored"
+	CompanyCreated  = "company.created"
+	CompanyUpdated  = "company.updated"
+	CompanyDeleted  = "company.deleted"
+	CompanyRestored = "company.restored"
+)
+
+// EventTypes lists every event type that can be subscribed to
+var EventTypes = []string{JobCreated, JobUpdated, JobDeleted, JobRestored, CompanyCreated, CompanyUpdated, CompanyDeleted, CompanyRestored}
+
+// Publish writes a domain event to the outbox. It must be called with the transaction performing the
+// change so that the event is only published if the change commits; a dispatcher delivers it later.
+func Publish(ctx context.Context, tx *sql.Tx, eventType string, companyID int, data json.RawMessage) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO outbox_events (event_type, company_id, payload)
+		VALUES ($1, $2, $3)`, eventType, companyID, []byte(data))
+	if err != nil {
+		return fmt.Errorf("publish %s: %w", eventType, err)
+	}
+	return nil
+}
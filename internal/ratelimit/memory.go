@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps token buckets in process memory. It is suitable for a single instance deployment.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// sweepInterval is how often idle buckets are removed from memory.
+const sweepInterval = time.Minute
+
+// NewMemoryStore creates a new in-memory token bucket store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Take consumes a token from the bucket identified by key.
+func (s *MemoryStore) Take(_ context.Context, key string, p Policy) (Result, error) {
+	if err := p.validate(); err != nil {
+		return Result{}, err
+	}
+	key = p.Name + ":" + key
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		// A new bucket starts full
+		b = &bucket{tokens: float64(p.Burst), updated: now, idle: p.idle()}
+		s.buckets[key] = b
+	}
+	return b.take(now, p), nil
+}
+
+// sweep removes buckets that have been idle long enough to be full again, so memory does not grow unbounded.
+// Every bucket is compared with the refill time of its own policy, not that of the caller.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for k, b := range s.buckets {
+		if now.Sub(b.updated) > max(b.idle, sweepInterval) {
+			delete(s.buckets, k)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PostgresStore keeps token buckets in the rate_limits table so that limits are shared between instances.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a new Postgres-backed token bucket store.
+func NewPostgresStore(db *sql.DB) (*PostgresStore, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// Take consumes a token from the bucket identified by key. The bucket row is locked for the
+// duration of the transaction so concurrent instances cannot both spend the same token.
+func (s *PostgresStore) Take(ctx context.Context, key string, p Policy) (Result, error) {
+	if err := p.validate(); err != nil {
+		return Result{}, err
+	}
+	key = p.Name + ":" + key
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit take: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Make sure the bucket exists, starting full, then lock it
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limits (key, tokens, updated_at)
+		VALUES ($1, $2, now()) ON CONFLICT (key) DO NOTHING`, key, p.Burst)
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit take: %w", err)
+	}
+
+	var b bucket
+	err = tx.QueryRowContext(ctx, `
+		SELECT tokens, updated_at FROM rate_limits WHERE key = $1 FOR UPDATE`, key).Scan(&b.tokens, &b.updated)
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit take: %w", err)
+	}
+
+	// Use the database clock so that instances with skewed clocks agree on refills
+	var now time.Time
+	if err := tx.QueryRowContext(ctx, "SELECT now()").Scan(&now); err != nil {
+		return Result{}, fmt.Errorf("rate limit take: %w", err)
+	}
+	res := b.take(now, p)
+
+	// The bucket can be purged once it is full again
+	_, err = tx.ExecContext(ctx, "UPDATE rate_limits SET tokens = $1, updated_at = $2, idle_until = $3 WHERE key = $4",
+		b.tokens, b.updated, b.updated.Add(p.idle()), key)
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit take: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return Result{}, fmt.Errorf("rate limit take: %w", err)
+	}
+	return res, nil
+}
+
+// RunPurge deletes buckets that are full again every interval until ctx is cancelled. A deleted bucket is
+// recreated full, so purging does not change any limit.
+func (s *PostgresStore) RunPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		res, err := s.db.ExecContext(ctx, "DELETE FROM rate_limits WHERE idle_until < now()")
+		if err != nil {
+			log.Error().Err(err).Send()
+		} else if n, _ := res.RowsAffected(); n > 0 {
+			log.Info().Int64("deleted", n).Msg("purged idle rate limit buckets")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// Policy describes a token bucket: Burst is the bucket capacity and Rate is the number of tokens added per second.
+type Policy struct {
+	Name  string  // Name identifies the policy and is used as a key prefix so routes do not share buckets.
+	Burst int     // Burst is the maximum number of tokens the bucket can hold.
+	Rate  float64 // Rate is the number of tokens refilled per second.
+}
+
+// NewPolicy creates a policy allowing `limit` requests per `window`, with a burst equal to the limit.
+func NewPolicy(name string, limit int, window time.Duration) Policy {
+	return Policy{
+		Name:  name,
+		Burst: limit,
+		Rate:  float64(limit) / window.Seconds(),
+	}
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed    bool          // Allowed reports whether a token was available.
+	Limit      int           // Limit is the bucket capacity.
+	Remaining  int           // Remaining is the number of whole tokens left after this request.
+	ResetAfter time.Duration // ResetAfter is the time until the bucket is full again.
+	RetryAfter time.Duration // RetryAfter is the time until the next token is available, only set when not allowed.
+}
+
+// Store is a backend that keeps token bucket state.
+type Store interface {
+	Take(ctx context.Context, key string, p Policy) (Result, error)
+}
+
+// ErrInvalidPolicy is returned when a policy has no capacity or refill rate.
+var ErrInvalidPolicy = errors.New("rate limit policy must have a positive burst and rate")
+
+// bucket is the persisted state of a single token bucket.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	idle    time.Duration // idle is how long the bucket of its policy takes to refill, after which it can be dropped.
+}
+
+// take refills the bucket up to now, consumes one token if possible and returns the result.
+func (b *bucket) take(now time.Time, p Policy) Result {
+	// Refill the bucket based on the time elapsed since the last update
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(p.Burst), b.tokens+elapsed*p.Rate)
+	}
+	b.updated = now
+
+	res := Result{Limit: p.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - b.tokens) / p.Rate)
+	}
+	res.Remaining = int(math.Floor(b.tokens))
+	res.ResetAfter = secondsToDuration((float64(p.Burst) - b.tokens) / p.Rate)
+	return res
+}
+
+// secondsToDuration converts fractional seconds to a duration.
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// idle returns the time an empty bucket takes to be full again. A bucket idle for that long is indistinguishable
+// from a new one.
+func (p Policy) idle() time.Duration {
+	return secondsToDuration(float64(p.Burst) / p.Rate)
+}
+
+// validate checks that the policy can be used for a bucket.
+func (p Policy) validate() error {
+	if p.Burst <= 0 || p.Rate <= 0 {
+		return ErrInvalidPolicy
+	}
+	return nil
+}
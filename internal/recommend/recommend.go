@@ -0,0 +1,244 @@
+// Package recommend scores how well a job matches a candidate from the candidate's skills, desired salary,
+// location and the jobs they viewed or applied to. Job titles are compared as TF-IDF weighted terms, so rare
+// words such as "kubernetes" count more than common ones such as "engineer".
+package recommend
+
+import (
+	"fmt"
+	"job-portal-api/internal/dedup"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Weights of the parts of a score. Parts that do not apply, such as the salary of a candidate without a desired
+// salary, are left out and the others scaled up.
+const (
+	skillsWeight   = 0.5
+	historyWeight  = 0.2
+	salaryWeight   = 0.2
+	locationWeight = 0.1
+)
+
+// applyBoost is how much more an application counts than a view in the candidate's history
+const applyBoost = 3
+
+// sameCountryScore is the location score of a job in the wanted country but another city
+const sameCountryScore = 0.5
+
+// minHistorySimilarity is the similarity to the history from which it is given as a reason
+const minHistorySimilarity = 0.3
+
+// Interaction is a job the candidate viewed or applied to
+type Interaction struct {
+	Role    string // Role is the title of the job
+	Applied bool   // Applied is set for applications, which count more than views
+}
+
+// Candidate is what is known of a candidate
+type Candidate struct {
+	Skills        []string      // Skills are the candidate's skills
+	DesiredSalary float64       // DesiredSalary is the lowest salary the candidate looks for per year in the base currency, 0 if any
+	City          string        // City is the place the candidate wants to work in, as geocoded, empty for anywhere in the country
+	Country       string        // Country is the ISO 3166-1 alpha-2 code of the country the candidate wants to work in, empty if any
+	History       []Interaction // History are the jobs the candidate viewed or applied to
+}
+
+// Job is what is matched of a job
+type Job struct {
+	Role    string  // Role is the job title
+	Salary  float64 // Salary is the job's salary per year in the base currency, 0 if unknown
+	City    string  // City is where the job is, as geocoded from its location or the company address
+	Country string  // Country is the ISO 3166-1 alpha-2 code of the job's country
+}
+
+// Match is the score of a job for a candidate
+type Match struct {
+	Score   float64  // Score is from 0 to 1
+	Reasons []string // Reasons explain the score, most important first
+}
+
+// Index holds the document frequencies of title terms over the jobs being ranked
+type Index struct {
+	df map[string]int
+	n  int
+}
+
+// NewIndex builds an index over the titles of the jobs being ranked
+func NewIndex(roles []string) *Index {
+	ix := &Index{df: map[string]int{}, n: len(roles)}
+	for _, role := range roles {
+		for _, t := range dedup.RoleTerms(role) {
+			ix.df[t]++
+		}
+	}
+	return ix
+}
+
+// NewIndexFromCounts builds an index from the number of titles each term is in, out of n titles. Counting is left
+// to the caller, such as the database, so the titles do not have to be read.
+func NewIndexFromCounts(df map[string]int, n int) *Index {
+	return &Index{df: df, n: n}
+}
+
+// idf returns the inverse document frequency of a term, terms not seen before weigh the most
+func (ix *Index) idf(term string) float64 {
+	return math.Log(1 + float64(ix.n+1)/float64(ix.df[term]+1))
+}
+
+// vector returns the TF-IDF vector of a title, with every term counted once
+func (ix *Index) vector(role string) map[string]float64 {
+	v := map[string]float64{}
+	for _, t := range dedup.RoleTerms(role) {
+		v[t] = ix.idf(t)
+	}
+	return v
+}
+
+// Score matches a job against a candidate
+func (ix *Index) Score(c Candidate, j Job) Match {
+	job := ix.vector(j.Role)
+	var total, weights float64
+	type reason struct {
+		text         string
+		contribution float64
+	}
+	var reasons []reason
+	add := func(weight, score float64, text string) {
+		total += weight * score
+		weights += weight
+		if score > 0 && text != "" {
+			reasons = append(reasons, reason{text, weight * score})
+		}
+	}
+
+	if len(c.Skills) > 0 && len(job) > 0 {
+		score, matched := ix.skillCoverage(c.Skills, job)
+		reason := ""
+		if len(matched) > 0 {
+			reason = "skills match: " + strings.Join(matched, ", ")
+		}
+		add(skillsWeight, score, reason)
+	}
+
+	if len(c.History) > 0 && len(job) > 0 {
+		history := map[string]float64{}
+		applied := false
+		for _, in := range c.History {
+			boost := 1.0
+			if in.Applied {
+				boost = applyBoost
+			}
+			for t, w := range ix.vector(in.Role) {
+				history[t] += w * boost
+			}
+			applied = applied || in.Applied
+		}
+		score := cosine(job, history)
+		reason := ""
+		if score >= minHistorySimilarity {
+			reason = "similar to jobs viewed"
+			if applied {
+				reason = "similar to jobs viewed or applied to"
+			}
+		}
+		add(historyWeight, score, reason)
+	}
+
+	if c.DesiredSalary > 0 && j.Salary > 0 {
+		ratio := j.Salary / c.DesiredSalary
+		score := math.Max(0, math.Min(1, (ratio-0.5)/0.5))
+		reason := "salary meets the desired salary"
+		if ratio < 1 {
+			reason = fmt.Sprintf("salary is %d%% of the desired salary", int(ratio*100))
+		}
+		add(salaryWeight, score, reason)
+	}
+
+	if c.Country != "" {
+		score, reason := locationMatch(c, j)
+		add(locationWeight, score, reason)
+	}
+
+	if weights == 0 {
+		return Match{}
+	}
+	// The parts that added the most to the score first, in the order above among equals
+	sort.SliceStable(reasons, func(a, b int) bool {
+		return reasons[a].contribution > reasons[b].contribution
+	})
+	texts := make([]string, len(reasons))
+	for i, r := range reasons {
+		texts[i] = r.text
+	}
+	return Match{Score: math.Round(total/weights*1000) / 1000, Reasons: texts}
+}
+
+// skillCoverage returns the share of the job title's weight covered by the skills, and the skills found in it.
+// A skill of several words, such as "machine learning", only matches when all its words are in the title.
+func (ix *Index) skillCoverage(skills []string, job map[string]float64) (float64, []string) {
+	covered := map[string]bool{}
+	var matched []string
+	for _, skill := range skills {
+		terms := dedup.RoleTerms(skill)
+		if len(terms) == 0 {
+			continue
+		}
+		all := true
+		for _, t := range terms {
+			if _, ok := job[t]; !ok {
+				all = false
+				break
+			}
+		}
+		if !all {
+			continue
+		}
+		for _, t := range terms {
+			covered[t] = true
+		}
+		matched = append(matched, strings.Join(terms, " "))
+	}
+
+	var sum, hit float64
+	for t, w := range job {
+		sum += w
+		if covered[t] {
+			hit += w
+		}
+	}
+	sort.Strings(matched)
+	return hit / sum, matched
+}
+
+// locationMatch scores the geocoded place of a job against the one the candidate wants: fully in the wanted city,
+// or anywhere in the wanted country for a candidate without a city, and sameCountryScore elsewhere in the country
+func locationMatch(c Candidate, j Job) (float64, string) {
+	if !strings.EqualFold(c.Country, j.Country) {
+		return 0, ""
+	}
+	switch {
+	case c.City == "":
+		return 1, "located in " + j.Country
+	case strings.EqualFold(c.City, j.City):
+		return 1, "located in " + j.City
+	default:
+		return sameCountryScore, "located in " + j.Country
+	}
+}
+
+// cosine returns the cosine similarity of two sparse vectors
+func cosine(a, b map[string]float64) float64 {
+	var dot, na, nb float64
+	for t, w := range a {
+		dot += w * b[t]
+		na += w * w
+	}
+	for _, w := range b {
+		nb += w * w
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
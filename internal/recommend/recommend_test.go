@@ -0,0 +1,185 @@
+package recommend
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// testIndex weighs title terms over jobs where "engineer" is common and "kubernetes" rare
+func testIndex() *Index {
+	return NewIndex([]string{
+		"Software Engineer",
+		"Backend Engineer",
+		"Frontend Engineer",
+		"Data Engineer",
+		"Kubernetes Engineer",
+		"Product Manager",
+	})
+}
+
+func TestIDFWeighsRareTermsMore(t *testing.T) {
+	ix := testIndex()
+	if rare, common := ix.idf("kubernetes"), ix.idf("engineer"); rare <= common {
+		t.Errorf("idf(kubernetes) = %v, want more than idf(engineer) = %v", rare, common)
+	}
+	if unseen, rare := ix.idf("haskell"), ix.idf("kubernetes"); unseen <= rare {
+		t.Errorf("idf(haskell) = %v, want more than idf(kubernetes) = %v", unseen, rare)
+	}
+}
+
+func TestSkillCoverage(t *testing.T) {
+	ix := testIndex()
+	job := ix.vector("Kubernetes Engineer")
+
+	rare, matched := ix.skillCoverage([]string{"Kubernetes"}, job)
+	if !reflect.DeepEqual(matched, []string{"kubernetes"}) {
+		t.Errorf("skillCoverage() matched %v, want [kubernetes]", matched)
+	}
+	common, _ := ix.skillCoverage([]string{"engineer"}, job)
+	if rare <= 0.5 || rare+common < 0.999 || rare+common > 1.001 {
+		t.Errorf("skillCoverage() = %v for the rare term and %v for the common one, want the rare one to cover most", rare, common)
+	}
+
+	tests := []struct {
+		name    string
+		skills  []string
+		want    float64
+		matched []string
+	}{
+		{"all terms", []string{"kubernetes", "engineer"}, 1, []string{"engineer", "kubernetes"}},
+		{"no terms", []string{"python"}, 0, nil},
+		{"skill of several words needs all of them", []string{"kubernetes operator"}, 0, nil},
+		{"empty skill", []string{"", "  "}, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matched := ix.skillCoverage(tt.skills, job)
+			if math.Abs(got-tt.want) > 1e-9 || !reflect.DeepEqual(matched, tt.matched) {
+				t.Errorf("skillCoverage(%q) = %v, %v, want %v, %v", tt.skills, got, matched, tt.want, tt.matched)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	ix := testIndex()
+	tests := []struct {
+		name      string
+		candidate Candidate
+		job       Job
+		want      float64
+		reasons   []string
+	}{
+		{
+			name:      "nothing known",
+			candidate: Candidate{},
+			job:       Job{Role: "Kubernetes Engineer"},
+			want:      0,
+		},
+		{
+			name:      "every part matches",
+			candidate: Candidate{Skills: []string{"kubernetes", "engineer"}, DesiredSalary: 80000, City: "Berlin", Country: "DE"},
+			job:       Job{Role: "Kubernetes Engineer", Salary: 90000, City: "Berlin", Country: "DE"},
+			want:      1,
+			reasons:   []string{"skills match: engineer, kubernetes", "salary meets the desired salary", "located in Berlin"},
+		},
+		{
+			name:      "parts left out are scaled up",
+			candidate: Candidate{DesiredSalary: 80000},
+			job:       Job{Role: "Kubernetes Engineer", Salary: 60000},
+			want:      0.5,
+			reasons:   []string{"salary is 75% of the desired salary"},
+		},
+		{
+			name:      "salary at half the desired salary scores nothing",
+			candidate: Candidate{DesiredSalary: 80000},
+			job:       Job{Role: "Kubernetes Engineer", Salary: 40000},
+			want:      0,
+		},
+		{
+			name:      "unknown salary is left out",
+			candidate: Candidate{DesiredSalary: 80000, Country: "DE"},
+			job:       Job{Role: "Kubernetes Engineer", Country: "DE"},
+			want:      1,
+			reasons:   []string{"located in DE"},
+		},
+		{
+			name:      "other city of the country",
+			candidate: Candidate{City: "Berlin", Country: "DE"},
+			job:       Job{Role: "Kubernetes Engineer", City: "Munich", Country: "de"},
+			want:      0.5,
+			reasons:   []string{"located in de"},
+		},
+		{
+			name:      "other country",
+			candidate: Candidate{City: "Berlin", Country: "DE"},
+			job:       Job{Role: "Kubernetes Engineer", City: "Berlin", Country: "US"},
+			want:      0,
+		},
+		{
+			name:      "job that was not geocoded",
+			candidate: Candidate{Country: "DE"},
+			job:       Job{Role: "Kubernetes Engineer"},
+			want:      0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := ix.Score(tt.candidate, tt.job)
+			if m.Score != tt.want || len(m.Reasons)+len(tt.reasons) > 0 && !reflect.DeepEqual(m.Reasons, tt.reasons) {
+				t.Errorf("Score() = %v %q, want %v %q", m.Score, m.Reasons, tt.want, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestScoreRareSkillsCountMore(t *testing.T) {
+	ix := testIndex()
+	job := Job{Role: "Kubernetes Engineer"}
+	rare := ix.Score(Candidate{Skills: []string{"kubernetes"}}, job)
+	common := ix.Score(Candidate{Skills: []string{"engineer"}}, job)
+	if rare.Score <= common.Score {
+		t.Errorf("Score() = %v for a rare skill and %v for a common one, want the rare one higher", rare.Score, common.Score)
+	}
+}
+
+func TestScoreHistory(t *testing.T) {
+	ix := testIndex()
+	job := Job{Role: "Kubernetes Engineer"}
+
+	viewed := ix.Score(Candidate{History: []Interaction{{Role: "Kubernetes Engineer"}}}, job)
+	if viewed.Score != 1 || !reflect.DeepEqual(viewed.Reasons, []string{"similar to jobs viewed"}) {
+		t.Errorf("Score() = %v %q, want 1 and similar to jobs viewed", viewed.Score, viewed.Reasons)
+	}
+
+	// An application outweighs a view of another job
+	history := []Interaction{{Role: "Product Manager"}, {Role: "Kubernetes Engineer", Applied: true}}
+	applied := ix.Score(Candidate{History: history}, job)
+	history[1].Applied = false
+	onlyViewed := ix.Score(Candidate{History: history}, job)
+	if applied.Score <= onlyViewed.Score {
+		t.Errorf("Score() = %v after applying, want more than %v after viewing", applied.Score, onlyViewed.Score)
+	}
+	if !reflect.DeepEqual(applied.Reasons, []string{"similar to jobs viewed or applied to"}) {
+		t.Errorf("Score() reasons = %q, want similar to jobs viewed or applied to", applied.Reasons)
+	}
+
+	unrelated := ix.Score(Candidate{History: []Interaction{{Role: "Product Manager"}}}, job)
+	if unrelated.Score != 0 || len(unrelated.Reasons) != 0 {
+		t.Errorf("Score() = %v %q for an unrelated history, want 0 without reasons", unrelated.Score, unrelated.Reasons)
+	}
+}
+
+func TestScoreReasonsMostImportantFirst(t *testing.T) {
+	ix := testIndex()
+	// The skills cover little of the title while the location matches fully, but the location weighs less
+	// than a full salary match, which outweighs both
+	c := Candidate{Skills: []string{"engineer"}, DesiredSalary: 50000, City: "Berlin", Country: "DE"}
+	j := Job{Role: "Kubernetes Engineer", Salary: 50000, City: "Berlin", Country: "DE"}
+	m := ix.Score(c, j)
+	want := []string{"salary meets the desired salary", "skills match: engineer", "located in Berlin"}
+	if !reflect.DeepEqual(m.Reasons, want) {
+		t.Errorf("Score() reasons = %q, want %q", m.Reasons, want)
+	}
+}
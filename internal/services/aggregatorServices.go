@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/feeds"
+	"job-portal-api/internal/models"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// AggregatorConfig represents the configuration of the job-board XML feed
+type AggregatorConfig struct {
+	Path      string        // Path is the file the feed is written to
+	MaxAge    time.Duration // MaxAge is how often the feed is rebuilt even when no job changed
+	Publisher string        // Publisher is the site name reported in the feed
+	BaseURL   string        // BaseURL is the absolute URL job links are built from
+}
+
+// DefaultAggregatorConfig returns the aggregator feed configuration from AGGREGATOR_FEED_PATH,
+// AGGREGATOR_FEED_INTERVAL_MINUTES, AGGREGATOR_PUBLISHER and PUBLIC_BASE_URL
+func DefaultAggregatorConfig() AggregatorConfig {
+	cfg := AggregatorConfig{
+		Path:      os.Getenv("AGGREGATOR_FEED_PATH"),
+		MaxAge:    time.Hour,
+		Publisher: os.Getenv("AGGREGATOR_PUBLISHER"),
+		BaseURL:   strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/"),
+	}
+	if cfg.Path == "" {
+		cfg.Path = filepath.Join(os.TempDir(), "job-portal-aggregator.xml")
+	}
+	if minutes, err := strconv.Atoi(os.Getenv("AGGREGATOR_FEED_INTERVAL_MINUTES")); err == nil && minutes > 0 {
+		cfg.MaxAge = time.Duration(minutes) * time.Minute
+	}
+	if cfg.Publisher == "" {
+		cfg.Publisher = "Job Portal"
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "http://localhost:3030"
+	}
+	return cfg
+}
+
+// AggregatorService builds the XML file of public jobs consumed by job aggregators.
+type AggregatorService struct {
+	js  *JobService
+	cfg AggregatorConfig
+
+	mu          sync.Mutex
+	lastEventID int64
+	generatedAt time.Time
+}
+
+// NewAggregatorService creates a new AggregatorService instance.
+func NewAggregatorService(js *JobService, cfg AggregatorConfig) (*AggregatorService, error) {
+	if js == nil {
+		return nil, errors.New("job service cannot be nil")
+	}
+	if cfg.Path == "" || cfg.MaxAge <= 0 {
+		return nil, errors.New("please provide all the values")
+	}
+	return &AggregatorService{js: js, cfg: cfg}, nil
+}
+
+// Path returns the file the feed is written to.
+func (as *AggregatorService) Path() string {
+	return as.cfg.Path
+}
+
+// Generate rebuilds the feed. It is written to a temporary file and renamed into place,
+// so readers always see a complete feed.
+func (as *AggregatorService) Generate(ctx context.Context) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	// Note the latest event first, so changes made while generating trigger another run
+	lastEventID, err := latestEventID(ctx, as.js.db)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(as.cfg.Path), ".aggregator-*.xml")
+	if err != nil {
+		return fmt.Errorf("generate aggregator feed: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	now := time.Now()
+	feed, err := feeds.NewAggregatorFeed(tmp, as.cfg.Publisher, as.cfg.BaseURL, now)
+	if err != nil {
+		return fmt.Errorf("generate aggregator feed: %w", err)
+	}
+	err = as.js.ExportAggregatorJobs(ctx, func(job *models.PublicJob) error {
+		return feed.Add(feeds.AggregatorJob{
+			Reference:   strconv.Itoa(job.ID),
+			Title:       job.JobRole,
+			Company:     job.CompanyName,
+			City:        job.City,
+			Country:     job.Country,
+			Salary:      strconv.Itoa(job.Salary) + " " + job.Currency + " per " + job.PayPeriod,
+			Description: feeds.JobDescription(job.CompanyName, job.JobRole, job.Salary, job.Currency, job.PayPeriod),
+			URL:         as.cfg.BaseURL + "/public/jobs/" + strconv.Itoa(job.ID),
+			Date:        job.CreatedAt,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("generate aggregator feed: %w", err)
+	}
+	if err := feed.Close(); err != nil {
+		return fmt.Errorf("generate aggregator feed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("generate aggregator feed: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), as.cfg.Path); err != nil {
+		return fmt.Errorf("generate aggregator feed: %w", err)
+	}
+
+	as.lastEventID = lastEventID
+	as.generatedAt = now
+	return nil
+}
+
+// latestEventID returns the ID of the newest job or company event in the outbox. Files built from public jobs
+// are stale once a newer event exists.
+func latestEventID(ctx context.Context, db *sql.DB) (int64, error) {
+	var id int64
+	err := db.QueryRowContext(ctx, "SELECT COALESCE(max(id), 0) FROM outbox_events").Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("query latest event: %w", err)
+	}
+	return id, nil
+}
+
+// stale reports whether a job or company changed since the feed was built, or the feed is older than MaxAge.
+func (as *AggregatorService) stale(ctx context.Context) (bool, error) {
+	latest, err := latestEventID(ctx, as.js.db)
+	if err != nil {
+		return false, err
+	}
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return latest > as.lastEventID || time.Since(as.generatedAt) >= as.cfg.MaxAge, nil
+}
+
+// Run builds the feed right away and then checks every interval whether a job-change event occurred
+// or the feed reached MaxAge, rebuilding it when so, until the context is cancelled.
+func (as *AggregatorService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		stale, err := as.stale(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("could not check aggregator feed")
+		} else if stale {
+			if err := as.Generate(ctx); err != nil {
+				log.Error().Err(err).Msg("could not generate aggregator feed")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/audit"
+	"job-portal-api/internal/images"
+	"job-portal-api/internal/outbox"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ErrLogoBusy is returned when the maximum of logos are already being decoded
+var ErrLogoBusy = errors.New("too many logos being processed, retry later")
+
+// DefaultLogoMaxBytes returns the largest accepted logo upload from COMPANY_LOGO_MAX_KB, defaulting to 2 MB.
+func DefaultLogoMaxBytes() int {
+	kb, err := strconv.Atoi(os.Getenv("COMPANY_LOGO_MAX_KB"))
+	if err != nil || kb <= 0 {
+		kb = 2048
+	}
+	return kb << 10
+}
+
+// DefaultLogoMaxConcurrent returns the most logos decoded at once from COMPANY_LOGO_MAX_CONCURRENT, defaulting to 2.
+// A decoded logo of the largest accepted dimensions takes 64 MB.
+func DefaultLogoMaxConcurrent() int {
+	n, err := strconv.Atoi(os.Getenv("COMPANY_LOGO_MAX_CONCURRENT"))
+	if err != nil || n <= 0 {
+		n = 2
+	}
+	return n
+}
+
+// UploadLogo validates an uploaded logo image of a company owned by the user, stores it resized to
+// images.ThumbnailSizes and points the company logo URL at the largest thumbnail. Only the image header is read
+// before a decoding slot is taken; it fails with ErrLogoBusy without waiting when all slots are taken.
+func (cs *CompanyService) UploadLogo(ctx context.Context, userID, companyID int, data []byte) error {
+	maxBytes := DefaultLogoMaxBytes()
+	if err := images.Check(data, maxBytes); err != nil {
+		return fmt.Errorf("upload logo: %w", err)
+	}
+	thumbs, err := cs.logoThumbnailsPNG(data, maxBytes)
+	if err != nil {
+		return fmt.Errorf("upload logo: %w", err)
+	}
+
+	tx, err := cs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("upload logo: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := rowJSON(ctx, tx, "SELECT row_to_json(c) FROM companies c WHERE userId = $1 AND id = $2 AND deleted_at IS NULL FOR UPDATE", userID, companyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("company not found for user with ID %d and company ID %d", userID, companyID)
+		}
+		return fmt.Errorf("query company existence: %w", err)
+	}
+
+	for size, encoded := range thumbs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO company_logos (company_id, size, data) VALUES ($1, $2, $3)
+			ON CONFLICT (company_id, size) DO UPDATE SET data = EXCLUDED.data`, companyID, size, encoded)
+		if err != nil {
+			return fmt.Errorf("upload logo: %w", err)
+		}
+	}
+
+	largest := images.ThumbnailSizes[len(images.ThumbnailSizes)-1]
+	_, err = tx.ExecContext(ctx, "UPDATE companies SET logoUrl = $1, logoUpdatedAt = now(), version = version + 1 WHERE id = $2",
+		logoPath(companyID, largest), companyID)
+	if err != nil {
+		return fmt.Errorf("upload logo: %w", err)
+	}
+
+	// Record the change in the audit log as part of the same transaction
+	after, err := rowJSON(ctx, tx, "SELECT row_to_json(c) FROM companies c WHERE id = $1", companyID)
+	if err != nil {
+		return fmt.Errorf("upload logo: %w", err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionUpdate, EntityType: audit.EntityCompany, EntityID: companyID, Before: before, After: after,
+	})
+	if err != nil {
+		return fmt.Errorf("upload logo: %w", err)
+	}
+	if err := outbox.Publish(ctx, tx, outbox.CompanyUpdated, companyID, after); err != nil {
+		return fmt.Errorf("upload logo: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("upload logo: %w", err)
+	}
+	return nil
+}
+
+// logoThumbnailsPNG decodes a logo in a decoding slot and encodes it at every thumbnail size. It runs before any
+// lock is taken, it is the slow part.
+func (cs *CompanyService) logoThumbnailsPNG(data []byte, maxBytes int) (map[int][]byte, error) {
+	select {
+	case cs.logoSlots <- struct{}{}:
+	default:
+		return nil, ErrLogoBusy
+	}
+	defer func() { <-cs.logoSlots }()
+
+	img, err := images.Decode(data, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	thumbs := make(map[int][]byte, len(images.ThumbnailSizes))
+	for _, size := range images.ThumbnailSizes {
+		encoded, err := images.EncodePNG(images.Thumbnail(img, size))
+		if err != nil {
+			return nil, err
+		}
+		thumbs[size] = encoded
+	}
+	return thumbs, nil
+}
+
+// GetLogo returns a PNG logo thumbnail of a live company and when it was uploaded.
+func (cs *CompanyService) GetLogo(ctx context.Context, companyID, size int) ([]byte, time.Time, error) {
+	var data []byte
+	var updatedAt time.Time
+	err := cs.db.QueryRowContext(ctx, `
+		SELECT l.data, c.logoUpdatedAt FROM company_logos l JOIN companies c ON c.id = l.company_id
+		WHERE l.company_id = $1 AND l.size = $2 AND c.deleted_at IS NULL AND c.logoUpdatedAt IS NOT NULL`, companyID, size).Scan(&data, &updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, time.Time{}, fmt.Errorf("logo of company %d at size %d not found", companyID, size)
+		}
+		return nil, time.Time{}, fmt.Errorf("get logo: %w", err)
+	}
+	return data, updatedAt, nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"job-portal-api/internal/images"
+	"testing"
+)
+
+// testLogo returns a PNG logo of the size
+func testLogo(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLogoThumbnailsPNG(t *testing.T) {
+	cs := &CompanyService{logoSlots: make(chan struct{}, 1)}
+	thumbs, err := cs.logoThumbnailsPNG(testLogo(t, 300, 150), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thumbs) != len(images.ThumbnailSizes) {
+		t.Errorf("logoThumbnailsPNG() = %d thumbnails, want %d", len(thumbs), len(images.ThumbnailSizes))
+	}
+	for _, size := range images.ThumbnailSizes {
+		cfg, err := png.DecodeConfig(bytes.NewReader(thumbs[size]))
+		if err != nil || cfg.Width != size || cfg.Height != size/2 {
+			t.Errorf("thumbnail %d = %dx%d, %v, want %dx%d", size, cfg.Width, cfg.Height, err, size, size/2)
+		}
+	}
+	if len(cs.logoSlots) != 0 {
+		t.Errorf("logoThumbnailsPNG() kept %d slots, want them released", len(cs.logoSlots))
+	}
+}
+
+func TestUploadLogoBusy(t *testing.T) {
+	cs := &CompanyService{logoSlots: make(chan struct{}, 1)}
+	cs.logoSlots <- struct{}{}
+
+	if err := cs.UploadLogo(context.Background(), 1, 2, testLogo(t, 10, 10)); !errors.Is(err, ErrLogoBusy) {
+		t.Errorf("UploadLogo() with every slot taken error = %v, want %v", err, ErrLogoBusy)
+	}
+	// Images refused from their header do not wait for a slot
+	if err := cs.UploadLogo(context.Background(), 1, 2, testLogo(t, 5000, 1)); !errors.Is(err, images.ErrTooLarge) {
+		t.Errorf("UploadLogo() of an oversized image error = %v, want %v", err, images.ErrTooLarge)
+	}
+	if err := cs.UploadLogo(context.Background(), 1, 2, []byte("not an image")); !errors.Is(err, images.ErrUnsupportedType) {
+		t.Errorf("UploadLogo() of text error = %v, want %v", err, images.ErrUnsupportedType)
+	}
+}
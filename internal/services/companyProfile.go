@@ -0,0 +1,138 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/models"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrCompanyNameTaken is returned when another live company already uses the normalized name
+var ErrCompanyNameTaken = errors.New("company name already taken")
+
+// Limits of the company profile fields
+const (
+	maxDescriptionLength = 5000
+	maxIndustryLength    = 100
+	maxSocialLinks       = 10
+	maxSocialNetworkName = 30
+	minFoundedYear       = 1800
+)
+
+// normalizeCompanyName folds a company name for uniqueness checks: compatibility characters are unified,
+// case is folded and runs of whitespace become single spaces, so "OpenAI  Inc." and "openai inc." collide.
+func normalizeCompanyName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(norm.NFKC.String(name))), " ")
+}
+
+// validateProfile checks the optional profile details of a company.
+func validateProfile(p models.CompanyProfile) error {
+	if utf8.RuneCountInString(p.Description) > maxDescriptionLength {
+		return fmt.Errorf("description cannot be longer than %d characters", maxDescriptionLength)
+	}
+	if p.Website != "" && !validWebURL(p.Website) {
+		return errors.New("website must be an http or https URL")
+	}
+	if utf8.RuneCountInString(p.Industry) > maxIndustryLength {
+		return fmt.Errorf("industry cannot be longer than %d characters", maxIndustryLength)
+	}
+	if p.SizeBand != "" {
+		ok := false
+		for _, b := range models.CompanySizeBands {
+			if p.SizeBand == b {
+				ok = true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("sizeBand must be one of %s", strings.Join(models.CompanySizeBands, ", "))
+		}
+	}
+	if p.FoundedYear != nil && (*p.FoundedYear < minFoundedYear || *p.FoundedYear > time.Now().Year()) {
+		return fmt.Errorf("foundedYear must be between %d and this year", minFoundedYear)
+	}
+	if len(p.SocialLinks) > maxSocialLinks {
+		return fmt.Errorf("at most %d social links are allowed", maxSocialLinks)
+	}
+	for network, link := range p.SocialLinks {
+		if network == "" || len(network) > maxSocialNetworkName {
+			return fmt.Errorf("invalid social network name %q", network)
+		}
+		if !validWebURL(link) {
+			return fmt.Errorf("social link %q must be an http or https URL", network)
+		}
+	}
+	return nil
+}
+
+// validWebURL reports whether s is an absolute http or https URL.
+func validWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && len(s) <= 2048
+}
+
+// prepareCompanyUpdates validates a company update and returns the column values to write. Profile fields are
+// checked like on creation, social links are encoded for the JSONB column and a new name also renormalizes it.
+func prepareCompanyUpdates(updates map[string]interface{}) (map[string]interface{}, error) {
+	if err := checkBrandingFields(updates); err != nil {
+		return nil, err
+	}
+
+	// Decode the profile fields into the model, whose JSON keys match the column names case-insensitively
+	raw, err := json.Marshal(updates)
+	if err != nil {
+		return nil, err
+	}
+	var profile models.CompanyProfile
+	if err := json.Unmarshal(raw, &profile); err != nil {
+		return nil, fmt.Errorf("invalid profile fields: %w", err)
+	}
+	if err := validateProfile(profile); err != nil {
+		return nil, err
+	}
+
+	prepared := make(map[string]interface{}, len(updates)+1)
+	for key, value := range updates {
+		switch strings.ToLower(key) {
+		case "sociallinks":
+			links := profile.SocialLinks
+			if links == nil {
+				links = map[string]string{}
+			}
+			encoded, err := json.Marshal(links)
+			if err != nil {
+				return nil, err
+			}
+			prepared[key] = string(encoded)
+		case "name":
+			name, ok := value.(string)
+			if !ok || strings.TrimSpace(name) == "" {
+				return nil, errors.New("name must be a non-empty string")
+			}
+			prepared[key] = strings.TrimSpace(name)
+			prepared["normalizedName"] = normalizeCompanyName(name)
+		default:
+			prepared[key] = value
+		}
+	}
+	return prepared, nil
+}
+
+// logoThumbnails returns the URLs of the uploaded logo thumbnails of a company.
+func logoThumbnails(companyID int, sizes []int) map[string]string {
+	thumbs := make(map[string]string, len(sizes))
+	for _, size := range sizes {
+		thumbs[fmt.Sprint(size)] = logoPath(companyID, size)
+	}
+	return thumbs
+}
+
+// logoPath returns the public path of a logo thumbnail.
+func logoPath(companyID, size int) string {
+	return fmt.Sprintf("/public/companies/%d/logo/%d.png", companyID, size)
+}
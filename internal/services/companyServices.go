@@ -1,59 +1,234 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"job-portal-api/internal/audit"
+	"job-portal-api/internal/images"
 	"job-portal-api/internal/models"
-	"log"
+	"job-portal-api/internal/outbox"
+	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
 )
 
+// ErrCompanyNotFound is returned when the company does not exist, is deleted or is not owned by the user
+var ErrCompanyNotFound = errors.New("company not found")
+
 // CompanyService handles business logic related to company operations.
 type CompanyService struct {
-	db *sql.DB
+	db         *sql.DB
+	retention  time.Duration
+	moderation *ModerationService
+	cursors    *Cursors
+	logoSlots  chan struct{} // logoSlots holds a token per logo being decoded
 }
 
-// NewCompanyService creates a new CompanyService instance.
-func NewCompanyService(db *sql.DB) (*CompanyService, error) {
+// NewCompanyService creates a new CompanyService instance. Soft deleted companies can be restored
+// for the retention period, after which they are purged. New and edited companies are screened by the
+// moderation service. Exports stream through the shared cursors. At most DefaultLogoMaxConcurrent logos are decoded
+// at once.
+func NewCompanyService(db *sql.DB, retention time.Duration, ms *ModerationService, cursors *Cursors) (*CompanyService, error) {
 	if db == nil {
 		return nil, errors.New("db connection cannot be nil")
 	}
-	return &CompanyService{db: db}, nil
+	if retention <= 0 {
+		return nil, errors.New("retention must be positive")
+	}
+	if ms == nil {
+		return nil, errors.New("moderation service cannot be nil")
+	}
+	if cursors == nil {
+		return nil, errors.New("cursors cannot be nil")
+	}
+	return &CompanyService{
+		db: db, retention: retention, moderation: ms, cursors: cursors, logoSlots: make(chan struct{}, DefaultLogoMaxConcurrent()),
+	}, nil
 }
 
-// CreateCompany creates a new company record in the database.
-func (cs *CompanyService) CreateCompany(userId int, name, address string) (*models.Company, error) {
-	// Convert name and address to lowercase
-	name = strings.ToLower(name)
-	address = strings.ToLower(address)
+// companyColumns are the columns read into models.Company, in the order scanCompany expects. They are selected
+// from companyFrom, the rating columns are NULL for companies without approved reviews.
+const companyColumns = "id, name, normalizedName, address, userId, version, aggregatorOptOut, slug, logoUrl, brandColor, " +
+	"description, website, industry, sizeBand, foundedYear, socialLinks, logoUpdatedAt, verified, verifiedDomain, verifiedAt, " +
+	"review_count, overall_rating, work_life_rating, compensation_rating, culture_rating, management_rating, career_growth_rating, " +
+	"rating_revision, moderationStatus, city, region, country, latitude, longitude"
+
+// companyFrom joins companies with their review aggregates for companyColumns
+const companyFrom = "companies LEFT JOIN company_ratings ON company_ratings.company_id = companies.id"
+
+// companyUpdatableFields are the columns that can be changed through UpdateCompaniesByUserID
+var companyUpdatableFields = []string{
+	"name", "address", "aggregatorOptOut", "logoUrl", "brandColor",
+	"description", "website", "industry", "sizeBand", "foundedYear", "socialLinks",
+}
+
+// companyPublished restricts a query on companyFrom to published companies
+const companyPublished = "companies.moderationStatus = 'approved'"
+
+// allCompaniesQuery lists live companies, shared by the JSON listing and the streamed export
+const allCompaniesQuery = "SELECT " + companyColumns + " FROM " + companyFrom + " WHERE deleted_at IS NULL AND " + companyPublished + " ORDER BY id"
+
+// scanCompany scans a row selected with companyColumns
+func scanCompany(row interface{ Scan(...interface{}) error }) (*models.Company, error) {
+	var company models.Company
+	var foundedYear sql.NullInt64
+	var socialLinks []byte
+	var logoUpdatedAt, verifiedAt sql.NullTime
+	var verifiedDomain sql.NullString
+	var reviewCount, ratingRevision sql.NullInt64
+	var ratings [6]sql.NullFloat64
+	var lat, lng sql.NullFloat64
+	err := row.Scan(&company.ID, &company.Name, &company.NormalizedName, &company.Address, &company.UserId, &company.Version,
+		&company.AggregatorOptOut, &company.Slug, &company.LogoURL, &company.BrandColor,
+		&company.Description, &company.Website, &company.Industry, &company.SizeBand, &foundedYear, &socialLinks, &logoUpdatedAt,
+		&company.Verified, &verifiedDomain, &verifiedAt,
+		&reviewCount, &ratings[0], &ratings[1], &ratings[2], &ratings[3], &ratings[4], &ratings[5], &ratingRevision,
+		&company.ModerationStatus, &company.Location.City, &company.Location.Region, &company.Location.Country, &lat, &lng)
+	if err != nil {
+		return nil, err
+	}
+	company.Location.Latitude, company.Location.Longitude = nullableFloat(lat), nullableFloat(lng)
+	if foundedYear.Valid {
+		year := int(foundedYear.Int64)
+		company.FoundedYear = &year
+	}
+	if err := json.Unmarshal(socialLinks, &company.SocialLinks); err != nil {
+		return nil, fmt.Errorf("decode social links: %w", err)
+	}
+	if logoUpdatedAt.Valid {
+		company.LogoUpdatedAt = &logoUpdatedAt.Time
+		company.LogoThumbnails = logoThumbnails(company.ID, images.ThumbnailSizes)
+	}
+	company.VerifiedDomain = verifiedDomain.String
+	if verifiedAt.Valid {
+		company.VerifiedAt = &verifiedAt.Time
+	}
+	company.RatingRevision = int(ratingRevision.Int64)
+	if reviewCount.Int64 > 0 {
+		company.Rating = &models.CompanyRating{ReviewCount: int(reviewCount.Int64)}
+		dst := []**float64{&company.Rating.Overall, &company.Rating.WorkLife, &company.Rating.Compensation,
+			&company.Rating.Culture, &company.Rating.Management, &company.Rating.CareerGrowth}
+		for i, r := range ratings {
+			if r.Valid {
+				avg := r.Float64
+				*dst[i] = &avg
+			}
+		}
+	}
+	return &company, nil
+}
+
+// DefaultSoftDeleteRetention returns how long soft deleted companies and jobs are kept, from
+// SOFT_DELETE_RETENTION_DAYS, defaulting to 30 days.
+func DefaultSoftDeleteRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("SOFT_DELETE_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// CreateCompany creates a new company record in the database. The name is kept as entered for display
+// and must be unique among live companies once normalized.
+func (cs *CompanyService) CreateCompany(ctx context.Context, userId int, nc models.NewCompany) (*models.Company, error) {
+	if err := validateProfile(nc.CompanyProfile); err != nil {
+		return nil, fmt.Errorf("create company: %w", err)
+	}
 
 	company := models.Company{
-		Name:    name,
-		Address: address,
-		UserId:  userId,
+		Name:           strings.TrimSpace(nc.Name),
+		NormalizedName: normalizeCompanyName(nc.Name),
+		Address:        strings.TrimSpace(nc.Address),
+		UserId:         userId,
+		Version:        1,
+		CompanyProfile: nc.CompanyProfile,
+	}
+	if company.SocialLinks == nil {
+		company.SocialLinks = map[string]string{}
+	}
+	socialLinks, err := json.Marshal(company.SocialLinks)
+	if err != nil {
+		return nil, fmt.Errorf("create company: %w", err)
+	}
+
+	tx, err := cs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create company: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkNotSuspended(ctx, tx, userId); err != nil {
+		return nil, fmt.Errorf("create company: %w", err)
+	}
+
+	// Reject names that only differ in case or spacing from a live company
+	var taken bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM companies WHERE normalizedName = $1 AND deleted_at IS NULL)",
+		company.NormalizedName).Scan(&taken)
+	if err != nil {
+		return nil, fmt.Errorf("create company: %w", err)
+	}
+	if taken {
+		return nil, fmt.Errorf("create company: %w", ErrCompanyNameTaken)
+	}
+
+	// Give the company a unique careers page slug from its name
+	company.Slug, err = uniqueSlug(ctx, tx, company.Name)
+	if err != nil {
+		return nil, fmt.Errorf("create company: %w", err)
 	}
 
 	// Execute the SQL query to insert a new company and retrieve the generated ID
-	row := cs.db.QueryRow(`
-		INSERT INTO companies (name, address, userId)
-		VALUES ($1, $2, $3) RETURNING id`, name, address, userId)
+	row := tx.QueryRowContext(ctx, `
+		INSERT INTO companies (name, normalizedName, address, userId, slug, description, website, industry, sizeBand, foundedYear, socialLinks)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`,
+		company.Name, company.NormalizedName, company.Address, userId, company.Slug,
+		company.Description, company.Website, company.Industry, company.SizeBand, company.FoundedYear, string(socialLinks))
+
+	err = row.Scan(&company.ID)
+	if err != nil {
+		return nil, fmt.Errorf("create company: %w", err)
+	}
+	if company.ModerationStatus, err = cs.moderation.screenCompanyTx(ctx, tx, company.ID); err != nil {
+		return nil, fmt.Errorf("create company: %w", err)
+	}
+	if company.Location, err = locateCompanyTx(ctx, tx, company.ID); err != nil {
+		return nil, fmt.Errorf("create company: %w", err)
+	}
 
-	err := row.Scan(&company.ID)
+	// Record the new company in the audit log as part of the same transaction
+	after, err := rowJSON(ctx, tx, "SELECT row_to_json(c) FROM companies c WHERE id = $1", company.ID)
 	if err != nil {
 		return nil, fmt.Errorf("create company: %w", err)
 	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userId, Action: audit.ActionCreate, EntityType: audit.EntityCompany, EntityID: company.ID, After: after,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create company: %w", err)
+	}
+	if err := outbox.Publish(ctx, tx, outbox.CompanyCreated, company.ID, after); err != nil {
+		return nil, fmt.Errorf("create company: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("create company: %w", err)
+	}
 	return &company, nil
 }
 
 // GetAllCompanies retrieves all companies from the database.
 func (cs *CompanyService) GetAllCompanies() ([]*models.Company, error) {
-	fmt.Println("**************")
 	var companies []*models.Company
 
 	// Execute the SQL query to select all companies
-	rows, err := cs.db.Query("SELECT id, name, address, userid FROM companies")
+	rows, err := cs.db.Query(allCompaniesQuery)
 	if err != nil {
 		return nil, fmt.Errorf("get all companies: %w", err)
 	}
@@ -61,13 +236,11 @@ func (cs *CompanyService) GetAllCompanies() ([]*models.Company, error) {
 
 	// Iterate over the result rows and populate the companies slice
 	for rows.Next() {
-		log.Println("**************")
-		var company models.Company
-		if err := rows.Scan(&company.ID, &company.Name, &company.Address, &company.UserId); err != nil {
+		company, err := scanCompany(rows)
+		if err != nil {
 			return nil, fmt.Errorf("get all companies: %w", err)
 		}
-		log.Println("**************", company)
-		companies = append(companies, &company)
+		companies = append(companies, company)
 	}
 
 	// Check for any errors during iteration
@@ -78,25 +251,96 @@ func (cs *CompanyService) GetAllCompanies() ([]*models.Company, error) {
 	return companies, nil
 }
 
+// companiesByRatingQuery lists live companies with the best rated first. Companies without approved reviews come
+// last, and ties go to the company with more reviews.
+const companiesByRatingQuery = "SELECT " + companyColumns + " FROM " + companyFrom + " WHERE deleted_at IS NULL AND " + companyPublished + " " +
+	"ORDER BY overall_rating DESC NULLS LAST, review_count DESC NULLS LAST, id"
+
+// GetCompaniesByRating retrieves all live companies sorted by their overall rating.
+func (cs *CompanyService) GetCompaniesByRating(ctx context.Context) ([]*models.Company, error) {
+	rows, err := cs.db.QueryContext(ctx, companiesByRatingQuery)
+	if err != nil {
+		return nil, fmt.Errorf("get companies by rating: %w", err)
+	}
+	defer rows.Close()
+
+	companies := []*models.Company{}
+	for rows.Next() {
+		company, err := scanCompany(rows)
+		if err != nil {
+			return nil, fmt.Errorf("get companies by rating: %w", err)
+		}
+		companies = append(companies, company)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get companies by rating: %w", err)
+	}
+	return companies, nil
+}
+
+// ExportAllCompanies streams all companies to fn through a database cursor. It selects the same rows as
+// GetAllCompanies, or as GetCompaniesByRating in the same order when byRating is set.
+func (cs *CompanyService) ExportAllCompanies(ctx context.Context, byRating bool, fn func(*models.Company) error) error {
+	query := allCompaniesQuery
+	if byRating {
+		query = companiesByRatingQuery
+	}
+	err := cs.cursors.stream(ctx, query, nil, func(rows *sql.Rows) error {
+		company, err := scanCompany(rows)
+		if err != nil {
+			return err
+		}
+		return fn(company)
+	})
+	if err != nil {
+		return fmt.Errorf("export all companies: %w", err)
+	}
+	return nil
+}
+
+// checkCompanyPublished fails with ErrCompanyNotFound unless the company is live and published
+func checkCompanyPublished(ctx context.Context, db *sql.DB, id int) error {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM companies WHERE id = $1 AND deleted_at IS NULL AND "+companyPublished+")", id).
+		Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w with ID %d", ErrCompanyNotFound, id)
+	}
+	return nil
+}
+
+// GetCompanyBySlug retrieves a live company by its careers page slug.
+func (cs *CompanyService) GetCompanyBySlug(ctx context.Context, slug string) (*models.Company, error) {
+	company, err := scanCompany(cs.db.QueryRowContext(ctx, "SELECT "+companyColumns+" FROM "+companyFrom+" WHERE slug = $1 AND deleted_at IS NULL AND "+companyPublished, slug))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("company with slug %q not found", slug)
+		}
+		return nil, fmt.Errorf("get company by slug: %w", err)
+	}
+	return company, nil
+}
+
 // GetCompanyByID retrieves a company by its ID from the database.
 func (cs *CompanyService) GetCompanyByID(id int) (*models.Company, error) {
-	var company models.Company
-
 	// Execute the SQL query to select a company by ID
-	err := cs.db.QueryRow("SELECT * FROM companies WHERE id= $1", id).Scan(&company.ID, &company.Name, &company.Address, &company.UserId)
+	company, err := scanCompany(cs.db.QueryRow("SELECT "+companyColumns+" FROM "+companyFrom+" WHERE id= $1 AND deleted_at IS NULL AND "+companyPublished, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("company not found")
 		}
 		return nil, fmt.Errorf("get company by ID: %w", err)
 	}
-	return &company, nil
+	return company, nil
 }
 
 // GetCompaniesByUserID retrieves all companies associated with a user from the database.
 func (cs *CompanyService) GetCompaniesByUserID(userID int) ([]*models.Company, error) {
 	// Execute the SQL query to select companies by user ID
-	rows, err := cs.db.Query("SELECT * FROM companies WHERE userId = $1", userID)
+	rows, err := cs.db.Query("SELECT "+companyColumns+" FROM "+companyFrom+" WHERE userId = $1 AND deleted_at IS NULL", userID)
 	if err != nil {
 		return nil, fmt.Errorf("query companies by user ID: %w", err)
 	}
@@ -106,12 +350,11 @@ func (cs *CompanyService) GetCompaniesByUserID(userID int) ([]*models.Company, e
 
 	// Iterate over the result rows and populate the companies slice
 	for rows.Next() {
-		var company models.Company
-		err := rows.Scan(&company.ID, &company.Name, &company.Address, &company.UserId)
+		company, err := scanCompany(rows)
 		if err != nil {
 			return nil, fmt.Errorf("scan company row: %w", err)
 		}
-		companies = append(companies, &company)
+		companies = append(companies, company)
 	}
 
 	// Check for any errors during iteration
@@ -122,39 +365,301 @@ func (cs *CompanyService) GetCompaniesByUserID(userID int) ([]*models.Company, e
 	return companies, nil
 }
 
-// DeleteCompaniesByUserID deletes a company associated with a user from the database.
-func (cs *CompanyService) DeleteCompaniesByUserID(userID, companyID int) error {
-	// Check if the company exists for the given user
-	var count int
-	err := cs.db.QueryRow("SELECT COUNT(*) FROM companies WHERE userId = $1 AND id = $2", userID, companyID).Scan(&count)
+// DeleteCompaniesByUserID soft deletes a company associated with a user, archiving its jobs with it.
+// The company can be restored until the purge worker removes it after the retention window.
+// A non-zero expectedVersion makes the delete conditional on the company's current version.
+func (cs *CompanyService) DeleteCompaniesByUserID(ctx context.Context, userID, companyID, expectedVersion int) error {
+	tx, err := cs.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("delete company with ID %d: %w", companyID, err)
+	}
+	defer tx.Rollback()
+
+	// Check if the company exists for the given user, locking it and keeping its state for the audit log
+	before, err := rowJSON(ctx, tx, "SELECT row_to_json(c) FROM companies c WHERE userId = $1 AND id = $2 AND deleted_at IS NULL FOR UPDATE", userID, companyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("company not found for user with ID %d and company ID %d", userID, companyID)
+		}
 		return fmt.Errorf("query company existence: %w", err)
 	}
 
-	if count == 0 {
-		return fmt.Errorf("company not found for user with ID %d and company ID %d", userID, companyID)
+	// Mark the company deleted, checking the version in the same statement
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		UPDATE companies SET deleted_at = now(), version = version + 1
+		WHERE id = $1 AND ($2 = 0 OR version = $2) RETURNING deleted_at`, companyID, expectedVersion).Scan(&deletedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("delete company with ID %d: %w", companyID, ErrVersionMismatch)
+		}
+		return fmt.Errorf("delete company with ID %d: %w", companyID, err)
+	}
+
+	after, err := rowJSON(ctx, tx, "SELECT row_to_json(c) FROM companies c WHERE id = $1", companyID)
+	if err != nil {
+		return fmt.Errorf("delete company with ID %d: %w", companyID, err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionDelete, EntityType: audit.EntityCompany, EntityID: companyID, Before: before, After: after,
+	})
+	if err != nil {
+		return fmt.Errorf("delete company with ID %d: %w", companyID, err)
+	}
+	if err := outbox.Publish(ctx, tx, outbox.CompanyDeleted, companyID, before); err != nil {
+		return fmt.Errorf("delete company with ID %d: %w", companyID, err)
 	}
 
-	// Delete the company
-	_, err = cs.db.Exec("DELETE FROM companies WHERE userId = $1 AND id = $2", userID, companyID)
+	// Archive the company's jobs with the same timestamp, so a restore brings back exactly these jobs
+	err = cs.archiveJobs(ctx, tx, userID, companyID, deletedAt)
 	if err != nil {
 		return fmt.Errorf("delete company with ID %d: %w", companyID, err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("delete company with ID %d: %w", companyID, err)
+	}
+	return nil
+}
+
+// archiveJobs soft deletes the live jobs of a company, recording each one in the audit log and outbox.
+func (cs *CompanyService) archiveJobs(ctx context.Context, tx *sql.Tx, userID, companyID int, deletedAt time.Time) error {
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE jobs SET deleted_at = $1, version = version + 1, updated_at = now() WHERE companyId = $2 AND deleted_at IS NULL
+		RETURNING id, row_to_json(jobs)`, deletedAt, companyID)
+	if err != nil {
+		return fmt.Errorf("archive jobs: %w", err)
+	}
+	type archived struct {
+		id  int
+		row json.RawMessage
+	}
+	var jobs []archived
+	for rows.Next() {
+		var a archived
+		var raw []byte
+		if err := rows.Scan(&a.id, &raw); err != nil {
+			rows.Close()
+			return fmt.Errorf("archive jobs: %w", err)
+		}
+		a.row = raw
+		jobs = append(jobs, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("archive jobs: %w", err)
+	}
+
+	for _, j := range jobs {
+		err := audit.Record(ctx, tx, audit.Event{
+			ActorID: userID, Action: audit.ActionDelete, EntityType: audit.EntityJob, EntityID: j.id, After: j.row,
+		})
+		if err != nil {
+			return fmt.Errorf("archive jobs: %w", err)
+		}
+		if err := outbox.Publish(ctx, tx, outbox.JobDeleted, companyID, j.row); err != nil {
+			return fmt.Errorf("archive jobs: %w", err)
+		}
+	}
+	return nil
+}
+
+// RestoreCompanyByUserID restores a soft deleted company of the user, and the jobs archived with it,
+// as long as it was deleted within the retention period.
+func (cs *CompanyService) RestoreCompanyByUserID(ctx context.Context, userID, companyID int) error {
+	tx, err := cs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("restore company with ID %d: %w", companyID, err)
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT deleted_at FROM companies
+		WHERE userId = $1 AND id = $2 AND deleted_at > $3 FOR UPDATE`, userID, companyID, time.Now().Add(-cs.retention)).Scan(&deletedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("no restorable company for user with ID %d and company ID %d", userID, companyID)
+		}
+		return fmt.Errorf("restore company with ID %d: %w", companyID, err)
+	}
+
+	// Restoring fails if another live company has taken the name in the meantime
+	_, err = tx.ExecContext(ctx, "UPDATE companies SET deleted_at = NULL, version = version + 1 WHERE id = $1", companyID)
+	if err != nil {
+		return fmt.Errorf("restore company with ID %d: %w", companyID, err)
+	}
+	after, err := rowJSON(ctx, tx, "SELECT row_to_json(c) FROM companies c WHERE id = $1", companyID)
+	if err != nil {
+		return fmt.Errorf("restore company with ID %d: %w", companyID, err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionRestore, EntityType: audit.EntityCompany, EntityID: companyID, After: after,
+	})
+	if err != nil {
+		return fmt.Errorf("restore company with ID %d: %w", companyID, err)
+	}
+	if err := outbox.Publish(ctx, tx, outbox.CompanyRestored, companyID, after); err != nil {
+		return fmt.Errorf("restore company with ID %d: %w", companyID, err)
+	}
+
+	// Bring back the jobs that were archived together with the company
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE jobs SET deleted_at = NULL, version = version + 1, updated_at = now() WHERE companyId = $1 AND deleted_at = $2
+		RETURNING id, row_to_json(jobs)`, companyID, deletedAt)
+	if err != nil {
+		return fmt.Errorf("restore company with ID %d: %w", companyID, err)
+	}
+	restored := map[int]json.RawMessage{}
+	for rows.Next() {
+		var id int
+		var raw []byte
+		if err := rows.Scan(&id, &raw); err != nil {
+			rows.Close()
+			return fmt.Errorf("restore company with ID %d: %w", companyID, err)
+		}
+		restored[id] = raw
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("restore company with ID %d: %w", companyID, err)
+	}
+	for id, row := range restored {
+		err := audit.Record(ctx, tx, audit.Event{
+			ActorID: userID, Action: audit.ActionRestore, EntityType: audit.EntityJob, EntityID: id, After: row,
+		})
+		if err != nil {
+			return fmt.Errorf("restore company with ID %d: %w", companyID, err)
+		}
+		if err := outbox.Publish(ctx, tx, outbox.JobRestored, companyID, row); err != nil {
+			return fmt.Errorf("restore company with ID %d: %w", companyID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("restore company with ID %d: %w", companyID, err)
+	}
 	return nil
 }
 
+// PurgeDeleted permanently deletes jobs and companies that were soft deleted before the cutoff.
+// Jobs go first so that no foreign key still points at a purged company.
+func (cs *CompanyService) PurgeDeleted(ctx context.Context, cutoff time.Time) (int, error) {
+	tx, err := cs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("purge deleted: %w", err)
+	}
+	defer tx.Rollback()
+
+	purged := 0
+	queries := []struct {
+		entity string
+		query  string
+	}{
+		{audit.EntityJob, "DELETE FROM jobs WHERE deleted_at < $1 RETURNING id, row_to_json(jobs)"},
+		{audit.EntityCompany, `DELETE FROM companies WHERE deleted_at < $1
+			AND NOT EXISTS (SELECT 1 FROM jobs WHERE jobs.companyId = companies.id)
+			RETURNING id, row_to_json(companies)`},
+	}
+	for _, q := range queries {
+		rows, err := tx.QueryContext(ctx, q.query, cutoff)
+		if err != nil {
+			return 0, fmt.Errorf("purge deleted: %w", err)
+		}
+		var events []audit.Event
+		for rows.Next() {
+			ev := audit.Event{ActorID: audit.SystemActor, Action: audit.ActionPurge, EntityType: q.entity}
+			var raw []byte
+			if err := rows.Scan(&ev.EntityID, &raw); err != nil {
+				rows.Close()
+				return 0, fmt.Errorf("purge deleted: %w", err)
+			}
+			ev.Before = raw
+			events = append(events, ev)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, fmt.Errorf("purge deleted: %w", err)
+		}
+		for _, ev := range events {
+			if err := audit.Record(ctx, tx, ev); err != nil {
+				return 0, fmt.Errorf("purge deleted: %w", err)
+			}
+		}
+		purged += len(events)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("purge deleted: %w", err)
+	}
+	return purged, nil
+}
+
+// RunPurge purges soft deleted rows older than the retention period every interval until ctx is cancelled.
+func (cs *CompanyService) RunPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := cs.PurgeDeleted(ctx, time.Now().Add(-cs.retention))
+		if err != nil {
+			log.Error().Err(err).Send()
+		} else if n > 0 {
+			log.Info().Int("purged", n).Msg("purged soft deleted companies and jobs")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // UpdateCompaniesByUserID updates a company associated with a user in the database.
-func (cs *CompanyService) UpdateCompaniesByUserID(userID, companyID int, updates map[string]interface{}) error {
-	// Check if the company exists for the given user
-	var count int
-	err := cs.db.QueryRow("SELECT COUNT(*) FROM companies WHERE userId = $1 AND id = $2", userID, companyID).Scan(&count)
+// A non-zero expectedVersion makes the update conditional on the company's current version.
+func (cs *CompanyService) UpdateCompaniesByUserID(ctx context.Context, userID, companyID, expectedVersion int, updates map[string]interface{}) error {
+	if err := checkUpdatableFields(updates, companyUpdatableFields); err != nil {
+		return fmt.Errorf("patch company with ID %d: %w", companyID, err)
+	}
+	updates, err := prepareCompanyUpdates(updates)
+	if err != nil {
+		return fmt.Errorf("patch company with ID %d: %w", companyID, err)
+	}
+
+	tx, err := cs.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("patch company with ID %d: %w", companyID, err)
+	}
+	defer tx.Rollback()
+
+	if err := checkNotSuspended(ctx, tx, userID); err != nil {
+		return fmt.Errorf("patch company with ID %d: %w", companyID, err)
+	}
+
+	// Check if the company exists for the given user, locking it and keeping its state for the audit log
+	before, err := rowJSON(ctx, tx, "SELECT row_to_json(c) FROM companies c WHERE userId = $1 AND id = $2 AND deleted_at IS NULL FOR UPDATE", userID, companyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("company not found for user with ID %d and company ID %d", userID, companyID)
+		}
 		return fmt.Errorf("query company existence: %w", err)
 	}
 
-	if count == 0 {
-		return fmt.Errorf("company not found for user with ID %d and company ID %d", userID, companyID)
+	// A rename must not collide with another live company, and drops the verified badge since the
+	// verification was approved for the old name
+	renamed := false
+	if normalized, ok := updates["normalizedName"]; ok {
+		var taken bool
+		err = tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM companies WHERE normalizedName = $1 AND id <> $2 AND deleted_at IS NULL),
+			(SELECT normalizedName <> $1 FROM companies WHERE id = $2)`,
+			normalized, companyID).Scan(&taken, &renamed)
+		if err != nil {
+			return fmt.Errorf("patch company with ID %d: %w", companyID, err)
+		}
+		if taken {
+			return fmt.Errorf("patch company with ID %d: %w", companyID, ErrCompanyNameTaken)
+		}
 	}
 
 	// Build the UPDATE query dynamically based on the fields provided in the updates map
@@ -169,18 +674,62 @@ func (cs *CompanyService) UpdateCompaniesByUserID(userID, companyID int, updates
 		i++
 	}
 
-	// Remove the trailing comma and space from the query
-	query = query[:len(query)-2]
+	if renamed {
+		query += "verified = false, verifiedDomain = NULL, verifiedAt = NULL, "
+	}
+
+	// Every update bumps the version used for optimistic concurrency
+	query += "version = version + 1"
 
-	// Add the WHERE conditions to update the specific company
+	// Add the WHERE conditions to update the specific company, at the expected version
 	query += " WHERE userId = $" + strconv.Itoa(i) + " AND id = $" + strconv.Itoa(i+1)
 	values = append(values, userID, companyID)
+	if expectedVersion != 0 {
+		query += " AND version = $" + strconv.Itoa(i+2)
+		values = append(values, expectedVersion)
+	}
 
 	// Execute the dynamic UPDATE query
-	_, err = cs.db.Exec(query, values...)
+	res, err := tx.ExecContext(ctx, query, values...)
+	if err != nil {
+		return fmt.Errorf("patch company with ID %d: %w", companyID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("patch company with ID %d: %w", companyID, err)
+	} else if n == 0 {
+		// The row is locked and exists, so only the version can have failed to match
+		return fmt.Errorf("patch company with ID %d: %w", companyID, ErrVersionMismatch)
+	}
+	if _, err := cs.moderation.screenCompanyTx(ctx, tx, companyID); err != nil {
+		return fmt.Errorf("patch company with ID %d: %w", companyID, err)
+	}
+	// A new address moves the company, and the jobs at its address with it
+	for key := range updates {
+		if strings.EqualFold(key, "address") {
+			if _, err := locateCompanyTx(ctx, tx, companyID); err != nil {
+				return fmt.Errorf("patch company with ID %d: %w", companyID, err)
+			}
+			break
+		}
+	}
+
+	// Record what changed in the audit log as part of the same transaction
+	after, err := rowJSON(ctx, tx, "SELECT row_to_json(c) FROM companies c WHERE id = $1", companyID)
 	if err != nil {
 		return fmt.Errorf("patch company with ID %d: %w", companyID, err)
 	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionUpdate, EntityType: audit.EntityCompany, EntityID: companyID, Before: before, After: after,
+	})
+	if err != nil {
+		return fmt.Errorf("patch company with ID %d: %w", companyID, err)
+	}
+	if err := outbox.Publish(ctx, tx, outbox.CompanyUpdated, companyID, after); err != nil {
+		return fmt.Errorf("patch company with ID %d: %w", companyID, err)
+	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("patch company with ID %d: %w", companyID, err)
+	}
 	return nil
 }
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSlugLength is the longest slug generated from a company name
+const maxSlugLength = 60
+
+// brandColorPattern matches a #rrggbb colour
+var brandColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// slugify turns a company name into a lower case, hyphen separated URL segment.
+func slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	// Decompose accented letters so "Café" becomes "cafe"
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			hyphen = false
+		case !hyphen && b.Len() > 0:
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	if len(slug) > maxSlugLength {
+		slug = strings.Trim(slug[:maxSlugLength], "-")
+	}
+	if slug == "" {
+		slug = "company"
+	}
+	return slug
+}
+
+// uniqueSlug returns the slug of the name, suffixed with a number when another company already uses it.
+func uniqueSlug(ctx context.Context, tx *sql.Tx, name string) (string, error) {
+	base := slugify(name)
+	rows, err := tx.QueryContext(ctx, "SELECT slug FROM companies WHERE slug = $1 OR slug LIKE $2", base, base+"-%")
+	if err != nil {
+		return "", fmt.Errorf("query slugs: %w", err)
+	}
+	defer rows.Close()
+
+	taken := map[string]bool{}
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return "", fmt.Errorf("query slugs: %w", err)
+		}
+		taken[s] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("query slugs: %w", err)
+	}
+
+	slug := base
+	for n := 2; taken[slug]; n++ {
+		slug = base + "-" + strconv.Itoa(n)
+	}
+	return slug, nil
+}
+
+// checkBrandingFields validates the logo URL and brand colour in a company update.
+func checkBrandingFields(updates map[string]interface{}) error {
+	for key, value := range updates {
+		switch strings.ToLower(key) {
+		case "logourl":
+			s, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("logoUrl must be a string")
+			}
+			if s == "" {
+				continue
+			}
+			u, err := url.Parse(s)
+			if err != nil || u.Scheme != "https" || u.Host == "" {
+				return fmt.Errorf("logoUrl must be an https URL")
+			}
+		case "brandcolor":
+			s, ok := value.(string)
+			if !ok || (s != "" && !brandColorPattern.MatchString(s)) {
+				return fmt.Errorf("brandColor must be a #rrggbb colour")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/money"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSalary is returned when a salary is quoted in a malformed currency or per an unknown pay period
+var ErrInvalidSalary = errors.New("currency must be an ISO 4217 code and payPeriod one of hour, day, week, month or year")
+
+// ErrInvalidSalaryAmount is returned when a salary is not a positive whole number
+var ErrInvalidSalaryAmount = errors.New("salary must be a positive whole number")
+
+// CurrencyConfig represents the configuration of salary currencies
+type CurrencyConfig struct {
+	Base string // Base is the ISO 4217 currency annual salaries are filtered, sorted and aggregated in
+}
+
+// DefaultCurrencyConfig returns the salary currency configuration from SALARY_CURRENCY (default USD)
+func DefaultCurrencyConfig() CurrencyConfig {
+	cfg := CurrencyConfig{Base: strings.ToUpper(os.Getenv("SALARY_CURRENCY"))}
+	if cfg.Base == "" {
+		cfg.Base = "USD"
+	}
+	return cfg
+}
+
+// CurrencyService keeps the exchange rates salaries are converted with, and the salaries of jobs per year in the
+// base currency
+type CurrencyService struct {
+	db  *sql.DB
+	cfg CurrencyConfig
+}
+
+// NewCurrencyService creates a new CurrencyService with the provided configuration
+func NewCurrencyService(db *sql.DB, cfg CurrencyConfig) (*CurrencyService, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	if !money.ValidCurrency(cfg.Base) {
+		return nil, errors.New("please provide all the values")
+	}
+	return &CurrencyService{db: db, cfg: cfg}, nil
+}
+
+// Base returns the currency annual salaries are compared in
+func (cs *CurrencyService) Base() string {
+	return cs.cfg.Base
+}
+
+// normalizeSalary checks the currency and pay period of a salary, defaulting them to the base currency and a year
+func (cs *CurrencyService) normalizeSalary(currency, period string) (string, string, error) {
+	currency, period = strings.ToUpper(strings.TrimSpace(currency)), strings.ToLower(strings.TrimSpace(period))
+	if currency == "" {
+		currency = cs.cfg.Base
+	}
+	if period == "" {
+		period = money.PeriodYear
+	}
+	if !money.ValidCurrency(currency) || !money.ValidPeriod(period) {
+		return "", "", ErrInvalidSalary
+	}
+	return currency, period, nil
+}
+
+// annualSalarySQL computes a salary per year in the base currency, in parameter $1, from the columns of the salary
+// per pay period, its currency and the pay period. It is NULL when the currency has no exchange rate.
+func annualSalarySQL(salary, currency, period string) string {
+	perYear := "CASE " + period
+	for _, p := range money.Periods {
+		perYear += fmt.Sprintf(" WHEN '%s' THEN %g", p, money.PerYear(p))
+	}
+	perYear += " END"
+	return salary + " * " + perYear + " / COALESCE(" +
+		"(SELECT r.rate FROM exchange_rates r WHERE r.currency = " + currency + "), CASE WHEN " + currency + " = $1 THEN 1 END)"
+}
+
+// normalizeSalaryTx computes the annual salary in the base currency of a job inside the transaction, or of all
+// live jobs for a jobID of 0. It returns the number of jobs computed.
+func (cs *CurrencyService) normalizeSalaryTx(ctx context.Context, tx *sql.Tx, jobID int) (int, error) {
+	res, err := tx.ExecContext(ctx,
+		"UPDATE jobs SET salaryAnnualBase = "+annualSalarySQL("jobs.salary", "jobs.currency", "jobs.payPeriod")+
+			" WHERE ($2 = 0 AND deleted_at IS NULL) OR id = $2",
+		cs.cfg.Base, jobID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// normalizeDesiredSalaryTx computes the desired salary per year in the base currency of a candidate profile inside
+// the transaction, or of all profiles with a desired salary for a userID of 0. It returns the number of profiles
+// computed.
+func (cs *CurrencyService) normalizeDesiredSalaryTx(ctx context.Context, tx *sql.Tx, userID int) (int, error) {
+	res, err := tx.ExecContext(ctx, "UPDATE candidate_profiles SET desired_salary_annual_base = "+
+		annualSalarySQL("desired_salary", "desired_currency", "desired_pay_period")+
+		" WHERE ($2 = 0 AND desired_salary IS NOT NULL) OR user_id = $2", cs.cfg.Base, userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// rates reads the exchange rates against the base currency, with the time they were last loaded
+func (cs *CurrencyService) rates(ctx context.Context) (money.Rates, *time.Time, error) {
+	rows, err := cs.db.QueryContext(ctx, "SELECT currency, rate, updated_at FROM exchange_rates ORDER BY currency")
+	if err != nil {
+		return money.Rates{}, nil, err
+	}
+	defer rows.Close()
+	rates := money.Rates{Base: cs.cfg.Base, Rates: map[string]float64{}}
+	var updatedAt *time.Time
+	for rows.Next() {
+		var code string
+		var rate float64
+		var at time.Time
+		if err := rows.Scan(&code, &rate, &at); err != nil {
+			return money.Rates{}, nil, err
+		}
+		if code != rates.Base {
+			rates.Rates[code] = rate
+		}
+		if updatedAt == nil || at.After(*updatedAt) {
+			updatedAt = &at
+		}
+	}
+	return rates, updatedAt, rows.Err()
+}
+
+// Rates returns the exchange rates against the base currency
+func (cs *CurrencyService) Rates(ctx context.Context) (*models.ExchangeRates, error) {
+	rates, updatedAt, err := cs.rates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list exchange rates: %w", err)
+	}
+	return &models.ExchangeRates{Base: rates.Base, Rates: rates.Rates, UpdatedAt: updatedAt}, nil
+}
+
+// LoadRates replaces the exchange rates, rebased onto the base currency, then computes the annual salary in the
+// base currency of all live jobs and desired salaries again. Rates against another currency must include the base
+// currency, or loading fails with money.ErrUnknownCurrency.
+func (cs *CurrencyService) LoadRates(ctx context.Context, rates money.Rates) (*models.ExchangeRateReport, error) {
+	if err := rates.Validate(); err != nil {
+		return nil, fmt.Errorf("load exchange rates: %w", err)
+	}
+	rates, err := rates.Rebase(cs.cfg.Base)
+	if err != nil {
+		return nil, fmt.Errorf("load exchange rates: %w", err)
+	}
+
+	tx, err := cs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("load exchange rates: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM exchange_rates"); err != nil {
+		return nil, fmt.Errorf("load exchange rates: %w", err)
+	}
+	report := &models.ExchangeRateReport{}
+	for code, rate := range rates.Rates {
+		if code == rates.Base {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO exchange_rates (currency, rate) VALUES ($1, $2)", code, rate); err != nil {
+			return nil, fmt.Errorf("load exchange rates: %w", err)
+		}
+		report.Rates++
+	}
+	if report.Jobs, err = cs.normalizeSalaryTx(ctx, tx, 0); err != nil {
+		return nil, fmt.Errorf("load exchange rates: %w", err)
+	}
+	if report.Profiles, err = cs.normalizeDesiredSalaryTx(ctx, tx, 0); err != nil {
+		return nil, fmt.Errorf("load exchange rates: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("load exchange rates: %w", err)
+	}
+	return report, nil
+}
+
+// toBase converts a salary range in the currency into the base currency
+func (cs *CurrencyService) toBase(ctx context.Context, currency string, minSalary, maxSalary float64) (float64, float64, error) {
+	if currency == "" || currency == cs.cfg.Base {
+		return minSalary, maxSalary, nil
+	}
+	rates, _, err := cs.rates(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	if minSalary, err = rates.Convert(minSalary, currency, cs.cfg.Base); err != nil {
+		return 0, 0, err
+	}
+	if maxSalary, err = rates.Convert(maxSalary, currency, cs.cfg.Base); err != nil {
+		return 0, 0, err
+	}
+	return minSalary, maxSalary, nil
+}
+
+// ConvertSalaries converts the salaries of the jobs into the currency, failing with money.ErrUnknownCurrency
+// when it has no exchange rate. Jobs in a currency without a rate are left unconverted.
+func (cs *CurrencyService) ConvertSalaries(ctx context.Context, jobs []*models.Job, currency string) error {
+	rates, _, err := cs.rates(ctx)
+	if err != nil {
+		return fmt.Errorf("convert salaries: %w", err)
+	}
+	if _, err := rates.Rate(currency); err != nil {
+		return fmt.Errorf("convert salaries: %w", err)
+	}
+	for _, job := range jobs {
+		salary, err := rates.Convert(float64(job.Salary), job.Currency, currency)
+		if errors.Is(err, money.ErrUnknownCurrency) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("convert salaries: %w", err)
+		}
+		job.Converted = &models.ConvertedSalary{
+			Currency:     currency,
+			Salary:       roundCents(salary),
+			AnnualSalary: roundCents(salary * money.PerYear(job.PayPeriod)),
+		}
+	}
+	return nil
+}
+
+// roundCents rounds an amount of money to two decimals
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
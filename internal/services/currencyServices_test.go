@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/money"
+	"job-portal-api/internal/sqltest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCurrencyService returns a currency service in USD on a sqltest database with rates for EUR and JPY
+func newTestCurrencyService(t *testing.T) (*CurrencyService, *sqltest.DB) {
+	t.Helper()
+	db, fake := sqltest.Open(t)
+	cs, err := NewCurrencyService(db, CurrencyConfig{Base: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	fake.Query("FROM exchange_rates ORDER BY currency", func([]driver.Value) (*sqltest.Rows, error) {
+		return sqltest.NewRows("currency", "rate", "updated_at").Add("EUR", 0.8, at).Add("JPY", 150.0, at), nil
+	})
+	return cs, fake
+}
+
+func TestRoundCents(t *testing.T) {
+	tests := []struct {
+		amount, want float64
+	}{
+		{0, 0},
+		{12.344, 12.34},
+		{12.345, 12.35},
+		{12.3449, 12.34},
+		{0.005, 0.01},
+		{0.004, 0},
+		{-0.125, -0.13},
+		{99999.999, 100000},
+		{1234567.891, 1234567.89},
+	}
+	for _, tt := range tests {
+		if got := roundCents(tt.amount); got != tt.want {
+			t.Errorf("roundCents(%v) = %v, want %v", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeSalary(t *testing.T) {
+	cs := &CurrencyService{cfg: CurrencyConfig{Base: "USD"}}
+	tests := []struct {
+		currency, period         string
+		wantCurrency, wantPeriod string
+		err                      error
+	}{
+		{"", "", "USD", "year", nil},
+		{" eur ", "Month", "EUR", "month", nil},
+		{"JPY", "hour", "JPY", "hour", nil},
+		{"usd", " WEEK ", "USD", "week", nil},
+		{"", "day", "USD", "day", nil},
+		{"EURO", "", "", "", ErrInvalidSalary},
+		{"E1R", "", "", "", ErrInvalidSalary},
+		{"", "fortnight", "", "", ErrInvalidSalary},
+	}
+	for _, tt := range tests {
+		currency, period, err := cs.normalizeSalary(tt.currency, tt.period)
+		if !errors.Is(err, tt.err) || currency != tt.wantCurrency || period != tt.wantPeriod {
+			t.Errorf("normalizeSalary(%q, %q) = %q, %q, %v, want %q, %q, %v",
+				tt.currency, tt.period, currency, period, err, tt.wantCurrency, tt.wantPeriod, tt.err)
+		}
+	}
+}
+
+func TestAnnualSalarySQL(t *testing.T) {
+	got := annualSalarySQL("jobs.salary", "jobs.currency", "jobs.payPeriod")
+	for _, want := range []string{
+		"jobs.salary * CASE jobs.payPeriod WHEN 'hour' THEN 2080 WHEN 'day' THEN 260 WHEN 'week' THEN 52 WHEN 'month' THEN 12 WHEN 'year' THEN 1 END",
+		"/ COALESCE((SELECT r.rate FROM exchange_rates r WHERE r.currency = jobs.currency), CASE WHEN jobs.currency = $1 THEN 1 END)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("annualSalarySQL() = %s, want it to contain %s", got, want)
+		}
+	}
+}
+
+func TestConvertSalaries(t *testing.T) {
+	cs, _ := newTestCurrencyService(t)
+	tests := []struct {
+		name     string
+		job      models.Job
+		currency string
+		want     *models.ConvertedSalary
+	}{
+		{"per hour", models.Job{Salary: 40, Currency: "USD", PayPeriod: money.PeriodHour}, "EUR",
+			&models.ConvertedSalary{Currency: "EUR", Salary: 32, AnnualSalary: 66560}},
+		{"per day", models.Job{Salary: 300, Currency: "EUR", PayPeriod: money.PeriodDay}, "USD",
+			&models.ConvertedSalary{Currency: "USD", Salary: 375, AnnualSalary: 97500}},
+		{"per week", models.Job{Salary: 1000, Currency: "USD", PayPeriod: money.PeriodWeek}, "JPY",
+			&models.ConvertedSalary{Currency: "JPY", Salary: 150000, AnnualSalary: 7800000}},
+		{"per month", models.Job{Salary: 600000, Currency: "JPY", PayPeriod: money.PeriodMonth}, "EUR",
+			&models.ConvertedSalary{Currency: "EUR", Salary: 3200, AnnualSalary: 38400}},
+		{"per year", models.Job{Salary: 85000, Currency: "USD", PayPeriod: money.PeriodYear}, "USD",
+			&models.ConvertedSalary{Currency: "USD", Salary: 85000, AnnualSalary: 85000}},
+		{"rounded to cents", models.Job{Salary: 1000, Currency: "JPY", PayPeriod: money.PeriodHour}, "USD",
+			&models.ConvertedSalary{Currency: "USD", Salary: 6.67, AnnualSalary: 13866.67}},
+		{"missing rate", models.Job{Salary: 50000, Currency: "GBP", PayPeriod: money.PeriodYear}, "USD", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := tt.job
+			if err := cs.ConvertSalaries(context.Background(), []*models.Job{&job}, tt.currency); err != nil {
+				t.Fatal(err)
+			}
+			if (job.Converted == nil) != (tt.want == nil) || job.Converted != nil && *job.Converted != *tt.want {
+				t.Errorf("ConvertSalaries() = %+v, want %+v", job.Converted, tt.want)
+			}
+		})
+	}
+
+	if err := cs.ConvertSalaries(context.Background(), nil, "GBP"); !errors.Is(err, money.ErrUnknownCurrency) {
+		t.Errorf("ConvertSalaries(GBP) error = %v, want %v", err, money.ErrUnknownCurrency)
+	}
+}
+
+func TestToBase(t *testing.T) {
+	cs, _ := newTestCurrencyService(t)
+	tests := []struct {
+		currency  string
+		minSalary float64
+		maxSalary float64
+		wantMin   float64
+		wantMax   float64
+		err       error
+	}{
+		{"", 1000, 2000, 1000, 2000, nil},
+		{"USD", 1000, 2000, 1000, 2000, nil},
+		{"EUR", 800, 1600, 1000, 2000, nil},
+		{"JPY", 150000, 0, 1000, 0, nil},
+		{"GBP", 1000, 2000, 0, 0, money.ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		minSalary, maxSalary, err := cs.toBase(context.Background(), tt.currency, tt.minSalary, tt.maxSalary)
+		if !errors.Is(err, tt.err) || minSalary != tt.wantMin || maxSalary != tt.wantMax {
+			t.Errorf("toBase(%q, %v, %v) = %v, %v, %v, want %v, %v, %v",
+				tt.currency, tt.minSalary, tt.maxSalary, minSalary, maxSalary, err, tt.wantMin, tt.wantMax, tt.err)
+		}
+	}
+}
+
+func TestLoadRates(t *testing.T) {
+	cs, fake := newTestCurrencyService(t)
+	report, err := cs.LoadRates(context.Background(), money.Rates{Base: "EUR", Rates: map[string]float64{"USD": 1.25, "JPY": 200}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rates != 2 || fake.Commits() != 1 {
+		t.Errorf("LoadRates() = %+v with %d commits, want 2 rates committed", report, fake.Commits())
+	}
+	// The rates are stored against the base currency, which is not stored itself
+	want := map[string]float64{"EUR": 0.8, "JPY": 160}
+	inserts := fake.Execs("INSERT INTO exchange_rates")
+	if len(inserts) != len(want) {
+		t.Fatalf("LoadRates() inserted %v, want %v", inserts, want)
+	}
+	for _, e := range inserts {
+		code, rate := e.Args[0].(string), e.Args[1].(float64)
+		if r, ok := want[code]; !ok || rate < r-1e-9 || rate > r+1e-9 {
+			t.Errorf("LoadRates() inserted %s at %v, want %v", code, rate, want)
+		}
+	}
+	for _, fragment := range []string{"UPDATE jobs SET salaryAnnualBase", "UPDATE candidate_profiles SET desired_salary_annual_base"} {
+		if execs := fake.Execs(fragment); len(execs) != 1 || execs[0].Args[0] != "USD" || execs[0].Args[1] != int64(0) {
+			t.Errorf("LoadRates() ran %v, want every row annualised in USD", execs)
+		}
+	}
+
+	// Rates that cannot be rebased onto the base currency are not loaded
+	_, err = cs.LoadRates(context.Background(), money.Rates{Base: "EUR", Rates: map[string]float64{"JPY": 160}})
+	if !errors.Is(err, money.ErrUnknownCurrency) {
+		t.Errorf("LoadRates() without the base currency error = %v, want %v", err, money.ErrUnknownCurrency)
+	}
+	if _, err := cs.LoadRates(context.Background(), money.Rates{Base: "EUR", Rates: map[string]float64{"USD": -1}}); err == nil {
+		t.Error("LoadRates() with a negative rate succeeded, want an error")
+	}
+	if fake.Commits() != 1 {
+		t.Errorf("LoadRates() committed %d times, want only the first load", fake.Commits())
+	}
+}
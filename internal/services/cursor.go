@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// cursorFetchSize is the number of rows fetched from a cursor at a time
+const cursorFetchSize = 1000
+
+// ErrExportBusy is returned when the maximum of exports are already streaming
+var ErrExportBusy = errors.New("too many exports in progress, retry later")
+
+// ExportConfig represents the limits on exports streamed through database cursors
+type ExportConfig struct {
+	MaxConcurrent int           // MaxConcurrent is the most cursors open at once
+	Timeout       time.Duration // Timeout is how long a cursor may stay open, however slowly the client reads
+}
+
+// DefaultExportConfig returns the export limits from EXPORT_MAX_CONCURRENT (default 4) and
+// EXPORT_TIMEOUT_SECONDS (default 300)
+func DefaultExportConfig() ExportConfig {
+	cfg := ExportConfig{MaxConcurrent: 4, Timeout: 5 * time.Minute}
+	if n, err := strconv.Atoi(os.Getenv("EXPORT_MAX_CONCURRENT")); err == nil && n > 0 {
+		cfg.MaxConcurrent = n
+	}
+	if seconds, err := strconv.Atoi(os.Getenv("EXPORT_TIMEOUT_SECONDS")); err == nil && seconds > 0 {
+		cfg.Timeout = time.Duration(seconds) * time.Second
+	}
+	return cfg
+}
+
+// Cursors streams exports through server-side cursors. A cursor holds a connection and a snapshot until the
+// last row is written to the client, so only MaxConcurrent are open at once and each is closed after Timeout.
+type Cursors struct {
+	db      *sql.DB
+	timeout time.Duration
+	slots   chan struct{} // slots holds a token per open cursor
+}
+
+// NewCursors creates a new Cursors instance.
+func NewCursors(db *sql.DB, cfg ExportConfig) (*Cursors, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	if cfg.MaxConcurrent <= 0 || cfg.Timeout <= 0 {
+		return nil, errors.New("please provide all the values")
+	}
+	return &Cursors{db: db, timeout: cfg.Timeout, slots: make(chan struct{}, cfg.MaxConcurrent)}, nil
+}
+
+// stream runs the query through a server-side cursor in a read-only transaction and calls scan
+// for every row, so exports of any size only hold one chunk of rows in memory. It fails with ErrExportBusy
+// without waiting when all cursors are taken.
+func (c *Cursors) stream(ctx context.Context, query string, args []interface{}, scan func(*sql.Rows) error) error {
+	select {
+	case c.slots <- struct{}{}:
+	default:
+		return ErrExportBusy
+	}
+	defer func() { <-c.slots }()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DECLARE export_cursor NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		return fmt.Errorf("declare cursor: %w", err)
+	}
+
+	fetch := "FETCH " + strconv.Itoa(cursorFetchSize) + " FROM export_cursor"
+	for {
+		rows, err := tx.QueryContext(ctx, fetch)
+		if err != nil {
+			return fmt.Errorf("fetch cursor: %w", err)
+		}
+
+		n := 0
+		for rows.Next() {
+			n++
+			if err := scan(rows); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("fetch cursor: %w", err)
+		}
+
+		// A short chunk means the cursor is exhausted
+		if n < cursorFetchSize {
+			return nil
+		}
+	}
+}
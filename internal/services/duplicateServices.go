@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/dedup"
+	"job-portal-api/internal/models"
+	"os"
+	"strconv"
+)
+
+// ErrDuplicateJob is returned when the block policy refuses a job that repeats another job of the same owner
+var ErrDuplicateJob = errors.New("duplicate job")
+
+// Policies for near duplicate jobs
+const (
+	DuplicatePolicyOff   = "off"   // DuplicatePolicyOff does not look for duplicates on create and update.
+	DuplicatePolicyWarn  = "warn"  // DuplicatePolicyWarn saves duplicates and reports what they repeat.
+	DuplicatePolicyBlock = "block" // DuplicatePolicyBlock refuses jobs repeating a job of the same owner, and warns otherwise.
+)
+
+// DuplicateJobError is returned with ErrDuplicateJob, naming the job that was repeated
+type DuplicateJobError struct {
+	DuplicateOf int // DuplicateOf is the first posting of the role
+}
+
+// Error describes the duplicate
+func (e *DuplicateJobError) Error() string {
+	return fmt.Sprintf("duplicate of job %d", e.DuplicateOf)
+}
+
+// Unwrap makes the error match ErrDuplicateJob
+func (e *DuplicateJobError) Unwrap() error {
+	return ErrDuplicateJob
+}
+
+// DuplicateConfig represents the configuration of near duplicate job detection
+type DuplicateConfig struct {
+	Policy      string // Policy is one of the DuplicatePolicy values
+	MaxDistance int    // MaxDistance is the largest fingerprint distance of near duplicates, below dedup.Bands
+}
+
+// DefaultDuplicateConfig returns the near duplicate configuration from DUPLICATE_JOB_POLICY (off, warn or block,
+// default warn) and DUPLICATE_JOB_MAX_DISTANCE (0 to 3, default 3).
+func DefaultDuplicateConfig() DuplicateConfig {
+	cfg := DuplicateConfig{Policy: DuplicatePolicyWarn, MaxDistance: dedup.Bands - 1}
+	switch p := os.Getenv("DUPLICATE_JOB_POLICY"); p {
+	case DuplicatePolicyOff, DuplicatePolicyWarn, DuplicatePolicyBlock:
+		cfg.Policy = p
+	}
+	if n, err := strconv.Atoi(os.Getenv("DUPLICATE_JOB_MAX_DISTANCE")); err == nil && n >= 0 && n < dedup.Bands {
+		cfg.MaxDistance = n
+	}
+	return cfg
+}
+
+// DuplicateService fingerprints jobs and finds the near duplicates among them
+type DuplicateService struct {
+	db  *sql.DB
+	cfg DuplicateConfig
+}
+
+// NewDuplicateService creates a new DuplicateService with the provided configuration
+func NewDuplicateService(db *sql.DB, cfg DuplicateConfig) (*DuplicateService, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	switch cfg.Policy {
+	case DuplicatePolicyOff, DuplicatePolicyWarn, DuplicatePolicyBlock:
+	default:
+		return nil, fmt.Errorf("unknown duplicate policy %q", cfg.Policy)
+	}
+	if cfg.MaxDistance < 0 || cfg.MaxDistance >= dedup.Bands {
+		return nil, fmt.Errorf("duplicate max distance must be between 0 and %d", dedup.Bands-1)
+	}
+	return &DuplicateService{db: db, cfg: cfg}, nil
+}
+
+// jobPostingQuery selects what is fingerprinted of a job: its role, company and location, the company address
+// for jobs without one, with the owner
+const jobPostingQuery = `
+	SELECT j.id, j.jobRole, c.name, COALESCE(NULLIF(j.location, ''), c.address), c.userId
+	FROM jobs j JOIN companies c ON c.id = j.companyId`
+
+// fingerprintBands matches jobs sharing a band with the fingerprints in $2 to $5, the same expressions as the
+// band indexes
+const fingerprintBands = `((j.fingerprint >> 48) & 65535 = $2 OR (j.fingerprint >> 32) & 65535 = $3
+	OR (j.fingerprint >> 16) & 65535 = $4 OR j.fingerprint & 65535 = $5)`
+
+// checkJobTx fingerprints a job after it was created or edited in the transaction and links it to the first
+// posting of the live jobs it nearly duplicates, confirming the fingerprint matches with dedup.Similar. It returns that posting, or 0 when the job is not a duplicate.
+// Under the block policy a job repeating a job of the same owner fails with a DuplicateJobError instead.
+func (ds *DuplicateService) checkJobTx(ctx context.Context, tx *sql.Tx, jobID int) (int, error) {
+	if ds.cfg.Policy == DuplicatePolicyOff {
+		return 0, nil
+	}
+
+	var p dedup.Posting
+	var id, ownerID int
+	err := tx.QueryRowContext(ctx, jobPostingQuery+" WHERE j.id = $1", jobID).Scan(&id, &p.Role, &p.Company, &p.Location, &ownerID)
+	if err != nil {
+		return 0, fmt.Errorf("check duplicate job: %w", err)
+	}
+	fp := dedup.Fingerprint(p)
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT j.fingerprint, COALESCE(j.duplicateOf, j.id), c.userId, j.jobRole, c.name, COALESCE(NULLIF(j.location, ''), c.address)
+		FROM jobs j JOIN companies c ON c.id = j.companyId
+		WHERE j.id <> $1 AND j.deleted_at IS NULL AND j.fingerprint IS NOT NULL AND `+fingerprintBands,
+		jobID, dedup.Band(fp, 0), dedup.Band(fp, 1), dedup.Band(fp, 2), dedup.Band(fp, 3))
+	if err != nil {
+		return 0, fmt.Errorf("check duplicate job: %w", err)
+	}
+	duplicateOf, ownDuplicateOf := 0, 0
+	for rows.Next() {
+		var other int64
+		var first, otherOwner int
+		var op dedup.Posting
+		if err := rows.Scan(&other, &first, &otherOwner, &op.Role, &op.Company, &op.Location); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("check duplicate job: %w", err)
+		}
+		if first == jobID || dedup.Distance(fp, uint64(other)) > ds.cfg.MaxDistance || !dedup.Similar(p, op) {
+			continue
+		}
+		if duplicateOf == 0 || first < duplicateOf {
+			duplicateOf = first
+		}
+		if otherOwner == ownerID && (ownDuplicateOf == 0 || first < ownDuplicateOf) {
+			ownDuplicateOf = first
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("check duplicate job: %w", err)
+	}
+	if ds.cfg.Policy == DuplicatePolicyBlock && ownDuplicateOf != 0 {
+		return 0, &DuplicateJobError{DuplicateOf: ownDuplicateOf}
+	}
+
+	// A job can only be a duplicate of an earlier one, later postings point to it instead
+	if duplicateOf > jobID {
+		duplicateOf = 0
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE jobs SET fingerprint = $1, duplicateOf = NULLIF($2, 0) WHERE id = $3", int64(fp), duplicateOf, jobID)
+	if err != nil {
+		return 0, fmt.Errorf("check duplicate job: %w", err)
+	}
+	return duplicateOf, nil
+}
+
+// ClusterJobs fingerprints all live jobs and groups the near duplicates, pointing every job of a group to the
+// first posting of it. It catches up on jobs created before fingerprinting and on chains of edits that
+// checkJobTx only links one at a time. With dryRun nothing is written.
+func (ds *DuplicateService) ClusterJobs(ctx context.Context, dryRun bool) (*models.DuplicateReport, error) {
+	tx, err := ds.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cluster jobs: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT j.id, j.jobRole, c.name, COALESCE(NULLIF(j.location, ''), c.address), j.fingerprint, j.duplicateOf
+		FROM jobs j JOIN companies c ON c.id = j.companyId
+		WHERE j.deleted_at IS NULL ORDER BY j.id FOR UPDATE OF j`)
+	if err != nil {
+		return nil, fmt.Errorf("cluster jobs: %w", err)
+	}
+	type current struct {
+		fingerprint sql.NullInt64
+		duplicateOf sql.NullInt64
+	}
+	var items []dedup.Item
+	stored := map[int]current{}
+	for rows.Next() {
+		var p dedup.Posting
+		var id int
+		var cur current
+		if err := rows.Scan(&id, &p.Role, &p.Company, &p.Location, &cur.fingerprint, &cur.duplicateOf); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("cluster jobs: %w", err)
+		}
+		items = append(items, dedup.Item{ID: id, Fingerprint: dedup.Fingerprint(p), Posting: p})
+		stored[id] = cur
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("cluster jobs: %w", err)
+	}
+
+	firsts := dedup.Cluster(items, ds.cfg.MaxDistance)
+	report := &models.DuplicateReport{Jobs: len(items)}
+	sizes := map[int]int{}
+	var ids []int32
+	var fingerprints, duplicateOfs []int64
+	for _, it := range items {
+		first := firsts[it.ID]
+		sizes[first]++
+		duplicateOf := sql.NullInt64{}
+		if first != it.ID {
+			report.Duplicates++
+			duplicateOf = sql.NullInt64{Int64: int64(first), Valid: true}
+		}
+		cur := stored[it.ID]
+		if cur.fingerprint == (sql.NullInt64{Int64: int64(it.Fingerprint), Valid: true}) && cur.duplicateOf == duplicateOf {
+			continue
+		}
+		ids = append(ids, int32(it.ID))
+		fingerprints = append(fingerprints, int64(it.Fingerprint))
+		duplicateOfs = append(duplicateOfs, duplicateOf.Int64)
+	}
+	for _, n := range sizes {
+		if n > 1 {
+			report.Clusters++
+		}
+	}
+	report.Updated = len(ids)
+	if dryRun || len(ids) == 0 {
+		return report, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE jobs SET fingerprint = u.fingerprint, duplicateOf = NULLIF(u.duplicate_of, 0)
+		FROM unnest($1::int[], $2::bigint[], $3::bigint[]) AS u(id, fingerprint, duplicate_of)
+		WHERE jobs.id = u.id`, ids, fingerprints, duplicateOfs)
+	if err != nil {
+		return nil, fmt.Errorf("cluster jobs: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("cluster jobs: %w", err)
+	}
+	return report, nil
+}
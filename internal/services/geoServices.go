@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/geo"
+	"job-portal-api/internal/models"
+	"strings"
+)
+
+// ErrInvalidRemote is returned when a job restricts remote work to unknown countries or time zones
+var ErrInvalidRemote = errors.New("remote countries must be ISO 3166-1 alpha-2 codes and remote time zones IANA names")
+
+// geoBatchSize is the number of rows of the geocoding table inserted per statement
+const geoBatchSize = 1000
+
+// maxGeocodeCandidates is the number of places of the same name weighed against each other, most populous first
+const maxGeocodeCandidates = 50
+
+// GeoService loads the offline geocoding table jobs and companies are located with
+type GeoService struct {
+	db *sql.DB
+}
+
+// NewGeoService creates a new GeoService
+func NewGeoService(db *sql.DB) (*GeoService, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	return &GeoService{db: db}, nil
+}
+
+// normalizeRemote checks the remote restrictions of a job, returning the country codes in upper case
+func normalizeRemote(countries, timezones []string) ([]string, []string, error) {
+	codes := make([]string, 0, len(countries))
+	for _, c := range countries {
+		c = strings.ToUpper(strings.TrimSpace(c))
+		if len(c) != 2 || strings.Trim(c, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return nil, nil, ErrInvalidRemote
+		}
+		codes = append(codes, c)
+	}
+	zones := make([]string, 0, len(timezones))
+	for _, tz := range timezones {
+		tz = strings.TrimSpace(tz)
+		if !geo.ValidTimezone(tz) {
+			return nil, nil, ErrInvalidRemote
+		}
+		zones = append(zones, tz)
+	}
+	return codes, zones, nil
+}
+
+// textArray encodes a slice for ARRAY(SELECT jsonb_array_elements_text($n::jsonb))
+func textArray(values []string) ([]byte, error) {
+	if values == nil {
+		values = []string{}
+	}
+	return json.Marshal(values)
+}
+
+// nullableFloat returns the value of a nullable column, nil for NULL
+func nullableFloat(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	f := v.Float64
+	return &f
+}
+
+// geocode looks a location text up in the geocoding table. It returns the place the text most likely means, or
+// only the country when the text names a country but no known place in it, and the zero location otherwise.
+func geocode(ctx context.Context, q queryer, text string) (models.Location, error) {
+	loc := models.Location{Text: text}
+	query := geo.ParseQuery(text)
+	if query.Empty() {
+		return loc, nil
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT code FROM geo_countries
+		WHERE name_keys && $1::text[] OR code = ANY($2::text[]) OR iso3 = ANY($2::text[])`, query.Names, query.Codes)
+	if err != nil {
+		return loc, err
+	}
+	var countries []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			rows.Close()
+			return loc, err
+		}
+		countries = append(countries, code)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return loc, err
+	}
+
+	rows, err = q.QueryContext(ctx, `
+		SELECT id, name, country, region_code, region, latitude, longitude, population, timezone
+		FROM geo_places WHERE name_keys && $1::text[] ORDER BY population DESC, id LIMIT $2`, query.Names, maxGeocodeCandidates)
+	if err != nil {
+		return loc, err
+	}
+	var places []geo.Place
+	for rows.Next() {
+		var p geo.Place
+		err := rows.Scan(&p.ID, &p.Name, &p.Country, &p.RegionCode, &p.Region, &p.Latitude, &p.Longitude, &p.Population, &p.Timezone)
+		if err != nil {
+			rows.Close()
+			return loc, err
+		}
+		places = append(places, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return loc, err
+	}
+
+	if p, ok := query.Best(places, countries); ok {
+		loc.City, loc.Region, loc.Country = p.Name, p.Region, p.Country
+		loc.Latitude, loc.Longitude = &p.Latitude, &p.Longitude
+	} else if len(countries) == 1 {
+		loc.Country = countries[0]
+	}
+	return loc, nil
+}
+
+// locateCompanyTx geocodes the address of a company inside the transaction, and moves the jobs without a
+// location of their own along with it
+func locateCompanyTx(ctx context.Context, tx *sql.Tx, companyID int) (models.Location, error) {
+	var address string
+	if err := tx.QueryRowContext(ctx, "SELECT address FROM companies WHERE id = $1", companyID).Scan(&address); err != nil {
+		return models.Location{}, err
+	}
+	loc, err := geocode(ctx, tx, address)
+	if err != nil {
+		return models.Location{}, err
+	}
+	loc.Text = ""
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE companies SET city = $1, region = $2, country = $3, latitude = $4, longitude = $5 WHERE id = $6`,
+		loc.City, loc.Region, loc.Country, loc.Latitude, loc.Longitude, companyID)
+	if err != nil {
+		return models.Location{}, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE jobs SET city = $1, region = $2, country = $3, latitude = $4, longitude = $5
+		WHERE companyId = $6 AND location = ''`,
+		loc.City, loc.Region, loc.Country, loc.Latitude, loc.Longitude, companyID)
+	if err != nil {
+		return models.Location{}, err
+	}
+	return loc, nil
+}
+
+// locateJobTx geocodes the location text of a job inside the transaction. A job without one is at the place
+// of its company.
+func locateJobTx(ctx context.Context, tx *sql.Tx, jobID int) (models.Location, error) {
+	var loc models.Location
+	var lat, lng sql.NullFloat64
+	err := tx.QueryRowContext(ctx, `
+		SELECT j.location, c.city, c.region, c.country, c.latitude, c.longitude
+		FROM jobs j JOIN companies c ON c.id = j.companyId WHERE j.id = $1`, jobID).
+		Scan(&loc.Text, &loc.City, &loc.Region, &loc.Country, &lat, &lng)
+	if err != nil {
+		return models.Location{}, err
+	}
+	loc.Latitude, loc.Longitude = nullableFloat(lat), nullableFloat(lng)
+	if loc.Text != "" {
+		if loc, err = geocode(ctx, tx, loc.Text); err != nil {
+			return models.Location{}, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE jobs SET city = $1, region = $2, country = $3, latitude = $4, longitude = $5 WHERE id = $6`,
+		loc.City, loc.Region, loc.Country, loc.Latitude, loc.Longitude, jobID)
+	if err != nil {
+		return models.Location{}, err
+	}
+	return loc, nil
+}
+
+// locateProfileTx geocodes the location of a candidate profile inside the transaction
+func locateProfileTx(ctx context.Context, tx *sql.Tx, userID int) error {
+	var text string
+	if err := tx.QueryRowContext(ctx, "SELECT location FROM candidate_profiles WHERE user_id = $1", userID).Scan(&text); err != nil {
+		return err
+	}
+	loc, err := geocode(ctx, tx, text)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE candidate_profiles SET city = $1, country = $2 WHERE user_id = $3", loc.City, loc.Country, userID)
+	return err
+}
+
+// LoadDataset replaces the geocoding table with the countries and places, then geocodes all live companies,
+// jobs and candidate profiles again so that they point at the new places.
+func (gs *GeoService) LoadDataset(ctx context.Context, countries []geo.Country, places []geo.Place) (*models.GeoSeedReport, error) {
+	tx, err := gs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("load geocoding table: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "TRUNCATE geo_countries, geo_places"); err != nil {
+		return nil, fmt.Errorf("load geocoding table: %w", err)
+	}
+	report := &models.GeoSeedReport{}
+	for start := 0; start < len(countries); start += geoBatchSize {
+		batch, err := json.Marshal(countries[start:min(start+geoBatchSize, len(countries))])
+		if err != nil {
+			return nil, fmt.Errorf("load geocoding table: %w", err)
+		}
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO geo_countries (code, iso3, name, name_keys, currency)
+			SELECT c."Code", c."ISO3", c."Name", ARRAY(SELECT jsonb_array_elements_text(c."Keys")), c."Currency"
+			FROM jsonb_to_recordset($1::jsonb) AS c("Code" text, "ISO3" text, "Name" text, "Keys" jsonb, "Currency" text)
+			ON CONFLICT (code) DO NOTHING`, batch)
+		if err != nil {
+			return nil, fmt.Errorf("load geocoding table: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		report.Countries += int(n)
+	}
+	for start := 0; start < len(places); start += geoBatchSize {
+		batch, err := json.Marshal(places[start:min(start+geoBatchSize, len(places))])
+		if err != nil {
+			return nil, fmt.Errorf("load geocoding table: %w", err)
+		}
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO geo_places (id, name, name_keys, country, region_code, region, latitude, longitude, population, timezone)
+			SELECT p."ID", p."Name", ARRAY(SELECT jsonb_array_elements_text(p."Keys")), p."Country", p."RegionCode", p."Region",
+			       p."Latitude", p."Longitude", p."Population", p."Timezone"
+			FROM jsonb_to_recordset($1::jsonb) AS p("ID" int, "Name" text, "Keys" jsonb, "Country" text, "RegionCode" text,
+			     "Region" text, "Latitude" float8, "Longitude" float8, "Population" bigint, "Timezone" text)
+			ON CONFLICT (id) DO NOTHING`, batch)
+		if err != nil {
+			return nil, fmt.Errorf("load geocoding table: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		report.Places += int(n)
+	}
+
+	// Companies first, which also moves the jobs at their address, then the jobs with a location of their own
+	ids, err := idsTx(ctx, tx, "SELECT id FROM companies WHERE deleted_at IS NULL ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("load geocoding table: %w", err)
+	}
+	for _, id := range ids {
+		if _, err := locateCompanyTx(ctx, tx, id); err != nil {
+			return nil, fmt.Errorf("load geocoding table: %w", err)
+		}
+	}
+	report.Companies = len(ids)
+	ids, err = idsTx(ctx, tx, "SELECT id FROM jobs WHERE deleted_at IS NULL AND location <> '' ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("load geocoding table: %w", err)
+	}
+	for _, id := range ids {
+		if _, err := locateJobTx(ctx, tx, id); err != nil {
+			return nil, fmt.Errorf("load geocoding table: %w", err)
+		}
+	}
+	report.Jobs = len(ids)
+	ids, err = idsTx(ctx, tx, "SELECT user_id FROM candidate_profiles WHERE location <> '' ORDER BY user_id")
+	if err != nil {
+		return nil, fmt.Errorf("load geocoding table: %w", err)
+	}
+	for _, id := range ids {
+		if err := locateProfileTx(ctx, tx, id); err != nil {
+			return nil, fmt.Errorf("load geocoding table: %w", err)
+		}
+	}
+	report.Candidates = len(ids)
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("load geocoding table: %w", err)
+	}
+	return report, nil
+}
+
+// idsTx reads the IDs selected by the query inside the transaction
+func idsTx(ctx context.Context, tx *sql.Tx, query string) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/dedup"
+	"job-portal-api/internal/models"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrUnknownLocation is returned when a location text names no known place or country
+var ErrUnknownLocation = errors.New("unknown location")
+
+// maxInsightMonths is the longest salary trend, the postings the aggregates are computed from
+const maxInsightMonths = 36
+
+// InsightConfig represents the configuration of the salary insights
+type InsightConfig struct {
+	MinPostings     int           // MinPostings is the smallest group of postings whose percentiles are published
+	MinCompanies    int           // MinCompanies is the fewest companies a published group must be posted by
+	MaxCompanyShare int           // MaxCompanyShare is the largest percentage of a published group one company may post
+	RefreshInterval time.Duration // RefreshInterval is how often the aggregates are computed again
+	Currency        string        // Currency is the base currency the annual salaries are aggregated in
+}
+
+// DefaultInsightConfig returns the salary insights configuration from SALARY_INSIGHTS_MIN_POSTINGS (default 10),
+// SALARY_INSIGHTS_MIN_COMPANIES (default 3), SALARY_INSIGHTS_MAX_COMPANY_SHARE (percent, default 50) and
+// SALARY_INSIGHTS_REFRESH_MINUTES (default 60), in the SALARY_CURRENCY base currency
+func DefaultInsightConfig() InsightConfig {
+	cfg := InsightConfig{MinPostings: 10, MinCompanies: 3, MaxCompanyShare: 50, RefreshInterval: time.Hour, Currency: DefaultCurrencyConfig().Base}
+	if n, err := strconv.Atoi(os.Getenv("SALARY_INSIGHTS_MIN_POSTINGS")); err == nil && n > 0 {
+		cfg.MinPostings = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("SALARY_INSIGHTS_MIN_COMPANIES")); err == nil && n > 0 {
+		cfg.MinCompanies = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("SALARY_INSIGHTS_MAX_COMPANY_SHARE")); err == nil && n > 0 && n <= 100 {
+		cfg.MaxCompanyShare = n
+	}
+	if minutes, err := strconv.Atoi(os.Getenv("SALARY_INSIGHTS_REFRESH_MINUTES")); err == nil && minutes > 0 {
+		cfg.RefreshInterval = time.Duration(minutes) * time.Minute
+	}
+	return cfg
+}
+
+// InsightService serves salary statistics from the salary_insights materialized view
+type InsightService struct {
+	db  *sql.DB
+	cfg InsightConfig
+}
+
+// NewInsightService creates a new InsightService with the provided configuration
+func NewInsightService(db *sql.DB, cfg InsightConfig) (*InsightService, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	if cfg.MinPostings <= 0 || cfg.MinCompanies <= 0 || cfg.MaxCompanyShare <= 0 || cfg.MaxCompanyShare > 100 || cfg.RefreshInterval <= 0 || cfg.Currency == "" {
+		return nil, errors.New("please provide all the values")
+	}
+	return &InsightService{db: db, cfg: cfg}, nil
+}
+
+// Locate geocodes a location text for a salary query, failing with ErrUnknownLocation when nothing in it is known
+func (is *InsightService) Locate(ctx context.Context, text string) (models.Location, error) {
+	loc, err := geocode(ctx, is.db, text)
+	if err != nil {
+		return models.Location{}, fmt.Errorf("locate %q: %w", text, err)
+	}
+	if loc.Country == "" {
+		return models.Location{}, fmt.Errorf("locate %q: %w", text, ErrUnknownLocation)
+	}
+	return loc, nil
+}
+
+// RefreshSalaryInsights computes the salary aggregates again. Jobs posted before roles were normalized get their
+// normalized role first.
+func (is *InsightService) RefreshSalaryInsights(ctx context.Context) error {
+	rows, err := is.db.QueryContext(ctx, "SELECT id, jobRole FROM jobs WHERE normalizedRole = '' AND COALESCE(jobRole, '') <> ''")
+	if err != nil {
+		return fmt.Errorf("refresh salary insights: %w", err)
+	}
+	roles := map[int]string{}
+	for rows.Next() {
+		var id int
+		var role string
+		if err := rows.Scan(&id, &role); err != nil {
+			rows.Close()
+			return fmt.Errorf("refresh salary insights: %w", err)
+		}
+		roles[id] = dedup.NormalizeRole(role)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("refresh salary insights: %w", err)
+	}
+	for id, role := range roles {
+		if _, err := is.db.ExecContext(ctx, "UPDATE jobs SET normalizedRole = $1 WHERE id = $2", role, id); err != nil {
+			return fmt.Errorf("refresh salary insights: %w", err)
+		}
+	}
+
+	if _, err := is.db.ExecContext(ctx, "REFRESH MATERIALIZED VIEW CONCURRENTLY salary_insights"); err != nil {
+		return fmt.Errorf("refresh salary insights: %w", err)
+	}
+	return nil
+}
+
+// RunRefresh computes the salary aggregates again every refresh interval until ctx is cancelled.
+func (is *InsightService) RunRefresh(ctx context.Context) {
+	ticker := time.NewTicker(is.cfg.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		if err := is.RefreshSalaryInsights(ctx); err != nil {
+			log.Error().Err(err).Send()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stats returns the distribution of a group, without the percentiles when the group is too small or one company,
+// with topCompany of the postings, posted more than MaxCompanyShare percent of them
+func (is *InsightService) stats(postings, companies, topCompany int, percentiles [5]sql.NullFloat64) models.SalaryStats {
+	s := models.SalaryStats{Postings: postings, Companies: companies}
+	if postings < is.cfg.MinPostings || companies < is.cfg.MinCompanies || topCompany*100 > postings*is.cfg.MaxCompanyShare {
+		s.Suppressed = true
+		return s
+	}
+	s.P10, s.P25, s.Median = nullableFloat(percentiles[0]), nullableFloat(percentiles[1]), nullableFloat(percentiles[2])
+	s.P75, s.P90 = nullableFloat(percentiles[3]), nullableFloat(percentiles[4])
+	return s
+}
+
+// SalaryInsights returns the salary distribution of the postings matching the query over the last three years,
+// with its trend over the last q.Months months.
+func (is *InsightService) SalaryInsights(ctx context.Context, q models.SalaryQuery) (*models.SalaryInsights, error) {
+	if q.Months <= 0 || q.Months > maxInsightMonths {
+		return nil, fmt.Errorf("salary insights: months must be between 1 and %d", maxInsightMonths)
+	}
+	// An empty dimension matches the rows aggregated over all of its values, which hold NULL
+	dimension := func(v string) interface{} {
+		if v == "" {
+			return nil
+		}
+		return v
+	}
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month()-time.Month(q.Months-1), 1, 0, 0, 0, 0, time.UTC)
+
+	rows, err := is.db.QueryContext(ctx, `
+		SELECT month, postings, companies, top_company_postings, p10, p25, median, p75, p90, refreshed_at FROM salary_insights
+		WHERE role IS NOT DISTINCT FROM $1::text AND country IS NOT DISTINCT FROM $2::text
+		  AND city IS NOT DISTINCT FROM $3::text AND size_band IS NOT DISTINCT FROM $4::text
+		  AND (month IS NULL OR month >= $5)
+		ORDER BY month NULLS FIRST`,
+		dimension(q.Role), dimension(q.Country), dimension(q.City), dimension(q.CompanySize), from)
+	if err != nil {
+		return nil, fmt.Errorf("salary insights: %w", err)
+	}
+	defer rows.Close()
+
+	insights := &models.SalaryInsights{
+		Role: q.Role, Country: q.Country, City: q.City, CompanySize: q.CompanySize, Currency: is.cfg.Currency,
+		MinPostings: is.cfg.MinPostings, MinCompanies: is.cfg.MinCompanies, MaxCompanyShare: is.cfg.MaxCompanyShare,
+	}
+	insights.SalaryStats = is.stats(0, 0, 0, [5]sql.NullFloat64{})
+	months := map[string]models.SalaryStats{}
+	for rows.Next() {
+		var month sql.NullTime
+		var postings, companies, topCompany int
+		var percentiles [5]sql.NullFloat64
+		var refreshedAt time.Time
+		err := rows.Scan(&month, &postings, &companies, &topCompany, &percentiles[0], &percentiles[1], &percentiles[2], &percentiles[3], &percentiles[4],
+			&refreshedAt)
+		if err != nil {
+			return nil, fmt.Errorf("salary insights: %w", err)
+		}
+		insights.RefreshedAt = &refreshedAt
+		if !month.Valid {
+			insights.SalaryStats = is.stats(postings, companies, topCompany, percentiles)
+			continue
+		}
+		months[month.Time.Format("2006-01")] = is.stats(postings, companies, topCompany, percentiles)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("salary insights: %w", err)
+	}
+
+	// Months without postings are listed too, so that the trend has a point for every month
+	insights.Trend = make([]models.SalaryTrendPoint, 0, q.Months)
+	for m := from; !m.After(now); m = m.AddDate(0, 1, 0) {
+		month := m.Format("2006-01")
+		s, ok := months[month]
+		if !ok {
+			s = is.stats(0, 0, 0, [5]sql.NullFloat64{})
+		}
+		insights.Trend = append(insights.Trend, models.SalaryTrendPoint{Month: month, SalaryStats: s})
+	}
+	return insights, nil
+}
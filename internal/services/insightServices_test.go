@@ -0,0 +1,33 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestInsightStatsSuppression(t *testing.T) {
+	is := &InsightService{cfg: InsightConfig{MinPostings: 10, MinCompanies: 3, MaxCompanyShare: 50}}
+	percentiles := [5]sql.NullFloat64{{Float64: 1, Valid: true}, {Float64: 2, Valid: true}, {Float64: 3, Valid: true},
+		{Float64: 4, Valid: true}, {Float64: 5, Valid: true}}
+	tests := []struct {
+		desc                            string
+		postings, companies, topCompany int
+		suppressed                      bool
+	}{
+		{"published", 10, 3, 4, false},
+		{"top company at the share", 10, 3, 5, false},
+		{"top company over the share", 10, 3, 6, true},
+		{"too few postings", 9, 3, 3, true},
+		{"too few companies", 10, 2, 5, true},
+		{"empty", 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		s := is.stats(tt.postings, tt.companies, tt.topCompany, percentiles)
+		if s.Suppressed != tt.suppressed || (s.Median == nil) != tt.suppressed {
+			t.Errorf("stats(%s) suppressed = %v with median %v, want suppressed %v", tt.desc, s.Suppressed, s.Median, tt.suppressed)
+		}
+		if s.Postings != tt.postings || s.Companies != tt.companies {
+			t.Errorf("stats(%s) counts = %d, %d, want %d, %d", tt.desc, s.Postings, s.Companies, tt.postings, tt.companies)
+		}
+	}
+}
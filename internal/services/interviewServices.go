@@ -0,0 +1,658 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/audit"
+	"job-portal-api/internal/geo"
+	"job-portal-api/internal/ical"
+	"job-portal-api/internal/mailer"
+	"job-portal-api/internal/models"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Interview scheduling errors
+var (
+	ErrJobNotFound        = errors.New("job not found")
+	ErrNotApplied         = errors.New("the candidate has not applied to the job")
+	ErrInvalidSlot        = errors.New("slots must start in the future, end after they start, fit the longest slot and have an IANA time zone")
+	ErrSlotOverlap        = errors.New("slot overlaps another slot of the recruiter")
+	ErrSlotNotFound       = errors.New("interview slot not found")
+	ErrSlotUnavailable    = errors.New("slot is already booked or has started")
+	ErrSlotBooked         = errors.New("slot has a scheduled interview")
+	ErrInterviewScheduled = errors.New("an interview is already scheduled for the application")
+	ErrScheduleConflict   = errors.New("the candidate has another interview at that time")
+	ErrInterviewNotFound  = errors.New("interview not found")
+	ErrInterviewClosed    = errors.New("interview is cancelled or has started")
+)
+
+// InterviewConfig represents the configuration of interview scheduling
+type InterviewConfig struct {
+	BaseURL       string        // BaseURL is the absolute URL of the API, whose host makes invitation UIDs unique
+	Publisher     string        // Publisher is the site name invitations are sent from
+	MaxSlotLength time.Duration // MaxSlotLength is the longest availability slot
+}
+
+// DefaultInterviewConfig returns the interview configuration from PUBLIC_BASE_URL, AGGREGATOR_PUBLISHER and
+// INTERVIEW_MAX_SLOT_MINUTES (default 480)
+func DefaultInterviewConfig() InterviewConfig {
+	cfg := InterviewConfig{
+		BaseURL:       strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/"),
+		Publisher:     os.Getenv("AGGREGATOR_PUBLISHER"),
+		MaxSlotLength: 8 * time.Hour,
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "http://localhost:3030"
+	}
+	if cfg.Publisher == "" {
+		cfg.Publisher = "Job Portal"
+	}
+	if minutes, err := strconv.Atoi(os.Getenv("INTERVIEW_MAX_SLOT_MINUTES")); err == nil && minutes > 0 {
+		cfg.MaxSlotLength = time.Duration(minutes) * time.Minute
+	}
+	return cfg
+}
+
+// InterviewService schedules interviews of candidates who applied to a job into the availability slots
+// recruiters publish for it, and sends both sides iCalendar invitations
+type InterviewService struct {
+	db     *sql.DB
+	mailer mailer.Mailer
+	cfg    InterviewConfig
+	host   string
+}
+
+// NewInterviewService creates a new InterviewService with the provided configuration
+func NewInterviewService(db *sql.DB, m mailer.Mailer, cfg InterviewConfig) (*InterviewService, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	if m == nil {
+		return nil, errors.New("mailer cannot be nil")
+	}
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil || u.Hostname() == "" || cfg.Publisher == "" || cfg.MaxSlotLength <= 0 {
+		return nil, errors.New("please provide all the values")
+	}
+	return &InterviewService{db: db, mailer: m, cfg: cfg, host: u.Hostname()}, nil
+}
+
+// slotColumns are the columns read into models.InterviewSlot, in the order scanSlot expects
+const slotColumns = "s.id, s.job_id, s.recruiter_id, s.starts_at, s.ends_at, s.timezone, s.location, s.created_at, " +
+	"EXISTS (SELECT 1 FROM interviews bi WHERE bi.slot_id = s.id AND bi.status = 'scheduled')"
+
+// interviewColumns are the columns read into models.Interview from interviewTables, in the order scanInterview
+// expects
+const interviewColumns = "i.id, i.application_id, a.job_id, j.jobRole, c.name, a.user_id, i.recruiter_id, i.slot_id, " +
+	"i.starts_at, i.ends_at, i.timezone, i.location, i.status, i.sequence, i.cancel_reason, i.created_at, i.updated_at"
+
+// interviewTables joins an interview to its application, job and company
+const interviewTables = " FROM interviews i JOIN job_applications a ON a.id = i.application_id " +
+	"JOIN jobs j ON j.id = a.job_id JOIN companies c ON c.id = j.companyId"
+
+// interviewAuditQuery selects an interview for the audit log
+const interviewAuditQuery = "SELECT row_to_json(i) FROM interviews i WHERE id = $1"
+
+// inZone returns the time in the named time zone, unchanged when the zone is unknown
+func inZone(t time.Time, name string) time.Time {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return t
+	}
+	return t.In(loc)
+}
+
+// scanSlot scans a row selected with slotColumns
+func scanSlot(row interface{ Scan(...interface{}) error }) (*models.InterviewSlot, error) {
+	var s models.InterviewSlot
+	err := row.Scan(&s.ID, &s.JobId, &s.RecruiterId, &s.StartsAt, &s.EndsAt, &s.Timezone, &s.Location, &s.CreatedAt, &s.Booked)
+	if err != nil {
+		return nil, err
+	}
+	s.StartsAt, s.EndsAt = inZone(s.StartsAt, s.Timezone), inZone(s.EndsAt, s.Timezone)
+	return &s, nil
+}
+
+// scanInterview scans a row selected with interviewColumns
+func scanInterview(row interface{ Scan(...interface{}) error }) (*models.Interview, error) {
+	var iv models.Interview
+	var slotID sql.NullInt64
+	err := row.Scan(&iv.ID, &iv.ApplicationId, &iv.JobId, &iv.JobRole, &iv.CompanyName, &iv.CandidateId, &iv.RecruiterId, &slotID,
+		&iv.StartsAt, &iv.EndsAt, &iv.Timezone, &iv.Location, &iv.Status, &iv.Sequence, &iv.CancelReason, &iv.CreatedAt, &iv.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if slotID.Valid {
+		id := int(slotID.Int64)
+		iv.SlotId = &id
+	}
+	iv.StartsAt, iv.EndsAt = inZone(iv.StartsAt, iv.Timezone), inZone(iv.EndsAt, iv.Timezone)
+	return &iv, nil
+}
+
+// lockUser locks the row of a user, serializing the scheduling changes of a recruiter or candidate. The lock
+// does not block rows referencing the user.
+func lockUser(ctx context.Context, tx *sql.Tx, userID int) error {
+	_, err := tx.ExecContext(ctx, "SELECT 1 FROM users WHERE id = $1 FOR NO KEY UPDATE", userID)
+	return err
+}
+
+// CreateSlots publishes availability slots of the recruiter for a live job of a company they own. Slots of a
+// recruiter cannot overlap each other, across all their jobs.
+func (is *InterviewService) CreateSlots(ctx context.Context, recruiterID, jobID int, slots []models.NewInterviewSlot) ([]*models.InterviewSlot, error) {
+	now := time.Now()
+	for _, s := range slots {
+		if !geo.ValidTimezone(s.Timezone) || !s.StartsAt.After(now) || !s.EndsAt.After(s.StartsAt) ||
+			s.EndsAt.Sub(s.StartsAt) > is.cfg.MaxSlotLength {
+			return nil, fmt.Errorf("create interview slots: %w", ErrInvalidSlot)
+		}
+	}
+	sorted := append([]models.NewInterviewSlot(nil), slots...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].StartsAt.Before(sorted[j].StartsAt) })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].StartsAt.Before(sorted[i-1].EndsAt) {
+			return nil, fmt.Errorf("create interview slots: %w", ErrSlotOverlap)
+		}
+	}
+
+	tx, err := is.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create interview slots: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkNotSuspended(ctx, tx, recruiterID); err != nil {
+		return nil, fmt.Errorf("create interview slots: %w", err)
+	}
+	var exists bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM jobs j JOIN companies c ON c.id = j.companyId
+		WHERE j.id = $1 AND c.userId = $2 AND j.deleted_at IS NULL AND c.deleted_at IS NULL)`, jobID, recruiterID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("create interview slots: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("create interview slots for job %d: %w", jobID, ErrJobNotFound)
+	}
+
+	// Publishing slots of the same recruiter is serialized, so that concurrent requests cannot overlap either
+	if err := lockUser(ctx, tx, recruiterID); err != nil {
+		return nil, fmt.Errorf("create interview slots: %w", err)
+	}
+	created := make([]*models.InterviewSlot, 0, len(slots))
+	for _, s := range slots {
+		var overlaps bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM interview_slots WHERE recruiter_id = $1 AND starts_at < $3 AND ends_at > $2)`,
+			recruiterID, s.StartsAt, s.EndsAt).Scan(&overlaps)
+		if err != nil {
+			return nil, fmt.Errorf("create interview slots: %w", err)
+		}
+		if overlaps {
+			return nil, fmt.Errorf("create interview slots: %w", ErrSlotOverlap)
+		}
+
+		slot, err := scanSlot(tx.QueryRowContext(ctx, `
+			INSERT INTO interview_slots AS s (job_id, recruiter_id, starts_at, ends_at, timezone, location)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING `+slotColumns,
+			jobID, recruiterID, s.StartsAt, s.EndsAt, s.Timezone, strings.TrimSpace(s.Location)))
+		if err != nil {
+			return nil, fmt.Errorf("create interview slots: %w", err)
+		}
+		after, err := rowJSON(ctx, tx, "SELECT row_to_json(s) FROM interview_slots s WHERE id = $1", slot.ID)
+		if err != nil {
+			return nil, fmt.Errorf("create interview slots: %w", err)
+		}
+		err = audit.Record(ctx, tx, audit.Event{
+			ActorID: recruiterID, Action: audit.ActionCreate, EntityType: audit.EntityInterviewSlot, EntityID: slot.ID, After: after,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create interview slots: %w", err)
+		}
+		created = append(created, slot)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("create interview slots: %w", err)
+	}
+	return created, nil
+}
+
+// ListSlots returns the upcoming slots of a job. The company owner sees all of them with whether they are
+// booked, a candidate who applied to the job only the free ones; anyone else gets ErrNotApplied.
+func (is *InterviewService) ListSlots(ctx context.Context, userID, jobID int) ([]*models.InterviewSlot, error) {
+	var owner, applied bool
+	err := is.db.QueryRowContext(ctx, `
+		SELECT c.userId = $2, EXISTS (SELECT 1 FROM job_applications WHERE job_id = j.id AND user_id = $2)
+		FROM jobs j JOIN companies c ON c.id = j.companyId WHERE j.id = $1 AND j.deleted_at IS NULL`, jobID, userID).
+		Scan(&owner, &applied)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("list interview slots of job %d: %w", jobID, ErrJobNotFound)
+		}
+		return nil, fmt.Errorf("list interview slots: %w", err)
+	}
+	if !owner && !applied {
+		return nil, fmt.Errorf("list interview slots: %w", ErrNotApplied)
+	}
+
+	query := "SELECT " + slotColumns + " FROM interview_slots s WHERE s.job_id = $1 AND s.ends_at > now()"
+	if !owner {
+		query += " AND s.starts_at > now() AND NOT EXISTS (SELECT 1 FROM interviews fi WHERE fi.slot_id = s.id AND fi.status = 'scheduled')"
+	}
+	rows, err := is.db.QueryContext(ctx, query+" ORDER BY s.starts_at, s.id", jobID)
+	if err != nil {
+		return nil, fmt.Errorf("list interview slots: %w", err)
+	}
+	defer rows.Close()
+
+	slots := []*models.InterviewSlot{}
+	for rows.Next() {
+		slot, err := scanSlot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list interview slots: %w", err)
+		}
+		slots = append(slots, slot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list interview slots: %w", err)
+	}
+	return slots, nil
+}
+
+// DeleteSlot withdraws a slot of the recruiter. A slot with a scheduled interview cannot be deleted; the
+// interview has to be rescheduled or cancelled first.
+func (is *InterviewService) DeleteSlot(ctx context.Context, recruiterID, slotID int) error {
+	tx, err := is.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("delete interview slot %d: %w", slotID, err)
+	}
+	defer tx.Rollback()
+
+	before, err := rowJSON(ctx, tx, "SELECT row_to_json(s) FROM interview_slots s WHERE id = $1 AND recruiter_id = $2 FOR UPDATE",
+		slotID, recruiterID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("delete interview slot %d: %w", slotID, ErrSlotNotFound)
+		}
+		return fmt.Errorf("delete interview slot %d: %w", slotID, err)
+	}
+	// Bookings lock the slot before checking it is free, so none can slip in now
+	var booked bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM interviews WHERE slot_id = $1 AND status = 'scheduled')", slotID).
+		Scan(&booked)
+	if err != nil {
+		return fmt.Errorf("delete interview slot %d: %w", slotID, err)
+	}
+	if booked {
+		return fmt.Errorf("delete interview slot %d: %w", slotID, ErrSlotBooked)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM interview_slots WHERE id = $1", slotID); err != nil {
+		return fmt.Errorf("delete interview slot %d: %w", slotID, err)
+	}
+
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: recruiterID, Action: audit.ActionDelete, EntityType: audit.EntityInterviewSlot, EntityID: slotID, Before: before,
+	})
+	if err != nil {
+		return fmt.Errorf("delete interview slot %d: %w", slotID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("delete interview slot %d: %w", slotID, err)
+	}
+	return nil
+}
+
+// slotTx locks a slot of the job that is free and has not started, for a candidate locked with lockUser who has
+// no other interview at that time. exceptID is an interview of the candidate being moved, whose own time does not
+// conflict.
+func slotTx(ctx context.Context, tx *sql.Tx, candidateID, jobID, slotID, exceptID int) (*models.InterviewSlot, error) {
+	slot, err := scanSlot(tx.QueryRowContext(ctx, "SELECT "+slotColumns+" FROM interview_slots s WHERE s.id = $1 AND s.job_id = $2 FOR UPDATE",
+		slotID, jobID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSlotNotFound
+		}
+		return nil, err
+	}
+	// Under READ COMMITTED this statement sees a booking committed while the lock above was waited for
+	var booked bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM interviews WHERE slot_id = $1 AND status = 'scheduled')", slotID).
+		Scan(&booked)
+	if err != nil {
+		return nil, err
+	}
+	if booked || !slot.StartsAt.After(time.Now()) {
+		return nil, ErrSlotUnavailable
+	}
+
+	var conflict bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM interviews i JOIN job_applications a ON a.id = i.application_id
+		WHERE a.user_id = $1 AND i.status = 'scheduled' AND i.id <> $2 AND i.starts_at < $4 AND i.ends_at > $3)`,
+		candidateID, exceptID, slot.StartsAt, slot.EndsAt).Scan(&conflict)
+	if err != nil {
+		return nil, err
+	}
+	if conflict {
+		return nil, ErrScheduleConflict
+	}
+	return slot, nil
+}
+
+// BookInterview books a slot of a job for an interview of the candidate, who must have applied to it. The
+// candidate and then the slot are locked, so that two candidates cannot book the same slot and a candidate
+// cannot book two interviews at the same time. Both sides are sent an invitation.
+func (is *InterviewService) BookInterview(ctx context.Context, candidateID, jobID, slotID int) (*models.Interview, error) {
+	tx, err := is.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("book interview: %w", err)
+	}
+	defer tx.Rollback()
+
+	var applicationID int
+	err = tx.QueryRowContext(ctx, `
+		SELECT a.id FROM job_applications a JOIN jobs j ON j.id = a.job_id
+		WHERE a.job_id = $1 AND a.user_id = $2 AND j.deleted_at IS NULL`, jobID, candidateID).Scan(&applicationID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("book interview: %w", ErrNotApplied)
+		}
+		return nil, fmt.Errorf("book interview: %w", err)
+	}
+	if err := lockUser(ctx, tx, candidateID); err != nil {
+		return nil, fmt.Errorf("book interview: %w", err)
+	}
+	var scheduled bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM interviews WHERE application_id = $1 AND status = 'scheduled')",
+		applicationID).Scan(&scheduled)
+	if err != nil {
+		return nil, fmt.Errorf("book interview: %w", err)
+	}
+	if scheduled {
+		return nil, fmt.Errorf("book interview: %w", ErrInterviewScheduled)
+	}
+	slot, err := slotTx(ctx, tx, candidateID, jobID, slotID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("book interview: %w", err)
+	}
+
+	var interviewID int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO interviews (application_id, slot_id, recruiter_id, starts_at, ends_at, timezone, location)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		applicationID, slot.ID, slot.RecruiterId, slot.StartsAt, slot.EndsAt, slot.Timezone, slot.Location).Scan(&interviewID)
+	if err != nil {
+		return nil, fmt.Errorf("book interview: %w", err)
+	}
+	after, err := rowJSON(ctx, tx, interviewAuditQuery, interviewID)
+	if err != nil {
+		return nil, fmt.Errorf("book interview: %w", err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: candidateID, Action: audit.ActionCreate, EntityType: audit.EntityInterview, EntityID: interviewID, After: after,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("book interview: %w", err)
+	}
+	iv, err := scanInterview(tx.QueryRowContext(ctx, "SELECT "+interviewColumns+interviewTables+" WHERE i.id = $1", interviewID))
+	if err != nil {
+		return nil, fmt.Errorf("book interview: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("book interview: %w", err)
+	}
+	is.sendInvitations(ctx, iv, "Interview scheduled")
+	return iv, nil
+}
+
+// lockInterviewTx locks a scheduled interview that has not started, of which the user is the candidate or the
+// owner of the hiring company, returning it with its state for the audit log
+func lockInterviewTx(ctx context.Context, tx *sql.Tx, userID, interviewID int) (*models.Interview, []byte, error) {
+	iv, err := scanInterview(tx.QueryRowContext(ctx, "SELECT "+interviewColumns+interviewTables+
+		" WHERE i.id = $1 AND (a.user_id = $2 OR c.userId = $2) FOR UPDATE OF i", interviewID, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrInterviewNotFound
+		}
+		return nil, nil, err
+	}
+	if iv.Status != models.InterviewScheduled || !iv.StartsAt.After(time.Now()) {
+		return nil, nil, ErrInterviewClosed
+	}
+	before, err := rowJSON(ctx, tx, interviewAuditQuery, interviewID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return iv, before, nil
+}
+
+// RescheduleInterview moves a scheduled interview to another free slot of the job, on behalf of the candidate or
+// the recruiter. The invitation sequence is incremented, so that calendars replace the event, and both sides are
+// sent the update.
+func (is *InterviewService) RescheduleInterview(ctx context.Context, userID, interviewID, slotID int) (*models.Interview, error) {
+	tx, err := is.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("reschedule interview %d: %w", interviewID, err)
+	}
+	defer tx.Rollback()
+
+	iv, before, err := lockInterviewTx(ctx, tx, userID, interviewID)
+	if err != nil {
+		return nil, fmt.Errorf("reschedule interview %d: %w", interviewID, err)
+	}
+	if iv.SlotId != nil && *iv.SlotId == slotID {
+		return nil, fmt.Errorf("reschedule interview %d: %w", interviewID, ErrSlotUnavailable)
+	}
+	if err := lockUser(ctx, tx, iv.CandidateId); err != nil {
+		return nil, fmt.Errorf("reschedule interview %d: %w", interviewID, err)
+	}
+	slot, err := slotTx(ctx, tx, iv.CandidateId, iv.JobId, slotID, interviewID)
+	if err != nil {
+		return nil, fmt.Errorf("reschedule interview %d: %w", interviewID, err)
+	}
+
+	after, err := rowJSON(ctx, tx, `
+		UPDATE interviews i SET slot_id = $2, recruiter_id = $3, starts_at = $4, ends_at = $5, timezone = $6, location = $7,
+			sequence = sequence + 1, updated_at = now()
+		WHERE id = $1 RETURNING row_to_json(i)`,
+		interviewID, slot.ID, slot.RecruiterId, slot.StartsAt, slot.EndsAt, slot.Timezone, slot.Location)
+	if err != nil {
+		return nil, fmt.Errorf("reschedule interview %d: %w", interviewID, err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionUpdate, EntityType: audit.EntityInterview, EntityID: interviewID, Before: before, After: after,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reschedule interview %d: %w", interviewID, err)
+	}
+	iv, err = scanInterview(tx.QueryRowContext(ctx, "SELECT "+interviewColumns+interviewTables+" WHERE i.id = $1", interviewID))
+	if err != nil {
+		return nil, fmt.Errorf("reschedule interview %d: %w", interviewID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("reschedule interview %d: %w", interviewID, err)
+	}
+	is.sendInvitations(ctx, iv, "Interview rescheduled")
+	return iv, nil
+}
+
+// CancelInterview cancels a scheduled interview on behalf of the candidate or the recruiter, freeing its slot.
+// Both sides are sent the cancellation.
+func (is *InterviewService) CancelInterview(ctx context.Context, userID, interviewID int, reason string) (*models.Interview, error) {
+	tx, err := is.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cancel interview %d: %w", interviewID, err)
+	}
+	defer tx.Rollback()
+
+	_, before, err := lockInterviewTx(ctx, tx, userID, interviewID)
+	if err != nil {
+		return nil, fmt.Errorf("cancel interview %d: %w", interviewID, err)
+	}
+	after, err := rowJSON(ctx, tx, `
+		UPDATE interviews i SET status = $2, cancelled_by = $3, cancel_reason = $4, sequence = sequence + 1, updated_at = now()
+		WHERE id = $1 RETURNING row_to_json(i)`,
+		interviewID, models.InterviewCancelled, userID, strings.TrimSpace(reason))
+	if err != nil {
+		return nil, fmt.Errorf("cancel interview %d: %w", interviewID, err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionUpdate, EntityType: audit.EntityInterview, EntityID: interviewID, Before: before, After: after,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cancel interview %d: %w", interviewID, err)
+	}
+	iv, err := scanInterview(tx.QueryRowContext(ctx, "SELECT "+interviewColumns+interviewTables+" WHERE i.id = $1", interviewID))
+	if err != nil {
+		return nil, fmt.Errorf("cancel interview %d: %w", interviewID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("cancel interview %d: %w", interviewID, err)
+	}
+	is.sendInvitations(ctx, iv, "Interview cancelled")
+	return iv, nil
+}
+
+// ListInterviews returns the interviews of the user, as the candidate or the owner of the hiring company, soonest
+// first
+func (is *InterviewService) ListInterviews(ctx context.Context, userID int) ([]*models.Interview, error) {
+	rows, err := is.db.QueryContext(ctx, "SELECT "+interviewColumns+interviewTables+
+		" WHERE a.user_id = $1 OR c.userId = $1 ORDER BY i.starts_at, i.id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("list interviews: %w", err)
+	}
+	defer rows.Close()
+
+	interviews := []*models.Interview{}
+	for rows.Next() {
+		iv, err := scanInterview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list interviews: %w", err)
+		}
+		interviews = append(interviews, iv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list interviews: %w", err)
+	}
+	return interviews, nil
+}
+
+// GetInterview returns an interview of which the user is the candidate or the owner of the hiring company
+func (is *InterviewService) GetInterview(ctx context.Context, userID, interviewID int) (*models.Interview, error) {
+	iv, err := scanInterview(is.db.QueryRowContext(ctx, "SELECT "+interviewColumns+interviewTables+
+		" WHERE i.id = $1 AND (a.user_id = $2 OR c.userId = $2)", interviewID, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("get interview %d: %w", interviewID, ErrInterviewNotFound)
+		}
+		return nil, fmt.Errorf("get interview %d: %w", interviewID, err)
+	}
+	return iv, nil
+}
+
+// Invitation returns the iCalendar invitation of an interview of the user: a request while it is scheduled and a
+// cancellation once cancelled. The times are written in the time zone of the slot, or in timezone when given.
+func (is *InterviewService) Invitation(ctx context.Context, userID, interviewID int, timezone string) ([]byte, error) {
+	iv, err := is.GetInterview(ctx, userID, interviewID)
+	if err != nil {
+		return nil, err
+	}
+	if timezone != "" {
+		iv.StartsAt, iv.EndsAt = inZone(iv.StartsAt, timezone), inZone(iv.EndsAt, timezone)
+	}
+	candidate, recruiter, err := is.participants(ctx, iv)
+	if err != nil {
+		return nil, fmt.Errorf("interview %d invitation: %w", interviewID, err)
+	}
+	data, err := ical.Marshal(is.calendar(iv, candidate, recruiter))
+	if err != nil {
+		return nil, fmt.Errorf("interview %d invitation: %w", interviewID, err)
+	}
+	return data, nil
+}
+
+// participants returns the email addresses of the candidate and the recruiter of an interview
+func (is *InterviewService) participants(ctx context.Context, iv *models.Interview) (string, string, error) {
+	var candidate, recruiter string
+	err := is.db.QueryRowContext(ctx, `
+		SELECT (SELECT email FROM users WHERE id = $1), (SELECT email FROM users WHERE id = $2)`,
+		iv.CandidateId, iv.RecruiterId).Scan(&candidate, &recruiter)
+	return candidate, recruiter, err
+}
+
+// calendar returns the iCalendar object of an interview, organized by the recruiter
+func (is *InterviewService) calendar(iv *models.Interview, candidate, recruiter string) ical.Calendar {
+	method, status := ical.MethodRequest, ical.StatusConfirmed
+	description := fmt.Sprintf("Interview for the %s role at %s.", iv.JobRole, iv.CompanyName)
+	if iv.Status == models.InterviewCancelled {
+		method, status = ical.MethodCancel, ical.StatusCancelled
+		description = fmt.Sprintf("The interview for the %s role at %s is cancelled.", iv.JobRole, iv.CompanyName)
+		if iv.CancelReason != "" {
+			description += "\n\nReason: " + iv.CancelReason
+		}
+	}
+	return ical.Calendar{
+		ProdID: "-//" + is.cfg.Publisher + "//Interviews//EN",
+		Method: method,
+		Events: []ical.Event{{
+			UID:         "interview-" + strconv.Itoa(iv.ID) + "@" + is.host,
+			Sequence:    iv.Sequence,
+			Stamp:       iv.UpdatedAt,
+			Start:       iv.StartsAt,
+			End:         iv.EndsAt,
+			Summary:     fmt.Sprintf("Interview: %s at %s", iv.JobRole, iv.CompanyName),
+			Description: description,
+			Location:    iv.Location,
+			URL:         is.cfg.BaseURL + "/public/jobs/" + strconv.Itoa(iv.JobId),
+			Status:      status,
+			Organizer:   ical.Attendee{Name: iv.CompanyName, Email: recruiter},
+			Attendees:   []ical.Attendee{{Email: candidate}},
+		}},
+	}
+}
+
+// sendInvitations emails the invitation of an interview to the candidate and the recruiter. It runs after the
+// change committed, so a failed email is only logged; the invitation can still be downloaded.
+func (is *InterviewService) sendInvitations(ctx context.Context, iv *models.Interview, subject string) {
+	candidate, recruiter, err := is.participants(ctx, iv)
+	if err != nil {
+		log.Error().Err(err).Int("interview", iv.ID).Msg("interview invitation not sent")
+		return
+	}
+	cal := is.calendar(iv, candidate, recruiter)
+	data, err := ical.Marshal(cal)
+	if err != nil {
+		log.Error().Err(err).Int("interview", iv.ID).Msg("interview invitation not sent")
+		return
+	}
+	attachment := mailer.Attachment{Name: "invite.ics", ContentType: ical.ContentType + "; method=" + cal.Method, Data: data}
+	body := fmt.Sprintf("%s: %s at %s\n\nWhen: %s to %s (%s)\n", subject, iv.JobRole, iv.CompanyName,
+		iv.StartsAt.Format("Mon 2 Jan 2006 15:04"), iv.EndsAt.Format("15:04"), iv.Timezone)
+	if iv.Location != "" {
+		body += "Where: " + iv.Location + "\n"
+	}
+	if iv.Status == models.InterviewCancelled && iv.CancelReason != "" {
+		body += "\nReason: " + iv.CancelReason + "\n"
+	}
+	body += "\nThe attached invitation adds the interview to your calendar."
+	for _, to := range []string{candidate, recruiter} {
+		if err := is.mailer.Send(to, subject+": "+iv.JobRole+" at "+iv.CompanyName, body, attachment); err != nil {
+			log.Error().Err(err).Int("interview", iv.ID).Msg("interview invitation not sent")
+		}
+	}
+}
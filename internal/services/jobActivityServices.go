@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/models"
+)
+
+// ErrAlreadyApplied is returned when the candidate already applied to the job
+var ErrAlreadyApplied = errors.New("already applied")
+
+// RecordView remembers that the user looked at a job, for their recommendations.
+func (js *JobService) RecordView(ctx context.Context, userID, jobID int) error {
+	_, err := js.db.ExecContext(ctx, `
+		INSERT INTO job_views (user_id, job_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, job_id) DO UPDATE SET view_count = job_views.view_count + 1, last_viewed_at = now()`,
+		userID, jobID)
+	if err != nil {
+		return fmt.Errorf("record job view: %w", err)
+	}
+	return nil
+}
+
+// Apply records a candidate applying to a published job.
+func (js *JobService) Apply(ctx context.Context, userID, jobID int) (*models.JobApplication, error) {
+	var app models.JobApplication
+	err := js.db.QueryRowContext(ctx, `
+		INSERT INTO job_applications (job_id, user_id)
+		SELECT id, $2 FROM jobs WHERE id = $1 AND deleted_at IS NULL AND `+jobPublished+`
+		ON CONFLICT (job_id, user_id) DO NOTHING
+		RETURNING id, job_id, user_id, created_at`, jobID, userID).Scan(&app.ID, &app.JobId, &app.UserId, &app.CreatedAt)
+	if err == nil {
+		return &app, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("apply to job %d: %w", jobID, err)
+	}
+
+	// Nothing was inserted: either the candidate applied before or the job is not published
+	var applied bool
+	err = js.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM job_applications WHERE job_id = $1 AND user_id = $2)",
+		jobID, userID).Scan(&applied)
+	if err != nil {
+		return nil, fmt.Errorf("apply to job %d: %w", jobID, err)
+	}
+	if applied {
+		return nil, fmt.Errorf("apply to job %d: %w", jobID, ErrAlreadyApplied)
+	}
+	return nil, fmt.Errorf("job %d not found", jobID)
+}
@@ -0,0 +1,531 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"job-portal-api/internal/audit"
+	"job-portal-api/internal/jobimport"
+	"job-portal-api/internal/models"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Import modes
+const (
+	ImportModeAllOrNothing = "all_or_nothing" // ImportModeAllOrNothing rolls back the whole import if any row fails.
+	ImportModeBestEffort   = "best_effort"    // ImportModeBestEffort inserts every valid row and reports the rest.
+)
+
+// Import statuses
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// importBatchSize is the number of rows inserted per transaction in best-effort mode and between progress updates
+const importBatchSize = 500
+
+// maxImportErrors caps the row errors kept in an import report
+const maxImportErrors = 1000
+
+// importHeartbeat is how often a running async import shows it is alive, and importStaleAfter how long without a
+// heartbeat before it is considered abandoned by a crashed or restarted instance
+const (
+	importHeartbeat  = time.Minute
+	importStaleAfter = 5 * time.Minute
+)
+
+// ErrImportBusy is returned when the instance runs its maximum of imports, or the company already has an async
+// import in progress
+var ErrImportBusy = errors.New("too many job imports in progress, retry later")
+
+// ErrInvalidImport is returned when the import options are invalid or the file cannot be read. Its message is
+// meant for the client.
+var ErrInvalidImport = errors.New("invalid import")
+
+// JobImportConfig represents the configuration of job imports
+type JobImportConfig struct {
+	MaxConcurrent int // MaxConcurrent is the number of imports an instance runs at once, sync and async together
+}
+
+// DefaultJobImportConfig returns the job import configuration from JOB_IMPORT_MAX_CONCURRENT (default 4)
+func DefaultJobImportConfig() JobImportConfig {
+	cfg := JobImportConfig{MaxConcurrent: 4}
+	if n, err := strconv.Atoi(os.Getenv("JOB_IMPORT_MAX_CONCURRENT")); err == nil && n > 0 {
+		cfg.MaxConcurrent = n
+	}
+	return cfg
+}
+
+// ImportOptions controls how a job import file is read and applied.
+type ImportOptions struct {
+	Format  string            // Format is jobimport.FormatCSV or jobimport.FormatJSONL.
+	Mapping jobimport.Mapping // Mapping maps job fields to source columns.
+	DryRun  bool              // DryRun only validates rows.
+	Mode    string            // Mode is ImportModeAllOrNothing or ImportModeBestEffort.
+}
+
+// JobImportService handles bulk importing jobs into a company.
+type JobImportService struct {
+	db         *sql.DB
+	moderation *ModerationService
+	duplicates *DuplicateService
+	skills     *SkillService
+	currencies *CurrencyService
+	slots      chan struct{} // slots holds a token per running import
+}
+
+// NewJobImportService creates a new JobImportService instance. Imported jobs are screened by the moderation service,
+// checked for near duplicates, tagged with skills and have their salaries converted like jobs created one by one.
+func NewJobImportService(db *sql.DB, ms *ModerationService, ds *DuplicateService, ss *SkillService, cs *CurrencyService, cfg JobImportConfig) (*JobImportService, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	if ms == nil || ds == nil || ss == nil || cs == nil {
+		return nil, errors.New("moderation, duplicate, skill and currency services cannot be nil")
+	}
+	if cfg.MaxConcurrent <= 0 {
+		return nil, errors.New("please provide all the values")
+	}
+	return &JobImportService{db: db, moderation: ms, duplicates: ds, skills: ss, currencies: cs,
+		slots: make(chan struct{}, cfg.MaxConcurrent)}, nil
+}
+
+// acquire takes a slot for an import without waiting, failing with ErrImportBusy when all are taken
+func (is *JobImportService) acquire() error {
+	select {
+	case is.slots <- struct{}{}:
+		return nil
+	default:
+		return ErrImportBusy
+	}
+}
+
+// release frees the slot of a finished import
+func (is *JobImportService) release() {
+	<-is.slots
+}
+
+// Import reads the file and imports its jobs into a company owned by the user, returning the report once done.
+func (is *JobImportService) Import(ctx context.Context, userID, companyID int, r io.Reader, opts ImportOptions) (*models.JobImport, error) {
+	if err := is.checkImport(ctx, userID, companyID, &opts); err != nil {
+		return nil, err
+	}
+
+	if err := is.acquire(); err != nil {
+		return nil, fmt.Errorf("import jobs: %w", err)
+	}
+	defer is.release()
+
+	report := newImportReport(companyID, opts)
+	if err := is.run(ctx, userID, companyID, r, opts, report, nil); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// StartImport spools the file to disk and imports it in the background. The returned report carries
+// the ID used to poll progress with GetImport. A company runs one async import at a time.
+func (is *JobImportService) StartImport(ctx context.Context, userID, companyID int, r io.Reader, opts ImportOptions) (*models.JobImport, error) {
+	if err := is.checkImport(ctx, userID, companyID, &opts); err != nil {
+		return nil, err
+	}
+	if err := is.acquire(); err != nil {
+		return nil, fmt.Errorf("start import: %w", err)
+	}
+	started := false
+	defer func() {
+		if !started {
+			is.release()
+		}
+	}()
+
+	// Spool the upload so the request can finish before the import does
+	f, err := os.CreateTemp("", "job-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("start import: %w", err)
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("start import: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("start import: %w", err)
+	}
+
+	report := newImportReport(companyID, opts)
+	report.Status = ImportStatusPending
+	if err := is.createImport(ctx, userID, companyID, report); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("start import: %w", err)
+	}
+
+	// The import outlives the request, but keeps its request ID and client IP for the audit log
+	bg := audit.WithMeta(context.Background(), audit.MetaFromContext(ctx))
+	started = true
+	go func() {
+		defer is.release()
+		defer cleanup()
+		stop := is.heartbeat(bg, report.ID)
+		defer stop()
+
+		state := *report
+		state.Status = ImportStatusRunning
+		is.saveProgress(bg, &state)
+
+		err := is.run(bg, userID, companyID, f, opts, &state, func(p *models.JobImport) { is.saveProgress(bg, p) })
+		if err != nil {
+			log.Error().Err(err).Int("importID", state.ID).Msg("job import failed")
+			state.Status = ImportStatusFailed
+			state.Message = "import failed, upload the file again"
+			if errors.Is(err, ErrInvalidImport) {
+				state.Message = err.Error()
+			}
+		}
+		now := time.Now()
+		state.FinishedAt = &now
+		is.saveProgress(bg, &state)
+	}()
+
+	return report, nil
+}
+
+// createImport records a pending async import, unless the company already has one in progress. The company is
+// locked so that two concurrent uploads cannot both start.
+func (is *JobImportService) createImport(ctx context.Context, userID, companyID int, report *models.JobImport) error {
+	tx, err := is.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockCompany(ctx, tx, companyID); err != nil {
+		return err
+	}
+	var active bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM job_imports WHERE company_id = $1 AND status IN ($2, $3))",
+		companyID, ImportStatusPending, ImportStatusRunning).Scan(&active)
+	if err != nil {
+		return err
+	}
+	if active {
+		return ErrImportBusy
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO job_imports (company_id, user_id, status, mode, dry_run)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`, companyID, userID, report.Status, report.Mode, report.DryRun).Scan(&report.ID, &report.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// heartbeat touches an async import every importHeartbeat until the returned function is called
+func (is *JobImportService) heartbeat(ctx context.Context, importID int) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(importHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := is.db.ExecContext(ctx, "UPDATE job_imports SET heartbeat_at = now() WHERE id = $1", importID); err != nil {
+					log.Error().Err(err).Int("importID", importID).Msg("could not save import heartbeat")
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// FailStaleImports marks the pending and running imports that have not had a heartbeat for importStaleAfter as
+// failed, as they were abandoned by an instance that stopped. It returns the number of imports failed.
+func (is *JobImportService) FailStaleImports(ctx context.Context) (int, error) {
+	res, err := is.db.ExecContext(ctx, `
+		UPDATE job_imports SET status = $1, message = 'import interrupted, upload the file again', finished_at = now()
+		WHERE status IN ($2, $3) AND heartbeat_at < now() - make_interval(secs => $4)`,
+		ImportStatusFailed, ImportStatusPending, ImportStatusRunning, importStaleAfter.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("fail stale imports: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// RunStaleCheck fails abandoned imports at startup and then every interval until ctx is cancelled.
+func (is *JobImportService) RunStaleCheck(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := is.FailStaleImports(ctx); err != nil {
+			log.Error().Err(err).Send()
+		} else if n > 0 {
+			log.Info().Int("failed", n).Msg("failed abandoned job imports")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetImport returns the progress of an async import of a company owned by the user.
+func (is *JobImportService) GetImport(ctx context.Context, userID, companyID, importID int) (*models.JobImport, error) {
+	report := models.JobImport{ID: importID, CompanyId: companyID}
+	var errs []byte
+	var message sql.NullString
+	var finishedAt sql.NullTime
+	err := is.db.QueryRowContext(ctx, `
+		SELECT i.status, i.mode, i.dry_run, i.total, i.valid, i.inserted, i.failed, i.errors, i.message, i.created_at, i.finished_at
+		FROM job_imports i JOIN companies c ON c.id = i.company_id
+		WHERE i.id = $1 AND i.company_id = $2 AND c.userId = $3`, importID, companyID, userID).Scan(
+		&report.Status, &report.Mode, &report.DryRun, &report.Total, &report.Valid, &report.Inserted, &report.Failed,
+		&errs, &message, &report.CreatedAt, &finishedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("import not found for company %d and import ID %d", companyID, importID)
+		}
+		return nil, fmt.Errorf("get import: %w", err)
+	}
+	if err := json.Unmarshal(errs, &report.Errors); err != nil {
+		return nil, fmt.Errorf("get import: %w", err)
+	}
+	report.Message = message.String
+	if finishedAt.Valid {
+		report.FinishedAt = &finishedAt.Time
+	}
+	return &report, nil
+}
+
+// checkImport validates the options and that the user owns the live company.
+func (is *JobImportService) checkImport(ctx context.Context, userID, companyID int, opts *ImportOptions) error {
+	if opts.Mode == "" {
+		opts.Mode = ImportModeAllOrNothing
+	}
+	if opts.Mode != ImportModeAllOrNothing && opts.Mode != ImportModeBestEffort {
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidImport, opts.Mode)
+	}
+	if opts.Format != jobimport.FormatCSV && opts.Format != jobimport.FormatJSONL {
+		return fmt.Errorf("%w: unsupported format %q", ErrInvalidImport, opts.Format)
+	}
+	if opts.Mapping == nil {
+		opts.Mapping = jobimport.DefaultMapping()
+	}
+
+	var count int
+	var suspended bool
+	err := is.db.QueryRowContext(ctx, `
+		SELECT (SELECT COUNT(*) FROM companies WHERE userId = $1 AND id = $2 AND deleted_at IS NULL),
+		       EXISTS (SELECT 1 FROM users WHERE id = $1 AND suspended_at IS NOT NULL)`, userID, companyID).Scan(&count, &suspended)
+	if err != nil {
+		return fmt.Errorf("query company existence: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%w for user with ID %d and company ID %d", ErrCompanyNotFound, userID, companyID)
+	}
+	if suspended {
+		return fmt.Errorf("import jobs: %w", ErrUserSuspended)
+	}
+	return nil
+}
+
+// run streams the file, validating every row and inserting valid ones according to the mode. Dry runs insert
+// the rows too, so they are checked exactly as in a real import, and roll them back.
+// progress, when set, is called after every batch.
+func (is *JobImportService) run(ctx context.Context, userID, companyID int, r io.Reader, opts ImportOptions, report *models.JobImport, progress func(*models.JobImport)) error {
+	reader, err := jobimport.NewReader(opts.Format, r)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidImport, err)
+	}
+
+	// In all-or-nothing mode a single transaction covers the whole file
+	var tx *sql.Tx
+	if opts.Mode == ImportModeAllOrNothing {
+		tx, err = is.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("import jobs: %w", err)
+		}
+		defer tx.Rollback()
+	}
+
+	batch := make([]*jobimport.Job, 0, importBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := is.insertBatch(ctx, tx, userID, companyID, batch, report, opts.DryRun); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		if progress != nil {
+			progress(report)
+		}
+		return nil
+	}
+
+	for {
+		rec, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidImport, err)
+		}
+		report.Total++
+
+		job, rowErrs := jobimport.Validate(rec, opts.Mapping)
+		if len(rowErrs) > 0 {
+			report.Failed++
+			addImportErrors(report, rowErrs...)
+			continue
+		}
+		report.Valid++
+
+		// Once an all-or-nothing import has failed, the remaining rows are only validated
+		if opts.Mode == ImportModeAllOrNothing && report.Failed > 0 {
+			continue
+		}
+		batch = append(batch, job)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if tx != nil {
+		if report.Failed > 0 || opts.DryRun {
+			// Nothing is kept when any row fails, or by a dry run
+			report.Inserted = 0
+			tx.Rollback()
+		} else if err := tx.Commit(); err != nil {
+			return fmt.Errorf("import jobs: %w", err)
+		}
+	}
+	if opts.DryRun {
+		report.Inserted = 0
+	}
+
+	report.Status = ImportStatusCompleted
+	return nil
+}
+
+// insertBatch inserts a batch of validated jobs. Without an enclosing transaction the batch gets its own, which a
+// dry run rolls back. Each row runs under a savepoint so a failing insert does not abort the rest of the batch.
+func (is *JobImportService) insertBatch(ctx context.Context, tx *sql.Tx, userID, companyID int, batch []*jobimport.Job, report *models.JobImport, dryRun bool) error {
+	own := tx == nil
+	if own {
+		var err error
+		tx, err = is.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("import jobs: %w", err)
+		}
+		defer tx.Rollback()
+	}
+
+	inserted := 0
+	for _, job := range batch {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT import_row"); err != nil {
+			return fmt.Errorf("import jobs: %w", err)
+		}
+		nj := models.NewJob{JobRole: job.JobRole, Salary: job.Salary, Currency: job.Currency, PayPeriod: job.PayPeriod, Location: job.Location}
+		if _, err := createJobTx(ctx, tx, is.moderation, is.duplicates, is.skills, is.currencies, userID, nj, companyID); err != nil {
+			if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT import_row"); rbErr != nil {
+				return fmt.Errorf("import jobs: %w", rbErr)
+			}
+			report.Failed++
+			addImportErrors(report, models.ImportRowError{Row: job.Row, Message: importRowMessage(err)})
+			continue
+		}
+		inserted++
+	}
+
+	if own && !dryRun {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("import jobs: %w", err)
+		}
+	}
+	report.Inserted += inserted
+	return nil
+}
+
+// importRowMessage returns the message reported for a row that could not be created. Known errors keep their
+// message, anything else is logged and reported generically, so database details do not reach the client.
+func importRowMessage(err error) string {
+	var dup *DuplicateJobError
+	switch {
+	case errors.As(err, &dup):
+		return dup.Error()
+	case errors.Is(err, ErrUserSuspended):
+		return ErrUserSuspended.Error()
+	case errors.Is(err, ErrCompanyNotFound):
+		return ErrCompanyNotFound.Error()
+	case errors.Is(err, ErrInvalidSalary):
+		return ErrInvalidSalary.Error()
+	case errors.Is(err, ErrInvalidRemote):
+		return ErrInvalidRemote.Error()
+	}
+	log.Error().Err(err).Msg("could not import job row")
+	return "could not create the job"
+}
+
+// saveProgress stores the state of an async import.
+func (is *JobImportService) saveProgress(ctx context.Context, report *models.JobImport) {
+	errs, err := json.Marshal(report.Errors)
+	if err != nil {
+		log.Error().Err(err).Send()
+		return
+	}
+	_, err = is.db.ExecContext(ctx, `
+		UPDATE job_imports SET status = $1, total = $2, valid = $3, inserted = $4, failed = $5, errors = $6,
+			message = NULLIF($7, ''), finished_at = $8, heartbeat_at = now()
+		WHERE id = $9`,
+		report.Status, report.Total, report.Valid, report.Inserted, report.Failed, errs, report.Message, report.FinishedAt, report.ID)
+	if err != nil {
+		log.Error().Err(err).Int("importID", report.ID).Msg("could not save import progress")
+	}
+}
+
+// newImportReport returns an empty report for the options.
+func newImportReport(companyID int, opts ImportOptions) *models.JobImport {
+	return &models.JobImport{
+		CompanyId: companyID,
+		Status:    ImportStatusRunning,
+		Mode:      opts.Mode,
+		DryRun:    opts.DryRun,
+		Errors:    []models.ImportRowError{},
+		CreatedAt: time.Now(),
+	}
+}
+
+// addImportErrors appends row errors to the report up to maxImportErrors.
+func addImportErrors(report *models.JobImport, errs ...models.ImportRowError) {
+	for _, e := range errs {
+		if len(report.Errors) >= maxImportErrors {
+			return
+		}
+		report.Errors = append(report.Errors, e)
+	}
+}
@@ -1,46 +1,234 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"job-portal-api/internal/audit"
+	"job-portal-api/internal/dedup"
+	"job-portal-api/internal/geo"
 	"job-portal-api/internal/models"
+	"job-portal-api/internal/outbox"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // JobService handles business logic related to job operations.
 type JobService struct {
-	db *sql.DB
+	db         *sql.DB
+	retention  time.Duration
+	moderation *ModerationService
+	duplicates *DuplicateService
+	skills     *SkillService
+	currencies *CurrencyService
+	cursors    *Cursors
 }
 
-// NewJobService creates a new JobService instance.
-func NewJobService(db *sql.DB) (*JobService, error) {
+// NewJobService creates a new JobService instance. Soft deleted jobs can be restored for the
+// retention period, after which they are purged. New and edited jobs are screened by the moderation service,
+// checked for near duplicates, tagged with the skills named in their roles and have their salaries converted to
+// the base currency. Exports stream through the shared cursors.
+func NewJobService(db *sql.DB, retention time.Duration, ms *ModerationService, ds *DuplicateService, ss *SkillService, cs *CurrencyService, cursors *Cursors) (*JobService, error) {
 	if db == nil {
 		return nil, errors.New("db connection cannot be nil")
 	}
-	return &JobService{db: db}, nil
+	if retention <= 0 {
+		return nil, errors.New("retention must be positive")
+	}
+	if ms == nil || ds == nil || ss == nil || cs == nil {
+		return nil, errors.New("moderation, duplicate, skill and currency services cannot be nil")
+	}
+	if cursors == nil {
+		return nil, errors.New("cursors cannot be nil")
+	}
+	return &JobService{db: db, retention: retention, moderation: ms, duplicates: ds, skills: ss, currencies: cs, cursors: cursors}, nil
+}
+
+// jobColumns are the columns read into models.Job, in the order scanJob expects. They must be selected from the
+// jobs table without an alias, as the verified badge of the company and the skills are looked up by jobs.companyId
+// and jobs.id.
+const jobColumns = "id, jobRole, salary, companyId, version, created_at, updated_at, moderationStatus, duplicateOf, " +
+	"EXISTS (SELECT 1 FROM companies vc WHERE vc.id = jobs.companyId AND vc.verified), " +
+	"(SELECT COALESCE(json_agg(sk.slug ORDER BY sk.slug), '[]') FROM job_skills jsk JOIN skills sk ON sk.id = jsk.skill_id WHERE jsk.job_id = jobs.id), " +
+	"location, city, region, country, latitude, longitude, remote, array_to_json(remoteCountries), array_to_json(remoteTimezones), " +
+	"currency, payPeriod, salaryAnnualBase"
+
+// jobPublished restricts a query on the jobs table to published jobs of published companies
+const jobPublished = "jobs.moderationStatus = 'approved' AND " +
+	"EXISTS (SELECT 1 FROM companies mc WHERE mc.id = jobs.companyId AND mc.moderationStatus = 'approved')"
+
+// jobUpdatableFields are the columns that can be changed through UpdateJobByUserID
+var jobUpdatableFields = []string{"jobRole", "salary", "currency", "payPeriod", "location", "remote", "remoteCountries", "remoteTimezones"}
+
+// jobArrayFields are the updatable TEXT[] columns, written from JSON arrays
+var jobArrayFields = []string{"remoteCountries", "remoteTimezones"}
+
+// Listing queries, shared by the JSON listings and the streamed exports
+const (
+	allJobsQuery       = "SELECT " + jobColumns + " FROM jobs WHERE deleted_at IS NULL AND " + jobPublished + " ORDER BY id"
+	jobsByCompanyQuery = "SELECT " + jobColumns + " FROM jobs WHERE companyId = $1 AND deleted_at IS NULL AND " + jobPublished + " ORDER BY id"
+)
+
+// scanJob scans a row selected with jobColumns
+func scanJob(row interface{ Scan(...interface{}) error }) (*models.Job, error) {
+	var job models.Job
+	var duplicateOf sql.NullInt64
+	var skills, remoteCountries, remoteTimezones []byte
+	var lat, lng, annualSalary sql.NullFloat64
+	err := row.Scan(&job.ID, &job.JobRole, &job.Salary, &job.CompanyId, &job.Version, &job.CreatedAt, &job.UpdatedAt, &job.ModerationStatus,
+		&duplicateOf, &job.CompanyVerified, &skills,
+		&job.Location.Text, &job.Location.City, &job.Location.Region, &job.Location.Country, &lat, &lng,
+		&job.Remote, &remoteCountries, &remoteTimezones, &job.Currency, &job.PayPeriod, &annualSalary)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(skills, &job.Skills); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(remoteCountries, &job.RemoteCountries); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(remoteTimezones, &job.RemoteTimezones); err != nil {
+		return nil, err
+	}
+	job.Location.Latitude, job.Location.Longitude = nullableFloat(lat), nullableFloat(lng)
+	job.AnnualSalary = nullableFloat(annualSalary)
+	if duplicateOf.Valid {
+		id := int(duplicateOf.Int64)
+		job.DuplicateOf = &id
+	}
+	return &job, nil
+}
+
+// scanWithExtra scans the columns of a scan function such as scanJob, followed by extra columns into extra
+type scanWithExtra struct {
+	row   interface{ Scan(...interface{}) error }
+	extra []interface{}
 }
 
-// CreateJob creates a new job record in the database.
-func (js *JobService) CreateJob(jobRole string, salary int, companyId int) (*models.Job, error) {
-	// Convert jobRole to lowercase
-	jobRole = strings.ToLower(jobRole)
+// Scan scans the row into dest and the extra destinations
+func (s scanWithExtra) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
+
+// CreateJob creates a new job record in the database on behalf of the given user.
+func (js *JobService) CreateJob(ctx context.Context, userID int, nj models.NewJob, companyId int) (*models.Job, error) {
+	tx, err := js.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	defer tx.Rollback()
+
+	job, err := createJobTx(ctx, tx, js.moderation, js.duplicates, js.skills, js.currencies, userID, nj, companyId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	return job, nil
+}
+
+// createJobTx inserts a job inside the transaction, screening it and recording it in the audit log and outbox.
+// A flagged job is created unpublished, with a pending moderation status, and a near duplicate is linked to
+// the posting it repeats or refused, depending on the duplicate policy. The job is tagged with the skills
+// named in its role and geocoded from its location, or placed at the company address without one. Its salary is
+// in the base currency per year unless it names another currency or pay period.
+func createJobTx(ctx context.Context, tx *sql.Tx, ms *ModerationService, ds *DuplicateService, ss *SkillService, cs *CurrencyService,
+	userID int, nj models.NewJob, companyId int) (*models.Job, error) {
+	if err := checkNotSuspended(ctx, tx, userID); err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	currency, period, err := cs.normalizeSalary(nj.Currency, nj.PayPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	remoteCountries, remoteTimezones, err := normalizeRemote(nj.RemoteCountries, nj.RemoteTimezones)
+	if err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
 
 	job := models.Job{
-		JobRole:   jobRole,
-		Salary:    salary,
-		CompanyId: companyId,
+		// Convert jobRole to lowercase
+		JobRole:         strings.ToLower(nj.JobRole),
+		Salary:          nj.Salary,
+		Currency:        currency,
+		PayPeriod:       period,
+		CompanyId:       companyId,
+		Version:         1,
+		Remote:          nj.Remote,
+		RemoteCountries: remoteCountries,
+		RemoteTimezones: remoteTimezones,
+	}
+	var annualSalary sql.NullFloat64
+	countries, err := textArray(remoteCountries)
+	if err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	timezones, err := textArray(remoteTimezones)
+	if err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
 	}
 
-	// Execute the SQL query to insert a new job and retrieve the generated ID
-	row := js.db.QueryRow(`
-		INSERT INTO jobs (jobRole, salary, companyId)
-		VALUES ($1, $2, $3) RETURNING id`, jobRole, salary, companyId)
+	// Execute the SQL query to insert a new job and retrieve the generated ID, only if the company is live and
+	// owned by the user
+	row := tx.QueryRowContext(ctx, `
+		INSERT INTO jobs (jobRole, normalizedRole, salary, currency, payPeriod, companyId, location, remote, remoteCountries, remoteTimezones)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, ARRAY(SELECT jsonb_array_elements_text($9::jsonb)), ARRAY(SELECT jsonb_array_elements_text($10::jsonb))
+		WHERE EXISTS (SELECT 1 FROM companies WHERE id = $6 AND userId = $11 AND deleted_at IS NULL)
+		RETURNING id, created_at, updated_at, (SELECT verified FROM companies WHERE id = $6)`,
+		job.JobRole, dedup.NormalizeRole(job.JobRole), job.Salary, job.Currency, job.PayPeriod, companyId, strings.TrimSpace(nj.Location),
+		job.Remote, countries, timezones, userID)
 
-	err := row.Scan(&job.ID)
+	err = row.Scan(&job.ID, &job.CreatedAt, &job.UpdatedAt, &job.CompanyVerified)
 	if err != nil {
-		fmt.Println("No Company Associated with that Id")
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("create job: %w for user with ID %d and company ID %d", ErrCompanyNotFound, userID, companyId)
+		}
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	// The salary is screened per year in the base currency, so it is normalized first
+	if _, err := cs.normalizeSalaryTx(ctx, tx, job.ID); err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	if job.ModerationStatus, err = ms.screenJobTx(ctx, tx, job.ID); err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	duplicateOf, err := ds.checkJobTx(ctx, tx, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	if duplicateOf != 0 {
+		job.DuplicateOf = &duplicateOf
+	}
+	if job.Skills, err = ss.tagJobTx(ctx, tx, job.ID); err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	if job.Location, err = locateJobTx(ctx, tx, job.ID); err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT salaryAnnualBase FROM jobs WHERE id = $1", job.ID).Scan(&annualSalary); err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	job.AnnualSalary = nullableFloat(annualSalary)
+
+	// Record the new job in the audit log as part of the same transaction
+	after, err := rowJSON(ctx, tx, "SELECT row_to_json(j) FROM jobs j WHERE id = $1", job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionCreate, EntityType: audit.EntityJob, EntityID: job.ID, After: after,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	if err := outbox.Publish(ctx, tx, outbox.JobCreated, companyId, after); err != nil {
 		return nil, fmt.Errorf("create job: %w", err)
 	}
 	return &job, nil
@@ -50,8 +238,13 @@ func (js *JobService) CreateJob(jobRole string, salary int, companyId int) (*mod
 func (js *JobService) GetJobsByCompaniesID(id int) ([]*models.Job, error) {
 	var jobs []*models.Job
 
+	// An unknown company is reported, instead of listing no jobs
+	if err := checkCompanyPublished(context.Background(), js.db, id); err != nil {
+		return nil, fmt.Errorf("get jobs by company ID: %w", err)
+	}
+
 	// Execute the SQL query to select jobs by company ID
-	rows, err := js.db.Query("SELECT * FROM Jobs WHERE companyId = $1", id)
+	rows, err := js.db.Query(jobsByCompanyQuery, id)
 	if err != nil {
 		return nil, fmt.Errorf("get jobs by company ID: %w", err)
 	}
@@ -59,11 +252,11 @@ func (js *JobService) GetJobsByCompaniesID(id int) ([]*models.Job, error) {
 
 	// Iterate over the result rows and populate the jobs slice
 	for rows.Next() {
-		var job models.Job
-		if err := rows.Scan(&job.ID, &job.JobRole, &job.Salary, &job.CompanyId); err != nil {
+		job, err := scanJob(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan job: %w", err)
 		}
-		jobs = append(jobs, &job)
+		jobs = append(jobs, job)
 	}
 
 	// Check for any errors during iteration
@@ -79,7 +272,7 @@ func (js *JobService) GetAllJobs() ([]*models.Job, error) {
 	var jobs []*models.Job
 
 	// Execute the SQL query to select all jobs
-	rows, err := js.db.Query("SELECT id, jobRole, salary, companyID FROM jobs")
+	rows, err := js.db.Query(allJobsQuery)
 	if err != nil {
 		return nil, fmt.Errorf("get all jobs: %w", err)
 	}
@@ -87,11 +280,11 @@ func (js *JobService) GetAllJobs() ([]*models.Job, error) {
 
 	// Iterate over the result rows and populate the jobs slice
 	for rows.Next() {
-		var job models.Job
-		if err := rows.Scan(&job.ID, &job.JobRole, &job.Salary, &job.CompanyId); err != nil {
+		job, err := scanJob(rows)
+		if err != nil {
 			return nil, fmt.Errorf("get all jobs: %w", err)
 		}
-		jobs = append(jobs, &job)
+		jobs = append(jobs, job)
 	}
 
 	// Check for any errors during iteration
@@ -102,54 +295,350 @@ func (js *JobService) GetAllJobs() ([]*models.Job, error) {
 	return jobs, nil
 }
 
+// JobFilter narrows the published jobs listed by SearchJobs
+type JobFilter struct {
+	Skill    string // Skill is the slug or an alias of a skill, matching the jobs of the skills below it too
+	Collapse bool   // Collapse returns a single result for every group of near duplicates
+
+	Near      bool    // Near keeps the jobs within RadiusKm of Latitude and Longitude, nearest first
+	Latitude  float64 // Latitude is the point of a radius search, in decimal degrees
+	Longitude float64 // Longitude is the point of a radius search, in decimal degrees
+	RadiusKm  float64 // RadiusKm is the radius of a radius search
+
+	Country  string // Country keeps the jobs in the country and the remote jobs open to candidates living there
+	Remote   bool   // Remote keeps the jobs that can be done remotely
+	Timezone string // Timezone keeps the remote jobs open to candidates in the time zone, or one at the same offset now
+
+	MinSalary      float64 // MinSalary keeps the jobs paying at least this much a year, 0 for no minimum
+	MaxSalary      float64 // MaxSalary keeps the jobs paying at most this much a year, 0 for no maximum
+	SalaryCurrency string  // SalaryCurrency is the ISO 4217 currency of MinSalary and MaxSalary, the base currency if empty
+	SortSalary     string  // SortSalary orders the jobs by annual salary, "asc" or "desc", before the distance of a radius search
+}
+
+// Salary sort orders of JobFilter.SortSalary
+const (
+	SortSalaryAsc  = "asc"
+	SortSalaryDesc = "desc"
+)
+
+// distanceKm is the great circle distance in kilometres between a job and the point in parameters $lat and $lng,
+// by the haversine formula of geo.DistanceKm
+func distanceKm(lat, lng string) string {
+	return fmt.Sprintf("(2 * %[3]g * asin(least(1, sqrt("+
+		"power(sin(radians(jobs.latitude - %[1]s) / 2), 2) + "+
+		"cos(radians(%[1]s)) * cos(radians(jobs.latitude)) * power(sin(radians(jobs.longitude - %[2]s) / 2), 2)))))",
+		lat, lng, geo.EarthRadiusKm)
+}
+
+// SearchJobs retrieves the published jobs matching the filter like GetAllJobs. With Collapse, every group of
+// near duplicates is returned as its newest posting, with the size of the group in DuplicateCount. A radius
+// search returns the nearest jobs first, with their DistanceKm. Salary filters and sorting compare the annual
+// salaries in the base currency, leaving out jobs in a currency without an exchange rate when filtering and
+// listing them last when sorting. An unknown skill fails with ErrSkillNotFound, and a salary currency without an
+// exchange rate with money.ErrUnknownCurrency.
+func (js *JobService) SearchJobs(ctx context.Context, f JobFilter) ([]*models.Job, error) {
+	query, args, err := js.jobSearchQuery(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("search jobs: %w", err)
+	}
+	rows, err := js.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.Job{}
+	for rows.Next() {
+		job, err := scanSearchedJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("search jobs: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// ExportSearchJobs returns a function streaming the jobs matching the filter to fn through a database cursor, in
+// the order of SearchJobs. The filter is checked first, so ErrSkillNotFound and money.ErrUnknownCurrency are
+// returned before anything is streamed.
+func (js *JobService) ExportSearchJobs(ctx context.Context, f JobFilter) (func(fn func(*models.Job) error) error, error) {
+	query, args, err := js.jobSearchQuery(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("export search jobs: %w", err)
+	}
+	return func(fn func(*models.Job) error) error {
+		err := js.cursors.stream(ctx, query, args, func(rows *sql.Rows) error {
+			job, err := scanSearchedJob(rows)
+			if err != nil {
+				return err
+			}
+			return fn(job)
+		})
+		if err != nil {
+			return fmt.Errorf("export search jobs: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// scanSearchedJob scans a row of jobSearchQuery
+func scanSearchedJob(rows *sql.Rows) (*models.Job, error) {
+	var count int
+	var distance sql.NullFloat64
+	job, err := scanJob(scanWithExtra{rows, []interface{}{&count, &distance}})
+	if err != nil {
+		return nil, err
+	}
+	if count > 1 {
+		job.DuplicateCount = count
+	}
+	job.DistanceKm = nullableFloat(distance)
+	return job, nil
+}
+
+// jobSearchQuery builds the query of SearchJobs, selecting jobColumns followed by the size of the group of near
+// duplicates and the distance
+func (js *JobService) jobSearchQuery(ctx context.Context, f JobFilter) (string, []interface{}, error) {
+	where := "deleted_at IS NULL AND " + jobPublished
+	var args []interface{}
+	param := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if f.Skill != "" {
+		ids, err := js.skills.skillFilter(ctx, f.Skill)
+		if err != nil {
+			return "", nil, err
+		}
+		where += " AND EXISTS (SELECT 1 FROM job_skills fs WHERE fs.job_id = jobs.id AND fs.skill_id = ANY(" + param(ids) + "::int[]))"
+	}
+	distance, orderBy := "NULL::float8", "id"
+	if f.Near {
+		// The bounding box is answered from the coordinates index, the exact distance only checked within it
+		minLat, maxLat, minLng, maxLng := geo.BoundingBox(f.Latitude, f.Longitude, f.RadiusKm)
+		distance = distanceKm(param(f.Latitude)+"::float8", param(f.Longitude)+"::float8")
+		where += " AND jobs.latitude BETWEEN " + param(minLat) + " AND " + param(maxLat) +
+			" AND jobs.longitude BETWEEN " + param(minLng) + " AND " + param(maxLng) +
+			" AND " + distance + " <= " + param(f.RadiusKm)
+		orderBy = "distance_km, id"
+	}
+	if f.Country != "" {
+		country := param(strings.ToUpper(f.Country))
+		where += " AND (jobs.country = " + country +
+			" OR (jobs.remote AND (cardinality(jobs.remoteCountries) = 0 OR " + country + " = ANY(jobs.remoteCountries))))"
+	}
+	if f.Remote {
+		where += " AND jobs.remote"
+	}
+	if f.Timezone != "" {
+		// Zones at the same UTC offset right now share working hours, whatever their names
+		where += " AND jobs.remote AND (cardinality(jobs.remoteTimezones) = 0 OR EXISTS (" +
+			"SELECT 1 FROM unnest(jobs.remoteTimezones) tz WHERE now() AT TIME ZONE tz = now() AT TIME ZONE " + param(f.Timezone) + "))"
+	}
+	if f.MinSalary > 0 || f.MaxSalary > 0 {
+		minSalary, maxSalary, err := js.currencies.toBase(ctx, f.SalaryCurrency, f.MinSalary, f.MaxSalary)
+		if err != nil {
+			return "", nil, err
+		}
+		if f.MinSalary > 0 {
+			where += " AND jobs.salaryAnnualBase >= " + param(minSalary)
+		}
+		if f.MaxSalary > 0 {
+			where += " AND jobs.salaryAnnualBase <= " + param(maxSalary)
+		}
+	}
+	switch f.SortSalary {
+	case SortSalaryAsc:
+		orderBy = "salaryAnnualBase NULLS LAST, " + orderBy
+	case SortSalaryDesc:
+		orderBy = "salaryAnnualBase DESC NULLS LAST, " + orderBy
+	}
+
+	query := "SELECT " + jobColumns + ", 1, " + distance + " AS distance_km FROM jobs WHERE " + where + " ORDER BY " + orderBy
+	if f.Collapse {
+		// The subquery keeps the jobs name that jobColumns refer to
+		query = "SELECT " + jobColumns + ", duplicate_count, " + distance + " AS distance_km FROM (" +
+			"SELECT *, count(*) OVER (PARTITION BY COALESCE(duplicateOf, id)) AS duplicate_count, " +
+			"row_number() OVER (PARTITION BY COALESCE(duplicateOf, id) ORDER BY id DESC) AS duplicate_rank " +
+			"FROM jobs WHERE " + where + ") jobs WHERE duplicate_rank = 1 ORDER BY " + orderBy
+	}
+	return query, args, nil
+}
+
+// ConvertSalaries converts the salaries of the jobs into the currency, failing with money.ErrUnknownCurrency
+// when it has no exchange rate
+func (js *JobService) ConvertSalaries(ctx context.Context, jobs []*models.Job, currency string) error {
+	return js.currencies.ConvertSalaries(ctx, jobs, currency)
+}
+
+// ExportJobsByCompanyID streams the jobs of a company to fn through a database cursor.
+// It selects the same rows as GetJobsByCompaniesID.
+func (js *JobService) ExportJobsByCompanyID(ctx context.Context, id int, fn func(*models.Job) error) error {
+	// An unknown company is reported before any file is sent, instead of exporting an empty one
+	if err := checkCompanyPublished(ctx, js.db, id); err != nil {
+		return fmt.Errorf("export jobs by company ID: %w", err)
+	}
+	err := js.cursors.stream(ctx, jobsByCompanyQuery, []interface{}{id}, func(rows *sql.Rows) error {
+		job, err := scanJob(rows)
+		if err != nil {
+			return err
+		}
+		return fn(job)
+	})
+	if err != nil {
+		return fmt.Errorf("export jobs by company ID: %w", err)
+	}
+	return nil
+}
+
+// ExportAllJobs streams all jobs to fn through a database cursor. It selects the same rows as GetAllJobs.
+func (js *JobService) ExportAllJobs(ctx context.Context, fn func(*models.Job) error) error {
+	err := js.cursors.stream(ctx, allJobsQuery, nil, func(rows *sql.Rows) error {
+		job, err := scanJob(rows)
+		if err != nil {
+			return err
+		}
+		return fn(job)
+	})
+	if err != nil {
+		return fmt.Errorf("export all jobs: %w", err)
+	}
+	return nil
+}
+
 // GetJobsByID retrieves a job by its ID from the database.
 func (js *JobService) GetJobsByID(id int) (*models.Job, error) {
-	var job models.Job
-
 	// Execute the SQL query to select a job by ID
-	err := js.db.QueryRow("SELECT id, jobRole, salary, companyId FROM jobs WHERE id= $1", id).Scan(&job.ID, &job.JobRole, &job.Salary, &job.CompanyId)
+	job, err := scanJob(js.db.QueryRow("SELECT "+jobColumns+" FROM jobs WHERE id= $1 AND deleted_at IS NULL AND "+jobPublished, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("job not found")
 		}
 		return nil, fmt.Errorf("get job by ID: %w", err)
 	}
-	return &job, nil
+	return job, nil
 }
 
-// DeleteJobsByUserID deletes a job associated with a user from the database.
-func (js *JobService) DeleteJobsByUserID(userID, jobID int) error {
-	// Check if the job exists for the given user
-	var count int
-	err := js.db.QueryRow("SELECT COUNT(*) FROM jobs j INNER JOIN companies c ON j.companyId = c.id WHERE c.userId = $1 AND j.id = $2", userID, jobID).Scan(&count)
+// DeleteJobsByUserID soft deletes a job associated with a user. It can be restored until the purge
+// worker removes it after the retention window. A non-zero expectedVersion makes the delete
+// conditional on the job's current version.
+func (js *JobService) DeleteJobsByUserID(ctx context.Context, userID, jobID, expectedVersion int) error {
+	tx, err := js.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("delete job with ID %d: %w", jobID, err)
+	}
+	defer tx.Rollback()
+
+	// Check if the job exists for the given user, locking it and keeping its state for the audit log
+	before, err := rowJSON(ctx, tx, `
+		SELECT row_to_json(j) FROM jobs j INNER JOIN companies c ON j.companyId = c.id
+		WHERE c.userId = $1 AND j.id = $2 AND j.deleted_at IS NULL FOR UPDATE OF j`, userID, jobID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("job not found for user with ID %d and job ID %d", userID, jobID)
+		}
 		return fmt.Errorf("query job existence: %w", err)
 	}
 
-	if count == 0 {
-		return fmt.Errorf("job not found for user with ID %d and job ID %d", userID, jobID)
+	// Mark the job deleted, checking the version in the same statement
+	after, err := rowJSON(ctx, tx, `
+		UPDATE jobs j SET deleted_at = now(), version = version + 1, updated_at = now()
+		WHERE id = $1 AND ($2 = 0 OR version = $2) RETURNING row_to_json(j)`, jobID, expectedVersion)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("delete job with ID %d: %w", jobID, ErrVersionMismatch)
+		}
+		return fmt.Errorf("delete job with ID %d: %w", jobID, err)
 	}
 
-	// Delete the job
-	_, err = js.db.Exec("DELETE FROM jobs WHERE id = $1", jobID)
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionDelete, EntityType: audit.EntityJob, EntityID: jobID, Before: before, After: after,
+	})
 	if err != nil {
 		return fmt.Errorf("delete job with ID %d: %w", jobID, err)
 	}
+	if err := publishJobEvent(ctx, tx, outbox.JobDeleted, before); err != nil {
+		return fmt.Errorf("delete job with ID %d: %w", jobID, err)
+	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("delete job with ID %d: %w", jobID, err)
+	}
 	return nil
 }
 
-// UpdateJobByUserID updates a job associated with a user in the database.
-func (js *JobService) UpdateJobByUserID(userID, jobID int, updates map[string]interface{}) error {
-	// Check if the job exists for the given user
-	var count int
-	err := js.db.QueryRow("SELECT COUNT(*) FROM jobs j INNER JOIN companies c ON j.companyId = c.id WHERE c.userId = $1 AND j.id = $2", userID, jobID).Scan(&count)
+// RestoreJobByUserID restores a soft deleted job of the user deleted within the retention period.
+// Jobs of a deleted company are restored by restoring the company.
+func (js *JobService) RestoreJobByUserID(ctx context.Context, userID, jobID int) error {
+	tx, err := js.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("query job existence: %w", err)
+		return fmt.Errorf("restore job with ID %d: %w", jobID, err)
+	}
+	defer tx.Rollback()
+
+	after, err := rowJSON(ctx, tx, `
+		UPDATE jobs j SET deleted_at = NULL, version = j.version + 1, updated_at = now()
+		FROM companies c
+		WHERE j.companyId = c.id AND c.userId = $1 AND j.id = $2
+		  AND c.deleted_at IS NULL AND j.deleted_at > $3
+		RETURNING row_to_json(j)`, userID, jobID, time.Now().Add(-js.retention))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("no restorable job for user with ID %d and job ID %d", userID, jobID)
+		}
+		return fmt.Errorf("restore job with ID %d: %w", jobID, err)
+	}
+
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionRestore, EntityType: audit.EntityJob, EntityID: jobID, After: after,
+	})
+	if err != nil {
+		return fmt.Errorf("restore job with ID %d: %w", jobID, err)
+	}
+	if err := publishJobEvent(ctx, tx, outbox.JobRestored, after); err != nil {
+		return fmt.Errorf("restore job with ID %d: %w", jobID, err)
 	}
 
-	if count == 0 {
-		return fmt.Errorf("job not found for user with ID %d and job ID %d", userID, jobID)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("restore job with ID %d: %w", jobID, err)
+	}
+	return nil
+}
+
+// UpdateJobByUserID updates a job associated with a user in the database. It returns the earlier posting the
+// edited job nearly duplicates, 0 if none. A non-zero expectedVersion makes the update conditional on the
+// job's current version.
+func (js *JobService) UpdateJobByUserID(ctx context.Context, userID, jobID, expectedVersion int, updates map[string]interface{}) (int, error) {
+	if err := checkUpdatableFields(updates, jobUpdatableFields); err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	}
+	updates, err := js.prepareJobUpdates(updates)
+	if err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	}
+
+	tx, err := js.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	}
+	defer tx.Rollback()
+
+	if err := checkNotSuspended(ctx, tx, userID); err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	}
+
+	// Check if the job exists for the given user, locking it and keeping its state for the audit log
+	before, err := rowJSON(ctx, tx, `
+		SELECT row_to_json(j) FROM jobs j INNER JOIN companies c ON j.companyId = c.id
+		WHERE c.userId = $1 AND j.id = $2 AND j.deleted_at IS NULL FOR UPDATE OF j`, userID, jobID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("job not found for user with ID %d and job ID %d", userID, jobID)
+		}
+		return 0, fmt.Errorf("query job existence: %w", err)
 	}
 
 	// Build the UPDATE query dynamically based on the fields provided in the updates map
@@ -158,24 +647,175 @@ func (js *JobService) UpdateJobByUserID(userID, jobID int, updates map[string]in
 
 	i := 1
 	for key, value := range updates {
-		// Append each field to the query
-		query += key + " = $" + strconv.Itoa(i) + ", "
+		// Append each field to the query, arrays are sent as JSON
+		if isField(key, jobArrayFields) {
+			query += key + " = ARRAY(SELECT jsonb_array_elements_text($" + strconv.Itoa(i) + "::jsonb)), "
+		} else {
+			query += key + " = $" + strconv.Itoa(i) + ", "
+		}
 		values = append(values, value)
 		i++
 	}
 
-	// Remove the trailing comma and space from the query
-	query = query[:len(query)-2]
+	// Every update bumps the version used for optimistic concurrency
+	query += "version = version + 1, updated_at = now()"
 
-	// Add the WHERE conditions to update the specific job
+	// Add the WHERE conditions to update the specific job, at the expected version
 	query += " WHERE id = $" + strconv.Itoa(i)
 	values = append(values, jobID)
+	if expectedVersion != 0 {
+		query += " AND version = $" + strconv.Itoa(i+1)
+		values = append(values, expectedVersion)
+	}
 
 	// Execute the dynamic UPDATE query
-	_, err = js.db.Exec(query, values...)
+	res, err := tx.ExecContext(ctx, query, values...)
+	if err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	} else if n == 0 {
+		// The row is locked and exists, so only the version can have failed to match
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, ErrVersionMismatch)
+	}
+	// The salary is screened per year in the base currency, so it is normalized first
+	if _, err := js.currencies.normalizeSalaryTx(ctx, tx, jobID); err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	}
+	if _, err := js.moderation.screenJobTx(ctx, tx, jobID); err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	}
+	duplicateOf, err := js.duplicates.checkJobTx(ctx, tx, jobID)
 	if err != nil {
-		return fmt.Errorf("update job with ID %d: %w", jobID, err)
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	}
+	if _, err := js.skills.tagJobTx(ctx, tx, jobID); err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	}
+	if _, err := locateJobTx(ctx, tx, jobID); err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
 	}
 
-	return nil
+	// Record what changed in the audit log as part of the same transaction
+	after, err := rowJSON(ctx, tx, "SELECT row_to_json(j) FROM jobs j WHERE id = $1", jobID)
+	if err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionUpdate, EntityType: audit.EntityJob, EntityID: jobID, Before: before, After: after,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	}
+	if err := publishJobEvent(ctx, tx, outbox.JobUpdated, after); err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("update job with ID %d: %w", jobID, err)
+	}
+	return duplicateOf, nil
+}
+
+// prepareJobUpdates validates a job update and returns the column values to write. The remote restrictions are
+// checked like on creation and encoded as JSON arrays, a new role is lowercased like on creation and also
+// renormalized, and the salary, currency and pay period are checked like on creation.
+func (js *JobService) prepareJobUpdates(updates map[string]interface{}) (map[string]interface{}, error) {
+	prepared := make(map[string]interface{}, len(updates)+1)
+	var countries, timezones []string
+	for key, value := range updates {
+		switch strings.ToLower(key) {
+		case "jobrole":
+			role, ok := value.(string)
+			if !ok || strings.TrimSpace(role) == "" {
+				return nil, errors.New("jobRole must be a non-empty string")
+			}
+			prepared[key] = strings.ToLower(role)
+			prepared["normalizedRole"] = dedup.NormalizeRole(role)
+		case "salary":
+			// JSON numbers decode as float64, a salary must be a positive int
+			salary, ok := value.(float64)
+			if !ok || salary <= 0 || salary != math.Trunc(salary) || salary > math.MaxInt32 {
+				return nil, ErrInvalidSalaryAmount
+			}
+			prepared[key] = int(salary)
+		case "currency":
+			currency, ok := value.(string)
+			if !ok || strings.TrimSpace(currency) == "" {
+				return nil, ErrInvalidSalary
+			}
+			currency, _, err := js.currencies.normalizeSalary(currency, "")
+			if err != nil {
+				return nil, err
+			}
+			prepared[key] = currency
+		case "payperiod":
+			period, ok := value.(string)
+			if !ok || strings.TrimSpace(period) == "" {
+				return nil, ErrInvalidSalary
+			}
+			_, period, err := js.currencies.normalizeSalary("", period)
+			if err != nil {
+				return nil, err
+			}
+			prepared[key] = period
+		case "location":
+			location, ok := value.(string)
+			if !ok || len(location) > 200 {
+				return nil, errors.New("location must be a string of at most 200 characters")
+			}
+			prepared[key] = strings.TrimSpace(location)
+		case "remote":
+			if _, ok := value.(bool); !ok {
+				return nil, errors.New("remote must be a boolean")
+			}
+			prepared[key] = value
+		case "remotecountries", "remotetimezones":
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			var list []string
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return nil, fmt.Errorf("%s must be an array of strings", key)
+			}
+			if strings.EqualFold(key, "remoteCountries") {
+				countries = list
+			} else {
+				timezones = list
+			}
+		default:
+			return nil, fmt.Errorf("field %q cannot be updated", key)
+		}
+	}
+	countries, timezones, err := normalizeRemote(countries, timezones)
+	if err != nil {
+		return nil, err
+	}
+	for key := range updates {
+		list := countries
+		if strings.EqualFold(key, "remoteTimezones") {
+			list = timezones
+		} else if !strings.EqualFold(key, "remoteCountries") {
+			continue
+		}
+		encoded, err := textArray(list)
+		if err != nil {
+			return nil, err
+		}
+		prepared[key] = encoded
+	}
+	return prepared, nil
+}
+
+// publishJobEvent publishes a job event to the outbox for the company the job row belongs to.
+func publishJobEvent(ctx context.Context, tx *sql.Tx, eventType string, row json.RawMessage) error {
+	var job struct {
+		CompanyID int `json:"companyid"`
+	}
+	if err := json.Unmarshal(row, &job); err != nil {
+		return fmt.Errorf("publish %s: %w", eventType, err)
+	}
+	return outbox.Publish(ctx, tx, eventType, job.CompanyID, row)
 }
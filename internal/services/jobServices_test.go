@@ -0,0 +1,51 @@
+package services
+
+import (
+	"errors"
+	"job-portal-api/internal/dedup"
+	"reflect"
+	"testing"
+)
+
+func TestPrepareJobUpdates(t *testing.T) {
+	js := &JobService{currencies: &CurrencyService{cfg: CurrencyConfig{Base: "USD"}}}
+	tests := []struct {
+		desc    string
+		updates map[string]interface{}
+		want    map[string]interface{}
+		err     error
+	}{
+		{"role", map[string]interface{}{"jobRole": "Sr. Go Developer"},
+			map[string]interface{}{"jobRole": "sr. go developer", "normalizedRole": dedup.NormalizeRole("Sr. Go Developer")}, nil},
+		{"salary", map[string]interface{}{"salary": float64(85000)}, map[string]interface{}{"salary": 85000}, nil},
+		{"salary any case", map[string]interface{}{"Salary": float64(1)}, map[string]interface{}{"Salary": 1}, nil},
+		{"currency and period", map[string]interface{}{"currency": " eur", "payPeriod": "Month"},
+			map[string]interface{}{"currency": "EUR", "payPeriod": "month"}, nil},
+		{"zero salary", map[string]interface{}{"salary": float64(0)}, nil, ErrInvalidSalaryAmount},
+		{"negative salary", map[string]interface{}{"salary": float64(-5)}, nil, ErrInvalidSalaryAmount},
+		{"fractional salary", map[string]interface{}{"salary": 12.5}, nil, ErrInvalidSalaryAmount},
+		{"huge salary", map[string]interface{}{"salary": 1e12}, nil, ErrInvalidSalaryAmount},
+		{"salary as text", map[string]interface{}{"salary": "85000"}, nil, ErrInvalidSalaryAmount},
+		{"salary null", map[string]interface{}{"salary": nil}, nil, ErrInvalidSalaryAmount},
+		{"unknown currency", map[string]interface{}{"currency": "EURO"}, nil, ErrInvalidSalary},
+	}
+	for _, tt := range tests {
+		got, err := js.prepareJobUpdates(tt.updates)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("prepareJobUpdates(%s) error = %v, want %v", tt.desc, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("prepareJobUpdates(%s) = %v, %v, want %v", tt.desc, got, err, tt.want)
+		}
+	}
+
+	if _, err := js.prepareJobUpdates(map[string]interface{}{"jobRole": "  "}); err == nil {
+		t.Error("prepareJobUpdates(blank role) succeeded, want an error")
+	}
+	if _, err := js.prepareJobUpdates(map[string]interface{}{"companyId": float64(9)}); err == nil {
+		t.Error("prepareJobUpdates(companyId) succeeded, want an error")
+	}
+}
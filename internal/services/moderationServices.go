@@ -0,0 +1,620 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/audit"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/moderation"
+	"job-portal-api/internal/outbox"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ErrUserSuspended is returned when a suspended user tries to post or edit jobs and companies
+var ErrUserSuspended = errors.New("account suspended")
+
+// ErrReportExists is returned when the user already has an open report on the job or company
+var ErrReportExists = errors.New("already reported")
+
+// Entity types of moderated content
+const (
+	moderatedJob     = "job"
+	moderatedCompany = "company"
+)
+
+// duplicateMinLength is the shortest text checked for duplicates, shorter texts such as job titles repeat legitimately
+const duplicateMinLength = 80
+
+// foldedMD5 is the SQL expression hashing whitespace and case folded text, matching the duplicate lookup indexes
+const foldedMD5 = "md5(lower(regexp_replace(btrim(%s), '\\s+', ' ', 'g')))"
+
+// ModerationConfig represents the configuration of the review queue
+type ModerationConfig struct {
+	ReportThreshold int           // ReportThreshold is the number of open abuse reports that sends published content back to review
+	SuspendAfter    int           // SuspendAfter is the number of rejections within SuspendWindow that suspends the owner
+	SuspendWindow   time.Duration // SuspendWindow is how far back rejections are counted
+}
+
+// DefaultModerationConfig returns the review queue configuration from MODERATION_REPORT_THRESHOLD (default 3),
+// MODERATION_SUSPEND_AFTER (default 3) and MODERATION_SUSPEND_WINDOW_DAYS (default 90).
+func DefaultModerationConfig() ModerationConfig {
+	cfg := ModerationConfig{ReportThreshold: 3, SuspendAfter: 3, SuspendWindow: 90 * 24 * time.Hour}
+	if n, err := strconv.Atoi(os.Getenv("MODERATION_REPORT_THRESHOLD")); err == nil && n > 0 {
+		cfg.ReportThreshold = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("MODERATION_SUSPEND_AFTER")); err == nil && n > 0 {
+		cfg.SuspendAfter = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("MODERATION_SUSPEND_WINDOW_DAYS")); err == nil && n > 0 {
+		cfg.SuspendWindow = time.Duration(n) * 24 * time.Hour
+	}
+	return cfg
+}
+
+// ModerationService screens jobs and companies with the rule engine, keeps the review queue and abuse reports,
+// and suspends users after repeated violations
+type ModerationService struct {
+	db     *sql.DB
+	engine *moderation.Engine
+	cfg    ModerationConfig
+}
+
+// NewModerationService creates a new ModerationService with the provided dependencies
+func NewModerationService(db *sql.DB, engine *moderation.Engine, cfg ModerationConfig) (*ModerationService, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	if engine == nil {
+		return nil, errors.New("moderation engine cannot be nil")
+	}
+	if cfg.ReportThreshold <= 0 || cfg.SuspendAfter <= 0 || cfg.SuspendWindow <= 0 {
+		return nil, errors.New("moderation thresholds must be positive")
+	}
+	return &ModerationService{db: db, engine: engine, cfg: cfg}, nil
+}
+
+// checkNotSuspended returns ErrUserSuspended if the user is suspended
+func checkNotSuspended(ctx context.Context, tx *sql.Tx, userID int) error {
+	var suspended bool
+	err := tx.QueryRowContext(ctx, "SELECT suspended_at IS NOT NULL FROM users WHERE id = $1", userID).Scan(&suspended)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if suspended {
+		return ErrUserSuspended
+	}
+	return nil
+}
+
+// duplicateRule looks long texts up among the live jobs and companies of other users
+func duplicateRule(tx *sql.Tx) moderation.Rule {
+	return moderation.DuplicateRule{
+		MinLength: duplicateMinLength,
+		Exists: func(ctx context.Context, c moderation.Content, f moderation.Field) (bool, error) {
+			var query string
+			switch {
+			case c.EntityType == moderatedJob && f.Name == "jobRole":
+				query = "SELECT EXISTS (SELECT 1 FROM jobs j JOIN companies c ON c.id = j.companyId WHERE " +
+					fmt.Sprintf(foldedMD5, "j.jobRole") + " = " + fmt.Sprintf(foldedMD5, "$1") +
+					" AND c.userId <> $2 AND j.id <> $3 AND j.deleted_at IS NULL)"
+			case c.EntityType == moderatedCompany && f.Name == "description":
+				query = "SELECT EXISTS (SELECT 1 FROM companies WHERE " +
+					fmt.Sprintf(foldedMD5, "description") + " = " + fmt.Sprintf(foldedMD5, "$1") +
+					" AND userId <> $2 AND id <> $3 AND deleted_at IS NULL)"
+			default:
+				return false, nil
+			}
+			var exists bool
+			err := tx.QueryRowContext(ctx, query, f.Text, c.UserID, c.EntityID).Scan(&exists)
+			return exists, err
+		},
+	}
+}
+
+// screenJobTx evaluates a job after it was created or edited in the transaction and returns its moderation status.
+// The salary is checked per year in the base currency, so normalizeSalaryTx must have run before.
+// A flagged job is unpublished and queued; an edited rejected job is queued again even without findings, since an
+// operator turned it down before.
+func (ms *ModerationService) screenJobTx(ctx context.Context, tx *sql.Tx, jobID int) (string, error) {
+	var jobRole string
+	var annualSalary sql.NullFloat64
+	var companyID, ownerID int
+	var status string
+	err := tx.QueryRowContext(ctx, `
+		SELECT j.jobRole, j.salaryAnnualBase, j.companyId, c.userId, j.moderationStatus
+		FROM jobs j JOIN companies c ON c.id = j.companyId WHERE j.id = $1`, jobID).Scan(&jobRole, &annualSalary, &companyID, &ownerID, &status)
+	if err != nil {
+		return "", fmt.Errorf("screen job: %w", err)
+	}
+
+	findings, err := ms.engine.Evaluate(ctx, moderation.Content{
+		EntityType: moderatedJob, EntityID: jobID, UserID: ownerID, AnnualSalary: nullableFloat(annualSalary),
+		Fields: []moderation.Field{{Name: "jobRole", Text: jobRole, CheckContact: true}},
+	}, duplicateRule(tx))
+	if err != nil {
+		return "", fmt.Errorf("screen job: %w", err)
+	}
+	return queueTx(ctx, tx, moderatedJob, jobID, companyID, ownerID, status, findings)
+}
+
+// screenCompanyTx evaluates a company after it was created or edited in the transaction, like screenJobTx.
+func (ms *ModerationService) screenCompanyTx(ctx context.Context, tx *sql.Tx, companyID int) (string, error) {
+	var name, address, description string
+	var ownerID int
+	var status string
+	err := tx.QueryRowContext(ctx, "SELECT name, address, description, userId, moderationStatus FROM companies WHERE id = $1",
+		companyID).Scan(&name, &address, &description, &ownerID, &status)
+	if err != nil {
+		return "", fmt.Errorf("screen company: %w", err)
+	}
+
+	findings, err := ms.engine.Evaluate(ctx, moderation.Content{
+		EntityType: moderatedCompany, EntityID: companyID, UserID: ownerID,
+		Fields: []moderation.Field{
+			{Name: "name", Text: name},
+			{Name: "address", Text: address},
+			{Name: "description", Text: description, CheckContact: true},
+		},
+	}, duplicateRule(tx))
+	if err != nil {
+		return "", fmt.Errorf("screen company: %w", err)
+	}
+	return queueTx(ctx, tx, moderatedCompany, companyID, companyID, ownerID, status, findings)
+}
+
+// queueTx unpublishes screened content and adds it to the review queue when needed, returning its moderation status
+func queueTx(ctx context.Context, tx *sql.Tx, entityType string, entityID, companyID, ownerID int,
+	status string, findings []models.ModerationFinding) (string, error) {
+	source := models.ModerationSourceRules
+	switch {
+	case status == models.ModerationRejected:
+		source = models.ModerationSourceResubmit
+	case status == models.ModerationSuspended:
+		// Suspended content stays hidden until the owner is reinstated
+		return status, nil
+	case len(findings) == 0:
+		return status, nil
+	}
+
+	if status != models.ModerationPending {
+		if err := setModerationStatus(ctx, tx, entityType, entityID, models.ModerationPending); err != nil {
+			return "", fmt.Errorf("queue %s: %w", entityType, err)
+		}
+	}
+	if err := enqueueTx(ctx, tx, entityType, entityID, companyID, ownerID, source, findings); err != nil {
+		return "", fmt.Errorf("queue %s: %w", entityType, err)
+	}
+	return models.ModerationPending, nil
+}
+
+// enqueueTx adds content to the review queue, or refreshes its pending item
+func enqueueTx(ctx context.Context, tx *sql.Tx, entityType string, entityID, companyID, ownerID int, source string,
+	findings []models.ModerationFinding) error {
+	encoded, err := json.Marshal(findings)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO moderation_items (entity_type, entity_id, company_id, user_id, source, findings)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (entity_type, entity_id) WHERE status = 'pending'
+		DO UPDATE SET source = EXCLUDED.source, findings = EXCLUDED.findings`,
+		entityType, entityID, companyID, ownerID, source, string(encoded))
+	return err
+}
+
+// setModerationStatus changes the moderation status of a job or company. The change of a job, or of the
+// jobs of a company, bumps their updated_at so feeds notice them appearing or disappearing.
+func setModerationStatus(ctx context.Context, tx *sql.Tx, entityType string, entityID int, status string) error {
+	var err error
+	if entityType == moderatedJob {
+		_, err = tx.ExecContext(ctx, "UPDATE jobs SET moderationStatus = $1, updated_at = now() WHERE id = $2", status, entityID)
+	} else {
+		_, err = tx.ExecContext(ctx, "UPDATE companies SET moderationStatus = $1 WHERE id = $2", status, entityID)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, "UPDATE jobs SET updated_at = now() WHERE companyId = $1 AND deleted_at IS NULL", entityID)
+		}
+	}
+	return err
+}
+
+// moderationItemAuditQuery selects a review queue item for the audit log
+const moderationItemAuditQuery = "SELECT row_to_json(m) FROM moderation_items m WHERE id = $1"
+
+// suspensionAuditQuery selects the suspension of a user for the audit log, leaving the credentials out
+const suspensionAuditQuery = "SELECT json_build_object('suspended_at', suspended_at, 'suspension_reason', suspension_reason) FROM users WHERE id = $1"
+
+// moderationItemColumns are the columns read into models.ModerationItem, in the order scanModerationItem expects
+const moderationItemColumns = "id, entity_type, entity_id, company_id, user_id, source, findings, status, reason, reviewed_by, reviewed_at, created_at"
+
+// scanModerationItem scans a row selected with moderationItemColumns
+func scanModerationItem(row interface{ Scan(...interface{}) error }) (*models.ModerationItem, error) {
+	var item models.ModerationItem
+	var findings []byte
+	var reviewedBy sql.NullInt64
+	var reviewedAt sql.NullTime
+	err := row.Scan(&item.ID, &item.EntityType, &item.EntityId, &item.CompanyId, &item.UserId, &item.Source, &findings,
+		&item.Status, &item.Reason, &reviewedBy, &reviewedAt, &item.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(findings, &item.Findings); err != nil {
+		return nil, fmt.Errorf("decode findings: %w", err)
+	}
+	if reviewedBy.Valid {
+		id := int(reviewedBy.Int64)
+		item.ReviewedBy = &id
+	}
+	if reviewedAt.Valid {
+		item.ReviewedAt = &reviewedAt.Time
+	}
+	return &item, nil
+}
+
+// ListQueue lists review queue items in a status for operators, oldest first. It defaults to the pending items.
+func (ms *ModerationService) ListQueue(ctx context.Context, status string) ([]*models.ModerationItem, error) {
+	if status == "" {
+		status = models.ModerationPending
+	}
+	return ms.listItems(ctx, "SELECT "+moderationItemColumns+" FROM moderation_items WHERE status = $1 ORDER BY id LIMIT 500", status)
+}
+
+// GetCompanyItems lists the review queue items of a company owned by the user, newest first, so owners can see
+// why their content is not published.
+func (ms *ModerationService) GetCompanyItems(ctx context.Context, userID, companyID int) ([]*models.ModerationItem, error) {
+	var owned bool
+	err := ms.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM companies WHERE userId = $1 AND id = $2 AND deleted_at IS NULL)",
+		userID, companyID).Scan(&owned)
+	if err != nil {
+		return nil, fmt.Errorf("get moderation items: %w", err)
+	}
+	if !owned {
+		return nil, fmt.Errorf("company not found for user with ID %d and company ID %d", userID, companyID)
+	}
+	return ms.listItems(ctx, "SELECT "+moderationItemColumns+" FROM moderation_items WHERE company_id = $1 ORDER BY id DESC LIMIT 100", companyID)
+}
+
+// listItems runs a query selecting moderationItemColumns
+func (ms *ModerationService) listItems(ctx context.Context, query string, args ...interface{}) ([]*models.ModerationItem, error) {
+	rows, err := ms.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list moderation items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*models.ModerationItem{}
+	for rows.Next() {
+		item, err := scanModerationItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list moderation items: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list moderation items: %w", err)
+	}
+	return items, nil
+}
+
+// entityAuditQuery returns the audit query and entity type of a moderated job or company
+func entityAuditQuery(entityType string) (string, string) {
+	if entityType == moderatedJob {
+		return "SELECT row_to_json(j) FROM jobs j WHERE id = $1", audit.EntityJob
+	}
+	return "SELECT row_to_json(c) FROM companies c WHERE id = $1", audit.EntityCompany
+}
+
+// Decide records an operator's decision on a pending review queue item. Approving publishes the content and
+// dismisses its open abuse reports; rejecting keeps it unpublished, upholds the reports and counts as a violation
+// of the owner, who is suspended after SuspendAfter violations within SuspendWindow.
+func (ms *ModerationService) Decide(ctx context.Context, operatorID, itemID int, approve bool, reason string) (*models.ModerationItem, error) {
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decide moderation item: %w", err)
+	}
+	defer tx.Rollback()
+
+	var companyID int
+	err = tx.QueryRowContext(ctx, "SELECT company_id FROM moderation_items WHERE id = $1", itemID).Scan(&companyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("moderation item %d not found", itemID)
+		}
+		return nil, fmt.Errorf("decide moderation item: %w", err)
+	}
+	if err := lockCompany(ctx, tx, companyID); err != nil {
+		return nil, fmt.Errorf("decide moderation item: %w", err)
+	}
+	item, err := scanModerationItem(tx.QueryRowContext(ctx, "SELECT "+moderationItemColumns+" FROM moderation_items WHERE id = $1 FOR UPDATE", itemID))
+	if err != nil {
+		return nil, fmt.Errorf("decide moderation item: %w", err)
+	}
+	if item.Status != models.ModerationPending {
+		return nil, fmt.Errorf("decide moderation item: %w", ErrInvalidTransition)
+	}
+	itemBefore, err := rowJSON(ctx, tx, moderationItemAuditQuery, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("decide moderation item: %w", err)
+	}
+
+	newStatus, reportStatus := models.ModerationRejected, models.ReportUpheld
+	if approve {
+		newStatus, reportStatus = models.ModerationApproved, models.ReportDismissed
+	}
+	decided, err := scanModerationItem(tx.QueryRowContext(ctx, `
+		UPDATE moderation_items SET status = $1, reason = $2, reviewed_by = $3, reviewed_at = now() WHERE id = $4
+		RETURNING `+moderationItemColumns, newStatus, reason, operatorID, itemID))
+	if err != nil {
+		return nil, fmt.Errorf("decide moderation item: %w", err)
+	}
+	itemAfter, err := rowJSON(ctx, tx, moderationItemAuditQuery, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("decide moderation item: %w", err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: operatorID, Action: audit.ActionUpdate, EntityType: audit.EntityModerationItem, EntityID: itemID,
+		Before: itemBefore, After: itemAfter,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decide moderation item: %w", err)
+	}
+
+	// Publish or keep hiding the content, unless it was suspended or deleted meanwhile
+	query, auditEntity := entityAuditQuery(item.EntityType)
+	before, err := rowJSON(ctx, tx, query, item.EntityId)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("decide moderation item: %w", err)
+	}
+	var current struct {
+		Status string `json:"moderationstatus"`
+	}
+	if before != nil {
+		if err := json.Unmarshal(before, &current); err != nil {
+			return nil, fmt.Errorf("decide moderation item: %w", err)
+		}
+	}
+	if current.Status == models.ModerationPending {
+		if err := setModerationStatus(ctx, tx, item.EntityType, item.EntityId, newStatus); err != nil {
+			return nil, fmt.Errorf("decide moderation item: %w", err)
+		}
+		if err := recordModerationChange(ctx, tx, operatorID, item.EntityType, item.EntityId, companyID, query, auditEntity, before); err != nil {
+			return nil, fmt.Errorf("decide moderation item: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE abuse_reports SET status = $1 WHERE entity_type = $2 AND entity_id = $3 AND status = $4",
+		reportStatus, item.EntityType, item.EntityId, models.ReportOpen)
+	if err != nil {
+		return nil, fmt.Errorf("decide moderation item: %w", err)
+	}
+
+	if !approve {
+		var violations int
+		err = tx.QueryRowContext(ctx, "SELECT count(*) FROM moderation_items WHERE user_id = $1 AND status = $2 AND reviewed_at > $3",
+			item.UserId, models.ModerationRejected, time.Now().Add(-ms.cfg.SuspendWindow)).Scan(&violations)
+		if err != nil {
+			return nil, fmt.Errorf("decide moderation item: %w", err)
+		}
+		if violations >= ms.cfg.SuspendAfter {
+			reason := fmt.Sprintf("%d moderation violations", violations)
+			if err := ms.suspendTx(ctx, tx, operatorID, item.UserId, reason); err != nil {
+				return nil, fmt.Errorf("decide moderation item: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("decide moderation item: %w", err)
+	}
+	return decided, nil
+}
+
+// recordModerationChange records a moderation status change of a job or company in the audit log and outbox
+func recordModerationChange(ctx context.Context, tx *sql.Tx, actorID int, entityType string, entityID, companyID int,
+	query, auditEntity string, before json.RawMessage) error {
+	after, err := rowJSON(ctx, tx, query, entityID)
+	if err != nil {
+		return err
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: actorID, Action: audit.ActionUpdate, EntityType: auditEntity, EntityID: entityID, Before: before, After: after,
+	})
+	if err != nil {
+		return err
+	}
+	eventType := outbox.CompanyUpdated
+	if entityType == moderatedJob {
+		eventType = outbox.JobUpdated
+	}
+	return outbox.Publish(ctx, tx, eventType, companyID, after)
+}
+
+// suspendTx suspends a user and hides their published companies and jobs. Already suspended users are left alone.
+func (ms *ModerationService) suspendTx(ctx context.Context, tx *sql.Tx, actorID, userID int, reason string) error {
+	before, err := rowJSON(ctx, tx, suspensionAuditQuery, userID)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, "UPDATE users SET suspended_at = now(), suspension_reason = $1 WHERE id = $2 AND suspended_at IS NULL",
+		reason, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if err := ms.setOwnerContentStatus(ctx, tx, userID, models.ModerationApproved, models.ModerationSuspended); err != nil {
+		return err
+	}
+	after, err := rowJSON(ctx, tx, suspensionAuditQuery, userID)
+	if err != nil {
+		return err
+	}
+	return audit.Record(ctx, tx, audit.Event{
+		ActorID: actorID, Action: audit.ActionUpdate, EntityType: audit.EntityUser, EntityID: userID, Before: before, After: after,
+	})
+}
+
+// setOwnerContentStatus moves the companies of a user, and the jobs of those companies, from one moderation
+// status to another
+func (ms *ModerationService) setOwnerContentStatus(ctx context.Context, tx *sql.Tx, userID int, from, to string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE jobs SET moderationStatus = $1, updated_at = now()
+		WHERE moderationStatus = $2 AND companyId IN (SELECT id FROM companies WHERE userId = $3)`, to, from, userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE companies SET moderationStatus = $1 WHERE moderationStatus = $2 AND userId = $3", to, from, userID)
+	return err
+}
+
+// Unsuspend lifts the suspension of a user and publishes the content hidden by it again.
+func (ms *ModerationService) Unsuspend(ctx context.Context, operatorID, userID int) error {
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unsuspend user: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := rowJSON(ctx, tx, suspensionAuditQuery, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("user %d not found", userID)
+		}
+		return fmt.Errorf("unsuspend user: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, "UPDATE users SET suspended_at = NULL, suspension_reason = '' WHERE id = $1 AND suspended_at IS NOT NULL", userID)
+	if err != nil {
+		return fmt.Errorf("unsuspend user: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("unsuspend user: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("unsuspend user %d: %w", userID, ErrInvalidTransition)
+	}
+	if err := ms.setOwnerContentStatus(ctx, tx, userID, models.ModerationSuspended, models.ModerationApproved); err != nil {
+		return fmt.Errorf("unsuspend user: %w", err)
+	}
+	after, err := rowJSON(ctx, tx, suspensionAuditQuery, userID)
+	if err != nil {
+		return fmt.Errorf("unsuspend user: %w", err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: operatorID, Action: audit.ActionUpdate, EntityType: audit.EntityUser, EntityID: userID, Before: before, After: after,
+	})
+	if err != nil {
+		return fmt.Errorf("unsuspend user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unsuspend user: %w", err)
+	}
+	return nil
+}
+
+// ReportJob records a user's abuse report on a published job.
+func (ms *ModerationService) ReportJob(ctx context.Context, userID, jobID int, nr models.NewAbuseReport) (*models.AbuseReport, error) {
+	return ms.report(ctx, userID, moderatedJob, jobID, nr)
+}
+
+// ReportCompany records a user's abuse report on a published company.
+func (ms *ModerationService) ReportCompany(ctx context.Context, userID, companyID int, nr models.NewAbuseReport) (*models.AbuseReport, error) {
+	return ms.report(ctx, userID, moderatedCompany, companyID, nr)
+}
+
+// report records an abuse report. Once ReportThreshold users have open reports on the content it is unpublished
+// and queued for review.
+func (ms *ModerationService) report(ctx context.Context, userID int, entityType string, entityID int, nr models.NewAbuseReport) (*models.AbuseReport, error) {
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("report %s: %w", entityType, err)
+	}
+	defer tx.Rollback()
+
+	// Only published content can be reported
+	lookup := "SELECT id, userId FROM companies WHERE id = $1 AND deleted_at IS NULL AND moderationStatus = 'approved'"
+	if entityType == moderatedJob {
+		lookup = `SELECT j.companyId, c.userId FROM jobs j JOIN companies c ON c.id = j.companyId
+			WHERE j.id = $1 AND j.deleted_at IS NULL AND j.moderationStatus = 'approved'`
+	}
+	var companyID, ownerID int
+	if err := tx.QueryRowContext(ctx, lookup, entityID).Scan(&companyID, &ownerID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%s %d not found", entityType, entityID)
+		}
+		return nil, fmt.Errorf("report %s: %w", entityType, err)
+	}
+	if err := lockCompany(ctx, tx, companyID); err != nil {
+		return nil, fmt.Errorf("report %s: %w", entityType, err)
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM abuse_reports WHERE entity_type = $1 AND entity_id = $2 AND reporter_id = $3 AND status = $4)",
+		entityType, entityID, userID, models.ReportOpen).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("report %s: %w", entityType, err)
+	}
+	if exists {
+		return nil, fmt.Errorf("report %s: %w", entityType, ErrReportExists)
+	}
+
+	var report models.AbuseReport
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO abuse_reports (entity_type, entity_id, reporter_id, reason, details) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, entity_type, entity_id, reason, details, status, created_at`,
+		entityType, entityID, userID, nr.Reason, nr.Details).
+		Scan(&report.ID, &report.EntityType, &report.EntityId, &report.Reason, &report.Details, &report.Status, &report.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("report %s: %w", entityType, err)
+	}
+
+	// Count the reasons of the open reports, and queue the content once enough users reported it
+	rows, err := tx.QueryContext(ctx, `
+		SELECT reason, count(*) FROM abuse_reports WHERE entity_type = $1 AND entity_id = $2 AND status = $3
+		GROUP BY reason ORDER BY count(*) DESC, reason`, entityType, entityID, models.ReportOpen)
+	if err != nil {
+		return nil, fmt.Errorf("report %s: %w", entityType, err)
+	}
+	total := 0
+	findings := []models.ModerationFinding{}
+	for rows.Next() {
+		var reason string
+		var n int
+		if err := rows.Scan(&reason, &n); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("report %s: %w", entityType, err)
+		}
+		total += n
+		findings = append(findings, models.ModerationFinding{Rule: "abuse_report", Field: reason, Detail: fmt.Sprintf("reported by %d users", n)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("report %s: %w", entityType, err)
+	}
+	if total >= ms.cfg.ReportThreshold {
+		query, auditEntity := entityAuditQuery(entityType)
+		before, err := rowJSON(ctx, tx, query, entityID)
+		if err != nil {
+			return nil, fmt.Errorf("report %s: %w", entityType, err)
+		}
+		if err := setModerationStatus(ctx, tx, entityType, entityID, models.ModerationPending); err != nil {
+			return nil, fmt.Errorf("report %s: %w", entityType, err)
+		}
+		if err := enqueueTx(ctx, tx, entityType, entityID, companyID, ownerID, models.ModerationSourceReports, findings); err != nil {
+			return nil, fmt.Errorf("report %s: %w", entityType, err)
+		}
+		if err := recordModerationChange(ctx, tx, audit.SystemActor, entityType, entityID, companyID, query, auditEntity, before); err != nil {
+			return nil, fmt.Errorf("report %s: %w", entityType, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("report %s: %w", entityType, err)
+	}
+	return &report, nil
+}
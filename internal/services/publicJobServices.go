@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"job-portal-api/internal/models"
+	"time"
+)
+
+// publicJobsQuery selects the jobs shown publicly: live and published jobs of live and published companies
+const publicJobsQuery = `
+	SELECT j.id, j.jobRole, j.salary, j.currency, j.payPeriod, j.companyId, c.name, j.city, j.country, j.created_at, j.updated_at, c.verified
+	FROM jobs j JOIN companies c ON c.id = j.companyId
+	WHERE j.deleted_at IS NULL AND c.deleted_at IS NULL
+	AND j.moderationStatus = 'approved' AND c.moderationStatus = 'approved'`
+
+// scanPublicJob scans a row selected with publicJobsQuery
+func scanPublicJob(row interface{ Scan(...interface{}) error }) (*models.PublicJob, error) {
+	var job models.PublicJob
+	err := row.Scan(&job.ID, &job.JobRole, &job.Salary, &job.Currency, &job.PayPeriod, &job.CompanyId, &job.CompanyName, &job.City, &job.Country, &job.CreatedAt, &job.UpdatedAt, &job.CompanyVerified)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetPublicJobs retrieves the most recently posted public jobs, of one company when companyID is not 0.
+func (js *JobService) GetPublicJobs(ctx context.Context, companyID, limit int) ([]*models.PublicJob, error) {
+	rows, err := js.db.QueryContext(ctx, publicJobsQuery+`
+		AND ($1 = 0 OR j.companyId = $1)
+		ORDER BY j.created_at DESC, j.id DESC LIMIT $2`, companyID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get public jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.PublicJob{}
+	for rows.Next() {
+		job, err := scanPublicJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("get public jobs: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get public jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// GetPublicJob retrieves a public job by its ID.
+func (js *JobService) GetPublicJob(ctx context.Context, id int) (*models.PublicJob, error) {
+	job, err := scanPublicJob(js.db.QueryRowContext(ctx, publicJobsQuery+" AND j.id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("public job with ID %d not found", id)
+		}
+		return nil, fmt.Errorf("get public job: %w", err)
+	}
+	return job, nil
+}
+
+// ExportPublicJobs streams every public job to fn through a database cursor.
+func (js *JobService) ExportPublicJobs(ctx context.Context, fn func(*models.PublicJob) error) error {
+	err := js.cursors.stream(ctx, publicJobsQuery+" ORDER BY j.id", nil, func(rows *sql.Rows) error {
+		job, err := scanPublicJob(rows)
+		if err != nil {
+			return err
+		}
+		return fn(job)
+	})
+	if err != nil {
+		return fmt.Errorf("export public jobs: %w", err)
+	}
+	return nil
+}
+
+// ExportAggregatorJobs streams the public jobs of companies that did not opt out of the aggregator feed.
+func (js *JobService) ExportAggregatorJobs(ctx context.Context, fn func(*models.PublicJob) error) error {
+	err := js.cursors.stream(ctx, publicJobsQuery+" AND NOT c.aggregatorOptOut ORDER BY j.id", nil, func(rows *sql.Rows) error {
+		job, err := scanPublicJob(rows)
+		if err != nil {
+			return err
+		}
+		return fn(job)
+	})
+	if err != nil {
+		return fmt.Errorf("export aggregator jobs: %w", err)
+	}
+	return nil
+}
+
+// PublicJobsLastModified returns when the public jobs, of one company when companyID is not 0, last changed.
+// Deleted jobs count too, since removing a job changes the listing.
+func (js *JobService) PublicJobsLastModified(ctx context.Context, companyID int) (time.Time, error) {
+	var t sql.NullTime
+	err := js.db.QueryRowContext(ctx, "SELECT max(updated_at) FROM jobs WHERE $1 = 0 OR companyId = $1", companyID).Scan(&t)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("public jobs last modified: %w", err)
+	}
+	return t.Time, nil
+}
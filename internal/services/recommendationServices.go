@@ -0,0 +1,403 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/dedup"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/recommend"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrProfileNotFound is returned when the candidate has not saved a profile yet
+var ErrProfileNotFound = errors.New("profile not found")
+
+// historyLimit is how many of a candidate's latest views and applications are matched against
+const historyLimit = 100
+
+// candidatePool is how many jobs or candidates are scored at most, the newest of the ones sharing a title term
+// with the other side, so that a request does not read every job or profile
+const candidatePool = 1000
+
+// termIndexTTL is how long the title term weights are cached, so that new jobs change them within it
+const termIndexTTL = 10 * time.Minute
+
+// RecommendationService keeps candidate profiles and matches candidates with jobs
+type RecommendationService struct {
+	db         *sql.DB
+	currencies *CurrencyService
+
+	mu      sync.Mutex
+	index   *recommend.Index
+	indexAt time.Time
+}
+
+// NewRecommendationService creates a new RecommendationService, comparing salaries per year in the base currency
+// of the CurrencyService
+func NewRecommendationService(db *sql.DB, cs *CurrencyService) (*RecommendationService, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	if cs == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	return &RecommendationService{db: db, currencies: cs}, nil
+}
+
+// candidateProfileColumns are the columns read into models.CandidateProfile, in the order scanCandidateProfile expects
+const candidateProfileColumns = "user_id, headline, array_to_json(skills), desired_salary, desired_currency, desired_pay_period, " +
+	"desired_salary_annual_base, location, city, country, open_to_work, updated_at"
+
+// scanCandidateProfile scans a row selected with candidateProfileColumns
+func scanCandidateProfile(row interface{ Scan(...interface{}) error }) (*models.CandidateProfile, error) {
+	var p models.CandidateProfile
+	var desiredSalary sql.NullInt64
+	var desiredCurrency, desiredPeriod sql.NullString
+	var desiredAnnual sql.NullFloat64
+	var skills []byte
+	err := row.Scan(&p.UserId, &p.Headline, &skills, &desiredSalary, &desiredCurrency, &desiredPeriod, &desiredAnnual,
+		&p.Location, &p.City, &p.Country, &p.OpenToWork, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(skills, &p.Skills); err != nil {
+		return nil, err
+	}
+	p.DesiredSalary, p.DesiredCurrency, p.DesiredPayPeriod = int(desiredSalary.Int64), desiredCurrency.String, desiredPeriod.String
+	p.DesiredAnnualSalary = nullableFloat(desiredAnnual)
+	return &p, nil
+}
+
+// roleTerms returns the distinct title terms of the texts, the same as a job's normalizedRole is made of
+func roleTerms(texts ...string) []string {
+	seen := map[string]bool{}
+	terms := []string{}
+	for _, text := range texts {
+		for _, t := range dedup.RoleTerms(text) {
+			if !seen[t] {
+				seen[t] = true
+				terms = append(terms, t)
+			}
+		}
+	}
+	return terms
+}
+
+// annualOrZero returns an annual salary in the base currency, or 0 when it is unknown, which recommend.Index
+// leaves out of the score
+func annualOrZero(salary *float64) float64 {
+	if salary == nil {
+		return 0
+	}
+	return *salary
+}
+
+// termIndex returns the cached weights of the title terms over the published jobs, counting them again once they
+// are older than termIndexTTL
+func (rs *RecommendationService) termIndex(ctx context.Context) (*recommend.Index, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.index != nil && time.Since(rs.indexAt) < termIndexTTL {
+		return rs.index, nil
+	}
+
+	var n int
+	err := rs.db.QueryRowContext(ctx, "SELECT count(*) FROM jobs WHERE deleted_at IS NULL AND "+jobPublished).Scan(&n)
+	if err != nil {
+		return nil, fmt.Errorf("count title terms: %w", err)
+	}
+	rows, err := rs.db.QueryContext(ctx, `
+		SELECT t, count(*) FROM jobs, unnest(string_to_array(NULLIF(jobs.normalizedRole, ''), ' ')) t
+		WHERE jobs.deleted_at IS NULL AND `+jobPublished+` GROUP BY t`)
+	if err != nil {
+		return nil, fmt.Errorf("count title terms: %w", err)
+	}
+	defer rows.Close()
+	df := map[string]int{}
+	for rows.Next() {
+		var term string
+		var count int
+		if err := rows.Scan(&term, &count); err != nil {
+			return nil, fmt.Errorf("count title terms: %w", err)
+		}
+		df[term] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("count title terms: %w", err)
+	}
+	rs.index, rs.indexAt = recommend.NewIndexFromCounts(df, n), time.Now()
+	return rs.index, nil
+}
+
+// GetProfile retrieves the profile of a candidate.
+func (rs *RecommendationService) GetProfile(ctx context.Context, userID int) (*models.CandidateProfile, error) {
+	p, err := scanCandidateProfile(rs.db.QueryRowContext(ctx, "SELECT "+candidateProfileColumns+" FROM candidate_profiles WHERE user_id = $1", userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProfileNotFound
+		}
+		return nil, fmt.Errorf("get profile: %w", err)
+	}
+	return p, nil
+}
+
+// SaveProfile creates or replaces the profile of a candidate. Skills are stored in lower case, once each, and
+// their title terms alongside for finding the candidates of a job. The desired salary is also stored per year in
+// the base currency, and the location geocoded; a malformed currency or pay period fails with ErrInvalidSalary.
+func (rs *RecommendationService) SaveProfile(ctx context.Context, userID int, np models.NewCandidateProfile) (*models.CandidateProfile, error) {
+	skills := []string{}
+	seen := map[string]bool{}
+	for _, s := range np.Skills {
+		s = strings.Join(strings.Fields(strings.ToLower(s)), " ")
+		if s != "" && !seen[s] {
+			seen[s] = true
+			skills = append(skills, s)
+		}
+	}
+	openToWork := true
+	if np.OpenToWork != nil {
+		openToWork = *np.OpenToWork
+	}
+	skillsJSON, err := json.Marshal(skills)
+	if err != nil {
+		return nil, fmt.Errorf("save profile: %w", err)
+	}
+	var currency, period interface{}
+	if np.DesiredSalary > 0 {
+		c, p, err := rs.currencies.normalizeSalary(np.DesiredCurrency, np.DesiredPayPeriod)
+		if err != nil {
+			return nil, fmt.Errorf("save profile: %w", err)
+		}
+		currency, period = c, p
+	}
+
+	tx, err := rs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("save profile: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO candidate_profiles (user_id, headline, skills, skill_terms, desired_salary, desired_currency, desired_pay_period,
+			location, open_to_work)
+		VALUES ($1, $2, ARRAY(SELECT jsonb_array_elements_text($3::jsonb)), $7::text[], NULLIF($4, 0), $8, $9, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET headline = EXCLUDED.headline, skills = EXCLUDED.skills,
+			skill_terms = EXCLUDED.skill_terms, desired_salary = EXCLUDED.desired_salary,
+			desired_currency = EXCLUDED.desired_currency, desired_pay_period = EXCLUDED.desired_pay_period,
+			location = EXCLUDED.location, open_to_work = EXCLUDED.open_to_work, updated_at = now()`,
+		userID, strings.TrimSpace(np.Headline), skillsJSON, np.DesiredSalary, strings.TrimSpace(np.Location), openToWork,
+		roleTerms(skills...), currency, period)
+	if err != nil {
+		return nil, fmt.Errorf("save profile: %w", err)
+	}
+	if _, err := rs.currencies.normalizeDesiredSalaryTx(ctx, tx, userID); err != nil {
+		return nil, fmt.Errorf("save profile: %w", err)
+	}
+	if err := locateProfileTx(ctx, tx, userID); err != nil {
+		return nil, fmt.Errorf("save profile: %w", err)
+	}
+	p, err := scanCandidateProfile(tx.QueryRowContext(ctx, "SELECT "+candidateProfileColumns+" FROM candidate_profiles WHERE user_id = $1", userID))
+	if err != nil {
+		return nil, fmt.Errorf("save profile: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("save profile: %w", err)
+	}
+	return p, nil
+}
+
+// history returns the latest jobs each of the users viewed or applied to, by user
+func (rs *RecommendationService) history(ctx context.Context, userIDs []int32) (map[int][]recommend.Interaction, error) {
+	rows, err := rs.db.QueryContext(ctx, `
+		SELECT user_id, jobRole, applied FROM (
+			SELECT h.user_id, j.jobRole, h.applied,
+				row_number() OVER (PARTITION BY h.user_id ORDER BY h.at DESC) AS n
+			FROM (
+				SELECT user_id, job_id, true AS applied, created_at AS at FROM job_applications WHERE user_id = ANY($1::int[])
+				UNION ALL
+				SELECT user_id, job_id, false, last_viewed_at FROM job_views WHERE user_id = ANY($1::int[])
+			) h JOIN jobs j ON j.id = h.job_id
+		) latest WHERE n <= $2`, userIDs, historyLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := map[int][]recommend.Interaction{}
+	for rows.Next() {
+		var userID int
+		var in recommend.Interaction
+		if err := rows.Scan(&userID, &in.Role, &in.Applied); err != nil {
+			return nil, err
+		}
+		history[userID] = append(history[userID], in)
+	}
+	return history, rows.Err()
+}
+
+// RecommendJobs ranks the published jobs for a candidate by how well they match the candidate's profile and the
+// jobs they viewed or applied to, best first. Jobs the candidate applied to and jobs that do not match at all
+// are left out. Only the newest candidatePool jobs whose titles share a term with the skills or history are
+// scored, or the newest jobs for a candidate with neither.
+func (rs *RecommendationService) RecommendJobs(ctx context.Context, userID, limit int) ([]*models.RecommendedJob, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	candidate := recommend.Candidate{}
+	profile, err := rs.GetProfile(ctx, userID)
+	switch {
+	case err == nil:
+		candidate.Skills, candidate.DesiredSalary = profile.Skills, annualOrZero(profile.DesiredAnnualSalary)
+		candidate.City, candidate.Country = profile.City, profile.Country
+	case !errors.Is(err, ErrProfileNotFound):
+		return nil, fmt.Errorf("recommend jobs: %w", err)
+	}
+	history, err := rs.history(ctx, []int32{int32(userID)})
+	if err != nil {
+		return nil, fmt.Errorf("recommend jobs: %w", err)
+	}
+	candidate.History = history[userID]
+	index, err := rs.termIndex(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("recommend jobs: %w", err)
+	}
+
+	texts := append([]string{}, candidate.Skills...)
+	for _, in := range candidate.History {
+		texts = append(texts, in.Role)
+	}
+	rows, err := rs.db.QueryContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE deleted_at IS NULL AND "+jobPublished+
+		" AND NOT EXISTS (SELECT 1 FROM job_applications ja WHERE ja.job_id = jobs.id AND ja.user_id = $1)"+
+		" AND (cardinality($2::text[]) = 0 OR string_to_array(jobs.normalizedRole, ' ') && $2::text[]) ORDER BY id DESC LIMIT $3",
+		userID, roleTerms(texts...), candidatePool)
+	if err != nil {
+		return nil, fmt.Errorf("recommend jobs: %w", err)
+	}
+	defer rows.Close()
+	var jobs []*models.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("recommend jobs: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("recommend jobs: %w", err)
+	}
+
+	recommended := []*models.RecommendedJob{}
+	for _, job := range jobs {
+		m := index.Score(candidate, recommend.Job{
+			Role: job.JobRole, Salary: annualOrZero(job.AnnualSalary), City: job.Location.City, Country: job.Location.Country,
+		})
+		if m.Score > 0 {
+			recommended = append(recommended, &models.RecommendedJob{Job: job, Score: m.Score, Reasons: m.Reasons})
+		}
+	}
+	// Newer jobs first among equal scores
+	sort.SliceStable(recommended, func(a, b int) bool {
+		if recommended[a].Score != recommended[b].Score {
+			return recommended[a].Score > recommended[b].Score
+		}
+		return recommended[a].Job.ID > recommended[b].Job.ID
+	})
+	if len(recommended) > limit {
+		recommended = recommended[:limit]
+	}
+	return recommended, nil
+}
+
+// SuggestCandidates ranks the candidates open to work by how well a job of the user's companies matches them,
+// best first. Candidates that do not match at all are left out. Only the candidatePool most recently updated
+// profiles are scored among those who applied to the job, or whose skills or history share a term with its title.
+func (rs *RecommendationService) SuggestCandidates(ctx context.Context, userID, jobID, limit int) ([]*models.SuggestedCandidate, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	var job recommend.Job
+	err := rs.db.QueryRowContext(ctx, `
+		SELECT j.jobRole, COALESCE(j.salaryAnnualBase, 0), j.city, j.country FROM jobs j JOIN companies c ON c.id = j.companyId
+		WHERE j.id = $1 AND c.userId = $2 AND j.deleted_at IS NULL`, jobID, userID).Scan(&job.Role, &job.Salary, &job.City, &job.Country)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("job not found for user with ID %d and job ID %d", userID, jobID)
+		}
+		return nil, fmt.Errorf("suggest candidates: %w", err)
+	}
+
+	// Title terms are weighted over the published jobs, the same as for recommendations
+	index, err := rs.termIndex(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("suggest candidates: %w", err)
+	}
+
+	rows, err := rs.db.QueryContext(ctx, `
+		SELECT `+candidateProfileColumns+`, EXISTS (SELECT 1 FROM job_applications ja WHERE ja.job_id = $1 AND ja.user_id = p.user_id)
+		FROM candidate_profiles p JOIN users u ON u.id = p.user_id
+		WHERE p.open_to_work AND u.role = 'user' AND u.suspended_at IS NULL AND (p.skill_terms && $2::text[]
+			OR EXISTS (SELECT 1 FROM job_applications ja WHERE ja.job_id = $1 AND ja.user_id = p.user_id)
+			OR EXISTS (SELECT 1 FROM job_views v JOIN jobs vj ON vj.id = v.job_id
+				WHERE v.user_id = p.user_id AND string_to_array(vj.normalizedRole, ' ') && $2::text[])
+			OR EXISTS (SELECT 1 FROM job_applications a JOIN jobs aj ON aj.id = a.job_id
+				WHERE a.user_id = p.user_id AND string_to_array(aj.normalizedRole, ' ') && $2::text[]))
+		ORDER BY p.updated_at DESC LIMIT $3`, jobID, roleTerms(job.Role), candidatePool)
+	if err != nil {
+		return nil, fmt.Errorf("suggest candidates: %w", err)
+	}
+	var profiles []*models.CandidateProfile
+	applied := map[int]bool{}
+	for rows.Next() {
+		var hasApplied bool
+		p, err := scanCandidateProfile(scanWithExtra{rows, []interface{}{&hasApplied}})
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("suggest candidates: %w", err)
+		}
+		profiles = append(profiles, p)
+		applied[p.UserId] = hasApplied
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("suggest candidates: %w", err)
+	}
+
+	userIDs := make([]int32, len(profiles))
+	for i, p := range profiles {
+		userIDs[i] = int32(p.UserId)
+	}
+	history, err := rs.history(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("suggest candidates: %w", err)
+	}
+
+	suggested := []*models.SuggestedCandidate{}
+	for _, p := range profiles {
+		m := index.Score(recommend.Candidate{
+			Skills: p.Skills, DesiredSalary: annualOrZero(p.DesiredAnnualSalary), City: p.City, Country: p.Country,
+			History: history[p.UserId],
+		}, job)
+		if m.Score > 0 {
+			suggested = append(suggested, &models.SuggestedCandidate{
+				UserId: p.UserId, Headline: p.Headline, Skills: p.Skills, Location: p.Location,
+				Applied: applied[p.UserId], Score: m.Score, Reasons: m.Reasons,
+			})
+		}
+	}
+	sort.SliceStable(suggested, func(a, b int) bool {
+		return suggested[a].Score > suggested[b].Score
+	})
+	if len(suggested) > limit {
+		suggested = suggested[:limit]
+	}
+	return suggested, nil
+}
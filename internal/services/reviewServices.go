@@ -0,0 +1,404 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/audit"
+	"job-portal-api/internal/models"
+)
+
+// ErrReviewExists is returned when the user already reviewed the company
+var ErrReviewExists = errors.New("company already reviewed")
+
+// ErrReviewOwnCompany is returned when a company owner reviews their own company
+var ErrReviewOwnCompany = errors.New("cannot review your own company")
+
+// ReviewService handles candidate reviews of companies and the company ratings computed from them
+type ReviewService struct {
+	db *sql.DB
+}
+
+// NewReviewService creates a new ReviewService with the provided database connection
+func NewReviewService(db *sql.DB) (*ReviewService, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	return &ReviewService{db: db}, nil
+}
+
+// reviewColumns are the columns read into models.CompanyReview, in the order scanReview expects
+const reviewColumns = "id, company_id, user_id, overall, work_life, compensation, culture, management, career_growth, " +
+	"title, pros, cons, employment_status, job_title, status, moderation_notes, response, responded_at, created_at, updated_at"
+
+// reviewAuditQuery selects a review for the audit log
+const reviewAuditQuery = "SELECT row_to_json(r) FROM company_reviews r WHERE id = $1"
+
+// scanReview scans a row selected with reviewColumns
+func scanReview(row interface{ Scan(...interface{}) error }) (*models.CompanyReview, error) {
+	var review models.CompanyReview
+	var sub [5]sql.NullInt64
+	var respondedAt sql.NullTime
+	err := row.Scan(&review.ID, &review.CompanyId, &review.UserId, &review.Overall, &sub[0], &sub[1], &sub[2], &sub[3], &sub[4],
+		&review.Title, &review.Pros, &review.Cons, &review.EmploymentStatus, &review.JobTitle, &review.Status,
+		&review.ModerationNotes, &review.Response, &respondedAt, &review.CreatedAt, &review.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	dst := []**int{&review.WorkLife, &review.Compensation, &review.Culture, &review.Management, &review.CareerGrowth}
+	for i, v := range sub {
+		if v.Valid {
+			rating := int(v.Int64)
+			*dst[i] = &rating
+		}
+	}
+	if respondedAt.Valid {
+		review.RespondedAt = &respondedAt.Time
+	}
+	return &review, nil
+}
+
+// CreateReview submits the user's review of a live company. It is pending until an operator approves it.
+func (rs *ReviewService) CreateReview(ctx context.Context, userID, companyID int, nr models.NewCompanyReview) (*models.CompanyReview, error) {
+	tx, err := rs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+	defer tx.Rollback()
+
+	var ownerID int
+	err = tx.QueryRowContext(ctx, "SELECT userId FROM companies WHERE id = $1 AND deleted_at IS NULL FOR SHARE", companyID).Scan(&ownerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("company %d not found", companyID)
+		}
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+	if ownerID == userID {
+		return nil, fmt.Errorf("create review: %w", ErrReviewOwnCompany)
+	}
+
+	// The unique constraint would also catch it, but as a failed statement rather than a clear error
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM company_reviews WHERE company_id = $1 AND user_id = $2)",
+		companyID, userID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("create review: %w", ErrReviewExists)
+	}
+
+	review, err := scanReview(tx.QueryRowContext(ctx, `
+		INSERT INTO company_reviews (company_id, user_id, overall, work_life, compensation, culture, management, career_growth,
+			title, pros, cons, employment_status, job_title)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING `+reviewColumns,
+		companyID, userID, nr.Overall, nr.WorkLife, nr.Compensation, nr.Culture, nr.Management, nr.CareerGrowth,
+		nr.Title, nr.Pros, nr.Cons, nr.EmploymentStatus, nr.JobTitle))
+	if err != nil {
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+
+	after, err := rowJSON(ctx, tx, reviewAuditQuery, review.ID)
+	if err != nil {
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionCreate, EntityType: audit.EntityCompanyReview, EntityID: review.ID, After: after,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("create review: %w", err)
+	}
+	return review, nil
+}
+
+// UpdateReview replaces the user's review of a company. The edited review goes back to moderation, so an approved
+// review stops counting in the rating until it is approved again.
+func (rs *ReviewService) UpdateReview(ctx context.Context, userID, companyID int, nr models.NewCompanyReview) (*models.CompanyReview, error) {
+	tx, err := rs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("update review: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the company before the review, in the same order as moderation
+	if err := lockCompany(ctx, tx, companyID); err != nil {
+		return nil, fmt.Errorf("update review: %w", err)
+	}
+	var reviewID int
+	var status string
+	err = tx.QueryRowContext(ctx, "SELECT id, status FROM company_reviews WHERE company_id = $1 AND user_id = $2 FOR UPDATE",
+		companyID, userID).Scan(&reviewID, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("review of company %d by user %d not found", companyID, userID)
+		}
+		return nil, fmt.Errorf("update review: %w", err)
+	}
+
+	before, err := rowJSON(ctx, tx, reviewAuditQuery, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("update review: %w", err)
+	}
+	review, err := scanReview(tx.QueryRowContext(ctx, `
+		UPDATE company_reviews SET overall = $1, work_life = $2, compensation = $3, culture = $4, management = $5,
+			career_growth = $6, title = $7, pros = $8, cons = $9, employment_status = $10, job_title = $11,
+			status = $12, moderation_notes = '', moderated_by = NULL, moderated_at = NULL, updated_at = now()
+		WHERE id = $13
+		RETURNING `+reviewColumns,
+		nr.Overall, nr.WorkLife, nr.Compensation, nr.Culture, nr.Management, nr.CareerGrowth,
+		nr.Title, nr.Pros, nr.Cons, nr.EmploymentStatus, nr.JobTitle, models.ReviewPending, reviewID))
+	if err != nil {
+		return nil, fmt.Errorf("update review: %w", err)
+	}
+	if err := recordReviewUpdate(ctx, tx, userID, reviewID, before); err != nil {
+		return nil, fmt.Errorf("update review: %w", err)
+	}
+	if status == models.ReviewApproved {
+		if err := recomputeRating(ctx, tx, companyID); err != nil {
+			return nil, fmt.Errorf("update review: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("update review: %w", err)
+	}
+	return review, nil
+}
+
+// DeleteReview deletes the user's review of a company.
+func (rs *ReviewService) DeleteReview(ctx context.Context, userID, companyID int) error {
+	tx, err := rs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("delete review: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockCompany(ctx, tx, companyID); err != nil {
+		return fmt.Errorf("delete review: %w", err)
+	}
+	var reviewID int
+	var status string
+	err = tx.QueryRowContext(ctx, "SELECT id, status FROM company_reviews WHERE company_id = $1 AND user_id = $2 FOR UPDATE",
+		companyID, userID).Scan(&reviewID, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("review of company %d by user %d not found", companyID, userID)
+		}
+		return fmt.Errorf("delete review: %w", err)
+	}
+
+	before, err := rowJSON(ctx, tx, reviewAuditQuery, reviewID)
+	if err != nil {
+		return fmt.Errorf("delete review: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM company_reviews WHERE id = $1", reviewID); err != nil {
+		return fmt.Errorf("delete review: %w", err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionDelete, EntityType: audit.EntityCompanyReview, EntityID: reviewID, Before: before,
+	})
+	if err != nil {
+		return fmt.Errorf("delete review: %w", err)
+	}
+	if status == models.ReviewApproved {
+		if err := recomputeRating(ctx, tx, companyID); err != nil {
+			return fmt.Errorf("delete review: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("delete review: %w", err)
+	}
+	return nil
+}
+
+// GetReviews retrieves the approved reviews of a live and published company, newest first. A non-zero beforeID continues
+// from the last review of the previous page.
+func (rs *ReviewService) GetReviews(ctx context.Context, companyID, beforeID, limit int) ([]*models.CompanyReview, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return rs.listReviews(ctx, `
+		SELECT `+reviewColumns+` FROM company_reviews
+		WHERE company_id = $1 AND status = $2 AND ($3 = 0 OR id < $3)
+		AND EXISTS (SELECT 1 FROM companies WHERE id = $1 AND deleted_at IS NULL AND moderationStatus = 'approved')
+		ORDER BY id DESC LIMIT $4`, companyID, models.ReviewApproved, beforeID, limit)
+}
+
+// ListReviews lists reviews in a moderation status for operators, oldest first. It defaults to the pending reviews.
+func (rs *ReviewService) ListReviews(ctx context.Context, status string) ([]*models.CompanyReview, error) {
+	if status == "" {
+		status = models.ReviewPending
+	}
+	return rs.listReviews(ctx, "SELECT "+reviewColumns+" FROM company_reviews WHERE status = $1 ORDER BY id LIMIT 500", status)
+}
+
+// listReviews runs a query selecting reviewColumns
+func (rs *ReviewService) listReviews(ctx context.Context, query string, args ...interface{}) ([]*models.CompanyReview, error) {
+	rows, err := rs.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []*models.CompanyReview{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list reviews: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list reviews: %w", err)
+	}
+	return reviews, nil
+}
+
+// ModerateReview records an operator's decision on a review. Approved reviews are published and counted in the
+// company rating; an approved review can later be rejected to take it down.
+func (rs *ReviewService) ModerateReview(ctx context.Context, operatorID, reviewID int, approve bool, notes string) (*models.CompanyReview, error) {
+	tx, err := rs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("moderate review: %w", err)
+	}
+	defer tx.Rollback()
+
+	var companyID int
+	err = tx.QueryRowContext(ctx, "SELECT company_id FROM company_reviews WHERE id = $1", reviewID).Scan(&companyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("review %d not found", reviewID)
+		}
+		return nil, fmt.Errorf("moderate review: %w", err)
+	}
+	if err := lockCompany(ctx, tx, companyID); err != nil {
+		return nil, fmt.Errorf("moderate review: %w", err)
+	}
+	var status string
+	err = tx.QueryRowContext(ctx, "SELECT status FROM company_reviews WHERE id = $1 FOR UPDATE", reviewID).Scan(&status)
+	if err != nil {
+		return nil, fmt.Errorf("moderate review: %w", err)
+	}
+	newStatus := models.ReviewRejected
+	if approve {
+		newStatus = models.ReviewApproved
+	}
+	if status == newStatus {
+		return nil, fmt.Errorf("moderate review: %w", ErrInvalidTransition)
+	}
+
+	before, err := rowJSON(ctx, tx, reviewAuditQuery, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("moderate review: %w", err)
+	}
+	review, err := scanReview(tx.QueryRowContext(ctx, `
+		UPDATE company_reviews SET status = $1, moderation_notes = $2, moderated_by = $3, moderated_at = now() WHERE id = $4
+		RETURNING `+reviewColumns, newStatus, notes, operatorID, reviewID))
+	if err != nil {
+		return nil, fmt.Errorf("moderate review: %w", err)
+	}
+	if err := recordReviewUpdate(ctx, tx, operatorID, reviewID, before); err != nil {
+		return nil, fmt.Errorf("moderate review: %w", err)
+	}
+
+	// The rating only changes when a review enters or leaves the approved set
+	if approve || status == models.ReviewApproved {
+		if err := recomputeRating(ctx, tx, companyID); err != nil {
+			return nil, fmt.Errorf("moderate review: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("moderate review: %w", err)
+	}
+	return review, nil
+}
+
+// RespondToReview sets the public answer of a company owned by the user to one of its approved reviews,
+// replacing an earlier answer.
+func (rs *ReviewService) RespondToReview(ctx context.Context, userID, companyID, reviewID int, response string) (*models.CompanyReview, error) {
+	tx, err := rs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("respond to review: %w", err)
+	}
+	defer tx.Rollback()
+
+	var owned bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM companies WHERE userId = $1 AND id = $2 AND deleted_at IS NULL)",
+		userID, companyID).Scan(&owned)
+	if err != nil {
+		return nil, fmt.Errorf("respond to review: %w", err)
+	}
+	if !owned {
+		return nil, fmt.Errorf("company not found for user with ID %d and company ID %d", userID, companyID)
+	}
+
+	var status string
+	err = tx.QueryRowContext(ctx, "SELECT status FROM company_reviews WHERE id = $1 AND company_id = $2 FOR UPDATE",
+		reviewID, companyID).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("review %d of company %d not found", reviewID, companyID)
+		}
+		return nil, fmt.Errorf("respond to review: %w", err)
+	}
+	if status != models.ReviewApproved {
+		return nil, fmt.Errorf("respond to review: %w", ErrInvalidTransition)
+	}
+
+	before, err := rowJSON(ctx, tx, reviewAuditQuery, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("respond to review: %w", err)
+	}
+	review, err := scanReview(tx.QueryRowContext(ctx, `
+		UPDATE company_reviews SET response = $1, responded_by = $2, responded_at = now() WHERE id = $3
+		RETURNING `+reviewColumns, response, userID, reviewID))
+	if err != nil {
+		return nil, fmt.Errorf("respond to review: %w", err)
+	}
+	if err := recordReviewUpdate(ctx, tx, userID, reviewID, before); err != nil {
+		return nil, fmt.Errorf("respond to review: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("respond to review: %w", err)
+	}
+	return review, nil
+}
+
+// recomputeRating recomputes the aggregates of the approved reviews of a company and bumps the rating revision.
+// The company must be locked so concurrent moderation cannot write stale aggregates.
+func recomputeRating(ctx context.Context, tx *sql.Tx, companyID int) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO company_ratings (company_id, review_count, overall_rating, work_life_rating, compensation_rating,
+			culture_rating, management_rating, career_growth_rating)
+		SELECT $1, count(*), round(avg(overall), 2), round(avg(work_life), 2), round(avg(compensation), 2),
+			round(avg(culture), 2), round(avg(management), 2), round(avg(career_growth), 2)
+		FROM company_reviews WHERE company_id = $1 AND status = $2
+		ON CONFLICT (company_id) DO UPDATE SET
+			review_count = EXCLUDED.review_count, overall_rating = EXCLUDED.overall_rating,
+			work_life_rating = EXCLUDED.work_life_rating, compensation_rating = EXCLUDED.compensation_rating,
+			culture_rating = EXCLUDED.culture_rating, management_rating = EXCLUDED.management_rating,
+			career_growth_rating = EXCLUDED.career_growth_rating, rating_revision = company_ratings.rating_revision + 1`,
+		companyID, models.ReviewApproved)
+	return err
+}
+
+// recordReviewUpdate records the update of a review in the audit log
+func recordReviewUpdate(ctx context.Context, tx *sql.Tx, actorID, reviewID int, before []byte) error {
+	after, err := rowJSON(ctx, tx, reviewAuditQuery, reviewID)
+	if err != nil {
+		return err
+	}
+	return audit.Record(ctx, tx, audit.Event{
+		ActorID: actorID, Action: audit.ActionUpdate, EntityType: audit.EntityCompanyReview, EntityID: reviewID, Before: before, After: after,
+	})
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+// rowJSON runs a query selecting a single row_to_json value inside the transaction.
+// It returns sql.ErrNoRows when the row does not exist.
+func rowJSON(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (json.RawMessage, error) {
+	var raw []byte
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&raw)
+	if err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/feeds"
+	"job-portal-api/internal/models"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SitemapConfig represents the configuration of the sitemaps of public job pages
+type SitemapConfig struct {
+	Dir     string        // Dir is the directory the sitemap index and its sitemaps are written to
+	MaxAge  time.Duration // MaxAge is how often the sitemaps are rebuilt even when no job changed
+	BaseURL string        // BaseURL is the absolute URL sitemap and job links are built from
+}
+
+// DefaultSitemapConfig returns the sitemap configuration from SITEMAP_DIR, SITEMAP_INTERVAL_MINUTES and
+// PUBLIC_BASE_URL
+func DefaultSitemapConfig() SitemapConfig {
+	cfg := SitemapConfig{
+		Dir:     os.Getenv("SITEMAP_DIR"),
+		MaxAge:  time.Hour,
+		BaseURL: strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/"),
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = filepath.Join(os.TempDir(), "job-portal-sitemaps")
+	}
+	if minutes, err := strconv.Atoi(os.Getenv("SITEMAP_INTERVAL_MINUTES")); err == nil && minutes > 0 {
+		cfg.MaxAge = time.Duration(minutes) * time.Minute
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "http://localhost:3030"
+	}
+	return cfg
+}
+
+// SitemapService builds the sitemap index of public job pages and the sitemaps it lists, each with at most
+// feeds.MaxSitemapURLs jobs, so crawlers are served files instead of a database cursor per request.
+type SitemapService struct {
+	js  *JobService
+	cfg SitemapConfig
+
+	mu          sync.Mutex
+	lastEventID int64
+	generatedAt time.Time
+}
+
+// NewSitemapService creates a new SitemapService instance, creating its directory when missing.
+func NewSitemapService(js *JobService, cfg SitemapConfig) (*SitemapService, error) {
+	if js == nil {
+		return nil, errors.New("job service cannot be nil")
+	}
+	if cfg.Dir == "" || cfg.MaxAge <= 0 {
+		return nil, errors.New("please provide all the values")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create sitemap directory: %w", err)
+	}
+	return &SitemapService{js: js, cfg: cfg}, nil
+}
+
+// IndexPath returns the file the sitemap index is written to.
+func (ss *SitemapService) IndexPath() string {
+	return filepath.Join(ss.cfg.Dir, "sitemap.xml")
+}
+
+// PagePath returns the file the sitemap with the page number, from 1, is written to.
+func (ss *SitemapService) PagePath(page int) string {
+	return filepath.Join(ss.cfg.Dir, "sitemap-"+strconv.Itoa(page)+".xml")
+}
+
+// sitemapPage is a sitemap being written to a temporary file
+type sitemapPage struct {
+	tmp     *os.File
+	sm      *feeds.Sitemap
+	lastMod time.Time
+}
+
+// newPage starts a sitemap in a temporary file next to the published ones.
+func (ss *SitemapService) newPage() (*sitemapPage, error) {
+	tmp, err := os.CreateTemp(ss.cfg.Dir, ".sitemap-*.xml")
+	if err != nil {
+		return nil, err
+	}
+	sm, err := feeds.NewSitemap(tmp)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return &sitemapPage{tmp: tmp, sm: sm}, nil
+}
+
+// publish ends the sitemap and renames it into place as the page.
+func (ss *SitemapService) publish(p *sitemapPage, page int) error {
+	defer os.Remove(p.tmp.Name())
+	if err := p.sm.Close(); err != nil {
+		p.tmp.Close()
+		return err
+	}
+	if err := p.tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(p.tmp.Name(), ss.PagePath(page))
+}
+
+// discard removes a sitemap that will not be published.
+func (p *sitemapPage) discard() {
+	p.tmp.Close()
+	os.Remove(p.tmp.Name())
+}
+
+// Generate rebuilds the sitemaps and then the index. Every file is written to a temporary file and renamed into
+// place, so readers always see complete files. Pages left over from a larger build are removed last.
+func (ss *SitemapService) Generate(ctx context.Context) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	// Note the latest event first, so changes made while generating trigger another run
+	lastEventID, err := latestEventID(ctx, ss.js.db)
+	if err != nil {
+		return err
+	}
+
+	page, err := ss.newPage()
+	if err != nil {
+		return fmt.Errorf("generate sitemaps: %w", err)
+	}
+	var refs []feeds.SitemapRef
+	next := func() error {
+		if err := ss.publish(page, len(refs)+1); err != nil {
+			return err
+		}
+		refs = append(refs, feeds.SitemapRef{Loc: ss.pageURL(len(refs) + 1), LastMod: page.lastMod})
+		p, err := ss.newPage()
+		page = p
+		return err
+	}
+
+	err = ss.js.ExportPublicJobs(ctx, func(job *models.PublicJob) error {
+		loc := ss.cfg.BaseURL + "/public/jobs/" + strconv.Itoa(job.ID)
+		err := page.sm.Add(loc, job.UpdatedAt)
+		if errors.Is(err, feeds.ErrSitemapFull) {
+			if err := next(); err != nil {
+				return err
+			}
+			err = page.sm.Add(loc, job.UpdatedAt)
+		}
+		if err != nil {
+			return err
+		}
+		if job.UpdatedAt.After(page.lastMod) {
+			page.lastMod = job.UpdatedAt
+		}
+		return nil
+	})
+	if err != nil {
+		if page != nil {
+			page.discard()
+		}
+		return fmt.Errorf("generate sitemaps: %w", err)
+	}
+	// The last page is published even when empty, so the index always lists a sitemap
+	if err := ss.publish(page, len(refs)+1); err != nil {
+		return fmt.Errorf("generate sitemaps: %w", err)
+	}
+	refs = append(refs, feeds.SitemapRef{Loc: ss.pageURL(len(refs) + 1), LastMod: page.lastMod})
+
+	tmp, err := os.CreateTemp(ss.cfg.Dir, ".sitemap-index-*.xml")
+	if err != nil {
+		return fmt.Errorf("generate sitemaps: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := feeds.WriteSitemapIndex(tmp, refs); err != nil {
+		return fmt.Errorf("generate sitemaps: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("generate sitemaps: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), ss.IndexPath()); err != nil {
+		return fmt.Errorf("generate sitemaps: %w", err)
+	}
+
+	for n := len(refs) + 1; ; n++ {
+		if err := os.Remove(ss.PagePath(n)); err != nil {
+			break
+		}
+	}
+
+	ss.lastEventID = lastEventID
+	ss.generatedAt = time.Now()
+	return nil
+}
+
+// pageURL returns the absolute URL of the sitemap with the page number
+func (ss *SitemapService) pageURL(page int) string {
+	return ss.cfg.BaseURL + "/sitemap-" + strconv.Itoa(page) + ".xml"
+}
+
+// stale reports whether a job or company changed since the sitemaps were built, or they are older than MaxAge.
+func (ss *SitemapService) stale(ctx context.Context) (bool, error) {
+	latest, err := latestEventID(ctx, ss.js.db)
+	if err != nil {
+		return false, err
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return latest > ss.lastEventID || time.Since(ss.generatedAt) >= ss.cfg.MaxAge, nil
+}
+
+// Run builds the sitemaps right away and then checks every interval whether a job-change event occurred
+// or they reached MaxAge, rebuilding them when so, until the context is cancelled.
+func (ss *SitemapService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		stale, err := ss.stale(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("could not check sitemaps")
+		} else if stale {
+			if err := ss.Generate(ctx); err != nil {
+				log.Error().Err(err).Msg("could not generate sitemaps")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
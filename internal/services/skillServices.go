@@ -0,0 +1,404 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/audit"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/taxonomy"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errors returned when curating the skills taxonomy
+var (
+	ErrSkillNotFound = errors.New("skill not found")
+	ErrInvalidAlias  = errors.New("alias has no words")
+	ErrAliasExists   = errors.New("alias already exists")
+)
+
+// taxonomyTTL is how long the taxonomy is cached, so that curation on another instance shows up within it
+const taxonomyTTL = time.Minute
+
+// SkillService keeps the skills taxonomy and tags jobs with the skills named in their roles
+type SkillService struct {
+	db *sql.DB
+
+	mu       sync.Mutex
+	cached   *taxonomy.Taxonomy
+	loadedAt time.Time
+}
+
+// NewSkillService creates a new SkillService
+func NewSkillService(db *sql.DB) (*SkillService, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	return &SkillService{db: db}, nil
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// loadTaxonomy reads the skills and their aliases
+func loadTaxonomy(ctx context.Context, q queryer) (*taxonomy.Taxonomy, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, slug, name, COALESCE(parent_id, 0) FROM skills")
+	if err != nil {
+		return nil, err
+	}
+	var skills []taxonomy.Skill
+	for rows.Next() {
+		var s taxonomy.Skill
+		if err := rows.Scan(&s.ID, &s.Slug, &s.Name, &s.ParentID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		skills = append(skills, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.QueryContext(ctx, "SELECT alias, skill_id FROM skill_aliases")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var aliases []taxonomy.Alias
+	for rows.Next() {
+		var a taxonomy.Alias
+		if err := rows.Scan(&a.Alias, &a.SkillID); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return taxonomy.New(skills, aliases), nil
+}
+
+// taxonomy returns the cached taxonomy, reading it again once it is older than taxonomyTTL
+func (ss *SkillService) taxonomy(ctx context.Context) (*taxonomy.Taxonomy, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.cached != nil && time.Since(ss.loadedAt) < taxonomyTTL {
+		return ss.cached, nil
+	}
+	t, err := loadTaxonomy(ctx, ss.db)
+	if err != nil {
+		return nil, fmt.Errorf("load skills taxonomy: %w", err)
+	}
+	ss.cached, ss.loadedAt = t, time.Now()
+	return t, nil
+}
+
+// invalidate makes the next use of the taxonomy read it again
+func (ss *SkillService) invalidate() {
+	ss.mu.Lock()
+	ss.cached = nil
+	ss.mu.Unlock()
+}
+
+// skillFilter returns the IDs of the skill named by a slug or alias and of all the skills below it
+func (ss *SkillService) skillFilter(ctx context.Context, name string) ([]int32, error) {
+	t, err := ss.taxonomy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	skill, ok := t.Resolve(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrSkillNotFound, name)
+	}
+	var ids []int32
+	for _, id := range t.Descendants(skill.ID) {
+		ids = append(ids, int32(id))
+	}
+	return ids, nil
+}
+
+// tagJobsTx replaces the skills of the jobs, given by ID with their roles, with the skills the taxonomy finds in
+// the roles. It returns the slugs of the skills of every job.
+func tagJobsTx(ctx context.Context, tx *sql.Tx, t *taxonomy.Taxonomy, roles map[int]string) (map[int][]string, error) {
+	tags := map[int][]string{}
+	var jobIDs, tagJobIDs, tagSkillIDs []int32
+	for id, role := range roles {
+		jobIDs = append(jobIDs, int32(id))
+		tags[id] = []string{}
+		for _, s := range t.Extract(role) {
+			tagJobIDs = append(tagJobIDs, int32(id))
+			tagSkillIDs = append(tagSkillIDs, int32(s.ID))
+			tags[id] = append(tags[id], s.Slug)
+		}
+	}
+	if len(jobIDs) == 0 {
+		return tags, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM job_skills WHERE job_id = ANY($1::int[])", jobIDs); err != nil {
+		return nil, err
+	}
+	if len(tagJobIDs) > 0 {
+		_, err := tx.ExecContext(ctx, "INSERT INTO job_skills (job_id, skill_id) SELECT * FROM unnest($1::int[], $2::int[])",
+			tagJobIDs, tagSkillIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+// tagJobTx tags a job created or edited in the transaction with the skills named in its role, and returns
+// their slugs
+func (ss *SkillService) tagJobTx(ctx context.Context, tx *sql.Tx, jobID int) ([]string, error) {
+	t, err := ss.taxonomy(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("tag job skills: %w", err)
+	}
+	var role string
+	if err := tx.QueryRowContext(ctx, "SELECT jobRole FROM jobs WHERE id = $1", jobID).Scan(&role); err != nil {
+		return nil, fmt.Errorf("tag job skills: %w", err)
+	}
+	tags, err := tagJobsTx(ctx, tx, t, map[int]string{jobID: role})
+	if err != nil {
+		return nil, fmt.Errorf("tag job skills: %w", err)
+	}
+	return tags[jobID], nil
+}
+
+// retagJobsTx tags the live jobs matching the condition again with the taxonomy, and returns how many there were
+func retagJobsTx(ctx context.Context, tx *sql.Tx, t *taxonomy.Taxonomy, condition string, args ...interface{}) (int, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id, COALESCE(jobRole, '') FROM jobs WHERE deleted_at IS NULL AND "+condition, args...)
+	if err != nil {
+		return 0, err
+	}
+	roles := map[int]string{}
+	for rows.Next() {
+		var id int
+		var role string
+		if err := rows.Scan(&id, &role); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		roles[id] = role
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if _, err := tagJobsTx(ctx, tx, t, roles); err != nil {
+		return 0, err
+	}
+	return len(roles), nil
+}
+
+// LoadSeed creates or updates the skills of a seed file with their parents and aliases, then tags all live jobs
+// again. Skills and aliases that are not in the file are kept.
+func (ss *SkillService) LoadSeed(ctx context.Context, seed []taxonomy.SeedSkill) (*models.SkillSeedReport, error) {
+	tx, err := ss.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("load skills seed: %w", err)
+	}
+	defer tx.Rollback()
+
+	report := &models.SkillSeedReport{}
+	ids := map[string]int{}
+	for _, s := range seed {
+		var id int
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO skills (slug, name) VALUES ($1, $2)
+			ON CONFLICT (slug) DO UPDATE SET name = EXCLUDED.name
+			RETURNING id`, s.Slug, s.Name).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("load skills seed: %w", err)
+		}
+		ids[s.Slug] = id
+		report.Skills++
+	}
+	// Parents are set once all skills exist, as the file may list a category after its skills
+	for _, s := range seed {
+		_, err := tx.ExecContext(ctx, "UPDATE skills SET parent_id = (SELECT id FROM skills p WHERE p.slug = $1) WHERE id = $2",
+			s.Parent, ids[s.Slug])
+		if err != nil {
+			return nil, fmt.Errorf("load skills seed: %w", err)
+		}
+		for _, alias := range s.Aliases {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO skill_aliases (alias, skill_id) VALUES ($1, $2)
+				ON CONFLICT (alias) DO UPDATE SET skill_id = EXCLUDED.skill_id`, alias, ids[s.Slug])
+			if err != nil {
+				return nil, fmt.Errorf("load skills seed: %w", err)
+			}
+			report.Aliases++
+		}
+	}
+
+	t, err := loadTaxonomy(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("load skills seed: %w", err)
+	}
+	if report.Jobs, err = retagJobsTx(ctx, tx, t, "true"); err != nil {
+		return nil, fmt.Errorf("load skills seed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("load skills seed: %w", err)
+	}
+	ss.invalidate()
+	return report, nil
+}
+
+// ListSkills returns the taxonomy: every skill with its parent and aliases, by slug.
+func (ss *SkillService) ListSkills(ctx context.Context) ([]*models.Skill, error) {
+	rows, err := ss.db.QueryContext(ctx, `
+		SELECT s.id, s.slug, s.name, COALESCE(p.slug, '')
+		FROM skills s LEFT JOIN skills p ON p.id = s.parent_id ORDER BY s.slug`)
+	if err != nil {
+		return nil, fmt.Errorf("list skills: %w", err)
+	}
+	skills := []*models.Skill{}
+	byID := map[int]*models.Skill{}
+	for rows.Next() {
+		s := models.Skill{Aliases: []*models.SkillAlias{}}
+		if err := rows.Scan(&s.ID, &s.Slug, &s.Name, &s.Parent); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("list skills: %w", err)
+		}
+		skills = append(skills, &s)
+		byID[s.ID] = &s
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list skills: %w", err)
+	}
+
+	rows, err = ss.db.QueryContext(ctx, "SELECT id, alias, skill_id, created_at FROM skill_aliases ORDER BY alias")
+	if err != nil {
+		return nil, fmt.Errorf("list skills: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a models.SkillAlias
+		if err := rows.Scan(&a.ID, &a.Alias, &a.SkillId, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("list skills: %w", err)
+		}
+		if s, ok := byID[a.SkillId]; ok {
+			s.Aliases = append(s.Aliases, &a)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list skills: %w", err)
+	}
+	return skills, nil
+}
+
+// AddAlias adds another name to the skill with the slug on behalf of an operator, and tags the live jobs whose
+// roles may name it again.
+func (ss *SkillService) AddAlias(ctx context.Context, operatorID int, slug string, na models.NewSkillAlias) (*models.SkillAlias, error) {
+	alias := taxonomy.Normalize(na.Alias)
+	if alias == "" {
+		return nil, fmt.Errorf("add skill alias: %w", ErrInvalidAlias)
+	}
+
+	tx, err := ss.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("add skill alias: %w", err)
+	}
+	defer tx.Rollback()
+
+	a := models.SkillAlias{Alias: alias}
+	err = tx.QueryRowContext(ctx, "SELECT id FROM skills WHERE slug = $1", slug).Scan(&a.SkillId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("add skill alias: %w", ErrSkillNotFound)
+		}
+		return nil, fmt.Errorf("add skill alias: %w", err)
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO skill_aliases (alias, skill_id) VALUES ($1, $2)
+		ON CONFLICT (alias) DO NOTHING
+		RETURNING id, created_at`, alias, a.SkillId).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("add skill alias: %w", ErrAliasExists)
+		}
+		return nil, fmt.Errorf("add skill alias: %w", err)
+	}
+
+	after, err := rowJSON(ctx, tx, "SELECT row_to_json(a) FROM skill_aliases a WHERE id = $1", a.ID)
+	if err != nil {
+		return nil, fmt.Errorf("add skill alias: %w", err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: operatorID, Action: audit.ActionCreate, EntityType: audit.EntitySkillAlias, EntityID: a.ID, After: after,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("add skill alias: %w", err)
+	}
+
+	// Only roles containing the longest word of the alias can name it
+	words := strings.Fields(alias)
+	sort.Slice(words, func(i, j int) bool { return len(words[i]) > len(words[j]) })
+	t, err := loadTaxonomy(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("add skill alias: %w", err)
+	}
+	if _, err := retagJobsTx(ctx, tx, t, "jobRole ILIKE '%' || $1 || '%'", words[0]); err != nil {
+		return nil, fmt.Errorf("add skill alias: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("add skill alias: %w", err)
+	}
+	ss.invalidate()
+	return &a, nil
+}
+
+// DeleteAlias removes an alias on behalf of an operator, and tags the live jobs of its skill again.
+func (ss *SkillService) DeleteAlias(ctx context.Context, operatorID, aliasID int) error {
+	tx, err := ss.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("delete skill alias: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := rowJSON(ctx, tx, "SELECT row_to_json(a) FROM skill_aliases a WHERE id = $1 FOR UPDATE", aliasID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("skill alias %d not found", aliasID)
+		}
+		return fmt.Errorf("delete skill alias: %w", err)
+	}
+	var skillID int
+	err = tx.QueryRowContext(ctx, "DELETE FROM skill_aliases WHERE id = $1 RETURNING skill_id", aliasID).Scan(&skillID)
+	if err != nil {
+		return fmt.Errorf("delete skill alias: %w", err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: operatorID, Action: audit.ActionDelete, EntityType: audit.EntitySkillAlias, EntityID: aliasID, Before: before,
+	})
+	if err != nil {
+		return fmt.Errorf("delete skill alias: %w", err)
+	}
+
+	t, err := loadTaxonomy(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("delete skill alias: %w", err)
+	}
+	if _, err := retagJobsTx(ctx, tx, t, "id IN (SELECT job_id FROM job_skills WHERE skill_id = $1)", skillID); err != nil {
+		return fmt.Errorf("delete skill alias: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("delete skill alias: %w", err)
+	}
+	ss.invalidate()
+	return nil
+}
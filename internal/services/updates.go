@@ -0,0 +1,34 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrVersionMismatch is returned when a conditional update or delete finds the row at a different version
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// checkUpdatableFields returns an error if updates is empty or contains a column that is not allowed.
+// Column names are compared case-insensitively, as Postgres folds unquoted identifiers to lower case.
+func checkUpdatableFields(updates map[string]interface{}, allowed []string) error {
+	if len(updates) == 0 {
+		return errors.New("no fields to update")
+	}
+	for key := range updates {
+		if !isField(key, allowed) {
+			return fmt.Errorf("field %q cannot be updated", key)
+		}
+	}
+	return nil
+}
+
+// isField reports whether the column name is one of fields, compared case-insensitively
+func isField(key string, fields []string) bool {
+	for _, f := range fields {
+		if strings.EqualFold(key, f) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,27 +1,85 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"job-portal-api/internal/mailer"
 	"job-portal-api/internal/models"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrAccountLocked is returned by Authenticate while an account is locked after too many failed logins.
+var ErrAccountLocked = errors.New("account locked")
+
+// LockoutConfig controls progressive account lockout after failed password checks.
+type LockoutConfig struct {
+	MaxFailures int           // MaxFailures is the number of failed password checks before the account is locked.
+	BaseLockout time.Duration // BaseLockout is the first lock duration; it doubles on every further lock.
+	MaxLockout  time.Duration // MaxLockout caps the lock duration.
+	UnlockURL   string        // UnlockURL is the absolute link sent in unlock emails; the token is set as its token query parameter.
+	TokenTTL    time.Duration // TokenTTL is how long an unlock token stays valid.
+}
+
+// DefaultLockoutConfig returns the lockout configuration from the environment, falling back to sane defaults.
+func DefaultLockoutConfig() LockoutConfig {
+	cfg := LockoutConfig{
+		MaxFailures: 5,
+		BaseLockout: 15 * time.Minute,
+		MaxLockout:  24 * time.Hour,
+		UnlockURL:   os.Getenv("UNLOCK_URL"),
+		TokenTTL:    time.Hour,
+	}
+	if n, err := strconv.Atoi(os.Getenv("LOCKOUT_MAX_FAILURES")); err == nil && n > 0 {
+		cfg.MaxFailures = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("LOCKOUT_BASE_DURATION")); err == nil && d > 0 {
+		cfg.BaseLockout = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("LOCKOUT_MAX_DURATION")); err == nil && d > 0 {
+		cfg.MaxLockout = d
+	}
+	return cfg
+}
+
 // UserService handles business logic related to user operations.
 type UserService struct {
-	db *sql.DB
+	db        *sql.DB
+	mailer    mailer.Mailer
+	lockout   LockoutConfig
+	unlockURL *url.URL
 }
 
 // NewUserService creates a new UserService instance.
-func NewUserService(db *sql.DB) (*UserService, error) {
+func NewUserService(db *sql.DB, m mailer.Mailer, lockout LockoutConfig) (*UserService, error) {
 	// Check if the database connection is nil
 	if db == nil {
 		return nil, errors.New("db connection cannot be nil")
 	}
-	return &UserService{db: db}, nil
+	if m == nil {
+		return nil, errors.New("mailer cannot be nil")
+	}
+	if lockout.MaxFailures <= 0 {
+		return nil, errors.New("lockout max failures must be positive")
+	}
+	// Locked out users could never unlock their account without a link to do so
+	if lockout.UnlockURL == "" {
+		return nil, errors.New("unlock url cannot be empty")
+	}
+	unlockURL, err := url.Parse(lockout.UnlockURL)
+	if err != nil || !unlockURL.IsAbs() || unlockURL.Host == "" {
+		return nil, fmt.Errorf("unlock url %q must be an absolute URL", lockout.UnlockURL)
+	}
+	return &UserService{db: db, mailer: m, lockout: lockout, unlockURL: unlockURL}, nil
 }
 
 // Create generates a new user record in the database.
@@ -68,11 +126,28 @@ func (us *UserService) Authenticate(email, password, role string) (*models.User,
 	}
 
 	// Execute the SQL query to retrieve user information by email
+	var lockedUntil sql.NullTime
 	row := us.db.QueryRow(`
-	SELECT id, password_hash, role
+	SELECT id, password_hash, role, locked_until
 	FROM users WHERE email=$1`, email)
-	err := row.Scan(&user.ID, &user.PasswordHash, &user.Role)
+	err := row.Scan(&user.ID, &user.PasswordHash, &user.Role, &lockedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("authenticate: %w", err)
+	}
+
+	// Refuse to check the password at all while the account is locked
+	if lockedUntil.Valid && lockedUntil.Time.After(time.Now()) {
+		return nil, fmt.Errorf("authenticate: %w until %s", ErrAccountLocked, lockedUntil.Time.Format(time.RFC3339))
+	}
+
+	// Compare the provided password with the hashed password in the database. It is checked before the role, so
+	// that failures count towards the lockout whatever role is tried and the role of an account is not revealed
+	// without its password.
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
+		if lockErr := us.recordFailedLogin(user.ID, user.Email); lockErr != nil {
+			return nil, fmt.Errorf("authenticate: %w", lockErr)
+		}
 		return nil, fmt.Errorf("authenticate: %w", err)
 	}
 
@@ -81,8 +156,10 @@ func (us *UserService) Authenticate(email, password, role string) (*models.User,
 		return nil, fmt.Errorf("authenticate: invalid role")
 	}
 
-	// Compare the provided password with the hashed password in the database
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	// Clear any previous failures now that the password is correct
+	_, err = us.db.Exec(`
+	UPDATE users SET failed_logins = 0, locked_until = NULL, unlock_token_hash = NULL
+	WHERE id = $1 AND failed_logins > 0`, user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("authenticate: %w", err)
 	}
@@ -90,3 +167,113 @@ func (us *UserService) Authenticate(email, password, role string) (*models.User,
 	// Authentication successful, return the user
 	return &user, nil
 }
+
+// recordFailedLogin increments the failure counter and locks the account every MaxFailures failures.
+// Each further lock doubles in length up to MaxLockout, and the user is emailed an unlock link.
+func (us *UserService) recordFailedLogin(userID int, email string) error {
+	var failures int
+	err := us.db.QueryRow(`
+	UPDATE users SET failed_logins = failed_logins + 1
+	WHERE id = $1 RETURNING failed_logins`, userID).Scan(&failures)
+	if err != nil {
+		return fmt.Errorf("record failed login: %w", err)
+	}
+	if failures%us.lockout.MaxFailures != 0 {
+		return nil
+	}
+
+	// Work out the progressive lock duration
+	duration := us.lockout.BaseLockout
+	for i := 1; i < failures/us.lockout.MaxFailures && duration < us.lockout.MaxLockout; i++ {
+		duration *= 2
+	}
+	if duration > us.lockout.MaxLockout {
+		duration = us.lockout.MaxLockout
+	}
+
+	_, err = us.db.Exec(`
+	UPDATE users SET locked_until = $1 WHERE id = $2`, time.Now().Add(duration), userID)
+	if err != nil {
+		return fmt.Errorf("lock account: %w", err)
+	}
+	return us.sendUnlockEmail(userID, email)
+}
+
+// sendUnlockEmail stores a fresh unlock token for the user and emails it to them.
+func (us *UserService) sendUnlockEmail(userID int, email string) error {
+	token, err := randomToken()
+	if err != nil {
+		return fmt.Errorf("send unlock email: %w", err)
+	}
+
+	_, err = us.db.Exec(`
+	UPDATE users SET unlock_token_hash = $1, unlock_token_expires_at = $2 WHERE id = $3`,
+		hashToken(token), time.Now().Add(us.lockout.TokenTTL), userID)
+	if err != nil {
+		return fmt.Errorf("send unlock email: %w", err)
+	}
+
+	// The token is set on a copy of the link, keeping any query parameters it already has
+	link := *us.unlockURL
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	body := "Your account was locked after too many failed sign-in attempts.\n\n" +
+		"Use this link to unlock it: " + link.String() + "\n\n" +
+		"If this wasn't you, consider changing your password."
+	if err := us.mailer.Send(email, "Unlock your account", body); err != nil {
+		return fmt.Errorf("send unlock email: %w", err)
+	}
+	return nil
+}
+
+// RequestUnlock emails a new unlock link if the account exists and is locked.
+// It does not report whether the email is registered.
+func (us *UserService) RequestUnlock(email string) error {
+	email = strings.ToLower(email)
+
+	var userID int
+	err := us.db.QueryRow(`
+	SELECT id FROM users WHERE email = $1 AND locked_until > now()`, email).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return fmt.Errorf("request unlock: %w", err)
+	}
+	return us.sendUnlockEmail(userID, email)
+}
+
+// Unlock clears the lock on the account owning the given unlock token.
+func (us *UserService) Unlock(token string) error {
+	res, err := us.db.Exec(`
+	UPDATE users SET failed_logins = 0, locked_until = NULL, unlock_token_hash = NULL, unlock_token_expires_at = NULL
+	WHERE unlock_token_hash = $1 AND unlock_token_expires_at > now()`, hashToken(token))
+	if err != nil {
+		return fmt.Errorf("unlock: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unlock: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("unlock: invalid or expired token")
+	}
+	return nil
+}
+
+// randomToken returns a random hex encoded token suitable for links sent by email.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken returns the SHA-256 of a token so that only hashes are stored in the database.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"database/sql/driver"
+	"job-portal-api/internal/mailer"
+	"job-portal-api/internal/sqltest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// sentMail records the emails sent through it
+type sentMail struct {
+	bodies []string
+}
+
+func (m *sentMail) Send(to, subject, body string, attachments ...mailer.Attachment) error {
+	m.bodies = append(m.bodies, body)
+	return nil
+}
+
+// testLockout returns a lockout configuration sending links to unlockURL
+func testLockout(unlockURL string) LockoutConfig {
+	return LockoutConfig{MaxFailures: 5, BaseLockout: time.Minute, MaxLockout: time.Hour, UnlockURL: unlockURL, TokenTTL: time.Hour}
+}
+
+func TestNewUserServiceUnlockURL(t *testing.T) {
+	db, _ := sqltest.Open(t)
+	for _, u := range []string{"", "/unlock", "unlock?x=1", "https://", "://bad"} {
+		if _, err := NewUserService(db, &sentMail{}, testLockout(u)); err == nil {
+			t.Errorf("NewUserService(%q) succeeded, want an error", u)
+		}
+	}
+	if _, err := NewUserService(db, &sentMail{}, testLockout("https://jobs.example.com/unlock")); err != nil {
+		t.Errorf("NewUserService() error: %v", err)
+	}
+}
+
+func TestUnlockLink(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	sent := &sentMail{}
+	us, err := NewUserService(db, sent, testLockout("https://jobs.example.com/unlock?lang=de#form"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.Query("SELECT id FROM users WHERE email = $1 AND locked_until > now()", func([]driver.Value) (*sqltest.Rows, error) {
+		return sqltest.Row(int64(5)), nil
+	})
+
+	if err := us.RequestUnlock("carl@example.com"); err != nil {
+		t.Fatalf("RequestUnlock() error: %v", err)
+	}
+	if len(sent.bodies) != 1 {
+		t.Fatalf("RequestUnlock() sent %d emails, want 1", len(sent.bodies))
+	}
+	_, rest, _ := strings.Cut(sent.bodies[0], "unlock it: ")
+	link, err := url.Parse(strings.Fields(rest)[0])
+	if err != nil {
+		t.Fatalf("unlock link %q: %v", rest, err)
+	}
+	token := link.Query().Get("token")
+	if link.Host != "jobs.example.com" || link.Path != "/unlock" || link.Query().Get("lang") != "de" || link.Fragment != "form" || token == "" {
+		t.Errorf("unlock link = %s, want the configured link with a token", link)
+	}
+	stored := fake.Execs("SET unlock_token_hash = $1")
+	if len(stored) != 1 || stored[0].Args[0] != hashToken(token) {
+		t.Errorf("stored token hashes %v, want the hash of the emailed token", stored)
+	}
+}
+
+func TestAuthenticateChecksPasswordBeforeRole(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	us, err := NewUserService(db, &sentMail{}, testLockout("https://jobs.example.com/unlock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.Query("FROM users WHERE email=$1", func([]driver.Value) (*sqltest.Rows, error) {
+		return sqltest.Row(int64(5), string(hash), "admin", nil), nil
+	})
+	failures := 0
+	fake.Query("SET failed_logins = failed_logins + 1", func([]driver.Value) (*sqltest.Rows, error) {
+		failures++
+		return sqltest.Row(int64(failures)), nil
+	})
+
+	// A wrong password is a failed login whatever the role
+	_, err = us.Authenticate("carl@example.com", "wrong", "user")
+	if err == nil || strings.Contains(err.Error(), "invalid role") || failures != 1 {
+		t.Errorf("Authenticate(wrong password, other role) = %v with %d failures, want a password error and 1 failure", err, failures)
+	}
+	// The right password with another role is refused without counting as a failure
+	_, err = us.Authenticate("carl@example.com", "correct horse", "user")
+	if err == nil || !strings.Contains(err.Error(), "invalid role") || failures != 1 {
+		t.Errorf("Authenticate(right password, other role) = %v with %d failures, want invalid role and 1 failure", err, failures)
+	}
+	if user, err := us.Authenticate("carl@example.com", "correct horse", "Admin"); err != nil || user.ID != 5 {
+		t.Errorf("Authenticate() = %v, %v, want user 5", user, err)
+	}
+}
@@ -0,0 +1,589 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/audit"
+	"job-portal-api/internal/mailer"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/outbox"
+	"job-portal-api/internal/verification"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ErrAlreadyVerified is returned when verification is started for a company that is already verified
+var ErrAlreadyVerified = errors.New("company is already verified")
+
+// ErrProofNotFound is returned when the DNS record is not published or the emailed code does not match
+var ErrProofNotFound = errors.New("proof of domain control not found")
+
+// ErrInvalidTransition is returned when a verification request or dispute is not in a state allowing the action
+var ErrInvalidTransition = errors.New("not allowed in the current state")
+
+// ErrDisputeOpen is returned when the user already has an open dispute over the company
+var ErrDisputeOpen = errors.New("dispute already open")
+
+// VerificationConfig represents the configuration of company verification
+type VerificationConfig struct {
+	TTL time.Duration // TTL is how long the owner has to complete the proof
+}
+
+// DefaultVerificationConfig returns the verification configuration from COMPANY_VERIFICATION_TTL_HOURS, defaulting to 72 hours.
+func DefaultVerificationConfig() VerificationConfig {
+	hours, err := strconv.Atoi(os.Getenv("COMPANY_VERIFICATION_TTL_HOURS"))
+	if err != nil || hours <= 0 {
+		hours = 72
+	}
+	return VerificationConfig{TTL: time.Duration(hours) * time.Hour}
+}
+
+// VerificationService handles proving control of company domains, operator review and name disputes
+type VerificationService struct {
+	db       *sql.DB
+	mailer   mailer.Mailer
+	resolver verification.Resolver
+	cfg      VerificationConfig
+}
+
+// NewVerificationService creates a new VerificationService with the provided dependencies
+func NewVerificationService(db *sql.DB, m mailer.Mailer, r verification.Resolver, cfg VerificationConfig) (*VerificationService, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	if m == nil || r == nil {
+		return nil, errors.New("please provide all the values")
+	}
+	if cfg.TTL <= 0 {
+		return nil, errors.New("verification ttl must be positive")
+	}
+	return &VerificationService{db: db, mailer: m, resolver: r, cfg: cfg}, nil
+}
+
+// verificationColumns are the columns read into models.Verification, in the order scanVerification expects
+const verificationColumns = "id, company_id, requested_by, method, domain, email, dns_token, expires_at, status, proven_at, reviewed_by, reviewed_at, notes, created_at"
+
+// verificationAuditQuery selects a verification request for the audit log, leaving out the secrets
+const verificationAuditQuery = `SELECT row_to_json(v) FROM (SELECT id, company_id, method, domain, email, status, proven_at,
+	reviewed_by, reviewed_at, notes FROM company_verifications WHERE id = $1) v`
+
+// scanVerification scans a row selected with verificationColumns
+func scanVerification(row interface{ Scan(...interface{}) error }) (*models.Verification, error) {
+	var v models.Verification
+	var email, token sql.NullString
+	var provenAt, reviewedAt sql.NullTime
+	var reviewedBy sql.NullInt64
+	err := row.Scan(&v.ID, &v.CompanyId, &v.RequestedBy, &v.Method, &v.Domain, &email, &token, &v.ExpiresAt, &v.Status,
+		&provenAt, &reviewedBy, &reviewedAt, &v.Notes, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	v.Email = email.String
+	if v.Method == verification.MethodDNS {
+		v.RecordName = verification.RecordName(v.Domain)
+		v.RecordValue = verification.RecordValue(token.String)
+	}
+	if provenAt.Valid {
+		v.ProvenAt = &provenAt.Time
+	}
+	if reviewedBy.Valid {
+		id := int(reviewedBy.Int64)
+		v.ReviewedBy = &id
+	}
+	if reviewedAt.Valid {
+		v.ReviewedAt = &reviewedAt.Time
+	}
+	return &v, nil
+}
+
+// StartVerification starts verifying a company owned by the user. For the dns method the returned request holds
+// the TXT record to publish; for the email method a code is sent to the address. Earlier open requests expire.
+func (vs *VerificationService) StartVerification(ctx context.Context, userID, companyID int, nv models.NewVerification) (*models.Verification, error) {
+	var domain, email string
+	var err error
+	switch nv.Method {
+	case verification.MethodDNS:
+		domain, err = verification.NormalizeDomain(nv.Domain)
+	case verification.MethodEmail:
+		email = nv.Email
+		domain, err = verification.EmailDomain(nv.Email)
+	default:
+		err = fmt.Errorf("unknown verification method %q", nv.Method)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("start verification: %w", err)
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("start verification: %w", err)
+	}
+	var dnsToken, codeHash, emailArg interface{}
+	if nv.Method == verification.MethodDNS {
+		dnsToken = token
+	} else {
+		codeHash, emailArg = hashToken(token), email
+	}
+
+	tx, err := vs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("start verification: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Check the user owns the live company, locking it so concurrent requests expire each other in order
+	var verified bool
+	err = tx.QueryRowContext(ctx, "SELECT verified FROM companies WHERE userId = $1 AND id = $2 AND deleted_at IS NULL FOR UPDATE",
+		userID, companyID).Scan(&verified)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("company not found for user with ID %d and company ID %d", userID, companyID)
+		}
+		return nil, fmt.Errorf("query company existence: %w", err)
+	}
+	if verified {
+		return nil, fmt.Errorf("start verification: %w", ErrAlreadyVerified)
+	}
+
+	// Only the latest request of a company can be completed
+	_, err = tx.ExecContext(ctx, "UPDATE company_verifications SET status = $1 WHERE company_id = $2 AND status IN ($3, $4)",
+		models.VerificationExpired, companyID, models.VerificationPending, models.VerificationProven)
+	if err != nil {
+		return nil, fmt.Errorf("start verification: %w", err)
+	}
+
+	v, err := scanVerification(tx.QueryRowContext(ctx, `
+		INSERT INTO company_verifications (company_id, requested_by, method, domain, email, dns_token, email_code_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+verificationColumns,
+		companyID, userID, nv.Method, domain, emailArg, dnsToken, codeHash, time.Now().Add(vs.cfg.TTL)))
+	if err != nil {
+		return nil, fmt.Errorf("start verification: %w", err)
+	}
+
+	after, err := rowJSON(ctx, tx, verificationAuditQuery, v.ID)
+	if err != nil {
+		return nil, fmt.Errorf("start verification: %w", err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionCreate, EntityType: audit.EntityCompanyVerification, EntityID: v.ID, After: after,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start verification: %w", err)
+	}
+
+	// Send the code before committing, so a request whose email failed is not left behind
+	if nv.Method == verification.MethodEmail {
+		body := "Someone asked to verify a company on the job portal using this address.\n\n" +
+			"Verification code: " + token + "\n\n" +
+			"The code expires on " + v.ExpiresAt.UTC().Format(time.RFC1123) + ". If you did not expect this email, ignore it."
+		if err := vs.mailer.Send(email, "Verify your company", body); err != nil {
+			return nil, fmt.Errorf("start verification: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("start verification: %w", err)
+	}
+	return v, nil
+}
+
+// ConfirmVerification checks the proof of a pending verification request: the DNS TXT record is looked up, or the
+// emailed code compared. A proven request waits for an operator to approve it.
+func (vs *VerificationService) ConfirmVerification(ctx context.Context, userID, companyID, verificationID int, code string) (*models.Verification, error) {
+	tx, err := vs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("confirm verification: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the request of a live company owned by the user
+	var dnsToken, codeHash sql.NullString
+	var v models.Verification
+	err = tx.QueryRowContext(ctx, `
+		SELECT v.method, v.domain, v.dns_token, v.email_code_hash, v.status, v.expires_at
+		FROM company_verifications v JOIN companies c ON c.id = v.company_id
+		WHERE v.id = $1 AND v.company_id = $2 AND c.userId = $3 AND c.deleted_at IS NULL
+		FOR UPDATE OF v`, verificationID, companyID, userID).Scan(&v.Method, &v.Domain, &dnsToken, &codeHash, &v.Status, &v.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("verification %d not found for company %d", verificationID, companyID)
+		}
+		return nil, fmt.Errorf("confirm verification: %w", err)
+	}
+	if v.Status != models.VerificationPending || time.Now().After(v.ExpiresAt) {
+		return nil, fmt.Errorf("confirm verification: %w", ErrInvalidTransition)
+	}
+
+	proven := false
+	switch v.Method {
+	case verification.MethodDNS:
+		proven, err = verification.CheckTXT(ctx, vs.resolver, v.Domain, dnsToken.String)
+		if err != nil {
+			return nil, fmt.Errorf("confirm verification: %w", err)
+		}
+	case verification.MethodEmail:
+		proven = code != "" && subtle.ConstantTimeCompare([]byte(hashToken(code)), []byte(codeHash.String)) == 1
+	}
+	if !proven {
+		return nil, fmt.Errorf("confirm verification: %w", ErrProofNotFound)
+	}
+
+	before, err := rowJSON(ctx, tx, verificationAuditQuery, verificationID)
+	if err != nil {
+		return nil, fmt.Errorf("confirm verification: %w", err)
+	}
+	// The code is single use
+	proved, err := scanVerification(tx.QueryRowContext(ctx, `
+		UPDATE company_verifications SET status = $1, proven_at = now(), email_code_hash = NULL WHERE id = $2
+		RETURNING `+verificationColumns, models.VerificationProven, verificationID))
+	if err != nil {
+		return nil, fmt.Errorf("confirm verification: %w", err)
+	}
+	if err := vs.recordUpdate(ctx, tx, userID, audit.EntityCompanyVerification, verificationID, before, verificationAuditQuery); err != nil {
+		return nil, fmt.Errorf("confirm verification: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("confirm verification: %w", err)
+	}
+	return proved, nil
+}
+
+// GetVerifications lists the verification requests of a company owned by the user, newest first.
+func (vs *VerificationService) GetVerifications(ctx context.Context, userID, companyID int) ([]*models.Verification, error) {
+	var exists bool
+	err := vs.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM companies WHERE userId = $1 AND id = $2 AND deleted_at IS NULL)",
+		userID, companyID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("get verifications: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("company not found for user with ID %d and company ID %d", userID, companyID)
+	}
+	return vs.listVerifications(ctx, "SELECT "+verificationColumns+" FROM company_verifications WHERE company_id = $1 ORDER BY id DESC LIMIT 100", companyID)
+}
+
+// ListVerifications lists verification requests in a status for operators, oldest first. It defaults to the
+// proven requests waiting for review.
+func (vs *VerificationService) ListVerifications(ctx context.Context, status string) ([]*models.Verification, error) {
+	if status == "" {
+		status = models.VerificationProven
+	}
+	return vs.listVerifications(ctx, "SELECT "+verificationColumns+" FROM company_verifications WHERE status = $1 ORDER BY id LIMIT 500", status)
+}
+
+// listVerifications runs a query selecting verificationColumns
+func (vs *VerificationService) listVerifications(ctx context.Context, query string, args ...interface{}) ([]*models.Verification, error) {
+	rows, err := vs.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list verifications: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*models.Verification{}
+	for rows.Next() {
+		v, err := scanVerification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list verifications: %w", err)
+		}
+		list = append(list, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list verifications: %w", err)
+	}
+	return list, nil
+}
+
+// ReviewVerification records an operator's decision on a verification request. Approving a proven request
+// makes the company verified for the proven domain; pending and proven requests can be rejected.
+func (vs *VerificationService) ReviewVerification(ctx context.Context, operatorID, verificationID int, approve bool, notes string) (*models.Verification, error) {
+	tx, err := vs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("review verification: %w", err)
+	}
+	defer tx.Rollback()
+
+	var companyID int
+	err = tx.QueryRowContext(ctx, "SELECT company_id FROM company_verifications WHERE id = $1", verificationID).Scan(&companyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("verification %d not found", verificationID)
+		}
+		return nil, fmt.Errorf("review verification: %w", err)
+	}
+	if err := lockCompany(ctx, tx, companyID); err != nil {
+		return nil, fmt.Errorf("review verification: %w", err)
+	}
+	var status, domain string
+	err = tx.QueryRowContext(ctx, "SELECT status, domain FROM company_verifications WHERE id = $1 FOR UPDATE",
+		verificationID).Scan(&status, &domain)
+	if err != nil {
+		return nil, fmt.Errorf("review verification: %w", err)
+	}
+	newStatus := models.VerificationRejected
+	if approve {
+		newStatus = models.VerificationApproved
+	}
+	if status != models.VerificationProven && !(status == models.VerificationPending && !approve) {
+		return nil, fmt.Errorf("review verification: %w", ErrInvalidTransition)
+	}
+
+	before, err := rowJSON(ctx, tx, verificationAuditQuery, verificationID)
+	if err != nil {
+		return nil, fmt.Errorf("review verification: %w", err)
+	}
+	v, err := scanVerification(tx.QueryRowContext(ctx, `
+		UPDATE company_verifications SET status = $1, reviewed_by = $2, reviewed_at = now(), notes = $3 WHERE id = $4
+		RETURNING `+verificationColumns, newStatus, operatorID, notes, verificationID))
+	if err != nil {
+		return nil, fmt.Errorf("review verification: %w", err)
+	}
+	if err := vs.recordUpdate(ctx, tx, operatorID, audit.EntityCompanyVerification, verificationID, before, verificationAuditQuery); err != nil {
+		return nil, fmt.Errorf("review verification: %w", err)
+	}
+
+	if approve {
+		err = vs.setVerified(ctx, tx, operatorID, companyID,
+			"verified = true, verifiedDomain = $2, verifiedAt = now()", domain)
+		if err != nil {
+			return nil, fmt.Errorf("review verification: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("review verification: %w", err)
+	}
+	return v, nil
+}
+
+// disputeColumns are the columns read into models.Dispute, in the order scanDispute expects
+const disputeColumns = "id, company_id, raised_by, reason, domain, status, resolved_by, resolved_at, notes, created_at"
+
+// disputeAuditQuery selects a dispute for the audit log
+const disputeAuditQuery = "SELECT row_to_json(d) FROM company_disputes d WHERE id = $1"
+
+// scanDispute scans a row selected with disputeColumns
+func scanDispute(row interface{ Scan(...interface{}) error }) (*models.Dispute, error) {
+	var d models.Dispute
+	var resolvedBy sql.NullInt64
+	var resolvedAt sql.NullTime
+	err := row.Scan(&d.ID, &d.CompanyId, &d.RaisedBy, &d.Reason, &d.Domain, &d.Status, &resolvedBy, &resolvedAt, &d.Notes, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedBy.Valid {
+		id := int(resolvedBy.Int64)
+		d.ResolvedBy = &id
+	}
+	if resolvedAt.Valid {
+		d.ResolvedAt = &resolvedAt.Time
+	}
+	return &d, nil
+}
+
+// RaiseDispute records a user's claim that the name of a live company belongs to them. Owners cannot dispute
+// their own companies and a user can only have one open dispute per company.
+func (vs *VerificationService) RaiseDispute(ctx context.Context, userID, companyID int, nd models.NewDispute) (*models.Dispute, error) {
+	domain := ""
+	if nd.Domain != "" {
+		var err error
+		if domain, err = verification.NormalizeDomain(nd.Domain); err != nil {
+			return nil, fmt.Errorf("raise dispute: %w", err)
+		}
+	}
+
+	tx, err := vs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("raise dispute: %w", err)
+	}
+	defer tx.Rollback()
+
+	var ownerID int
+	err = tx.QueryRowContext(ctx, "SELECT userId FROM companies WHERE id = $1 AND deleted_at IS NULL FOR SHARE", companyID).Scan(&ownerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("company %d not found", companyID)
+		}
+		return nil, fmt.Errorf("raise dispute: %w", err)
+	}
+	if ownerID == userID {
+		return nil, fmt.Errorf("raise dispute: cannot dispute your own company: %w", ErrInvalidTransition)
+	}
+
+	var open bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM company_disputes WHERE company_id = $1 AND raised_by = $2 AND status = $3)",
+		companyID, userID, models.DisputeOpen).Scan(&open)
+	if err != nil {
+		return nil, fmt.Errorf("raise dispute: %w", err)
+	}
+	if open {
+		return nil, fmt.Errorf("raise dispute: %w", ErrDisputeOpen)
+	}
+
+	d, err := scanDispute(tx.QueryRowContext(ctx, `
+		INSERT INTO company_disputes (company_id, raised_by, reason, domain) VALUES ($1, $2, $3, $4)
+		RETURNING `+disputeColumns, companyID, userID, nd.Reason, domain))
+	if err != nil {
+		return nil, fmt.Errorf("raise dispute: %w", err)
+	}
+	after, err := rowJSON(ctx, tx, disputeAuditQuery, d.ID)
+	if err != nil {
+		return nil, fmt.Errorf("raise dispute: %w", err)
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: userID, Action: audit.ActionCreate, EntityType: audit.EntityCompanyDispute, EntityID: d.ID, After: after,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("raise dispute: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("raise dispute: %w", err)
+	}
+	return d, nil
+}
+
+// ListDisputes lists disputes in a status for operators, oldest first. It defaults to the open disputes.
+func (vs *VerificationService) ListDisputes(ctx context.Context, status string) ([]*models.Dispute, error) {
+	if status == "" {
+		status = models.DisputeOpen
+	}
+	rows, err := vs.db.QueryContext(ctx, "SELECT "+disputeColumns+" FROM company_disputes WHERE status = $1 ORDER BY id LIMIT 500", status)
+	if err != nil {
+		return nil, fmt.Errorf("list disputes: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*models.Dispute{}
+	for rows.Next() {
+		d, err := scanDispute(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list disputes: %w", err)
+		}
+		list = append(list, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list disputes: %w", err)
+	}
+	return list, nil
+}
+
+// ResolveDispute records an operator's decision on an open dispute. Upholding it removes the company's verified
+// badge and expires its open verification requests, so the holder has to verify again.
+func (vs *VerificationService) ResolveDispute(ctx context.Context, operatorID, disputeID int, upheld bool, notes string) (*models.Dispute, error) {
+	tx, err := vs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("resolve dispute: %w", err)
+	}
+	defer tx.Rollback()
+
+	var companyID int
+	err = tx.QueryRowContext(ctx, "SELECT company_id FROM company_disputes WHERE id = $1", disputeID).Scan(&companyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("dispute %d not found", disputeID)
+		}
+		return nil, fmt.Errorf("resolve dispute: %w", err)
+	}
+	if err := lockCompany(ctx, tx, companyID); err != nil {
+		return nil, fmt.Errorf("resolve dispute: %w", err)
+	}
+	var status string
+	err = tx.QueryRowContext(ctx, "SELECT status FROM company_disputes WHERE id = $1 FOR UPDATE", disputeID).Scan(&status)
+	if err != nil {
+		return nil, fmt.Errorf("resolve dispute: %w", err)
+	}
+	if status != models.DisputeOpen {
+		return nil, fmt.Errorf("resolve dispute: %w", ErrInvalidTransition)
+	}
+	newStatus := models.DisputeDismissed
+	if upheld {
+		newStatus = models.DisputeUpheld
+	}
+
+	before, err := rowJSON(ctx, tx, disputeAuditQuery, disputeID)
+	if err != nil {
+		return nil, fmt.Errorf("resolve dispute: %w", err)
+	}
+	d, err := scanDispute(tx.QueryRowContext(ctx, `
+		UPDATE company_disputes SET status = $1, resolved_by = $2, resolved_at = now(), notes = $3 WHERE id = $4
+		RETURNING `+disputeColumns, newStatus, operatorID, notes, disputeID))
+	if err != nil {
+		return nil, fmt.Errorf("resolve dispute: %w", err)
+	}
+	if err := vs.recordUpdate(ctx, tx, operatorID, audit.EntityCompanyDispute, disputeID, before, disputeAuditQuery); err != nil {
+		return nil, fmt.Errorf("resolve dispute: %w", err)
+	}
+
+	if upheld {
+		_, err = tx.ExecContext(ctx, "UPDATE company_verifications SET status = $1 WHERE company_id = $2 AND status IN ($3, $4)",
+			models.VerificationExpired, companyID, models.VerificationPending, models.VerificationProven)
+		if err != nil {
+			return nil, fmt.Errorf("resolve dispute: %w", err)
+		}
+		err = vs.setVerified(ctx, tx, operatorID, companyID, "verified = false, verifiedDomain = NULL, verifiedAt = NULL")
+		if err != nil {
+			return nil, fmt.Errorf("resolve dispute: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("resolve dispute: %w", err)
+	}
+	return d, nil
+}
+
+// lockCompany locks a company row, deleted or not. Writes to verification requests, disputes and reviews lock the
+// company before their own row, the same order as StartVerification, so they cannot deadlock with each other.
+func lockCompany(ctx context.Context, tx *sql.Tx, companyID int) error {
+	_, err := tx.ExecContext(ctx, "SELECT 1 FROM companies WHERE id = $1 FOR UPDATE", companyID)
+	return err
+}
+
+// setVerified changes the verification columns of a company, bumping its version and recording the change in the
+// audit log and outbox. The company must be locked, the assignments can use $2 onwards for args and a deleted
+// company is left alone.
+func (vs *VerificationService) setVerified(ctx context.Context, tx *sql.Tx, actorID, companyID int, assignments string, args ...interface{}) error {
+	before, err := rowJSON(ctx, tx, "SELECT row_to_json(c) FROM companies c WHERE id = $1 AND deleted_at IS NULL", companyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE companies SET "+assignments+", version = version + 1 WHERE id = $1",
+		append([]interface{}{companyID}, args...)...)
+	if err != nil {
+		return err
+	}
+
+	after, err := rowJSON(ctx, tx, "SELECT row_to_json(c) FROM companies c WHERE id = $1", companyID)
+	if err != nil {
+		return err
+	}
+	err = audit.Record(ctx, tx, audit.Event{
+		ActorID: actorID, Action: audit.ActionUpdate, EntityType: audit.EntityCompany, EntityID: companyID, Before: before, After: after,
+	})
+	if err != nil {
+		return err
+	}
+	return outbox.Publish(ctx, tx, outbox.CompanyUpdated, companyID, after)
+}
+
+// recordUpdate records the update of a verification request or dispute in the audit log, reading the new state
+// with the same query as before
+func (vs *VerificationService) recordUpdate(ctx context.Context, tx *sql.Tx, actorID int, entityType string, id int, before json.RawMessage, query string) error {
+	after, err := rowJSON(ctx, tx, query, id)
+	if err != nil {
+		return err
+	}
+	return audit.Record(ctx, tx, audit.Event{
+		ActorID: actorID, Action: audit.ActionUpdate, EntityType: entityType, EntityID: id, Before: before, After: after,
+	})
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"job-portal-api/internal/mailer"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/sqltest"
+	"job-portal-api/internal/verification"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCompany is the companies row the verification flow reads and updates
+type fakeCompany struct {
+	id, owner      int64
+	verified       bool
+	verifiedDomain string
+}
+
+// fakeVerification is a company_verifications row
+type fakeVerification struct {
+	id, companyID, requestedBy int64
+	method, domain, status     string
+	email, dnsToken, codeHash  driver.Value
+	expiresAt                  time.Time
+	provenAt, reviewedAt       driver.Value
+	reviewedBy                 driver.Value
+	notes                      string
+}
+
+// values returns the row in the order of verificationColumns
+func (v fakeVerification) values() []driver.Value {
+	return []driver.Value{v.id, v.companyID, v.requestedBy, v.method, v.domain, v.email, v.dnsToken, v.expiresAt,
+		v.status, v.provenAt, v.reviewedBy, v.reviewedAt, v.notes, time.Unix(1700000000, 0)}
+}
+
+// fakeVerificationDB keeps one company and its verification requests in memory and answers the statements of
+// VerificationService on a sqltest database, so that the flow can be tested without Postgres. A transaction that
+// is not committed is undone.
+type fakeVerificationDB struct {
+	company       fakeCompany
+	verifications []fakeVerification
+	outbox        int // outbox counts published events
+
+	// saved is the state at the start of the open transaction
+	savedCompany       fakeCompany
+	savedVerifications []fakeVerification
+	savedOutbox        int
+}
+
+// find returns the verification with the id, or nil
+func (db *fakeVerificationDB) find(id driver.Value) *fakeVerification {
+	for i := range db.verifications {
+		if db.verifications[i].id == id {
+			return &db.verifications[i]
+		}
+	}
+	return nil
+}
+
+// register answers the statements of VerificationService from the state
+func (db *fakeVerificationDB) register(fake *sqltest.DB) {
+	fake.Strict = true
+	fake.OnBegin = func() {
+		db.savedCompany = db.company
+		db.savedVerifications = append([]fakeVerification(nil), db.verifications...)
+		db.savedOutbox = db.outbox
+	}
+	fake.OnRollback = func() {
+		db.company = db.savedCompany
+		db.verifications = db.savedVerifications
+		db.outbox = db.savedOutbox
+	}
+
+	ignore := func([]driver.Value) error { return nil }
+	fake.Exec("INSERT INTO audit_events", ignore)
+	fake.Exec("FROM companies WHERE id = $1 FOR UPDATE", ignore)
+	fake.Exec("INSERT INTO outbox_events", func([]driver.Value) error {
+		db.outbox++
+		return nil
+	})
+	fake.Exec("UPDATE company_verifications SET status = $1 WHERE company_id = $2", func(args []driver.Value) error {
+		for i, v := range db.verifications {
+			if v.companyID == args[1] && (v.status == args[2] || v.status == args[3]) {
+				db.verifications[i].status = args[0].(string)
+			}
+		}
+		return nil
+	})
+	fake.Exec("UPDATE companies SET verified = true, verifiedDomain = $2", func(args []driver.Value) error {
+		db.company.verified = true
+		db.company.verifiedDomain = args[1].(string)
+		return nil
+	})
+
+	fake.Query("SELECT row_to_json", func([]driver.Value) (*sqltest.Rows, error) {
+		return sqltest.Row([]byte(`{}`)), nil
+	})
+	fake.Query("SELECT verified FROM companies", func(args []driver.Value) (*sqltest.Rows, error) {
+		if db.company.owner == args[0] && db.company.id == args[1] {
+			return sqltest.Row(db.company.verified), nil
+		}
+		return nil, nil
+	})
+	fake.Query("INSERT INTO company_verifications", func(args []driver.Value) (*sqltest.Rows, error) {
+		v := fakeVerification{
+			id: int64(len(db.verifications) + 1), companyID: args[0].(int64), requestedBy: args[1].(int64),
+			method: args[2].(string), domain: args[3].(string), email: args[4], dnsToken: args[5],
+			codeHash: args[6], expiresAt: args[7].(time.Time), status: models.VerificationPending,
+		}
+		db.verifications = append(db.verifications, v)
+		return sqltest.Row(v.values()...), nil
+	})
+	fake.Query("SELECT v.method, v.domain, v.dns_token, v.email_code_hash, v.status, v.expires_at", func(args []driver.Value) (*sqltest.Rows, error) {
+		if v := db.find(args[0]); v != nil && v.companyID == args[1] && db.company.owner == args[2] {
+			return sqltest.Row(v.method, v.domain, v.dnsToken, v.codeHash, v.status, v.expiresAt), nil
+		}
+		return nil, nil
+	})
+	fake.Query("SET status = $1, proven_at = now(), email_code_hash = NULL", func(args []driver.Value) (*sqltest.Rows, error) {
+		v := db.find(args[1])
+		v.status, v.provenAt, v.codeHash = args[0].(string), time.Now(), nil
+		return sqltest.Row(v.values()...), nil
+	})
+	fake.Query("SELECT company_id FROM company_verifications", func(args []driver.Value) (*sqltest.Rows, error) {
+		if v := db.find(args[0]); v != nil {
+			return sqltest.Row(v.companyID), nil
+		}
+		return nil, nil
+	})
+	fake.Query("SELECT status, domain FROM company_verifications", func(args []driver.Value) (*sqltest.Rows, error) {
+		v := db.find(args[0])
+		return sqltest.Row(v.status, v.domain), nil
+	})
+	fake.Query("SET status = $1, reviewed_by = $2, reviewed_at = now(), notes = $3", func(args []driver.Value) (*sqltest.Rows, error) {
+		v := db.find(args[3])
+		v.status, v.reviewedBy, v.reviewedAt, v.notes = args[0].(string), args[1], time.Now(), args[2].(string)
+		return sqltest.Row(v.values()...), nil
+	})
+}
+
+// fakeResolver answers TXT lookups from a map, as if no other record existed
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if records, ok := r[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+// fakeMailer records the emails sent, failing with err when it is set
+type fakeMailer struct {
+	sent []string // sent are the recipients and bodies, as "to\nbody"
+	err  error
+}
+
+func (m *fakeMailer) Send(to, subject, body string, attachments ...mailer.Attachment) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, to+"\n"+body)
+	return nil
+}
+
+const (
+	testOwner    = 7
+	testCompany  = 3
+	testOperator = 1
+)
+
+// newTestVerificationService returns a service on a fake database holding an unverified company of testOwner
+func newTestVerificationService(t *testing.T) (*VerificationService, *fakeVerificationDB, fakeResolver, *fakeMailer) {
+	t.Helper()
+	fake := &fakeVerificationDB{company: fakeCompany{id: testCompany, owner: testOwner}}
+	db, sqlFake := sqltest.Open(t)
+	fake.register(sqlFake)
+	resolver, m := fakeResolver{}, &fakeMailer{}
+	vs, err := NewVerificationService(db, m, resolver, VerificationConfig{TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vs, fake, resolver, m
+}
+
+func TestVerificationDNSFlow(t *testing.T) {
+	vs, fake, resolver, _ := newTestVerificationService(t)
+	ctx := context.Background()
+
+	v, err := vs.StartVerification(ctx, testOwner, testCompany, models.NewVerification{Method: verification.MethodDNS, Domain: "https://Example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Domain != "example.com" || v.Status != models.VerificationPending || v.RecordName != "_job-portal-verification.example.com" ||
+		!strings.HasPrefix(v.RecordValue, "job-portal-verification=") {
+		t.Fatalf("StartVerification() = %+v", v)
+	}
+
+	// The record is not published yet
+	_, err = vs.ConfirmVerification(ctx, testOwner, testCompany, v.ID, "")
+	if !errors.Is(err, ErrProofNotFound) {
+		t.Fatalf("ConfirmVerification() before publishing = %v, want ErrProofNotFound", err)
+	}
+
+	resolver[v.RecordName] = []string{"v=spf1 -all", v.RecordValue}
+	if _, err := vs.ConfirmVerification(ctx, testOwner+1, testCompany, v.ID, ""); err == nil || errors.Is(err, ErrProofNotFound) {
+		t.Fatalf("ConfirmVerification() by another user = %v, want not found", err)
+	}
+	proven, err := vs.ConfirmVerification(ctx, testOwner, testCompany, v.ID, "")
+	if err != nil || proven.Status != models.VerificationProven || proven.ProvenAt == nil {
+		t.Fatalf("ConfirmVerification() = %+v, %v, want proven", proven, err)
+	}
+	if fake.company.verified {
+		t.Fatal("company verified before the operator approved it")
+	}
+
+	approved, err := vs.ReviewVerification(ctx, testOperator, v.ID, true, "checked")
+	if err != nil || approved.Status != models.VerificationApproved || approved.ReviewedBy == nil || *approved.ReviewedBy != testOperator {
+		t.Fatalf("ReviewVerification() = %+v, %v, want approved", approved, err)
+	}
+	if !fake.company.verified || fake.company.verifiedDomain != "example.com" || fake.outbox != 1 {
+		t.Errorf("company after approval = %+v with %d events, want verified for example.com and one event", fake.company, fake.outbox)
+	}
+
+	if _, err := vs.StartVerification(ctx, testOwner, testCompany, models.NewVerification{Method: verification.MethodDNS, Domain: "example.com"}); !errors.Is(err, ErrAlreadyVerified) {
+		t.Errorf("StartVerification() of a verified company = %v, want ErrAlreadyVerified", err)
+	}
+}
+
+func TestVerificationEmailFlow(t *testing.T) {
+	vs, fake, _, m := newTestVerificationService(t)
+	ctx := context.Background()
+
+	v, err := vs.StartVerification(ctx, testOwner, testCompany, models.NewVerification{Method: verification.MethodEmail, Email: "hr@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Domain != "example.com" || v.Email != "hr@example.com" || v.RecordName != "" || len(m.sent) != 1 {
+		t.Fatalf("StartVerification() = %+v and sent %d emails", v, len(m.sent))
+	}
+	to, body, _ := strings.Cut(m.sent[0], "\n")
+	_, code, _ := strings.Cut(body, "Verification code: ")
+	code, _, _ = strings.Cut(code, "\n")
+	if to != "hr@example.com" || code == "" {
+		t.Fatalf("sent %q, want a code to hr@example.com", m.sent[0])
+	}
+	if fake.verifications[0].codeHash == code {
+		t.Fatal("the code is stored in clear")
+	}
+
+	for _, wrong := range []string{"", "000000", code + "0"} {
+		if _, err := vs.ConfirmVerification(ctx, testOwner, testCompany, v.ID, wrong); !errors.Is(err, ErrProofNotFound) {
+			t.Errorf("ConfirmVerification(%q) = %v, want ErrProofNotFound", wrong, err)
+		}
+	}
+	if _, err := vs.ConfirmVerification(ctx, testOwner, testCompany, v.ID, code); err != nil {
+		t.Fatalf("ConfirmVerification() = %v", err)
+	}
+	// The code is single use
+	if _, err := vs.ConfirmVerification(ctx, testOwner, testCompany, v.ID, code); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("ConfirmVerification() twice = %v, want ErrInvalidTransition", err)
+	}
+
+	rejected, err := vs.ReviewVerification(ctx, testOperator, v.ID, false, "not the company's domain")
+	if err != nil || rejected.Status != models.VerificationRejected || rejected.Notes != "not the company's domain" {
+		t.Fatalf("ReviewVerification() = %+v, %v, want rejected", rejected, err)
+	}
+	if fake.company.verified || fake.outbox != 0 {
+		t.Errorf("company after rejection = %+v with %d events, want unverified", fake.company, fake.outbox)
+	}
+	if _, err := vs.ReviewVerification(ctx, testOperator, v.ID, true, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("ReviewVerification() of a rejected request = %v, want ErrInvalidTransition", err)
+	}
+}
+
+func TestStartVerificationErrors(t *testing.T) {
+	vs, fake, _, m := newTestVerificationService(t)
+	ctx := context.Background()
+	dns := models.NewVerification{Method: verification.MethodDNS, Domain: "example.com"}
+
+	if _, err := vs.StartVerification(ctx, testOwner+1, testCompany, dns); err == nil {
+		t.Error("StartVerification() by another user succeeded")
+	}
+	if _, err := vs.StartVerification(ctx, testOwner, testCompany, models.NewVerification{Method: verification.MethodDNS, Domain: "localhost"}); !errors.Is(err, verification.ErrInvalidDomain) {
+		t.Errorf("StartVerification() of localhost = %v, want ErrInvalidDomain", err)
+	}
+	if _, err := vs.StartVerification(ctx, testOwner, testCompany, models.NewVerification{Method: verification.MethodEmail, Email: "me@gmail.com"}); !errors.Is(err, verification.ErrFreeMailDomain) {
+		t.Errorf("StartVerification() at gmail.com = %v, want ErrFreeMailDomain", err)
+	}
+
+	// A request whose email failed is not left behind
+	m.err = errors.New("smtp down")
+	if _, err := vs.StartVerification(ctx, testOwner, testCompany, models.NewVerification{Method: verification.MethodEmail, Email: "hr@example.com"}); !errors.Is(err, m.err) {
+		t.Errorf("StartVerification() with a failing mailer = %v, want the mailer error", err)
+	}
+	if len(fake.verifications) != 0 {
+		t.Fatalf("failed requests stored: %+v", fake.verifications)
+	}
+
+	// Only the latest request can be completed, and a pending one cannot be approved
+	first, err := vs.StartVerification(ctx, testOwner, testCompany, dns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := vs.StartVerification(ctx, testOwner, testCompany, dns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.verifications[0].status != models.VerificationExpired {
+		t.Errorf("earlier request is %s, want expired", fake.verifications[0].status)
+	}
+	if _, err := vs.ConfirmVerification(ctx, testOwner, testCompany, first.ID, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("ConfirmVerification() of an expired request = %v, want ErrInvalidTransition", err)
+	}
+	if _, err := vs.ReviewVerification(ctx, testOperator, second.ID, true, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("ReviewVerification() approving a pending request = %v, want ErrInvalidTransition", err)
+	}
+
+	fake.verifications[1].expiresAt = time.Now().Add(-time.Minute)
+	if _, err := vs.ConfirmVerification(ctx, testOwner, testCompany, second.ID, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("ConfirmVerification() past the expiry = %v, want ErrInvalidTransition", err)
+	}
+}
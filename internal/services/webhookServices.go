@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"job-portal-api/internal/models"
+	"job-portal-api/internal/outbox"
+	"job-portal-api/internal/webhooks"
+	"time"
+)
+
+// TestEventType is the event type sent by TestWebhook
+const TestEventType = "webhook.test"
+
+// WebhookService handles business logic related to company webhooks.
+type WebhookService struct {
+	db     *sql.DB
+	sender *webhooks.Sender
+}
+
+// NewWebhookService creates a new WebhookService instance.
+func NewWebhookService(db *sql.DB, sender *webhooks.Sender) (*WebhookService, error) {
+	if db == nil {
+		return nil, errors.New("db connection cannot be nil")
+	}
+	if sender == nil {
+		return nil, errors.New("webhook sender cannot be nil")
+	}
+	return &WebhookService{db: db, sender: sender}, nil
+}
+
+// CreateWebhook registers a webhook endpoint for a company owned by the user. The generated signing
+// secret is only returned here.
+func (ws *WebhookService) CreateWebhook(ctx context.Context, userID, companyID int, nw models.NewWebhook) (*models.Webhook, error) {
+	// Only http and https endpoints on public addresses can receive events
+	if err := webhooks.CheckURL(ctx, nw.URL); err != nil {
+		return nil, fmt.Errorf("create webhook: %w", err)
+	}
+	for _, t := range nw.EventTypes {
+		if !validEventType(t) {
+			return nil, fmt.Errorf("create webhook: unknown event type %q", t)
+		}
+	}
+
+	if err := ws.checkCompanyOwner(ctx, userID, companyID); err != nil {
+		return nil, err
+	}
+
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return nil, fmt.Errorf("create webhook: %w", err)
+	}
+
+	wh := models.Webhook{
+		CompanyId:  companyID,
+		URL:        nw.URL,
+		Secret:     "whsec_" + hex.EncodeToString(secretBytes),
+		EventTypes: nw.EventTypes,
+		Active:     true,
+	}
+	if wh.EventTypes == nil {
+		wh.EventTypes = []string{}
+	}
+
+	eventTypes, err := json.Marshal(wh.EventTypes)
+	if err != nil {
+		return nil, fmt.Errorf("create webhook: %w", err)
+	}
+	err = ws.db.QueryRowContext(ctx, `
+		INSERT INTO webhook_endpoints (company_id, url, secret, event_types)
+		VALUES ($1, $2, $3, ARRAY(SELECT jsonb_array_elements_text($4::jsonb)))
+		RETURNING id, created_at`, companyID, wh.URL, wh.Secret, eventTypes).Scan(&wh.ID, &wh.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("create webhook: %w", err)
+	}
+	return &wh, nil
+}
+
+// GetWebhooksByCompanyID retrieves the webhooks of a company owned by the user, without their secrets.
+func (ws *WebhookService) GetWebhooksByCompanyID(ctx context.Context, userID, companyID int) ([]*models.Webhook, error) {
+	if err := ws.checkCompanyOwner(ctx, userID, companyID); err != nil {
+		return nil, err
+	}
+
+	rows, err := ws.db.QueryContext(ctx, `
+		SELECT id, company_id, url, array_to_json(event_types), active, created_at
+		FROM webhook_endpoints WHERE company_id = $1 ORDER BY id`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("get webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var hooks []*models.Webhook
+	for rows.Next() {
+		var wh models.Webhook
+		var eventTypes []byte
+		if err := rows.Scan(&wh.ID, &wh.CompanyId, &wh.URL, &eventTypes, &wh.Active, &wh.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan webhook: %w", err)
+		}
+		if err := json.Unmarshal(eventTypes, &wh.EventTypes); err != nil {
+			return nil, fmt.Errorf("scan webhook: %w", err)
+		}
+		hooks = append(hooks, &wh)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate webhooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// GetDeliveries retrieves the most recent deliveries of a webhook, newest first.
+func (ws *WebhookService) GetDeliveries(ctx context.Context, userID, companyID, webhookID int) ([]*models.WebhookDelivery, error) {
+	if _, _, err := ws.webhookForOwner(ctx, userID, companyID, webhookID); err != nil {
+		return nil, err
+	}
+
+	rows, err := ws.db.QueryContext(ctx, `
+		SELECT id, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+		       last_status_code, last_error, created_at, delivered_at
+		FROM webhook_deliveries WHERE endpoint_id = $1 ORDER BY id DESC LIMIT 100`, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("get deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var payload []byte
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("scan delivery: %w", err)
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ReplayDelivery queues a new delivery of the same event to the webhook, whatever the state of the original.
+func (ws *WebhookService) ReplayDelivery(ctx context.Context, userID, companyID, webhookID int, deliveryID int64) (int64, error) {
+	if _, _, err := ws.webhookForOwner(ctx, userID, companyID, webhookID); err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err := ws.db.QueryRowContext(ctx, `
+		INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload)
+		SELECT endpoint_id, event_id, event_type, payload FROM webhook_deliveries
+		WHERE id = $1 AND endpoint_id = $2
+		RETURNING id`, deliveryID, webhookID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("delivery not found for webhook %d and delivery ID %d", webhookID, deliveryID)
+		}
+		return 0, fmt.Errorf("replay delivery: %w", err)
+	}
+	return id, nil
+}
+
+// TestWebhook sends a signed webhook.test event to the endpoint right away. A failed delivery is
+// reported in the result rather than as an error.
+func (ws *WebhookService) TestWebhook(ctx context.Context, userID, companyID, webhookID int) (*models.WebhookTestResult, error) {
+	endpoint, secret, err := ws.webhookForOwner(ctx, userID, companyID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(map[string]int{"webhookId": webhookID, "companyId": companyID})
+	if err != nil {
+		return nil, fmt.Errorf("test webhook: %w", err)
+	}
+	env := webhooks.Envelope{Type: TestEventType, OccurredAt: time.Now().UTC(), Data: data}
+
+	var result models.WebhookTestResult
+	result.StatusCode, err = ws.sender.Send(ctx, endpoint, secret, 0, env)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Delivered = true
+	}
+	return &result, nil
+}
+
+// checkCompanyOwner returns an error unless the company exists and belongs to the user.
+func (ws *WebhookService) checkCompanyOwner(ctx context.Context, userID, companyID int) error {
+	var count int
+	err := ws.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM companies WHERE userId = $1 AND id = $2 AND deleted_at IS NULL", userID, companyID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("query company existence: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("company not found for user with ID %d and company ID %d", userID, companyID)
+	}
+	return nil
+}
+
+// webhookForOwner returns the URL and secret of a webhook of a company owned by the user.
+func (ws *WebhookService) webhookForOwner(ctx context.Context, userID, companyID, webhookID int) (string, string, error) {
+	var endpoint, secret string
+	err := ws.db.QueryRowContext(ctx, `
+		SELECT w.url, w.secret FROM webhook_endpoints w JOIN companies c ON c.id = w.company_id
+		WHERE w.id = $1 AND c.id = $2 AND c.userId = $3`, webhookID, companyID, userID).Scan(&endpoint, &secret)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", fmt.Errorf("webhook not found for company %d and webhook ID %d", companyID, webhookID)
+		}
+		return "", "", fmt.Errorf("query webhook: %w", err)
+	}
+	return endpoint, secret, nil
+}
+
+// validEventType reports whether t is a known outbox event type.
+func validEventType(t string) bool {
+	for _, known := range outbox.EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
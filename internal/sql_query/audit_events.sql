@@ -0,0 +1,32 @@
+CREATE TABLE audit_events (
+  id BIGSERIAL PRIMARY KEY,
+  occurred_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  actor_id INTEGER NOT NULL,
+  action TEXT NOT NULL,
+  entity_type TEXT NOT NULL,
+  entity_id INTEGER NOT NULL,
+  before JSONB,
+  after JSONB,
+  changes JSONB NOT NULL,
+  request_id TEXT NOT NULL DEFAULT '',
+  ip TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_events_actor_idx ON audit_events (actor_id, occurred_at);
+CREATE INDEX audit_events_entity_idx ON audit_events (entity_type, entity_id, occurred_at);
+CREATE INDEX audit_events_occurred_at_idx ON audit_events (occurred_at);
+
+-- The audit log is append-only: updates are always rejected and deletes are only
+-- allowed from the retention job, which sets audit.allow_purge for its transaction.
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'DELETE' AND current_setting('audit.allow_purge', true) = 'on' THEN
+    RETURN OLD;
+  END IF;
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+BEFORE UPDATE OR DELETE ON audit_events
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,43 @@
+-- Profiles candidates fill in to get job recommendations and to be suggested to recruiters
+CREATE TABLE candidate_profiles (
+  user_id INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+  headline TEXT NOT NULL DEFAULT '',
+  skills TEXT[] NOT NULL DEFAULT '{}',
+  skill_terms TEXT[] NOT NULL DEFAULT '{}',
+  desired_salary INTEGER,
+  desired_currency TEXT,
+  desired_pay_period TEXT,
+  -- The desired salary per year in the base currency, NULL without an exchange rate
+  desired_salary_annual_base DOUBLE PRECISION,
+  location TEXT NOT NULL DEFAULT '',
+  -- Where the location was geocoded to, empty when it was not found
+  city TEXT NOT NULL DEFAULT '',
+  country TEXT NOT NULL DEFAULT '',
+  open_to_work BOOLEAN NOT NULL DEFAULT true,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Suggested candidates are found by the title terms of their skills
+CREATE INDEX candidate_profiles_skill_terms_idx ON candidate_profiles USING gin (skill_terms) WHERE open_to_work;
+
+-- Jobs a user looked at, kept once per user and job
+CREATE TABLE job_views (
+  user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  job_id INTEGER NOT NULL REFERENCES jobs (id) ON DELETE CASCADE,
+  view_count INTEGER NOT NULL DEFAULT 1,
+  last_viewed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, job_id)
+);
+
+CREATE INDEX job_views_job_idx ON job_views (job_id);
+
+-- Applications of candidates to jobs
+CREATE TABLE job_applications (
+  id SERIAL PRIMARY KEY,
+  job_id INTEGER NOT NULL REFERENCES jobs (id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (job_id, user_id)
+);
+
+CREATE INDEX job_applications_user_idx ON job_applications (user_id, id);
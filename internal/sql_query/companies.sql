@@ -1,7 +1,47 @@
 CREATE TABLE companies (
   id SERIAL PRIMARY KEY,
-  name TEXT UNIQUE NOT NULL,
+  name TEXT NOT NULL,
+  normalizedName TEXT NOT NULL,
   address TEXT NOT NULL,
   userId SERIAL,
+  version INTEGER NOT NULL DEFAULT 1,
+  aggregatorOptOut BOOLEAN NOT NULL DEFAULT false,
+  slug TEXT NOT NULL,
+  logoUrl TEXT NOT NULL DEFAULT '',
+  brandColor TEXT NOT NULL DEFAULT '',
+  description TEXT NOT NULL DEFAULT '',
+  website TEXT NOT NULL DEFAULT '',
+  industry TEXT NOT NULL DEFAULT '',
+  sizeBand TEXT NOT NULL DEFAULT '',
+  foundedYear INTEGER,
+  socialLinks JSONB NOT NULL DEFAULT '{}',
+  logoUpdatedAt TIMESTAMPTZ,
+  verified BOOLEAN NOT NULL DEFAULT false,
+  verifiedDomain TEXT,
+  verifiedAt TIMESTAMPTZ,
+  -- Only approved companies are published, flagged ones wait in moderation_items
+  moderationStatus TEXT NOT NULL DEFAULT 'approved',
+  -- The place the address was geocoded to, shared by the jobs without a location of their own
+  city TEXT NOT NULL DEFAULT '',
+  region TEXT NOT NULL DEFAULT '',
+  country TEXT NOT NULL DEFAULT '',
+  latitude DOUBLE PRECISION,
+  longitude DOUBLE PRECISION,
+  deleted_at TIMESTAMPTZ,
   FOREIGN KEY (userId) REFERENCES users (id)
-);
\ No newline at end of file
+);
+
+-- Names only have to be unique among live companies, so a soft deleted company does not block its name.
+-- Uniqueness is checked on the normalized name, so names differing only in case or spacing collide.
+CREATE UNIQUE INDEX companies_name_live_idx ON companies (normalizedName) WHERE deleted_at IS NULL;
+-- Slugs stay unique across deleted companies too, so a restored company keeps its careers page URL
+CREATE UNIQUE INDEX companies_slug_idx ON companies (slug);
+CREATE INDEX companies_deleted_at_idx ON companies (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Resized PNG thumbnails of uploaded company logos
+CREATE TABLE company_logos (
+  company_id INTEGER NOT NULL REFERENCES companies (id) ON DELETE CASCADE,
+  size INTEGER NOT NULL,
+  data BYTEA NOT NULL,
+  PRIMARY KEY (company_id, size)
+);
@@ -0,0 +1,46 @@
+-- Reviews of companies by candidates. Only approved reviews are public and counted in the company rating.
+CREATE TABLE company_reviews (
+  id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies (id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users (id),
+  overall SMALLINT NOT NULL CHECK (overall BETWEEN 1 AND 5),
+  work_life SMALLINT CHECK (work_life BETWEEN 1 AND 5),
+  compensation SMALLINT CHECK (compensation BETWEEN 1 AND 5),
+  culture SMALLINT CHECK (culture BETWEEN 1 AND 5),
+  management SMALLINT CHECK (management BETWEEN 1 AND 5),
+  career_growth SMALLINT CHECK (career_growth BETWEEN 1 AND 5),
+  title TEXT NOT NULL DEFAULT '',
+  pros TEXT NOT NULL,
+  cons TEXT NOT NULL,
+  employment_status TEXT NOT NULL,
+  job_title TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'pending',
+  moderation_notes TEXT NOT NULL DEFAULT '',
+  moderated_by INTEGER REFERENCES users (id),
+  moderated_at TIMESTAMPTZ,
+  response TEXT NOT NULL DEFAULT '',
+  responded_by INTEGER REFERENCES users (id),
+  responded_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  -- One review per user per company, edits replace it
+  UNIQUE (company_id, user_id)
+);
+
+CREATE INDEX company_reviews_status_idx ON company_reviews (status, id);
+
+-- Aggregates of the approved reviews of a company, recomputed whenever they change so that
+-- companies can be sorted by rating. rating_revision is part of the company ETag.
+CREATE TABLE company_ratings (
+  company_id INTEGER PRIMARY KEY REFERENCES companies (id) ON DELETE CASCADE,
+  review_count INTEGER NOT NULL,
+  overall_rating NUMERIC(3, 2),
+  work_life_rating NUMERIC(3, 2),
+  compensation_rating NUMERIC(3, 2),
+  culture_rating NUMERIC(3, 2),
+  management_rating NUMERIC(3, 2),
+  career_growth_rating NUMERIC(3, 2),
+  rating_revision INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX company_ratings_overall_idx ON company_ratings (overall_rating DESC NULLS LAST, review_count DESC);
@@ -0,0 +1,41 @@
+-- Requests of company owners to verify their company by proving control of its domain.
+-- A request is pending until the proof is checked, then proven until an operator approves or rejects it.
+CREATE TABLE company_verifications (
+  id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies (id) ON DELETE CASCADE,
+  requested_by INTEGER NOT NULL REFERENCES users (id),
+  method TEXT NOT NULL,
+  domain TEXT NOT NULL,
+  email TEXT,
+  -- The DNS token is published by the owner anyway, the email code is only stored hashed
+  dns_token TEXT,
+  email_code_hash TEXT,
+  expires_at TIMESTAMPTZ NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending',
+  proven_at TIMESTAMPTZ,
+  reviewed_by INTEGER REFERENCES users (id),
+  reviewed_at TIMESTAMPTZ,
+  notes TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX company_verifications_company_idx ON company_verifications (company_id, id);
+CREATE INDEX company_verifications_status_idx ON company_verifications (status, id);
+
+-- Disputes raised by users claiming a company name belongs to someone else
+CREATE TABLE company_disputes (
+  id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies (id) ON DELETE CASCADE,
+  raised_by INTEGER NOT NULL REFERENCES users (id),
+  reason TEXT NOT NULL,
+  domain TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'open',
+  resolved_by INTEGER REFERENCES users (id),
+  resolved_at TIMESTAMPTZ,
+  notes TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- A user can only have one open dispute per company
+CREATE UNIQUE INDEX company_disputes_open_idx ON company_disputes (company_id, raised_by) WHERE status = 'open';
+CREATE INDEX company_disputes_status_idx ON company_disputes (status, id);
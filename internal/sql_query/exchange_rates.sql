@@ -0,0 +1,7 @@
+-- Exchange rates against the base currency, SALARY_CURRENCY, loaded with cmd/exchange-rates or by operators
+CREATE TABLE exchange_rates (
+  currency TEXT PRIMARY KEY,
+  -- Units of the currency one unit of the base currency buys
+  rate DOUBLE PRECISION NOT NULL CHECK (rate > 0),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,26 @@
+-- Offline geocoding table, loaded from GeoNames files with cmd/geo-seed
+CREATE TABLE geo_countries (
+  code TEXT PRIMARY KEY,
+  iso3 TEXT NOT NULL,
+  name TEXT NOT NULL,
+  -- Lower case names without accents the country is found by, see internal/geo
+  name_keys TEXT[] NOT NULL,
+  currency TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX geo_countries_name_keys_idx ON geo_countries USING GIN (name_keys);
+
+CREATE TABLE geo_places (
+  id INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  name_keys TEXT[] NOT NULL,
+  country TEXT NOT NULL,
+  region_code TEXT NOT NULL DEFAULT '',
+  region TEXT NOT NULL DEFAULT '',
+  latitude DOUBLE PRECISION NOT NULL,
+  longitude DOUBLE PRECISION NOT NULL,
+  population BIGINT NOT NULL DEFAULT 0,
+  timezone TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX geo_places_name_keys_idx ON geo_places USING GIN (name_keys);
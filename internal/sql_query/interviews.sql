@@ -0,0 +1,43 @@
+-- Interview availability recruiters publish for a job, shown in the time zone of the recruiter
+CREATE TABLE interview_slots (
+  id SERIAL PRIMARY KEY,
+  job_id INTEGER NOT NULL REFERENCES jobs (id) ON DELETE CASCADE,
+  recruiter_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  starts_at TIMESTAMPTZ NOT NULL,
+  ends_at TIMESTAMPTZ NOT NULL,
+  -- IANA time zone the slot is shown in and invitations are written in
+  timezone TEXT NOT NULL,
+  -- Where the interview happens, such as an address or a video call link
+  location TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX interview_slots_job_idx ON interview_slots (job_id, starts_at);
+CREATE INDEX interview_slots_recruiter_idx ON interview_slots (recruiter_id, starts_at);
+
+-- Interviews candidates booked for their applications. The time and place are copied from the slot, so that the
+-- invitation of a cancelled interview can still be written once its slot is deleted.
+CREATE TABLE interviews (
+  id SERIAL PRIMARY KEY,
+  application_id INTEGER NOT NULL REFERENCES job_applications (id) ON DELETE CASCADE,
+  slot_id INTEGER REFERENCES interview_slots (id) ON DELETE SET NULL,
+  recruiter_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  starts_at TIMESTAMPTZ NOT NULL,
+  ends_at TIMESTAMPTZ NOT NULL,
+  timezone TEXT NOT NULL,
+  location TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'scheduled',
+  -- Revision of the iCalendar invitation, incremented on every reschedule and on cancellation
+  sequence INTEGER NOT NULL DEFAULT 0,
+  cancelled_by INTEGER REFERENCES users (id) ON DELETE SET NULL,
+  cancel_reason TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- A slot holds one scheduled interview, and an application one scheduled interview at a time. Bookings lock the
+-- candidate and then the slot; these catch anything that gets past the locks.
+CREATE UNIQUE INDEX interviews_slot_scheduled_idx ON interviews (slot_id) WHERE status = 'scheduled';
+CREATE UNIQUE INDEX interviews_application_scheduled_idx ON interviews (application_id) WHERE status = 'scheduled';
+CREATE INDEX interviews_recruiter_idx ON interviews (recruiter_id, starts_at);
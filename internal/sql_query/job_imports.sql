@@ -0,0 +1,21 @@
+CREATE TABLE job_imports (
+  id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(id),
+  status TEXT NOT NULL,
+  mode TEXT NOT NULL,
+  dry_run BOOLEAN NOT NULL DEFAULT false,
+  total INTEGER NOT NULL DEFAULT 0,
+  valid INTEGER NOT NULL DEFAULT 0,
+  inserted INTEGER NOT NULL DEFAULT 0,
+  failed INTEGER NOT NULL DEFAULT 0,
+  errors JSONB NOT NULL DEFAULT '[]',
+  message TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  -- Touched every minute by the instance running an async import, so that imports it abandoned are failed
+  heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX job_imports_company_id_idx ON job_imports (company_id);
+CREATE INDEX job_imports_active_idx ON job_imports (heartbeat_at) WHERE status IN ('pending', 'running');
@@ -1,7 +1,52 @@
 CREATE TABLE jobs (
     id SERIAL PRIMARY KEY,
     jobRole TEXT,
+    -- The meaningful words of the role with abbreviations expanded, that salary insights are grouped by
+    normalizedRole TEXT NOT NULL DEFAULT '',
     salary INTEGER,
+    -- ISO 4217 currency and pay period of the salary, and the salary per year in the base currency, NULL
+    -- without an exchange rate for the currency
+    currency TEXT NOT NULL DEFAULT 'USD',
+    payPeriod TEXT NOT NULL DEFAULT 'year',
+    salaryAnnualBase DOUBLE PRECISION,
     companyId SERIAL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ,
+    -- Only approved jobs are published, flagged ones wait in moderation_items
+    moderationStatus TEXT NOT NULL DEFAULT 'approved',
+    -- Simhash of the role, company and location, and the first job of the near duplicates this job belongs to
+    fingerprint BIGINT,
+    duplicateOf INTEGER REFERENCES jobs (id) ON DELETE SET NULL,
+    -- Location as entered, empty for the company address, and the place it was geocoded to
+    location TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL DEFAULT '',
+    region TEXT NOT NULL DEFAULT '',
+    country TEXT NOT NULL DEFAULT '',
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    -- Remote jobs may be restricted to candidates in some countries or time zones, empty for anywhere
+    remote BOOLEAN NOT NULL DEFAULT false,
+    remoteCountries TEXT[] NOT NULL DEFAULT '{}',
+    remoteTimezones TEXT[] NOT NULL DEFAULT '{}',
     FOREIGN KEY (companyId) REFERENCES companies (id)
 );
+
+CREATE INDEX jobs_deleted_at_idx ON jobs (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Near duplicate lookups match one 16 bit band of the fingerprint exactly, see internal/dedup
+CREATE INDEX jobs_fingerprint_band0_idx ON jobs (((fingerprint >> 48) & 65535)) WHERE deleted_at IS NULL;
+CREATE INDEX jobs_fingerprint_band1_idx ON jobs (((fingerprint >> 32) & 65535)) WHERE deleted_at IS NULL;
+CREATE INDEX jobs_fingerprint_band2_idx ON jobs (((fingerprint >> 16) & 65535)) WHERE deleted_at IS NULL;
+CREATE INDEX jobs_fingerprint_band3_idx ON jobs ((fingerprint & 65535)) WHERE deleted_at IS NULL;
+
+-- Recommendations prefilter on the terms of the normalized role
+CREATE INDEX jobs_role_terms_idx ON jobs USING gin (string_to_array(normalizedRole, ' ')) WHERE deleted_at IS NULL;
+
+-- Radius searches prefilter on a bounding box of the coordinates
+CREATE INDEX jobs_coordinates_idx ON jobs (latitude, longitude) WHERE deleted_at IS NULL AND latitude IS NOT NULL;
+CREATE INDEX jobs_country_idx ON jobs (country) WHERE deleted_at IS NULL;
+
+-- Salary filters and sorting compare the annual salaries in the base currency
+CREATE INDEX jobs_salary_annual_base_idx ON jobs (salaryAnnualBase) WHERE deleted_at IS NULL;
@@ -0,0 +1,40 @@
+-- Review queue of flagged jobs and companies. An entity has at most one pending item.
+CREATE TABLE moderation_items (
+  id SERIAL PRIMARY KEY,
+  entity_type TEXT NOT NULL,
+  entity_id INTEGER NOT NULL,
+  company_id INTEGER NOT NULL REFERENCES companies (id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users (id),
+  source TEXT NOT NULL,
+  findings JSONB NOT NULL DEFAULT '[]',
+  status TEXT NOT NULL DEFAULT 'pending',
+  reason TEXT NOT NULL DEFAULT '',
+  reviewed_by INTEGER REFERENCES users (id),
+  reviewed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX moderation_items_pending_idx ON moderation_items (entity_type, entity_id) WHERE status = 'pending';
+CREATE INDEX moderation_items_status_idx ON moderation_items (status, id);
+CREATE INDEX moderation_items_company_idx ON moderation_items (company_id, id);
+-- Rejections per owner are counted for automatic suspension
+CREATE INDEX moderation_items_rejected_idx ON moderation_items (user_id, reviewed_at) WHERE status = 'rejected';
+
+-- Abuse reports on jobs and companies by users
+CREATE TABLE abuse_reports (
+  id SERIAL PRIMARY KEY,
+  entity_type TEXT NOT NULL,
+  entity_id INTEGER NOT NULL,
+  reporter_id INTEGER NOT NULL REFERENCES users (id),
+  reason TEXT NOT NULL,
+  details TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'open',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- A user can only have one open report per job or company
+CREATE UNIQUE INDEX abuse_reports_open_idx ON abuse_reports (entity_type, entity_id, reporter_id) WHERE status = 'open';
+
+-- Duplicate text lookups of the moderation rules, on the whitespace and case folded text
+CREATE INDEX companies_description_md5_idx ON companies (md5(lower(regexp_replace(btrim(description), '\s+', ' ', 'g'))));
+CREATE INDEX jobs_jobrole_md5_idx ON jobs (md5(lower(regexp_replace(btrim(jobRole), '\s+', ' ', 'g'))));
@@ -0,0 +1,9 @@
+CREATE TABLE rate_limits (
+  key TEXT PRIMARY KEY,
+  tokens DOUBLE PRECISION NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  -- When the bucket is full again under its policy, after which it is purged
+  idle_until TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX rate_limits_idle_until_idx ON rate_limits (idle_until);
@@ -0,0 +1,60 @@
+-- Annual salary statistics in the base currency of the published postings of the last three years, refreshed
+-- periodically by the API.
+-- Every combination of role, company size and month is aggregated, by country and city, by country only and
+-- over all locations. A NULL dimension stands for all of its values; near duplicates are left out so that
+-- reposts do not count twice. top_company_postings is the number of postings of the company with the most of
+-- them in the group, so that groups one company dominates can be withheld.
+CREATE MATERIALIZED VIEW salary_insights AS
+WITH postings AS (
+  SELECT
+    j.normalizedRole AS role,
+    j.country,
+    j.city,
+    c.sizeBand AS size_band,
+    date_trunc('month', j.created_at AT TIME ZONE 'UTC')::date AS month,
+    j.companyId AS company_id,
+    j.salaryAnnualBase AS salary
+  FROM jobs j JOIN companies c ON c.id = j.companyId
+  WHERE j.moderationStatus = 'approved' AND c.moderationStatus = 'approved' AND j.duplicateOf IS NULL
+    AND j.deleted_at IS NULL AND c.deleted_at IS NULL
+    AND j.salaryAnnualBase > 0 AND j.created_at >= now() - interval '36 months'
+),
+-- The grouping set tells a dimension aggregated over all of its values from one whose value is NULL
+groups AS (
+  SELECT
+    role, country, city, size_band, month,
+    GROUPING(role, size_band, month, country, city) AS grouping_set,
+    count(*) AS postings,
+    count(DISTINCT company_id) AS companies,
+    percentile_cont(0.1) WITHIN GROUP (ORDER BY salary) AS p10,
+    percentile_cont(0.25) WITHIN GROUP (ORDER BY salary) AS p25,
+    percentile_cont(0.5) WITHIN GROUP (ORDER BY salary) AS median,
+    percentile_cont(0.75) WITHIN GROUP (ORDER BY salary) AS p75,
+    percentile_cont(0.9) WITHIN GROUP (ORDER BY salary) AS p90
+  FROM postings
+  GROUP BY CUBE (role, size_band, month), ROLLUP (country, city)
+),
+company_groups AS (
+  SELECT
+    role, country, city, size_band, month,
+    GROUPING(role, size_band, month, country, city) AS grouping_set,
+    count(*) AS postings
+  FROM postings
+  GROUP BY company_id, CUBE (role, size_band, month), ROLLUP (country, city)
+),
+top_companies AS (
+  SELECT role, country, city, size_band, month, grouping_set, max(postings) AS top_company_postings
+  FROM company_groups
+  GROUP BY role, country, city, size_band, month, grouping_set
+)
+SELECT
+  g.role, g.country, g.city, g.size_band, g.month, g.postings, g.companies, t.top_company_postings,
+  g.p10, g.p25, g.median, g.p75, g.p90,
+  now() AS refreshed_at
+FROM groups g JOIN top_companies t ON t.grouping_set = g.grouping_set
+  AND t.role IS NOT DISTINCT FROM g.role AND t.country IS NOT DISTINCT FROM g.country
+  AND t.city IS NOT DISTINCT FROM g.city AND t.size_band IS NOT DISTINCT FROM g.size_band
+  AND t.month IS NOT DISTINCT FROM g.month;
+
+-- Required to refresh the view concurrently, without blocking reads
+CREATE UNIQUE INDEX salary_insights_key_idx ON salary_insights (role, country, city, size_band, month);
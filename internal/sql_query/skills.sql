@@ -0,0 +1,29 @@
+-- Canonical skills and the categories they belong to, seeded with cmd/skills-seed
+CREATE TABLE skills (
+  id SERIAL PRIMARY KEY,
+  slug TEXT NOT NULL UNIQUE,
+  name TEXT NOT NULL,
+  parent_id INTEGER REFERENCES skills (id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX skills_parent_idx ON skills (parent_id);
+
+-- Other names of skills, stored as lower case words separated by single spaces
+CREATE TABLE skill_aliases (
+  id SERIAL PRIMARY KEY,
+  alias TEXT NOT NULL UNIQUE,
+  skill_id INTEGER NOT NULL REFERENCES skills (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX skill_aliases_skill_idx ON skill_aliases (skill_id);
+
+-- Skills extracted from the role of every job
+CREATE TABLE job_skills (
+  job_id INTEGER NOT NULL REFERENCES jobs (id) ON DELETE CASCADE,
+  skill_id INTEGER NOT NULL REFERENCES skills (id) ON DELETE CASCADE,
+  PRIMARY KEY (job_id, skill_id)
+);
+
+CREATE INDEX job_skills_skill_idx ON job_skills (skill_id, job_id);
@@ -2,5 +2,12 @@ CREATE TABLE users (
   id SERIAL PRIMARY KEY,
   email TEXT UNIQUE NOT NULL,
   password_hash TEXT NOT NULL,
-  role TEXT NOT NULL
+  role TEXT NOT NULL,
+  failed_logins INTEGER NOT NULL DEFAULT 0,
+  locked_until TIMESTAMPTZ,
+  unlock_token_hash TEXT,
+  unlock_token_expires_at TIMESTAMPTZ,
+  -- Suspended users cannot post, set after repeated moderation violations
+  suspended_at TIMESTAMPTZ,
+  suspension_reason TEXT NOT NULL DEFAULT ''
 );
@@ -0,0 +1,248 @@
+// Package sqltest provides an in-memory database/sql driver for tests that run services without Postgres.
+// Statements are answered by handlers registered for a fragment of their SQL. Runs of whitespace are collapsed in
+// both, so reindenting or rewrapping a query does not break the tests that answer it.
+package sqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// QueryFunc answers a query with rows, or nil for no rows.
+type QueryFunc func(args []driver.Value) (*Rows, error)
+
+// ExecFunc runs a statement.
+type ExecFunc func(args []driver.Value) error
+
+// Exec is a statement that was run
+type Exec struct {
+	Query string         // Query is the statement with its whitespace collapsed
+	Args  []driver.Value // Args are the values of its parameters
+}
+
+// handler answers the statements containing fragment
+type handler struct {
+	fragment string
+	query    QueryFunc
+	exec     ExecFunc
+}
+
+// DB is the fake database. Handlers run with the database locked, one statement at a time.
+type DB struct {
+	// Strict fails statements run through Exec without a handler, instead of only recording them
+	Strict bool
+	// OnBegin and OnRollback, when set, are called as transactions begin and roll back, to save and restore state
+	OnBegin, OnRollback func()
+
+	mu        sync.Mutex
+	handlers  []handler
+	execs     []Exec
+	commits   int
+	rollbacks int
+}
+
+// Open returns a database/sql handle on a new fake database, closed when the test ends. It uses a single
+// connection, so statements of a transaction and outside of it are seen in the order they run.
+func Open(t testing.TB) (*sql.DB, *DB) {
+	t.Helper()
+	fake := &DB{}
+	db := sql.OpenDB(fake)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db, fake
+}
+
+// Query answers the queries containing fragment with fn. Handlers registered later take precedence.
+func (db *DB) Query(fragment string, fn QueryFunc) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.handlers = append(db.handlers, handler{fragment: normalize(fragment), query: fn})
+}
+
+// Exec runs fn for the statements containing fragment run through Exec. Handlers registered later take precedence.
+func (db *DB) Exec(fragment string, fn ExecFunc) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.handlers = append(db.handlers, handler{fragment: normalize(fragment), exec: fn})
+}
+
+// Execs returns the statements run through Exec that contain fragment, in order.
+func (db *DB) Execs(fragment string) []Exec {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	fragment = normalize(fragment)
+	var out []Exec
+	for _, e := range db.execs {
+		if strings.Contains(e.Query, fragment) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Commits returns the number of committed transactions.
+func (db *DB) Commits() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.commits
+}
+
+// Rollbacks returns the number of rolled back transactions.
+func (db *DB) Rollbacks() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.rollbacks
+}
+
+// find returns the latest handler of the kind for the query
+func (db *DB) find(query string, exec bool) *handler {
+	for i := len(db.handlers) - 1; i >= 0; i-- {
+		h := &db.handlers[i]
+		if (h.exec != nil) == exec && strings.Contains(query, h.fragment) {
+			return h
+		}
+	}
+	return nil
+}
+
+// Connect implements driver.Connector.
+func (db *DB) Connect(context.Context) (driver.Conn, error) { return conn{db}, nil }
+
+// Driver implements driver.Connector.
+func (db *DB) Driver() driver.Driver { return nil }
+
+type conn struct{ db *DB }
+
+func (c conn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("sqltest: prepared statements are not supported")
+}
+func (c conn) Close() error { return nil }
+
+func (c conn) Begin() (driver.Tx, error) {
+	if c.db.OnBegin != nil {
+		c.db.mu.Lock()
+		c.db.OnBegin()
+		c.db.mu.Unlock()
+	}
+	return tx(c), nil
+}
+
+// BeginTx begins a transaction, accepting the options of read-only ones, which the fake does not enforce.
+func (c conn) BeginTx(_ context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	return c.Begin()
+}
+
+func (c conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	query = normalize(query)
+	values := namedValues(args)
+	c.db.execs = append(c.db.execs, Exec{Query: query, Args: values})
+
+	h := c.db.find(query, true)
+	if h == nil {
+		if c.db.Strict {
+			return nil, errors.New("sqltest: unexpected statement: " + query)
+		}
+		return driver.RowsAffected(1), nil
+	}
+	if err := h.exec(values); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	query = normalize(query)
+	h := c.db.find(query, false)
+	if h == nil {
+		return nil, errors.New("sqltest: unexpected query: " + query)
+	}
+	rows, err := h.query(namedValues(args))
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		rows = &Rows{}
+	}
+	return rows, nil
+}
+
+type tx conn
+
+func (t tx) Commit() error {
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+	t.db.commits++
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+	t.db.rollbacks++
+	if t.db.OnRollback != nil {
+		t.db.OnRollback()
+	}
+	return nil
+}
+
+// Rows are canned result rows.
+type Rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+// NewRows returns an empty result with the columns. Column names only matter to code that reads them.
+func NewRows(columns ...string) *Rows {
+	return &Rows{columns: columns}
+}
+
+// Add appends a row and returns the rows, for chaining.
+func (r *Rows) Add(values ...driver.Value) *Rows {
+	r.values = append(r.values, values)
+	return r
+}
+
+// Row returns a single row result, for queries read with QueryRow.
+func Row(values ...driver.Value) *Rows {
+	return (&Rows{}).Add(values...)
+}
+
+func (r *Rows) Columns() []string {
+	if r.columns == nil && len(r.values) > 0 {
+		return make([]string, len(r.values[0]))
+	}
+	return r.columns
+}
+func (r *Rows) Close() error { return nil }
+func (r *Rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// normalize collapses runs of whitespace to single spaces
+func normalize(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// namedValues returns the values of the arguments
+func namedValues(args []driver.NamedValue) []driver.Value {
+	out := make([]driver.Value, len(args))
+	for i, a := range args {
+		out[i] = a.Value
+	}
+	return out
+}
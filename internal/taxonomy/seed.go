@@ -0,0 +1,76 @@
+package taxonomy
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+//go:embed seed.json
+var defaultSeed []byte
+
+// SeedSkill is a skill of a seed file, which lists skills by slug
+type SeedSkill struct {
+	Slug    string   `json:"slug"`
+	Name    string   `json:"name"`
+	Parent  string   `json:"parent,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// DefaultSeed returns the skills bundled with the API.
+func DefaultSeed() ([]SeedSkill, error) {
+	return ParseSeed(bytes.NewReader(defaultSeed))
+}
+
+// ParseSeed reads a seed file: a JSON array of skills, each with a slug, a name, the slug of its parent and its
+// aliases. Parents must be in the file too, there must be no cycles and an alias can only name one skill.
+func ParseSeed(r io.Reader) ([]SeedSkill, error) {
+	var seed []SeedSkill
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&seed); err != nil {
+		return nil, fmt.Errorf("parse skills seed: %w", err)
+	}
+
+	parents := map[string]string{}
+	for _, s := range seed {
+		if !ValidSlug(s.Slug) {
+			return nil, fmt.Errorf("parse skills seed: invalid slug %q", s.Slug)
+		}
+		if _, ok := parents[s.Slug]; ok {
+			return nil, fmt.Errorf("parse skills seed: skill %q is listed twice", s.Slug)
+		}
+		if s.Name == "" {
+			return nil, fmt.Errorf("parse skills seed: skill %q has no name", s.Slug)
+		}
+		parents[s.Slug] = s.Parent
+	}
+	aliases := map[string]string{}
+	for i, s := range seed {
+		if _, ok := parents[s.Parent]; s.Parent != "" && !ok {
+			return nil, fmt.Errorf("parse skills seed: skill %q has unknown parent %q", s.Slug, s.Parent)
+		}
+		// Walking up from the skill must reach the top within as many steps as there are skills
+		p := s.Parent
+		for n := 0; p != ""; n++ {
+			if p == s.Slug || n > len(seed) {
+				return nil, fmt.Errorf("parse skills seed: skill %q is its own ancestor", s.Slug)
+			}
+			p = parents[p]
+		}
+		for j, a := range s.Aliases {
+			a = Normalize(a)
+			if a == "" {
+				return nil, fmt.Errorf("parse skills seed: skill %q has an empty alias", s.Slug)
+			}
+			if other, ok := aliases[a]; ok && other != s.Slug {
+				return nil, fmt.Errorf("parse skills seed: alias %q names both %q and %q", a, other, s.Slug)
+			}
+			aliases[a] = s.Slug
+			seed[i].Aliases[j] = a
+		}
+	}
+	return seed, nil
+}
@@ -0,0 +1,152 @@
+// Package taxonomy normalizes the skills named in free text, such as job titles, to canonical skills.
+//
+// Every skill has a slug, a display name, aliases and an optional parent category, so "golang dev", "Go Engineer"
+// and "go developer" all name the skill go, which belongs to programming-languages, which belongs to
+// software-development.
+package taxonomy
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Skill is a canonical skill or category
+type Skill struct {
+	ID       int    // ID is the skill's database ID
+	Slug     string // Slug is the unique lower case key of the skill, such as "machine-learning"
+	Name     string // Name is the display name, such as "Machine Learning"
+	ParentID int    // ParentID is the category the skill belongs to, 0 for top level skills
+}
+
+// Alias is another name of a skill
+type Alias struct {
+	Alias   string // Alias is the name, normalized with Normalize
+	SkillID int    // SkillID is the skill it names
+}
+
+// Taxonomy holds the skills and their names
+type Taxonomy struct {
+	skills   map[int]Skill
+	bySlug   map[string]int
+	names    map[string]int
+	children map[int][]int
+	maxWords int
+}
+
+// slugPattern is the shape of a skill slug: lower case letters and digits, with single dashes between words
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ValidSlug reports whether s can be the slug of a skill
+func ValidSlug(s string) bool {
+	return len(s) <= 60 && slugPattern.MatchString(s)
+}
+
+// Words returns the lower case words of a text, split on anything but letters, digits, "+" and "#" so that
+// "C++" and "C#" keep their meaning. "Node.js" gives the words "node" and "js".
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(norm.NFKC.String(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#'
+	})
+}
+
+// Normalize returns the words of a name joined by single spaces, the form aliases are stored and matched in
+func Normalize(name string) string {
+	return strings.Join(Words(name), " ")
+}
+
+// New builds a taxonomy from the skills and their aliases. The slug and the name of every skill are aliases
+// of it as well. Aliases of unknown skills are ignored.
+func New(skills []Skill, aliases []Alias) *Taxonomy {
+	t := &Taxonomy{
+		skills:   map[int]Skill{},
+		bySlug:   map[string]int{},
+		names:    map[string]int{},
+		children: map[int][]int{},
+	}
+	for _, s := range skills {
+		t.skills[s.ID] = s
+		t.bySlug[s.Slug] = s.ID
+	}
+	for _, s := range skills {
+		if _, ok := t.skills[s.ParentID]; ok {
+			t.children[s.ParentID] = append(t.children[s.ParentID], s.ID)
+		}
+		t.addName(Normalize(s.Slug), s.ID)
+		t.addName(Normalize(s.Name), s.ID)
+	}
+	// Curated aliases win over the names derived from slugs
+	for _, a := range aliases {
+		if _, ok := t.skills[a.SkillID]; ok && a.Alias != "" {
+			t.names[a.Alias] = a.SkillID
+			t.maxWords = max(t.maxWords, len(strings.Fields(a.Alias)))
+		}
+	}
+	return t
+}
+
+// addName adds a derived name of a skill, unless another skill has it
+func (t *Taxonomy) addName(name string, id int) {
+	if _, ok := t.names[name]; ok || name == "" {
+		return
+	}
+	t.names[name] = id
+	t.maxWords = max(t.maxWords, len(strings.Fields(name)))
+}
+
+// Len returns the number of skills
+func (t *Taxonomy) Len() int {
+	return len(t.skills)
+}
+
+// Resolve returns the skill with the slug, or else the skill the name is an alias of
+func (t *Taxonomy) Resolve(name string) (Skill, bool) {
+	if id, ok := t.bySlug[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return t.skills[id], true
+	}
+	id, ok := t.names[Normalize(name)]
+	return t.skills[id], ok
+}
+
+// Descendants returns the IDs of the skill and of all the skills below it
+func (t *Taxonomy) Descendants(id int) []int {
+	seen := map[int]bool{id: true}
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range t.children[ids[i]] {
+			// Edits in the database could make a cycle, which is walked once
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// Extract returns the skills named in a text, sorted by slug. Longer names are preferred, so "machine learning
+// engineer" is machine-learning rather than a skill named "machine".
+func (t *Taxonomy) Extract(text string) []Skill {
+	words := Words(text)
+	found := map[int]bool{}
+	for i := 0; i < len(words); {
+		n := min(t.maxWords, len(words)-i)
+		for ; n > 0; n-- {
+			if id, ok := t.names[strings.Join(words[i:i+n], " ")]; ok {
+				found[id] = true
+				break
+			}
+		}
+		i += max(n, 1)
+	}
+
+	skills := make([]Skill, 0, len(found))
+	for id := range found {
+		skills = append(skills, t.skills[id])
+	}
+	sort.Slice(skills, func(a, b int) bool { return skills[a].Slug < skills[b].Slug })
+	return skills
+}
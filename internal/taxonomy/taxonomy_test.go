@@ -0,0 +1,204 @@
+package taxonomy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testTaxonomy returns software-development > programming-languages > go, plus machine-learning and a skill named
+// "Machine" that a curated alias of go takes a name from
+func testTaxonomy() *Taxonomy {
+	return New([]Skill{
+		{ID: 1, Slug: "software-development", Name: "Software Development"},
+		{ID: 2, Slug: "programming-languages", Name: "Programming Languages", ParentID: 1},
+		{ID: 3, Slug: "go", Name: "Go", ParentID: 2},
+		{ID: 4, Slug: "machine-learning", Name: "Machine Learning"},
+		{ID: 5, Slug: "machine", Name: "Machine"},
+		{ID: 6, Slug: "c-sharp", Name: "C#", ParentID: 2},
+		{ID: 7, Slug: "node-js", Name: "Node.js"},
+	}, []Alias{
+		{Alias: "golang", SkillID: 3},
+		{Alias: "go developer", SkillID: 3},
+		{Alias: "ml", SkillID: 4},
+		{Alias: "programming languages", SkillID: 3},
+		{Alias: "orphan", SkillID: 99},
+		{Alias: "", SkillID: 3},
+	})
+}
+
+func TestValidSlug(t *testing.T) {
+	tests := []struct {
+		slug string
+		want bool
+	}{
+		{"go", true},
+		{"machine-learning", true},
+		{"web3", true},
+		{"a-b-c", true},
+		{strings.Repeat("a", 60), true},
+		{strings.Repeat("a", 61), false},
+		{"", false},
+		{"Go", false},
+		{"-go", false},
+		{"go-", false},
+		{"machine--learning", false},
+		{"machine_learning", false},
+		{"c#", false},
+		{"café", false},
+	}
+	for _, tt := range tests {
+		if got := ValidSlug(tt.slug); got != tt.want {
+			t.Errorf("ValidSlug(%q) = %v, want %v", tt.slug, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Go", "go"},
+		{"  Machine   Learning ", "machine learning"},
+		{"machine-learning", "machine learning"},
+		{"Node.js", "node js"},
+		{"C++", "c++"},
+		{"C#", "c#"},
+		{"Sr. Go/Python Dev!", "sr go python dev"},
+		{"ＧＯＬＡＮＧ", "golang"},
+		{"Ｃ＃", "c#"},
+		{"Künstliche Intelligenz", "künstliche intelligenz"},
+		{"---", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.name); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tx := testTaxonomy()
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"go", "go"},
+		{" GO ", "go"},
+		{"golang", "go"},
+		{"GoLang", "go"},
+		{"Go Developer", "go"},
+		{"go   developer", "go"},
+		{"machine-learning", "machine-learning"},
+		{"Machine Learning", "machine-learning"},
+		{"ML", "machine-learning"},
+		{"c#", "c-sharp"},
+		{"C Sharp", "c-sharp"},
+		{"node.js", "node-js"},
+		{"Node JS", "node-js"},
+		// A slug wins over a curated alias, which wins over a name derived from the slug
+		{"programming-languages", "programming-languages"},
+		{"Programming Languages", "go"},
+		{"orphan", ""},
+		{"", ""},
+		{"rust", ""},
+	}
+	for _, tt := range tests {
+		s, ok := tx.Resolve(tt.name)
+		if ok != (tt.want != "") || s.Slug != tt.want {
+			t.Errorf("Resolve(%q) = %q, %v, want %q", tt.name, s.Slug, ok, tt.want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tx := testTaxonomy()
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Senior Golang Engineer", []string{"go"}},
+		{"Machine Learning Engineer", []string{"machine-learning"}},
+		{"Machine operator", []string{"machine"}},
+		{"Go developer with ML and C#", []string{"c-sharp", "go", "machine-learning"}},
+		{"Node.js backend", []string{"node-js"}},
+		{"Golang, golang and GO", []string{"go"}},
+		{"Gopher", []string{}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, s := range tx.Extract(tt.text) {
+			got = append(got, s.Slug)
+		}
+		if len(got)+len(tt.want) > 0 && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Extract(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDescendants(t *testing.T) {
+	tx := testTaxonomy()
+	if got := tx.Descendants(1); !reflect.DeepEqual(got, []int{1, 2, 3, 6}) {
+		t.Errorf("Descendants(1) = %v, want [1 2 3 6]", got)
+	}
+	if got := tx.Descendants(3); !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("Descendants(3) = %v, want [3]", got)
+	}
+
+	cycle := New([]Skill{{ID: 1, Slug: "a", Name: "A", ParentID: 2}, {ID: 2, Slug: "b", Name: "B", ParentID: 1}}, nil)
+	if got := cycle.Descendants(1); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("Descendants(1) of a cycle = %v, want [1 2]", got)
+	}
+}
+
+func TestParseSeed(t *testing.T) {
+	seed, err := ParseSeed(strings.NewReader(`[
+		{"slug": "software-development", "name": "Software Development"},
+		{"slug": "go", "name": "Go", "parent": "software-development", "aliases": ["GoLang", "Go  Developer", "golang"]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"golang", "go developer", "golang"}; !reflect.DeepEqual(seed[1].Aliases, want) {
+		t.Errorf("ParseSeed() aliases = %q, want %q", seed[1].Aliases, want)
+	}
+
+	tests := []struct {
+		name string
+		seed string
+		want string
+	}{
+		{"malformed", `{`, "parse skills seed"},
+		{"unknown field", `[{"slug": "go", "name": "Go", "level": 1}]`, "unknown field"},
+		{"invalid slug", `[{"slug": "Go Lang", "name": "Go"}]`, `invalid slug "Go Lang"`},
+		{"listed twice", `[{"slug": "go", "name": "Go"}, {"slug": "go", "name": "Golang"}]`, `skill "go" is listed twice`},
+		{"no name", `[{"slug": "go"}]`, `skill "go" has no name`},
+		{"unknown parent", `[{"slug": "go", "name": "Go", "parent": "languages"}]`, `unknown parent "languages"`},
+		{"own parent", `[{"slug": "go", "name": "Go", "parent": "go"}]`, `skill "go" is its own ancestor`},
+		{"cycle", `[{"slug": "a", "name": "A", "parent": "b"}, {"slug": "b", "name": "B", "parent": "a"}]`, "is its own ancestor"},
+		{"empty alias", `[{"slug": "go", "name": "Go", "aliases": ["--"]}]`, `skill "go" has an empty alias`},
+		{"alias of two skills", `[{"slug": "go", "name": "Go", "aliases": ["Golang"]}, {"slug": "rust", "name": "Rust", "aliases": ["golang"]}]`,
+			`alias "golang" names both "go" and "rust"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSeed(strings.NewReader(tt.seed))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseSeed() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultSeed(t *testing.T) {
+	seed, err := DefaultSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seed) == 0 {
+		t.Fatal("DefaultSeed() is empty")
+	}
+}
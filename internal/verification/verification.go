@@ -0,0 +1,107 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Methods of proving control of a company domain
+const (
+	MethodDNS   = "dns"   // MethodDNS is a TXT record published on the domain
+	MethodEmail = "email" // MethodEmail is a code sent to an address at the domain
+)
+
+// recordPrefix is the label under which the DNS TXT record is looked up, so it does not clash with the
+// domain's own TXT records such as SPF
+const recordPrefix = "_job-portal-verification."
+
+// valuePrefix starts the value of the DNS TXT record
+const valuePrefix = "job-portal-verification="
+
+// Resolver looks up DNS TXT records. *net.Resolver implements it; tests can provide fixed answers.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DefaultResolver returns the system DNS resolver.
+func DefaultResolver() Resolver {
+	return net.DefaultResolver
+}
+
+// ErrInvalidDomain is returned for values that are not a registrable host name
+var ErrInvalidDomain = errors.New("invalid domain")
+
+// ErrFreeMailDomain is returned for email addresses at public mailbox providers, which prove nothing about a company
+var ErrFreeMailDomain = errors.New("email must be at the company's own domain")
+
+// freeMailDomains are public mailbox providers that cannot be used for email verification
+var freeMailDomains = map[string]bool{
+	"gmail.com": true, "googlemail.com": true, "outlook.com": true, "hotmail.com": true, "live.com": true,
+	"yahoo.com": true, "icloud.com": true, "me.com": true, "aol.com": true, "proton.me": true,
+	"protonmail.com": true, "gmx.com": true, "mail.com": true, "yandex.com": true, "zoho.com": true,
+}
+
+// NormalizeDomain lower-cases a domain, converts it to its ASCII form and checks it has at least two labels.
+// A pasted URL such as "https://www.example.com/" is reduced to its host name.
+func NormalizeDomain(domain string) (string, error) {
+	d := strings.TrimSpace(strings.ToLower(domain))
+	d = strings.TrimPrefix(strings.TrimPrefix(d, "https://"), "http://")
+	d = strings.TrimSuffix(strings.SplitN(d, "/", 2)[0], ".")
+	d, err := idna.Lookup.ToASCII(d)
+	if err != nil || !strings.Contains(d, ".") || len(d) > 253 {
+		return "", ErrInvalidDomain
+	}
+	return d, nil
+}
+
+// EmailDomain returns the normalized domain of a company email address.
+func EmailDomain(email string) (string, error) {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != strings.TrimSpace(email) {
+		return "", errors.New("invalid email address")
+	}
+	at := strings.LastIndex(addr.Address, "@")
+	domain, err := NormalizeDomain(addr.Address[at+1:])
+	if err != nil {
+		return "", err
+	}
+	if freeMailDomains[domain] {
+		return "", ErrFreeMailDomain
+	}
+	return domain, nil
+}
+
+// RecordName returns the name of the TXT record proving control of the domain.
+func RecordName(domain string) string {
+	return recordPrefix + domain
+}
+
+// RecordValue returns the value of the TXT record for a verification token.
+func RecordValue(token string) string {
+	return valuePrefix + token
+}
+
+// CheckTXT reports whether the TXT record of the domain carries the token.
+// A missing record is not an error, only a failed lookup is.
+func CheckTXT(ctx context.Context, r Resolver, domain, token string) (bool, error) {
+	records, err := r.LookupTXT(ctx, RecordName(domain))
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	want := RecordValue(token)
+	for _, record := range records {
+		if strings.TrimSpace(record) == want {
+			return true, nil
+		}
+	}
+	return false, nil
+}
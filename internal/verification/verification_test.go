@@ -0,0 +1,108 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeResolver answers TXT lookups from a fixed map, failing with err when it is set
+type fakeResolver struct {
+	records map[string][]string
+	err     error
+}
+
+func (r fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	records, ok := r.records[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		in, want string
+		err      error
+	}{
+		{"example.com", "example.com", nil},
+		{"  Example.COM ", "example.com", nil},
+		{"https://www.example.com/careers?x=1", "www.example.com", nil},
+		{"http://example.com", "example.com", nil},
+		{"example.com.", "example.com", nil},
+		{"bücher.de", "xn--bcher-kva.de", nil},
+		{"localhost", "", ErrInvalidDomain},
+		{"", "", ErrInvalidDomain},
+		{"https://", "", ErrInvalidDomain},
+		{strings.Repeat("a.", 127) + "com", "", ErrInvalidDomain},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeDomain(tt.in)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("NormalizeDomain(%q) = %q, %v, want %q, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestEmailDomain(t *testing.T) {
+	tests := []struct {
+		in, want string
+		err      error
+	}{
+		{"hr@example.com", "example.com", nil},
+		{"jobs@Careers.Example.COM", "careers.example.com", nil},
+		{"someone@gmail.com", "", ErrFreeMailDomain},
+		{"someone@GMAIL.com", "", ErrFreeMailDomain},
+		{"hr@localhost", "", ErrInvalidDomain},
+	}
+	for _, tt := range tests {
+		got, err := EmailDomain(tt.in)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("EmailDomain(%q) = %q, %v, want %q, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+
+	for _, in := range []string{"", "not an email", "HR <hr@example.com>", "hr@example.com extra"} {
+		if got, err := EmailDomain(in); err == nil {
+			t.Errorf("EmailDomain(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	if got := RecordName("example.com"); got != "_job-portal-verification.example.com" {
+		t.Errorf("RecordName() = %q", got)
+	}
+	if got := RecordValue("abc"); got != "job-portal-verification=abc" {
+		t.Errorf("RecordValue() = %q", got)
+	}
+}
+
+func TestCheckTXT(t *testing.T) {
+	name := RecordName("example.com")
+	lookupErr := &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	tests := []struct {
+		desc     string
+		resolver fakeResolver
+		want     bool
+		err      error
+	}{
+		{"published", fakeResolver{records: map[string][]string{name: {"v=spf1 -all", RecordValue("tok")}}}, true, nil},
+		{"padded", fakeResolver{records: map[string][]string{name: {" " + RecordValue("tok") + " "}}}, true, nil},
+		{"other token", fakeResolver{records: map[string][]string{name: {RecordValue("other")}}}, false, nil},
+		{"token without prefix", fakeResolver{records: map[string][]string{name: {"tok"}}}, false, nil},
+		{"on the domain itself", fakeResolver{records: map[string][]string{"example.com": {RecordValue("tok")}}}, false, nil},
+		{"lookup failure", fakeResolver{err: lookupErr}, false, lookupErr},
+	}
+	for _, tt := range tests {
+		got, err := CheckTXT(context.Background(), tt.resolver, "example.com", "tok")
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("CheckTXT(%s) = %t, %v, want %t, %v", tt.desc, got, err, tt.want, tt.err)
+		}
+	}
+}
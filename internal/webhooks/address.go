@@ -0,0 +1,65 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrForbiddenAddress is returned for endpoints on addresses of the internal network, which webhooks must not
+// be able to reach or probe
+var ErrForbiddenAddress = errors.New("webhook endpoints cannot be on loopback, private, link-local or unspecified addresses")
+
+// ErrInvalidURL is returned for endpoints that are not absolute http or https URLs
+var ErrInvalidURL = errors.New("webhook endpoints must be absolute http or https URLs")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), internal to providers
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// CheckAddr returns ErrForbiddenAddress for loopback, private, link-local, multicast and unspecified addresses,
+// including IPv4 addresses mapped into IPv6
+func CheckAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() || sharedAddressSpace.Contains(addr) || (addr.Is4() && addr.As4()[0] == 0) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+	}
+	return nil
+}
+
+// CheckURL checks that an endpoint is an http or https URL whose host resolves only to public addresses. The
+// sender checks the address it connects to again, since DNS answers can change after registration.
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if err := CheckAddr(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dialControl returns a net.Dialer Control hook refusing connections to addresses rejected by check. It runs
+// after name resolution, for every address dialed, so a host cannot resolve to a public address at registration
+// and to an internal one at delivery.
+func dialControl(check func(netip.Addr) error) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		ap, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+		}
+		return check(ap.Addr())
+	}
+}
@@ -0,0 +1,268 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+// DispatcherConfig controls delivery retries
+type DispatcherConfig struct {
+	MaxAttempts int           // MaxAttempts before a delivery is moved to the dead-letter state
+	BaseBackoff time.Duration // BaseBackoff is the delay before the first retry; it doubles on every retry
+	MaxBackoff  time.Duration // MaxBackoff caps the retry delay
+	BatchSize   int           // BatchSize is the number of events or deliveries claimed per poll
+	Lease       time.Duration // Lease is how long a claimed delivery is hidden from other dispatchers
+	Retention   time.Duration // Retention is how long dispatched outbox events are kept
+}
+
+// DefaultDispatcherConfig returns the default retry policy, about a day of retries in total, keeping dispatched
+// outbox events for OUTBOX_RETENTION_DAYS (default 30)
+func DefaultDispatcherConfig() DispatcherConfig {
+	cfg := DispatcherConfig{
+		MaxAttempts: 10,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  6 * time.Hour,
+		BatchSize:   50,
+		Lease:       2 * time.Minute,
+		Retention:   30 * 24 * time.Hour,
+	}
+	if days, err := strconv.Atoi(os.Getenv("OUTBOX_RETENTION_DAYS")); err == nil && days > 0 {
+		cfg.Retention = time.Duration(days) * 24 * time.Hour
+	}
+	return cfg
+}
+
+// Dispatcher moves outbox events into per-endpoint deliveries and delivers them with retries.
+// Several instances can run at once; rows are claimed with SKIP LOCKED.
+type Dispatcher struct {
+	db     *sql.DB
+	sender *Sender
+	cfg    DispatcherConfig
+}
+
+// NewDispatcher creates a new Dispatcher.
+func NewDispatcher(db *sql.DB, sender *Sender, cfg DispatcherConfig) (*Dispatcher, error) {
+	if db == nil || sender == nil {
+		return nil, errors.New("db connection and sender cannot be nil")
+	}
+	if cfg.MaxAttempts <= 0 || cfg.BatchSize <= 0 || cfg.Retention <= 0 {
+		return nil, errors.New("max attempts, batch size and retention must be positive")
+	}
+	return &Dispatcher{db: db, sender: sender, cfg: cfg}, nil
+}
+
+// Run polls for work every interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.FanOut(ctx); err != nil {
+			log.Error().Err(err).Send()
+		}
+		if _, err := d.DeliverDue(ctx); err != nil {
+			log.Error().Err(err).Send()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// FanOut creates a delivery for every active endpoint subscribed to each undispatched outbox event,
+// and marks the events dispatched, in one transaction.
+func (d *Dispatcher) FanOut(ctx context.Context) (int, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("fan out events: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM outbox_events WHERE dispatched_at IS NULL
+		ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, d.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("fan out events: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("fan out events: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("fan out events: %w", err)
+	}
+
+	for _, id := range ids {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload)
+			SELECT w.id, e.id, e.event_type, e.payload
+			FROM outbox_events e
+			JOIN webhook_endpoints w ON w.company_id = e.company_id
+			WHERE e.id = $1 AND w.active
+			  AND (cardinality(w.event_types) = 0 OR e.event_type = ANY (w.event_types))`, id)
+		if err != nil {
+			return 0, fmt.Errorf("fan out event %d: %w", id, err)
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE outbox_events SET dispatched_at = now() WHERE id = $1", id)
+		if err != nil {
+			return 0, fmt.Errorf("fan out event %d: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("fan out events: %w", err)
+	}
+	return len(ids), nil
+}
+
+// claimed is a delivery claimed for sending
+type claimed struct {
+	id       int64
+	attempts int
+	url      string
+	secret   string
+	env      Envelope
+}
+
+// DeliverDue claims pending deliveries of active endpoints whose next attempt is due and sends them. Deliveries
+// of deactivated endpoints stay pending until the endpoint is active again.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	// Claim a batch by pushing its next attempt past the lease, so other dispatchers skip it while we send. The
+	// event may have been purged since, for replayed deliveries, so the delivery is dated by its own creation then.
+	rows, err := d.db.QueryContext(ctx, `
+		UPDATE webhook_deliveries wd SET next_attempt_at = now() + make_interval(secs => $2)
+		FROM webhook_endpoints w
+		WHERE wd.id IN (
+			SELECT pd.id FROM webhook_deliveries pd JOIN webhook_endpoints pw ON pw.id = pd.endpoint_id
+			WHERE pd.status = 'pending' AND pd.next_attempt_at <= now() AND pw.active
+			ORDER BY pd.next_attempt_at LIMIT $1 FOR UPDATE OF pd SKIP LOCKED)
+		  AND w.id = wd.endpoint_id AND w.active
+		RETURNING wd.id, wd.attempts, w.url, w.secret, wd.event_id, wd.event_type,
+			COALESCE((SELECT e.created_at FROM outbox_events e WHERE e.id = wd.event_id), wd.created_at), wd.payload`,
+		d.cfg.BatchSize, d.cfg.Lease.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("claim deliveries: %w", err)
+	}
+	var batch []claimed
+	for rows.Next() {
+		var c claimed
+		var payload []byte
+		if err := rows.Scan(&c.id, &c.attempts, &c.url, &c.secret, &c.env.ID, &c.env.Type, &c.env.OccurredAt, &payload); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("claim deliveries: %w", err)
+		}
+		c.env.Data = payload
+		batch = append(batch, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("claim deliveries: %w", err)
+	}
+
+	for _, c := range batch {
+		code, sendErr := d.sender.Send(ctx, c.url, c.secret, c.id, c.env)
+		if err := d.recordAttempt(ctx, c, code, sendErr); err != nil {
+			log.Error().Err(err).Int64("delivery_id", c.id).Send()
+		}
+	}
+	return len(batch), nil
+}
+
+// PurgeEvents deletes the outbox events dispatched longer than the retention ago, except those with a delivery
+// still pending. Deliveries keep their own copy of the payload, so they can still be listed and replayed.
+func (d *Dispatcher) PurgeEvents(ctx context.Context) (int64, error) {
+	res, err := d.db.ExecContext(ctx, `
+		DELETE FROM outbox_events e
+		WHERE e.dispatched_at < $1
+		  AND NOT EXISTS (SELECT 1 FROM webhook_deliveries wd WHERE wd.event_id = e.id AND wd.status = 'pending')`,
+		time.Now().Add(-d.cfg.Retention))
+	if err != nil {
+		return 0, fmt.Errorf("purge outbox events: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// RunRetention purges dispatched outbox events past the retention every interval until ctx is cancelled.
+func (d *Dispatcher) RunRetention(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := d.PurgeEvents(ctx)
+		if err != nil {
+			log.Error().Err(err).Send()
+		} else if n > 0 {
+			log.Info().Int64("deleted", n).Msg("purged dispatched outbox events")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordAttempt stores the outcome of a delivery attempt, scheduling a retry with exponential
+// backoff or moving the delivery to the dead-letter state once attempts are exhausted.
+func (d *Dispatcher) recordAttempt(ctx context.Context, c claimed, code int, sendErr error) error {
+	var statusCode interface{}
+	if code != 0 {
+		statusCode = code
+	}
+	attempts := c.attempts + 1
+
+	var err error
+	switch {
+	case sendErr == nil:
+		_, err = d.db.ExecContext(ctx, `
+			UPDATE webhook_deliveries SET status = $1, attempts = $2, last_status_code = $3, last_error = NULL, delivered_at = now()
+			WHERE id = $4`, StatusSucceeded, attempts, statusCode, c.id)
+	case attempts >= d.cfg.MaxAttempts:
+		_, err = d.db.ExecContext(ctx, `
+			UPDATE webhook_deliveries SET status = $1, attempts = $2, last_status_code = $3, last_error = $4
+			WHERE id = $5`, StatusDead, attempts, statusCode, sendErr.Error(), c.id)
+	default:
+		_, err = d.db.ExecContext(ctx, `
+			UPDATE webhook_deliveries SET attempts = $1, last_status_code = $2, last_error = $3, next_attempt_at = $4
+			WHERE id = $5`, attempts, statusCode, sendErr.Error(), time.Now().Add(d.backoff(attempts)), c.id)
+	}
+	if err != nil {
+		return fmt.Errorf("record delivery attempt: %w", err)
+	}
+	return nil
+}
+
+// backoff returns the delay before the given retry, doubling each time with up to 20% jitter.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.BaseBackoff
+	for i := 1; i < attempt && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.cfg.MaxBackoff {
+		delay = d.cfg.MaxBackoff
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
@@ -0,0 +1,157 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"job-portal-api/internal/sqltest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestDispatcher returns a dispatcher on a sqltest database, delivering to loopback
+func newTestDispatcher(t *testing.T, cfg DispatcherConfig) (*Dispatcher, *sqltest.DB) {
+	t.Helper()
+	db, fake := sqltest.Open(t)
+	d, err := NewDispatcher(db, newSender(time.Second, allowAll), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, fake
+}
+
+// claim returns a claimed delivery row for DeliverDue
+func claim(id int64, attempts int64, url string) []driver.Value {
+	return []driver.Value{id, attempts, url, "whsec_test", id * 10, "job.created", time.Unix(1700000000, 0), []byte(`{"jobId":1}`)}
+}
+
+func TestFanOut(t *testing.T) {
+	d, fake := newTestDispatcher(t, DefaultDispatcherConfig())
+	fake.Query("FROM outbox_events WHERE dispatched_at IS NULL", func([]driver.Value) (*sqltest.Rows, error) {
+		return sqltest.NewRows("id").Add(int64(3)).Add(int64(4)), nil
+	})
+
+	n, err := d.FanOut(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("FanOut() = %d, %v, want 2 events", n, err)
+	}
+	inserts := fake.Execs("INSERT INTO webhook_deliveries")
+	dispatched := fake.Execs("SET dispatched_at = now()")
+	if len(inserts) != 2 || len(dispatched) != 2 {
+		t.Fatalf("FanOut() ran %d inserts and %d updates, want 2 of each", len(inserts), len(dispatched))
+	}
+	for i, id := range []int64{3, 4} {
+		if inserts[i].Args[0] != id || dispatched[i].Args[0] != id {
+			t.Errorf("event %d fanned out as %v and %v", id, inserts[i].Args, dispatched[i].Args)
+		}
+	}
+	if fake.Commits() != 1 {
+		t.Errorf("FanOut() committed %d transactions, want 1", fake.Commits())
+	}
+}
+
+func TestDeliverDue(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		verify(t, "whsec_test", r.Header.Get(SignatureHeader), body)
+		if strings.HasSuffix(r.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cfg := DispatcherConfig{MaxAttempts: 3, BaseBackoff: time.Minute, MaxBackoff: time.Hour, BatchSize: 10, Lease: time.Minute, Retention: time.Hour}
+	d, fake := newTestDispatcher(t, cfg)
+	// Only deliveries of active endpoints are claimed, in the batch and when updating it
+	fake.Query("UPDATE webhook_deliveries wd", func([]driver.Value) (*sqltest.Rows, error) {
+		return nil, errors.New("claimed without checking the endpoint is active")
+	})
+	fake.Query("AND pw.active ORDER BY pd.next_attempt_at LIMIT $1 FOR UPDATE OF pd SKIP LOCKED) AND w.id = wd.endpoint_id AND w.active", func([]driver.Value) (*sqltest.Rows, error) {
+		rows := sqltest.NewRows("id", "attempts", "url", "secret", "event_id", "event_type", "created_at", "payload")
+		rows.Add(claim(1, 0, srv.URL+"/ok")...)   // delivered
+		rows.Add(claim(2, 0, srv.URL+"/fail")...) // first failure, retried
+		rows.Add(claim(3, 2, srv.URL+"/fail")...) // last attempt, dead-lettered
+		return rows, nil
+	})
+
+	start := time.Now()
+	n, err := d.DeliverDue(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("DeliverDue() = %d, %v, want 3 deliveries", n, err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("endpoint called %d times, want 3", calls.Load())
+	}
+
+	succeeded := fake.Execs("delivered_at = now()")
+	if len(succeeded) != 1 || succeeded[0].Args[0] != StatusSucceeded || succeeded[0].Args[1] != int64(1) ||
+		succeeded[0].Args[2] != int64(200) || succeeded[0].Args[3] != int64(1) {
+		t.Errorf("succeeded delivery recorded as %v", succeeded)
+	}
+
+	retried := fake.Execs("next_attempt_at = $4")
+	if len(retried) != 1 || retried[0].Args[0] != int64(1) || retried[0].Args[1] != int64(503) || retried[0].Args[4] != int64(2) {
+		t.Fatalf("retried delivery recorded as %v", retried)
+	}
+	next := retried[0].Args[3].(time.Time)
+	if delay := next.Sub(start); delay < cfg.BaseBackoff || delay > cfg.BaseBackoff*6/5+time.Second {
+		t.Errorf("first retry after %s, want %s with up to 20%% jitter", delay, cfg.BaseBackoff)
+	}
+
+	dead := fake.Execs("last_error = $4")
+	if len(dead) != 1 || dead[0].Args[0] != StatusDead || dead[0].Args[1] != int64(3) || dead[0].Args[4] != int64(3) {
+		t.Errorf("dead delivery recorded as %v", dead)
+	}
+}
+
+func TestPurgeEvents(t *testing.T) {
+	cfg := DefaultDispatcherConfig()
+	cfg.Retention = 48 * time.Hour
+	d, fake := newTestDispatcher(t, cfg)
+
+	start := time.Now()
+	if _, err := d.PurgeEvents(context.Background()); err != nil {
+		t.Fatalf("PurgeEvents() error: %v", err)
+	}
+	purged := fake.Execs("DELETE FROM outbox_events e WHERE e.dispatched_at < $1")
+	if len(purged) != 1 || !strings.Contains(purged[0].Query, "wd.status = 'pending'") {
+		t.Fatalf("PurgeEvents() ran %v, want a delete keeping events with pending deliveries", purged)
+	}
+	if before := purged[0].Args[0].(time.Time); before.Before(start.Add(-cfg.Retention)) || before.After(time.Now().Add(-cfg.Retention)) {
+		t.Errorf("PurgeEvents() deleted events dispatched before %v, want %v", before, start.Add(-cfg.Retention))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{cfg: DispatcherConfig{BaseBackoff: 30 * time.Second, MaxBackoff: 6 * time.Hour}}
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, 6 * time.Hour},
+		{50, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			got := d.backoff(tt.attempt)
+			if got < tt.base || got > tt.base+tt.base/5 {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", tt.attempt, got, tt.base, tt.base+tt.base/5)
+				break
+			}
+		}
+	}
+}
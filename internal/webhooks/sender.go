@@ -0,0 +1,112 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Envelope is the JSON body POSTed to webhook endpoints
+type Envelope struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Sign returns the signature header value for a body: "t=<unix time>,v1=<hex HMAC-SHA256>" where the
+// MAC covers "<unix time>.<body>". Receivers should recompute it and reject old timestamps to stop replays.
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sender POSTs signed events to webhook endpoints.
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender creates a new Sender with the given request timeout. It only connects to public addresses.
+func NewSender(timeout time.Duration) *Sender {
+	return newSender(timeout, CheckAddr)
+}
+
+// newSender creates a new Sender connecting to the addresses accepted by check
+func newSender(timeout time.Duration, check func(netip.Addr) error) *Sender {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second, Control: dialControl(check)}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would dial the endpoint on our behalf, past the address check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Sender{client: &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// Do not follow redirects, endpoints must be registered with their final URL. A redirect towards the
+		// internal network fails the delivery, so that it is not reported as a plain 3xx.
+		CheckRedirect: func(req *http.Request, _ []*http.Request) error {
+			addrs, err := net.DefaultResolver.LookupNetIP(req.Context(), "ip", req.URL.Hostname())
+			if err != nil {
+				return http.ErrUseLastResponse
+			}
+			for _, addr := range addrs {
+				if err := check(addr); err != nil {
+					return fmt.Errorf("redirect to %s: %w", req.URL.Hostname(), err)
+				}
+			}
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Send delivers the envelope to url, signed with secret. It returns the HTTP status code, and an error
+// unless the endpoint answered with a 2xx status.
+func (s *Sender) Send(ctx context.Context, url, secret string, deliveryID int64, env Envelope) (int, error) {
+	body, err := json.Marshal(env)
+	if err != nil {
+		return 0, fmt.Errorf("send webhook: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("send webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "job-portal-api-webhooks/1")
+	req.Header.Set(EventHeader, env.Type)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(deliveryID, 10))
+	req.Header.Set(SignatureHeader, Sign(secret, time.Now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("send webhook: endpoint responded %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,184 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+// allowAll accepts every address, so that tests can deliver to httptest servers on loopback
+func allowAll(netip.Addr) error { return nil }
+
+// verify recomputes the signature of a body the way a receiver does
+func verify(t *testing.T, secret, header string, body []byte) {
+	t.Helper()
+	ts, mac, ok := strings.Cut(header, ",")
+	if !ok || !strings.HasPrefix(ts, "t=") || !strings.HasPrefix(mac, "v1=") {
+		t.Fatalf("signature header %q is not t=<time>,v1=<mac>", header)
+	}
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strings.TrimPrefix(ts, "t=") + "." + string(body)))
+	if want := hex.EncodeToString(h.Sum(nil)); strings.TrimPrefix(mac, "v1=") != want {
+		t.Fatalf("signature %q, want v1=%s", mac, want)
+	}
+}
+
+func TestSign(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	body := []byte(`{"id":1}`)
+	got := Sign("whsec_test", ts, body)
+	if !strings.HasPrefix(got, "t=1700000000,v1=") {
+		t.Fatalf("Sign() = %q, want the unix time first", got)
+	}
+	verify(t, "whsec_test", got, body)
+
+	if Sign("other", ts, body) == got {
+		t.Error("signatures with different secrets are equal")
+	}
+	if Sign("whsec_test", ts.Add(time.Second), body) == got {
+		t.Error("signatures at different times are equal")
+	}
+}
+
+func TestSenderSend(t *testing.T) {
+	env := Envelope{ID: 42, Type: "job.created", OccurredAt: time.Unix(1700000000, 0).UTC(), Data: json.RawMessage(`{"jobId":7}`)}
+	var received Envelope
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verify(t, "whsec_test", r.Header.Get(SignatureHeader), body)
+		if r.Header.Get(EventHeader) != "job.created" || r.Header.Get(DeliveryHeader) != "9" {
+			t.Errorf("headers %v", r.Header)
+		}
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	code, err := newSender(time.Second, allowAll).Send(context.Background(), srv.URL, "whsec_test", 9, env)
+	if err != nil || code != http.StatusAccepted {
+		t.Fatalf("Send() = %d, %v, want 202", code, err)
+	}
+	if received.ID != env.ID || string(received.Data) != string(env.Data) {
+		t.Errorf("received %+v, want %+v", received, env)
+	}
+}
+
+func TestSenderSendFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	env := Envelope{ID: 1, Type: "job.created"}
+
+	code, err := newSender(time.Second, allowAll).Send(context.Background(), srv.URL, "s", 1, env)
+	if err == nil || code != http.StatusInternalServerError {
+		t.Errorf("Send() to a failing endpoint = %d, %v, want 500 and an error", code, err)
+	}
+
+	// The httptest server is on loopback, which the default sender refuses to dial
+	code, err = NewSender(time.Second).Send(context.Background(), srv.URL, "s", 1, env)
+	if !errors.Is(err, ErrForbiddenAddress) || code != 0 {
+		t.Errorf("Send() to loopback = %d, %v, want ErrForbiddenAddress", code, err)
+	}
+
+	// Redirects are not followed, and fail the delivery when they point to the internal network
+	code, err = newSender(time.Second, allowAll).Send(context.Background(), srv.URL+"/redirect?to=http://93.184.216.34/", "s", 1, env)
+	if err == nil || errors.Is(err, ErrForbiddenAddress) || code != http.StatusFound {
+		t.Errorf("Send() redirected to a public address = %d, %v, want an unfollowed 302", code, err)
+	}
+	rejectLinkLocal := func(addr netip.Addr) error {
+		if addr.IsLinkLocalUnicast() {
+			return CheckAddr(addr)
+		}
+		return nil
+	}
+	_, err = newSender(time.Second, rejectLinkLocal).Send(context.Background(), srv.URL+"/redirect?to=http://169.254.169.254/", "s", 1, env)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Send() redirected to the metadata address = %v, want ErrForbiddenAddress", err)
+	}
+}
+
+func TestCheckAddr(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"224.0.0.1":        false,
+		"::1":              false,
+		"::":               false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+		"::ffff:10.0.0.1":  false,
+		"93.184.216.34":    true,
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+	}
+	for addr, allowed := range tests {
+		err := CheckAddr(netip.MustParseAddr(addr))
+		if (err == nil) != allowed {
+			t.Errorf("CheckAddr(%s) = %v, want allowed %t", addr, err, allowed)
+		}
+		if err != nil && !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("CheckAddr(%s) = %v, want ErrForbiddenAddress", addr, err)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"https://93.184.216.34/hooks", nil},
+		{"http://8.8.8.8:8080/hooks", nil},
+		{"http://localhost/hooks", ErrForbiddenAddress},
+		{"http://127.0.0.1:3030/api", ErrForbiddenAddress},
+		{"http://10.0.0.5/", ErrForbiddenAddress},
+		{"http://169.254.169.254/latest/meta-data", ErrForbiddenAddress},
+		{"http://[::1]:80/", ErrForbiddenAddress},
+		{"http://0.0.0.0/", ErrForbiddenAddress},
+		{"ftp://93.184.216.34/", ErrInvalidURL},
+		{"/relative", ErrInvalidURL},
+		{"https://", ErrInvalidURL},
+	}
+	for _, tt := range tests {
+		err := CheckURL(context.Background(), tt.url)
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("CheckURL(%q) = %v, want %v", tt.url, err, tt.want)
+		}
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	control := dialControl(CheckAddr)
+	for address, allowed := range map[string]bool{
+		"93.184.216.34:443": true,
+		"127.0.0.1:80":      false,
+		"[::1]:443":         false,
+		"10.0.0.1:8080":     false,
+		"not-an-address":    false,
+	} {
+		if err := control("tcp", address, nil); (err == nil) != allowed {
+			t.Errorf("dial control of %s = %v, want allowed %t", address, err, allowed)
+		}
+	}
+}